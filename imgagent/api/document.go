@@ -13,12 +13,17 @@ type Document struct {
 	Name            string `json:"name"`
 	FileID          string `json:"file_id"`
 	SummaryImageURL string `json:"summary_image_url"`
+	ThumbnailURL    string `json:"thumbnail_url"`
+	MediumURL       string `json:"medium_url"`
+	Encoding        string `json:"encoding"`
 	Status          string `json:"status"`
 	CreatedAt       string `json:"created_at"`
 	UpdatedAt       string `json:"updated_at"`
+	DeletedAt       string `json:"deleted_at,omitempty"` // 移入回收站的时间，只在回收站列表中返回
 }
 
 type ListDocumentsResult struct {
+	Pagination
 	Documents []Document `json:"documents"`
 }
 
@@ -26,23 +31,68 @@ type Chapter struct {
 	ID         string   `json:"id"`
 	Index      int      `json:"index"`
 	DocumentID string   `json:"document_id"`
+	VolumeID   string   `json:"volume_id"`
 	Title      string   `json:"title"`
-	Content    string   `json:"content"`
+	Kind       string   `json:"kind"`       // 章节类型：chapter、prologue、epilogue、extra
+	Content    string   `json:"content"`    // 展示形式，段落之间以空行分隔
+	Paragraphs []string `json:"paragraphs"` // 章节段落
 	SceneIDs   []string `json:"scene_ids"`
 	CreatedAt  string   `json:"created_at"`
 	UpdatedAt  string   `json:"updated_at"`
+	DeletedAt  string   `json:"deleted_at,omitempty"`
+}
+
+type CreateChapterArgs struct {
+	VolumeID string
+	Title    string
+	Kind     string
+	Content  string
+	Prompt   string
 }
 
 type UpdateChapterArgs struct {
 	Content string `json:"content" binding:"required,max=6000"`
+	Prompt  string `json:"-"`
+}
+
+// DocumentTrashResult 文档回收站中单独删除的章节和场景
+type DocumentTrashResult struct {
+	Chapters []Chapter `json:"chapters"`
+	Scenes   []Scene   `json:"scenes"`
 }
 
 type ListChaptersResult struct {
+	Pagination
 	Chapters []Chapter `json:"chapters"`
 }
 
-type Records struct {
-	ID      string  `json:"id"`
-	Content string  `json:"content"`
-	Score   float32 `json:"score"`
+type Volume struct {
+	ID         string   `json:"id"`
+	Index      int      `json:"index"`
+	DocumentID string   `json:"document_id"`
+	Title      string   `json:"title"`
+	Number     int      `json:"number"`
+	ChapterIDs []string `json:"chapter_ids"`
+	CreatedAt  string   `json:"created_at"`
+	UpdatedAt  string   `json:"updated_at"`
+}
+
+type ListVolumesResult struct {
+	Volumes []Volume `json:"volumes"`
+}
+
+// SplitPreviewChapter 分割预览中的章节
+type SplitPreviewChapter struct {
+	Index   int    `json:"index"`
+	Title   string `json:"title"`
+	Kind    string `json:"kind"`
+	Number  int    `json:"number"`
+	Volume  string `json:"volume"`
+	Length  int    `json:"length"`  // 章节字数
+	Preview string `json:"preview"` // 章节开头的内容
+}
+
+type SplitPreviewResult struct {
+	Encoding string                `json:"encoding"`
+	Chapters []SplitPreviewChapter `json:"chapters"`
 }
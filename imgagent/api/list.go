@@ -0,0 +1,59 @@
+package api
+
+import "time"
+
+const (
+	DefaultListLimit = 100
+	MaxListLimit     = 1000
+
+	OrderAsc  = "asc"
+	OrderDesc = "desc"
+)
+
+// ListArgs 列表接口的分页和排序参数
+// 分页使用不透明的游标，取值为上一页响应中的 next_cursor，为空时从第一页开始
+type ListArgs struct {
+	Cursor string `form:"cursor"`
+	Limit  int    `form:"limit" binding:"omitempty,min=1,max=1000"` // 每页数量，为 0 时使用 DefaultListLimit
+	Order  string `form:"order" binding:"omitempty,oneof=asc desc"` // 排序方向，为空时使用各列表的默认方向
+	Offset int    `form:"-"`                                        // 由 Cursor 解析得到的偏移量
+}
+
+// Pagination 列表响应的分页信息，NextCursor 为空表示没有下一页
+type Pagination struct {
+	Total      int64  `json:"total"`
+	NextCursor string `json:"next_cursor"`
+}
+
+// ListDocumentsArgs 文档列表查询参数，时间格式为 2006-01-02 15:04:05
+type ListDocumentsArgs struct {
+	ListArgs
+	Status        string    `form:"status"`
+	NamePrefix    string    `form:"name_prefix"`
+	CreatedAfter  time.Time `form:"created_after" time_format:"2006-01-02 15:04:05" time_location:"Local"`
+	CreatedBefore time.Time `form:"created_before" time_format:"2006-01-02 15:04:05" time_location:"Local"`
+	Sort          string    `form:"sort" binding:"omitempty,oneof=created_at updated_at name"` // 默认 updated_at 倒序
+}
+
+// ListChaptersArgs 章节列表查询参数
+type ListChaptersArgs struct {
+	ListArgs
+	VolumeID string `form:"volume_id"`
+	Kind     string `form:"kind"`
+	Sort     string `form:"sort" binding:"omitempty,oneof=index created_at updated_at"` // 默认 index 正序
+}
+
+// ListScenesArgs 场景列表查询参数
+type ListScenesArgs struct {
+	ListArgs
+	ChapterID string `form:"chapter_id"`
+	Sort      string `form:"sort" binding:"omitempty,oneof=index created_at updated_at"` // 默认按章节 id、场景序号正序
+}
+
+// ListRolesArgs 角色列表查询参数
+type ListRolesArgs struct {
+	ListArgs
+	VolumeID   string `form:"volume_id"`
+	NamePrefix string `form:"name_prefix"`
+	Sort       string `form:"sort" binding:"omitempty,oneof=created_at name"` // 默认 created_at 正序
+}
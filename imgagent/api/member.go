@@ -0,0 +1,28 @@
+package api
+
+// 文档成员的角色，权限从低到高，高的角色包含低的角色的权限
+const (
+	MemberRoleViewer = "viewer" // 查看文档
+	MemberRoleEditor = "editor" // 编辑文档、章节、场景和角色
+	MemberRoleOwner  = "owner"  // 删除和恢复文档，管理成员
+)
+
+// AddMemberArgs 邀请成员请求参数，用户已是成员时修改角色
+type AddMemberArgs struct {
+	UserID int64  `json:"user_id" binding:"required"`
+	Role   string `json:"role" binding:"required,oneof=viewer editor owner"`
+}
+
+// Member 文档成员，文档创建者不在成员表中，角色为 owner
+type Member struct {
+	UserID    int64  `json:"user_id"`
+	Username  string `json:"username"`
+	Role      string `json:"role"`
+	Creator   bool   `json:"creator"` // 是否为文档创建者，创建者不能移除
+	CreatedAt string `json:"created_at"`
+}
+
+// ListMembersResult 文档成员列表，创建者在最前面，其余按加入时间排序
+type ListMembersResult struct {
+	Members []Member `json:"members"`
+}
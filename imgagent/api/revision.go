@@ -0,0 +1,62 @@
+package api
+
+// ChapterRevision 章节内容的修订记录
+type ChapterRevision struct {
+	Rev          int    `json:"rev"`
+	ChapterID    string `json:"chapter_id"`
+	DocumentID   string `json:"document_id"`
+	Author       string `json:"author"`
+	Content      string `json:"content"`
+	Diff         string `json:"diff"`
+	RestoredFrom int    `json:"restored_from,omitempty"`
+	CreatedAt    string `json:"created_at"`
+}
+
+// SceneRevision 场景内容的修订记录，包含该版本内容生成的图片和语音
+type SceneRevision struct {
+	Rev          int    `json:"rev"`
+	SceneID      string `json:"scene_id"`
+	DocumentID   string `json:"document_id"`
+	Author       string `json:"author"`
+	Content      string `json:"content"`
+	ImageURL     string `json:"image_url"`
+	ThumbnailURL string `json:"thumbnail_url"`
+	MediumURL    string `json:"medium_url"`
+	VoiceURL     string `json:"voice_url"`
+	Diff         string `json:"diff"`
+	RestoredFrom int    `json:"restored_from,omitempty"`
+	CreatedAt    string `json:"created_at"`
+}
+
+// RoleRevision 角色信息的修订记录
+type RoleRevision struct {
+	Rev          int    `json:"rev"`
+	RoleID       string `json:"role_id"`
+	DocumentID   string `json:"document_id"`
+	Author       string `json:"author"`
+	Name         string `json:"name"`
+	Gender       string `json:"gender"`
+	Character    string `json:"character"`
+	Appearance   string `json:"appearance"`
+	Diff         string `json:"diff"`
+	RestoredFrom int    `json:"restored_from,omitempty"`
+	CreatedAt    string `json:"created_at"`
+}
+
+// ListChapterRevisionsResult 章节修订列表响应
+type ListChapterRevisionsResult struct {
+	Pagination
+	Revisions []ChapterRevision `json:"revisions"`
+}
+
+// ListSceneRevisionsResult 场景修订列表响应
+type ListSceneRevisionsResult struct {
+	Pagination
+	Revisions []SceneRevision `json:"revisions"`
+}
+
+// ListRoleRevisionsResult 角色修订列表响应
+type ListRoleRevisionsResult struct {
+	Pagination
+	Revisions []RoleRevision `json:"revisions"`
+}
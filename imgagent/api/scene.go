@@ -4,6 +4,7 @@ package api
 type Role struct {
 	ID         string `json:"id"`
 	DocumentID string `json:"document_id"`
+	VolumeID   string `json:"volume_id"`
 	Name       string `json:"name"`
 	Gender     string `json:"gender"`
 	Character  string `json:"character"`
@@ -14,24 +15,29 @@ type Role struct {
 
 // Scene 场景信息
 type Scene struct {
-	ID         string `json:"id"`
-	ChapterID  string `json:"chapter_id"`
-	DocumentID string `json:"document_id"`
-	Index      int    `json:"index"`
-	Content    string `json:"content"`
-	ImageURL   string `json:"image_url"`
-	VoiceURL   string `json:"voice_url"`
-	CreatedAt  string `json:"created_at"`
-	UpdatedAt  string `json:"updated_at"`
+	ID           string `json:"id"`
+	ChapterID    string `json:"chapter_id"`
+	DocumentID   string `json:"document_id"`
+	Index        int    `json:"index"`
+	Content      string `json:"content"`
+	ImageURL     string `json:"image_url"`
+	ThumbnailURL string `json:"thumbnail_url"`
+	MediumURL    string `json:"medium_url"`
+	VoiceURL     string `json:"voice_url"`
+	CreatedAt    string `json:"created_at"`
+	UpdatedAt    string `json:"updated_at"`
+	DeletedAt    string `json:"deleted_at,omitempty"`
 }
 
 // ListRolesResult 角色列表响应
 type ListRolesResult struct {
+	Pagination
 	Roles []Role `json:"roles"`
 }
 
 // ListScenesResult 场景列表响应
 type ListScenesResult struct {
+	Pagination
 	Scenes []Scene `json:"scenes"`
 }
 
@@ -0,0 +1,44 @@
+package api
+
+// 全文检索的记录类型
+const (
+	SearchTypeChapter = "chapter"
+	SearchTypeScene   = "scene"
+	SearchTypeRole    = "role"
+)
+
+const (
+	DefaultSearchLimit = 20
+	MaxSearchLimit     = 100
+)
+
+// SearchArgs 全文检索请求参数
+type SearchArgs struct {
+	Q          string `form:"q" binding:"required,max=100"`                      // 检索词
+	DocumentID string `form:"document_id"`                                       // 为空时检索所有文档
+	Type       string `form:"type" binding:"omitempty,oneof=chapter scene role"` // 为空时检索章节、场景和角色
+	Limit      int    `form:"limit" binding:"omitempty,min=1,max=100"`           // 为 0 时使用 DefaultSearchLimit
+}
+
+// Records 全文检索命中的记录
+type Records struct {
+	ID         string  `json:"id"`
+	Type       string  `json:"type"`
+	DocumentID string  `json:"document_id"`
+	ChapterID  string  `json:"chapter_id,omitempty"`
+	Title      string  `json:"title,omitempty"`
+	Content    string  `json:"content"` // 命中内容片段，已做 HTML 转义，检索词用 <em></em> 标记
+	Score      float32 `json:"score"`
+}
+
+// SearchResult 全文检索响应，按得分倒序
+type SearchResult struct {
+	Records []Records `json:"records"`
+}
+
+// SemanticSearchArgs 语义检索请求参数，检索文档中与 q 语义相近的章节和场景
+type SemanticSearchArgs struct {
+	Q     string `form:"q" binding:"required,max=500"`                 // 检索内容
+	Type  string `form:"type" binding:"omitempty,oneof=chapter scene"` // 为空时检索章节和场景
+	Limit int    `form:"limit" binding:"omitempty,min=1,max=100"`      // 为 0 时使用 DefaultSearchLimit
+}
@@ -0,0 +1,28 @@
+package api
+
+type InitUploadArgs struct {
+	Name     string `json:"name" binding:"required,max=50"`
+	Filename string `json:"filename" binding:"required"`
+	Size     int64  `json:"size" binding:"required,gt=0"`
+	SHA256   string `json:"sha256"`   // 可选，完成时校验整个文件的 sha256
+	Encoding string `json:"encoding"` // 可选，指定 txt、md 的文本编码，默认自动检测
+
+	ChapterPatterns []string `json:"chapter_patterns"` // 可选，章节标题正则，默认使用全局配置
+}
+
+type Upload struct {
+	ID            string `json:"id"`
+	Name          string `json:"name"`
+	Filename      string `json:"filename"`
+	Size          int64  `json:"size"`
+	PartSize      int64  `json:"part_size"`
+	PartCount     int    `json:"part_count"`
+	UploadedParts []int  `json:"uploaded_parts"` // 已上传的分片序号，从 1 开始
+	ExpiresAt     string `json:"expires_at"`
+}
+
+type UploadPartResult struct {
+	PartNumber int    `json:"part_number"`
+	Size       int64  `json:"size"`
+	SHA256     string `json:"sha256"`
+}
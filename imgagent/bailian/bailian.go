@@ -7,6 +7,14 @@ import (
 	"go.uber.org/zap"
 )
 
+const (
+	ImageModel      = "qwen-image-plus"
+	TTSModel        = "qwen3-tts-flash"
+	TTSVoice        = "Cherry"
+	TTSLanguageType = "Chinese"
+	OCRModel        = "qwen-vl-ocr"
+)
+
 // Config 阿里云百炼配置
 type Config struct {
 	BaseURL        string `json:"base_url"`        // API 基础 URL
@@ -16,6 +24,7 @@ type Config struct {
 	ScenePrompt    string `json:"scene_prompt"`    // 场景生成 Prompt
 	ImageSize      string `json:"image_size"`      // 图片尺寸
 	ImageWatermark bool   `json:"image_watermark"` // 是否添加水印
+	ImageSeed      int    `json:"image_seed"`      // 场景图片固定 seed，非 0 时生成结果可复现并参与缓存
 	RequestTimeout int    `json:"request_timeout"` // 请求超时时间（秒）
 	MaxRetries     int    `json:"max_retries"`     // 最大重试次数
 }
@@ -4,16 +4,21 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"mime/multipart"
 	"net/http"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"imgagent/pkg/logger"
 )
 
+// ErrFileNotFound 百炼侧文件不存在（过期或被清理），需要重新上传
+var ErrFileNotFound = errors.New("bailian file not found")
+
 // UploadFile 上传文件到阿里云百炼
 // 返回 fileID 用于后续 qwen-long 调用
 func (c *Client) UploadFile(ctx context.Context, filename string) (string, error) {
@@ -105,3 +110,60 @@ func (c *Client) UploadFile(ctx context.Context, filename string) (string, error
 	log.Infof("File uploaded successfully, fileID: %s", uploadResp.ID)
 	return uploadResp.ID, nil
 }
+
+// DeleteFile 删除百炼上的文件，文件已不存在时视为成功
+func (c *Client) DeleteFile(ctx context.Context, fileID string) error {
+	log := logger.FromContext(ctx)
+	log.Infof("Deleting file from Bailian, fileID: %s", fileID)
+
+	url := fmt.Sprintf("%s/compatible-mode/v1/files/%s", c.config.BaseURL, fileID)
+	req, err := http.NewRequestWithContext(ctx, "DELETE", url, nil)
+	if err != nil {
+		log.Errorf("Failed to create request, err: %v", err)
+		return fmt.Errorf("create request failed: %w", err)
+	}
+
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", c.config.APIKey))
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		log.Errorf("Failed to send request, err: %v", err)
+		return fmt.Errorf("send request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		log.Errorf("Failed to read response, err: %v", err)
+		return fmt.Errorf("read response failed: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		if isFileNotFound(resp.StatusCode, respBody) {
+			log.Warnf("File already deleted, fileID: %s", fileID)
+			return nil
+		}
+		log.Errorf("Delete file failed, status: %d, body: %s", resp.StatusCode, string(respBody))
+		return fmt.Errorf("delete file failed, status: %d, body: %s", resp.StatusCode, string(respBody))
+	}
+
+	log.Infof("File deleted successfully, fileID: %s", fileID)
+	return nil
+}
+
+// isFileNotFound 判断百炼的错误响应是否为文件不存在
+func isFileNotFound(statusCode int, body []byte) bool {
+	if statusCode == http.StatusNotFound {
+		return true
+	}
+	if statusCode != http.StatusBadRequest {
+		return false
+	}
+	msg := strings.ToLower(string(body))
+	if !strings.Contains(msg, "file") {
+		return false
+	}
+	return strings.Contains(msg, "not found") ||
+		strings.Contains(msg, "cannot be found") ||
+		strings.Contains(msg, "not exist")
+}
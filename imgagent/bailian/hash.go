@@ -0,0 +1,45 @@
+package bailian
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+const (
+	AssetOperationTTS   = "tts"
+	AssetOperationImage = "image"
+)
+
+// AssetHash 计算生成请求的内容哈希，相同哈希的请求生成的资源可以复用
+// 输入文本做 NFC 规范化并合并空白，避免格式差异导致缓存失效
+func AssetHash(operation, model, voice, input string) string {
+	h := sha256.New()
+	for _, s := range []string{operation, model, voice, normalizeText(input)} {
+		h.Write([]byte(s))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// TTSHash 返回 GenerateTTS 请求的内容哈希
+func (c *Client) TTSHash(text string) string {
+	return AssetHash(AssetOperationTTS, TTSModel, TTSVoice+"/"+TTSLanguageType, text)
+}
+
+// ImageHash 返回 GenerateImage 请求的内容哈希
+// 未配置固定 seed 时生成结果不确定，返回空字符串表示不可缓存
+func (c *Client) ImageHash(sceneContent string, summary string, roles []RoleInfo) string {
+	if c.config.ImageSeed == 0 {
+		return ""
+	}
+	params := fmt.Sprintf("%s/%d/%t", c.config.ImageSize, c.config.ImageSeed, c.config.ImageWatermark)
+	return AssetHash(AssetOperationImage, ImageModel, params, buildImagePrompt(sceneContent, summary, roles))
+}
+
+func normalizeText(text string) string {
+	return strings.Join(strings.Fields(norm.NFC.String(text)), " ")
+}
@@ -0,0 +1,36 @@
+package bailian
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAssetHash(t *testing.T) {
+	t.Parallel()
+
+	h := AssetHash(AssetOperationTTS, TTSModel, TTSVoice, "祥子拉着车，  走在\n北平的街上。")
+	require.Len(t, h, 64)
+
+	// 空白差异不影响哈希
+	require.Equal(t, h, AssetHash(AssetOperationTTS, TTSModel, TTSVoice, " 祥子拉着车， 走在 北平的街上。 "))
+	// 操作、模型、音色不同时哈希不同
+	require.NotEqual(t, h, AssetHash(AssetOperationImage, TTSModel, TTSVoice, "祥子拉着车， 走在 北平的街上。"))
+	require.NotEqual(t, h, AssetHash(AssetOperationTTS, TTSModel, "Ethan", "祥子拉着车， 走在 北平的街上。"))
+}
+
+func TestImageHash(t *testing.T) {
+	t.Parallel()
+
+	c, err := NewClient(Config{})
+	require.NoError(t, err)
+	require.Empty(t, c.ImageHash("场景", "摘要", nil), "image without seed should not be cacheable")
+
+	c, err = NewClient(Config{ImageSeed: 42})
+	require.NoError(t, err)
+	roles := []RoleInfo{{Name: "祥子", Gender: "男", Appearance: "高大"}}
+	h := c.ImageHash("场景", "摘要", roles)
+	require.NotEmpty(t, h)
+	require.Equal(t, h, c.ImageHash("场景", "摘要", roles))
+	require.NotEqual(t, h, c.ImageHash("场景", "摘要", nil))
+}
@@ -23,7 +23,7 @@ func (c *Client) GenerateCoverImage(ctx context.Context, summary string) (string
 
 	// 构建请求
 	req := ImageGenerationRequest{
-		Model: "qwen-image-plus",
+		Model: ImageModel,
 		Input: ImageInput{
 			Messages: []ImageMessage{
 				{
@@ -133,9 +133,9 @@ func (c *Client) GenerateImage(ctx context.Context, sceneContent string, summary
 	prompt := buildImagePrompt(sceneContent, summary, roles)
 	log.Infof("Full image prompt: %s", prompt)
 
-	// 构建请求
+	// 构建请求，配置固定 seed 时关闭 prompt 扩写，保证相同输入生成相同图片
 	req := ImageGenerationRequest{
-		Model: "qwen-image-plus",
+		Model: ImageModel,
 		Input: ImageInput{
 			Messages: []ImageMessage{
 				{
@@ -148,9 +148,10 @@ func (c *Client) GenerateImage(ctx context.Context, sceneContent string, summary
 		},
 		Parameters: Parameters{
 			NegativePrompt: "",
-			PromptExtend:   true,
+			PromptExtend:   c.config.ImageSeed == 0,
 			Watermark:      c.config.ImageWatermark,
 			Size:           c.config.ImageSize,
+			Seed:           c.config.ImageSeed,
 		},
 	}
 
@@ -52,15 +52,18 @@ func (c *Client) ExtractSummary(ctx context.Context, fileID string) (string, err
 }
 
 // ExtractRoles 从文档中提取角色信息
-// 使用 qwen-long 分析整个文档
-func (c *Client) ExtractRoles(ctx context.Context, fileID string, summary string) ([]RoleInfo, error) {
+// 使用 qwen-long 分析整个文档，volume 不为空时只提取该卷出场的角色
+func (c *Client) ExtractRoles(ctx context.Context, fileID string, summary string, volume string) ([]RoleInfo, error) {
 	log := logger.FromContext(ctx)
-	log.Infof("Extracting roles from document, fileID: %s", fileID)
+	log.Infof("Extracting roles from document, fileID: %s, volume: %s", fileID, volume)
 
 	// 构建请求
 	prompt := c.config.RolePrompt
+	if volume != "" {
+		prompt = fmt.Sprintf("只分析小说中「%s」这一卷的内容，提取该卷中出场的角色。\n\n%s", volume, prompt)
+	}
 	if summary != "" {
-		prompt = fmt.Sprintf("小说摘要：\n%s\n\n%s", summary, c.config.RolePrompt)
+		prompt = fmt.Sprintf("小说摘要：\n%s\n\n%s", summary, prompt)
 	}
 
 	req := ChatCompletionRequest{
@@ -203,6 +206,10 @@ func (c *Client) callChatCompletion(ctx context.Context, req ChatCompletionReque
 
 	if resp.StatusCode != http.StatusOK {
 		log.Errorf("API call failed, status: %d, body: %s", resp.StatusCode, string(respBody))
+		// fileid 引用的文件失效，由调用方重新上传后重试
+		if isFileNotFound(resp.StatusCode, respBody) {
+			return nil, fmt.Errorf("%w, status: %d, body: %s", ErrFileNotFound, resp.StatusCode, string(respBody))
+		}
 		return nil, fmt.Errorf("API call failed, status: %d, body: %s", resp.StatusCode, string(respBody))
 	}
 
@@ -0,0 +1,94 @@
+package bailian
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"imgagent/pkg/logger"
+)
+
+// ocrPrompt 文字识别 Prompt
+const ocrPrompt = "请识别图片中的全部正文文字，按原文顺序输出，每个段落占一行。不要输出页眉、页脚和页码，不要添加任何说明。"
+
+// RecognizeImage 使用 Qwen-VL OCR 识别 PNG 图片中的文字，用于扫描版 PDF
+func (c *Client) RecognizeImage(ctx context.Context, image []byte) (string, error) {
+	log := logger.FromContext(ctx)
+	log.Infof("Recognizing image, size: %d", len(image))
+
+	req := OCRRequest{
+		Model: OCRModel,
+		Input: OCRInput{
+			Messages: []OCRMessage{
+				{
+					Role: "user",
+					Content: []OCRContent{
+						{Image: "data:image/png;base64," + base64.StdEncoding.EncodeToString(image)},
+						{Text: ocrPrompt},
+					},
+				},
+			},
+		},
+	}
+
+	reqBody, err := json.Marshal(req)
+	if err != nil {
+		log.Errorf("Failed to marshal request, err: %v", err)
+		return "", fmt.Errorf("marshal request failed: %w", err)
+	}
+
+	url := fmt.Sprintf("%s/api/v1/services/aigc/multimodal-generation/generation", c.config.BaseURL)
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(reqBody))
+	if err != nil {
+		log.Errorf("Failed to create request, err: %v", err)
+		return "", fmt.Errorf("create request failed: %w", err)
+	}
+
+	httpReq.Header.Set("Authorization", fmt.Sprintf("Bearer %s", c.config.APIKey))
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.httpClient.Do(httpReq)
+	if err != nil {
+		log.Errorf("Failed to send request, err: %v", err)
+		return "", fmt.Errorf("send request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		log.Errorf("Failed to read response, err: %v", err)
+		return "", fmt.Errorf("read response failed: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		log.Errorf("Recognize image failed, status: %d, body: %s", resp.StatusCode, string(respBody))
+		return "", fmt.Errorf("recognize image failed, status: %d, body: %s", resp.StatusCode, string(respBody))
+	}
+
+	var ocrResp OCRResponse
+	err = json.Unmarshal(respBody, &ocrResp)
+	if err != nil {
+		log.Errorf("Failed to parse response, err: %v, body: %s", err, string(respBody))
+		return "", fmt.Errorf("parse response failed: %w", err)
+	}
+
+	if len(ocrResp.Output.Choices) == 0 {
+		log.Errorf("No choices in response, body: %s", string(respBody))
+		return "", fmt.Errorf("no choices in response")
+	}
+
+	var texts []string
+	for _, content := range ocrResp.Output.Choices[0].Message.Content {
+		if content.Text != "" {
+			texts = append(texts, content.Text)
+		}
+	}
+	text := strings.TrimSpace(strings.Join(texts, "\n"))
+	log.Infof("Image recognized successfully, length: %d", len(text))
+	return text, nil
+}
@@ -16,11 +16,11 @@ func (c *Client) GenerateTTS(ctx context.Context, text string) (string, error) {
 	log.Infof("Generating TTS for text, length: %d", len(text))
 
 	req := TTSRequest{
-		Model: "qwen3-tts-flash",
+		Model: TTSModel,
 		Input: TTSInput{
 			Text:         text,
-			Voice:        "Cherry",
-			LanguageType: "Chinese",
+			Voice:        TTSVoice,
+			LanguageType: TTSLanguageType,
 		},
 	}
 
@@ -81,6 +81,7 @@ type Parameters struct {
 	PromptExtend   bool   `json:"prompt_extend"`
 	Watermark      bool   `json:"watermark"`
 	Size           string `json:"size"`
+	Seed           int    `json:"seed,omitempty"`
 }
 
 // ImageGenerationResponse 图片生成响应
@@ -162,3 +163,42 @@ type TTSUsage struct {
 	OutputTokens int `json:"output_tokens"`
 	Characters   int `json:"characters"`
 }
+
+// OCRRequest 文字识别请求
+type OCRRequest struct {
+	Model string   `json:"model"`
+	Input OCRInput `json:"input"`
+}
+
+// OCRInput 文字识别输入
+type OCRInput struct {
+	Messages []OCRMessage `json:"messages"`
+}
+
+// OCRMessage 文字识别消息
+type OCRMessage struct {
+	Role    string       `json:"role"`
+	Content []OCRContent `json:"content"`
+}
+
+// OCRContent 文字识别内容，图片为 URL 或 data URI
+type OCRContent struct {
+	Image string `json:"image,omitempty"`
+	Text  string `json:"text,omitempty"`
+}
+
+// OCRResponse 文字识别响应
+type OCRResponse struct {
+	Output OCROutput `json:"output"`
+}
+
+// OCROutput 输出
+type OCROutput struct {
+	Choices []OCRChoice `json:"choices"`
+}
+
+// OCRChoice 选择
+type OCRChoice struct {
+	FinishReason string     `json:"finish_reason"`
+	Message      OCRMessage `json:"message"`
+}
@@ -0,0 +1,38 @@
+package db
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Asset 生成资源缓存表，按请求内容哈希去重
+type Asset struct {
+	Hash         string    `gorm:"primaryKey;size:64;comment:'请求内容 sha256'"`
+	Operation    string    `gorm:"size:20;comment:'操作类型 tts|image'"`
+	Model        string    `gorm:"size:50;comment:'模型名称'"`
+	URL          string    `gorm:"size:500;comment:'资源url'"`
+	ThumbnailURL string    `gorm:"size:500;comment:'缩略图url'"`
+	MediumURL    string    `gorm:"size:500;comment:'中图url'"`
+	CreatedAt    time.Time `gorm:"comment:'创建时间'"`
+}
+
+func (Asset) TableName() string {
+	return "assets"
+}
+
+// ===== Asset DAO =====
+
+func (db *Database) GetAsset(ctx context.Context, hash string) (Asset, error) {
+	return gorm.G[Asset](db.db).Where("hash = ?", hash).Take(ctx)
+}
+
+// CreateAsset 保存生成资源，并发生成相同内容时保留先写入的记录
+func (db *Database) CreateAsset(ctx context.Context, asset *Asset) error {
+	if asset.CreatedAt.IsZero() {
+		asset.CreatedAt = time.Now()
+	}
+	return db.db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(asset).Error
+}
@@ -1,7 +1,9 @@
 package db
 
 import (
+	"context"
 	"encoding/hex"
+	"sync"
 
 	"github.com/google/uuid"
 	"go.uber.org/zap"
@@ -12,6 +14,10 @@ import (
 
 type Database struct {
 	db *gorm.DB
+
+	// 全文检索方式在首次检索时按数据库确定
+	searchOnce sync.Once
+	searcher   searcher
 }
 
 func NewDatabase(conf dbutil.Config) (*Database, error) {
@@ -23,12 +29,19 @@ func NewDatabase(conf dbutil.Config) (*Database, error) {
 	database := &Database{
 		db: db,
 	}
+	if err := registerOwnerCallbacks(db); err != nil {
+		zap.S().Errorf("Failed to register owner callbacks, err: %v", err)
+		database.Close()
+		return nil, err
+	}
 
-	// 这里可以添加表创建逻辑，需要指定字符集为 utf8mb4，默认为 utf8mb3
-	err = db.Set("gorm:table_options", "CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci").AutoMigrate(&Document{}, &Chapter{}, &Scene{}, &Role{})
-
+	// 表结构由 imgagent migrate up 维护，版本不一致时拒绝启动
+	migrator, err := NewMigrator(db)
+	if err == nil {
+		err = migrator.Check(context.Background())
+	}
 	if err != nil {
-		zap.S().Errorf("Failed to auto migrate, err: %v", err)
+		zap.S().Errorf("Failed to check schema version, err: %v", err)
 		database.Close()
 		return nil, err
 	}
@@ -37,6 +50,9 @@ func NewDatabase(conf dbutil.Config) (*Database, error) {
 }
 
 func (db *Database) SetDB(gdb *gorm.DB) {
+	if err := registerOwnerCallbacks(gdb); err != nil {
+		zap.S().Errorf("Failed to register owner callbacks, err: %v", err)
+	}
 	db.db = gdb
 }
 
@@ -12,6 +12,7 @@ import (
 const (
 	batchSize = 100
 
+	DocumentStatusOCRPending   = "ocrPending" // 扫描版 PDF 等待后台识别文字后分割章节
 	DocumentStatusChapterReady = "chapterReady"
 	DocumentStatusRoleReady    = "roleReady"
 	DocumentStatusSceneReady   = "sceneReady"
@@ -24,14 +25,22 @@ func (Role) TableName() string {
 
 // Document 文档表
 type Document struct {
-	ID              string    `gorm:"primaryKey;size:32;comment:'主键'"`
-	Name            string    `gorm:"uniqueIndex:uk_name;size:128;comment:'文档名称'"`
-	FileID          string    `gorm:"size:255;comment:'存储在阿里云百炼的 fileid'"`
-	Summary         string    `gorm:"size:1000;comment:'小说摘要'"`
-	SummaryImageURL string    `gorm:"size:500;comment:'小说封面图URL'"`
-	Status          string    `gorm:"size:20;comment:'状态 indexing|ready'"`
-	CreatedAt       time.Time `gorm:"comment:'创建时间'"`
-	UpdatedAt       time.Time `gorm:"comment:'更新时间'"`
+	ID              string         `gorm:"primaryKey;size:32;comment:'主键'"`
+	OwnerID         int64          `gorm:"uniqueIndex:uk_owner_name_deleted_key,priority:1;not null;default:0;comment:'所属用户 id'"`
+	Name            string         `gorm:"uniqueIndex:uk_owner_name_deleted_key,priority:2;size:128;comment:'文档名称'"`
+	FileID          string         `gorm:"size:255;comment:'存储在阿里云百炼的 fileid'"`
+	OriginFile      string         `gorm:"size:255;comment:'原始文件路径，用于重新上传百炼'"`
+	Encoding        string         `gorm:"size:20;comment:'原始文件编码，txt、md 上传时检测'"`
+	ChapterPatterns []string       `gorm:"type:json;serializer:json;comment:'章节标题正则，后台分割章节时使用'"`
+	Summary         string         `gorm:"size:1000;comment:'小说摘要'"`
+	SummaryImageURL string         `gorm:"size:500;comment:'小说封面图URL'"`
+	ThumbnailURL    string         `gorm:"size:500;comment:'封面缩略图URL'"`
+	MediumURL       string         `gorm:"size:500;comment:'封面中图URL'"`
+	Status          string         `gorm:"size:20;comment:'状态 indexing|ready'"`
+	CreatedAt       time.Time      `gorm:"comment:'创建时间'"`
+	UpdatedAt       time.Time      `gorm:"comment:'更新时间'"`
+	DeletedKey      string         `gorm:"uniqueIndex:uk_owner_name_deleted_key,priority:3;size:32;not null;default:'';comment:'删除标记，未删除时为空，回收站中为文档 id，与名称组成唯一索引使回收站中的文档不占用名称'"`
+	DeletedAt       gorm.DeletedAt `gorm:"index;comment:'删除时间，不为空表示在回收站中'"`
 }
 
 func (Document) TableName() string {
@@ -40,31 +49,55 @@ func (Document) TableName() string {
 
 // Chapter 章节表
 type Chapter struct {
+	ID         string         `gorm:"primaryKey;size:32;comment:'主键'"`
+	Index      int            `gorm:"uniqueIndex:uk_document_index,priority:2;comment:'章节序号'"`
+	DocumentID string         `gorm:"uniqueIndex:uk_document_index,priority:1;size:32;comment:'文档 id'"`
+	OwnerID    int64          `gorm:"not null;default:0;comment:'所属用户 id，与文档一致'"`
+	VolumeID   string         `gorm:"index:idx_volume_id;size:32;comment:'所属卷 id，未分卷时为空'"`
+	Title      string         `gorm:"size:100;comment:'标题'"`
+	Kind       string         `gorm:"size:20;comment:'章节类型 chapter|prologue|epilogue|extra'"`
+	Content    string         `gorm:"type:mediumtext;comment:'章节内容，展示形式'"`
+	Prompt     string         `gorm:"type:mediumtext;comment:'章节内容，提示词形式'"`
+	SceneIDs   []string       `gorm:"type:json;serializer:json;comment:'故事场景'"`
+	CreatedAt  time.Time      `gorm:"comment:'创建时间'"`
+	UpdatedAt  time.Time      `gorm:"comment:'更新时间'"`
+	DeletedAt  gorm.DeletedAt `gorm:"index;comment:'删除时间，不为空表示在回收站中'"`
+}
+
+func (Chapter) TableName() string {
+	return "chapters"
+}
+
+// Volume 卷表，长篇小说按卷（部）组织章节
+type Volume struct {
 	ID         string    `gorm:"primaryKey;size:32;comment:'主键'"`
-	Index      int       `gorm:"uniqueIndex:uk_document_index,priority:2;comment:'章节序号'"`
-	DocumentID string    `gorm:"uniqueIndex:uk_document_index,priority:1;size:32;comment:'文档 id'"`
+	Index      int       `gorm:"uniqueIndex:uk_volume_document_index,priority:2;comment:'卷序号'"`
+	DocumentID string    `gorm:"uniqueIndex:uk_volume_document_index,priority:1;size:32;comment:'文档 id'"`
 	Title      string    `gorm:"size:100;comment:'标题'"`
-	Content    string    `gorm:"size:10000;comment:'章节内容'"`
-	SceneIDs   []string  `gorm:"type:json;serializer:json;comment:'故事场景'"`
+	Number     int       `gorm:"comment:'原文中的卷号，未识别时为 0'"`
 	CreatedAt  time.Time `gorm:"comment:'创建时间'"`
 	UpdatedAt  time.Time `gorm:"comment:'更新时间'"`
 }
 
-func (Chapter) TableName() string {
-	return "chapters"
+func (Volume) TableName() string {
+	return "volumes"
 }
 
 // Scene 场景表
 type Scene struct {
-	ID         string    `gorm:"primaryKey;size:32;comment:'主键'"`
-	ChapterID  string    `gorm:"index:idx_chapter_id;size:32;comment:'chapter id'"`
-	DocumentID string    `gorm:"index:idx_document_id;size:32;comment:'文档 id'"`
-	Index      int       `gorm:"comment:'场景序号'"`
-	Content    string    `gorm:"size:1000;comment:'场景描述'"`
-	ImageURL   string    `gorm:"size:500;comment:'场景图片url'"`
-	VoiceURL   string    `gorm:"size:500;comment:'音频url'"`
-	CreatedAt  time.Time `gorm:"comment:'创建时间'"`
-	UpdatedAt  time.Time `gorm:"comment:'更新时间'"`
+	ID           string         `gorm:"primaryKey;size:32;comment:'主键'"`
+	ChapterID    string         `gorm:"index:idx_chapter_id;size:32;comment:'chapter id'"`
+	DocumentID   string         `gorm:"index:idx_document_id;size:32;comment:'文档 id'"`
+	OwnerID      int64          `gorm:"not null;default:0;comment:'所属用户 id，与文档一致'"`
+	Index        int            `gorm:"comment:'场景序号'"`
+	Content      string         `gorm:"size:1000;comment:'场景描述'"`
+	ImageURL     string         `gorm:"size:500;comment:'场景图片url'"`
+	ThumbnailURL string         `gorm:"size:500;comment:'场景缩略图url'"`
+	MediumURL    string         `gorm:"size:500;comment:'场景中图url'"`
+	VoiceURL     string         `gorm:"size:500;comment:'音频url'"`
+	CreatedAt    time.Time      `gorm:"comment:'创建时间'"`
+	UpdatedAt    time.Time      `gorm:"comment:'更新时间'"`
+	DeletedAt    gorm.DeletedAt `gorm:"index;comment:'删除时间，不为空表示在回收站中'"`
 }
 
 func (Scene) TableName() string {
@@ -73,27 +106,50 @@ func (Scene) TableName() string {
 
 // Role 任务角色表
 type Role struct {
-	ID         string    `gorm:"primaryKey;size:32;comment:'主键'"`
-	DocumentID string    `gorm:"index:idx_role_document_id;size:32;comment:'文档 id'"`
-	Name       string    `gorm:"size:50;comment:'角色名字'"`
-	Gender     string    `gorm:"size:10;comment:'性别'"`
-	Character  string    `gorm:"size:500;comment:'性格特点'"`
-	Appearance string    `gorm:"size:500;comment:'外貌描述'"`
-	CreatedAt  time.Time `gorm:"comment:'创建时间'"`
-	UpdatedAt  time.Time `gorm:"comment:'更新时间'"`
+	ID         string         `gorm:"primaryKey;size:32;comment:'主键'"`
+	DocumentID string         `gorm:"index:idx_role_document_id;size:32;comment:'文档 id'"`
+	OwnerID    int64          `gorm:"not null;default:0;comment:'所属用户 id，与文档一致'"`
+	VolumeID   string         `gorm:"size:32;comment:'角色出场的卷 id，为空表示全书角色'"`
+	Name       string         `gorm:"size:50;comment:'角色名字'"`
+	Gender     string         `gorm:"size:10;comment:'性别'"`
+	Character  string         `gorm:"size:500;comment:'性格特点'"`
+	Appearance string         `gorm:"size:500;comment:'外貌描述'"`
+	CreatedAt  time.Time      `gorm:"comment:'创建时间'"`
+	UpdatedAt  time.Time      `gorm:"comment:'更新时间'"`
+	DeletedAt  gorm.DeletedAt `gorm:"index;comment:'删除时间，不为空表示在回收站中'"`
 }
 
 // ===== Document DAO =====
 
-func (db *Database) CreateDocument(ctx context.Context, docID, fileID string, args *api.CreateDocumentArgs) (*Document, error) {
+// DocumentFile 文档的文件和分割信息，由服务端在创建文档时确定
+type DocumentFile struct {
+	FileID     string // 百炼文件 id
+	OriginFile string // 服务端保存的原始文件路径，用于重新上传百炼
+	Encoding   string // 原始文件的文本编码，仅 txt、md 文件有值
+
+	// OCRPending 扫描版 PDF 不在请求中分割章节，由后台识别文字后按 ChapterPatterns 分割
+	OCRPending      bool
+	ChapterPatterns []string
+}
+
+func (db *Database) CreateDocument(ctx context.Context, docID string, file DocumentFile, args *api.CreateDocumentArgs) (*Document, error) {
+	owner, _ := OwnerFromContext(ctx)
 	now := time.Now()
 	doc := Document{
-		ID:        docID,
-		FileID:    fileID,
-		Name:      args.Name,
-		Status:    DocumentStatusChapterReady,
-		CreatedAt: now,
-		UpdatedAt: now,
+		ID:         docID,
+		OwnerID:    owner.ID,
+		FileID:     file.FileID,
+		OriginFile: file.OriginFile,
+		Encoding:   file.Encoding,
+		Name:       args.Name,
+		Status:     DocumentStatusChapterReady,
+		CreatedAt:  now,
+		UpdatedAt:  now,
+
+		ChapterPatterns: file.ChapterPatterns,
+	}
+	if file.OCRPending {
+		doc.Status = DocumentStatusOCRPending
 	}
 	if err := gorm.G[Document](db.db).Create(ctx, &doc); err != nil {
 		return nil, err
@@ -105,8 +161,10 @@ func (db *Database) GetDocument(ctx context.Context, id string) (Document, error
 	return gorm.G[Document](db.db).Where("id = ?", id).Take(ctx)
 }
 
+// GetDocumentWithName 查询当前用户指定名称的文档，文档名称在同一用户下唯一，超级管理员也只查询自己的文档
 func (db *Database) GetDocumentWithName(ctx context.Context, name string) (Document, error) {
-	return gorm.G[Document](db.db).Where("name = ?", name).Take(ctx)
+	owner, _ := OwnerFromContext(ctx)
+	return gorm.G[Document](db.db).Where("owner_id = ? AND name = ?", owner.ID, name).Take(ctx)
 }
 
 func (db *Database) UpdateDocument(ctx context.Context, id string, args *api.UpdateDocumentArgs) error {
@@ -141,8 +199,74 @@ func (db *Database) DeleteDocument(ctx context.Context, id string) error {
 	return err
 }
 
-func (db *Database) ListDocuments(ctx context.Context) ([]Document, error) {
-	return gorm.G[Document](db.db).Order("updated_at DESC").Find(ctx)
+// DeleteDocumentCascade 在一个事务中彻底删除文档（包括回收站中的文档）及其场景、角色、章节、卷、修订记录、向量和成员，文档不存在时返回 gorm.ErrRecordNotFound
+// 生成资源（assets）按请求内容哈希在文档之间共享，不随文档删除
+func (db *Database) DeleteDocumentCascade(ctx context.Context, id string) error {
+	return db.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if _, err := gorm.G[Embedding](tx).Where("document_id = ?", id).Delete(ctx); err != nil {
+			return err
+		}
+		if _, err := gorm.G[SceneRevision](tx).Where("document_id = ?", id).Delete(ctx); err != nil {
+			return err
+		}
+		if _, err := gorm.G[RoleRevision](tx).Where("document_id = ?", id).Delete(ctx); err != nil {
+			return err
+		}
+		if _, err := gorm.G[ChapterRevision](tx).Where("document_id = ?", id).Delete(ctx); err != nil {
+			return err
+		}
+		if _, err := gorm.G[Scene](tx).Scopes(unscoped).Where("document_id = ?", id).Delete(ctx); err != nil {
+			return err
+		}
+		if _, err := gorm.G[Role](tx).Scopes(unscoped).Where("document_id = ?", id).Delete(ctx); err != nil {
+			return err
+		}
+		if _, err := gorm.G[Chapter](tx).Scopes(unscoped).Where("document_id = ?", id).Delete(ctx); err != nil {
+			return err
+		}
+		if _, err := gorm.G[Volume](tx).Where("document_id = ?", id).Delete(ctx); err != nil {
+			return err
+		}
+		if _, err := gorm.G[DocumentMember](tx).Where("document_id = ?", id).Delete(ctx); err != nil {
+			return err
+		}
+		rowsAffected, err := gorm.G[Document](tx).Scopes(unscoped).Where("id = ?", id).Delete(ctx)
+		if err != nil {
+			return err
+		}
+		if rowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return nil
+	})
+}
+
+// ListDocuments 按过滤、排序和分页参数查询文档，同时返回过滤后的总数，args 为 nil 时返回全部文档
+func (db *Database) ListDocuments(ctx context.Context, args *api.ListDocumentsArgs) ([]Document, int64, error) {
+	var page *api.ListArgs
+	if args == nil {
+		args = &api.ListDocumentsArgs{}
+	} else {
+		page = &args.ListArgs
+	}
+	order, err := db.orderBy(documentSortColumns, args.Sort, args.Order, "updated_at DESC", "updated_at")
+	if err != nil {
+		return nil, 0, err
+	}
+	q := gorm.G[Document](db.db).Scopes()
+	if args.Status != "" {
+		q = q.Where("status = ?", args.Status)
+	}
+	if args.NamePrefix != "" {
+		q = q.Where("name LIKE ? ESCAPE '!'", prefixLike(args.NamePrefix))
+	}
+	if !args.CreatedAfter.IsZero() {
+		q = q.Where("created_at >= ?", args.CreatedAfter)
+	}
+	if !args.CreatedBefore.IsZero() {
+		q = q.Where("created_at < ?", args.CreatedBefore)
+	}
+	return listPage(ctx, q, page, order)
 }
 
 func (db *Database) UpdateDocumentFileID(ctx context.Context, id string, fileID string) error {
@@ -178,6 +302,48 @@ func (db *Database) UpdateDocumentSummaryImageURL(ctx context.Context, id string
 	return nil
 }
 
+func (db *Database) UpdateDocumentSummaryImageVariants(ctx context.Context, id string, thumbnailURL, mediumURL string) error {
+	result := db.db.WithContext(ctx).Model(&Document{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"thumbnail_url": thumbnailURL,
+		"medium_url":    mediumURL,
+	})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// ListOCRPendingDocuments 查询等待后台识别文字的扫描版 PDF 文档
+func (db *Database) ListOCRPendingDocuments(ctx context.Context) ([]Document, error) {
+	return gorm.G[Document](db.db).Where("status = ?", DocumentStatusOCRPending).Order("created_at ASC").Find(ctx)
+}
+
+// CompleteDocumentOCR 在一个事务中创建识别文字后分割的卷和章节，并将文档置为 chapterReady
+// originFile 为识别出的纯文本文件，清空 fileID 使后续任务上传该文件；文档不在等待识别状态时返回 gorm.ErrRecordNotFound
+func (db *Database) CompleteDocumentOCR(ctx context.Context, id, originFile string, volumes []Volume, chapters []api.CreateChapterArgs) error {
+	return db.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&Document{}).Where("id = ? AND status = ?", id, DocumentStatusOCRPending).Updates(map[string]any{
+			"origin_file": originFile,
+			"file_id":     "",
+			"status":      DocumentStatusChapterReady,
+		})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		txdb := &Database{db: tx}
+		if err := txdb.CreateVolumes(ctx, volumes); err != nil {
+			return err
+		}
+		return txdb.CreateChapters(ctx, id, chapters)
+	})
+}
+
 func (db *Database) ListChapterReadyDocuments(ctx context.Context) ([]Document, error) {
 	return gorm.G[Document](db.db).Where("status = ?", DocumentStatusChapterReady).Order("created_at ASC").Find(ctx)
 }
@@ -190,18 +356,33 @@ func (db *Database) ListSceneReadyDocuments(ctx context.Context) ([]Document, er
 	return gorm.G[Document](db.db).Where("status = ?", DocumentStatusSceneReady).Order("created_at ASC").Find(ctx)
 }
 
+// ListFileReleasableDocuments 查询已完成图片生成但仍占用百炼文件的文档
+func (db *Database) ListFileReleasableDocuments(ctx context.Context) ([]Document, error) {
+	return gorm.G[Document](db.db).Where("status = ? AND file_id <> ?", DocumentStatusImgReady, "").Order("created_at ASC").Find(ctx)
+}
+
 // ===== Chapter DAO =====
 
-func (db *Database) CreateChapters(ctx context.Context, documentID string, texts []string) error {
+func (db *Database) CreateChapters(ctx context.Context, documentID string, chapters []api.CreateChapterArgs) error {
+	ownerID, err := documentOwner(ctx, db.db, documentID)
+	if err != nil {
+		return err
+	}
+
 	var Chapters []Chapter
 
 	now := time.Now()
-	for i, text := range texts {
+	for i, chapter := range chapters {
 		Chapters = append(Chapters, Chapter{
 			ID:         MakeUUID(),
 			Index:      i,
 			DocumentID: documentID,
-			Content:    text,
+			OwnerID:    ownerID,
+			VolumeID:   chapter.VolumeID,
+			Title:      chapter.Title,
+			Kind:       chapter.Kind,
+			Content:    chapter.Content,
+			Prompt:     chapter.Prompt,
 			CreatedAt:  now,
 			UpdatedAt:  now,
 		})
@@ -213,22 +394,6 @@ func (db *Database) GetChapter(ctx context.Context, id, documentID string) (Chap
 	return gorm.G[Chapter](db.db).Where("id = ? AND document_id = ?", id, documentID).Take(ctx)
 }
 
-func (db *Database) UpdateChapter(ctx context.Context, id string, args *api.UpdateChapterArgs) error {
-	now := time.Now()
-	seg := Chapter{
-		Content:   args.Content,
-		UpdatedAt: now,
-	}
-	rowsAffected, err := gorm.G[Chapter](db.db).Where("id = ?", id).Updates(ctx, seg)
-	if err != nil {
-		return err
-	}
-	if rowsAffected == 0 {
-		return gorm.ErrRecordNotFound
-	}
-	return err
-}
-
 func (db *Database) DeleteChapter(ctx context.Context, id, documentID string) error {
 	_, err := gorm.G[Chapter](db.db).Where("id = ? AND document_id = ?", id, documentID).Delete(ctx)
 	return err
@@ -239,8 +404,26 @@ func (db *Database) DeleteAllChapter(ctx context.Context, documentID string) err
 	return err
 }
 
-func (db *Database) ListChapters(ctx context.Context, documentID string) ([]Chapter, error) {
-	return gorm.G[Chapter](db.db).Where("document_id = ?", documentID).Order("`index` ASC").Find(ctx)
+// ListChapters 按过滤、排序和分页参数查询文档的章节，同时返回过滤后的总数，args 为 nil 时返回全部章节
+func (db *Database) ListChapters(ctx context.Context, documentID string, args *api.ListChaptersArgs) ([]Chapter, int64, error) {
+	var page *api.ListArgs
+	if args == nil {
+		args = &api.ListChaptersArgs{}
+	} else {
+		page = &args.ListArgs
+	}
+	order, err := db.orderBy(chapterSortColumns, args.Sort, args.Order, db.quote("index")+" ASC", "index")
+	if err != nil {
+		return nil, 0, err
+	}
+	q := gorm.G[Chapter](db.db).Where("document_id = ?", documentID)
+	if args.VolumeID != "" {
+		q = q.Where("volume_id = ?", args.VolumeID)
+	}
+	if args.Kind != "" {
+		q = q.Where("kind = ?", args.Kind)
+	}
+	return listPage(ctx, q, page, order)
 }
 
 func (db *Database) UpdateChapterSceneIDs(ctx context.Context, chapterID string, sceneIDs []string) error {
@@ -259,12 +442,33 @@ func (db *Database) UpdateChapterSceneIDs(ctx context.Context, chapterID string,
 	return nil
 }
 
+// ===== Volume DAO =====
+
+func (db *Database) CreateVolumes(ctx context.Context, volumes []Volume) error {
+	if len(volumes) == 0 {
+		return nil
+	}
+	return gorm.G[Volume](db.db).CreateInBatches(ctx, &volumes, batchSize)
+}
+
+func (db *Database) ListVolumes(ctx context.Context, documentID string) ([]Volume, error) {
+	return gorm.G[Volume](db.db).Where("document_id = ?", documentID).Order(db.quote("index") + " ASC").Find(ctx)
+}
+
+func (db *Database) DeleteVolumesByDocument(ctx context.Context, documentID string) error {
+	_, err := gorm.G[Volume](db.db).Where("document_id = ?", documentID).Delete(ctx)
+	return err
+}
+
 // ===== Scene DAO =====
 
 func (db *Database) CreateScenes(ctx context.Context, scenes []Scene) error {
 	if len(scenes) == 0 {
 		return nil
 	}
+	if err := setOwners(ctx, db.db, scenes, func(x *Scene) (string, *int64) { return x.DocumentID, &x.OwnerID }); err != nil {
+		return err
+	}
 	return gorm.G[Scene](db.db).CreateInBatches(ctx, &scenes, batchSize)
 }
 
@@ -273,15 +477,30 @@ func (db *Database) GetScene(ctx context.Context, id string) (Scene, error) {
 }
 
 func (db *Database) ListScenesByChapter(ctx context.Context, chapterID string) ([]Scene, error) {
-	return gorm.G[Scene](db.db).Where("chapter_id = ?", chapterID).Order("`index` ASC").Find(ctx)
+	return gorm.G[Scene](db.db).Where("chapter_id = ?", chapterID).Order(db.quote("index") + " ASC").Find(ctx)
 }
 
-func (db *Database) ListScenesByDocument(ctx context.Context, documentID string) ([]Scene, error) {
-	return gorm.G[Scene](db.db).Where("document_id = ?", documentID).Order("chapter_id ASC, `index` ASC").Find(ctx)
+// ListScenesByDocument 按过滤、排序和分页参数查询文档的场景，同时返回过滤后的总数，args 为 nil 时返回全部场景
+func (db *Database) ListScenesByDocument(ctx context.Context, documentID string, args *api.ListScenesArgs) ([]Scene, int64, error) {
+	var page *api.ListArgs
+	if args == nil {
+		args = &api.ListScenesArgs{}
+	} else {
+		page = &args.ListArgs
+	}
+	order, err := db.orderBy(sceneSortColumns, args.Sort, args.Order, "chapter_id ASC, "+db.quote("index")+" ASC", "index")
+	if err != nil {
+		return nil, 0, err
+	}
+	q := gorm.G[Scene](db.db).Where("document_id = ?", documentID)
+	if args.ChapterID != "" {
+		q = q.Where("chapter_id = ?", args.ChapterID)
+	}
+	return listPage(ctx, q, page, order)
 }
 
 func (db *Database) ListPendingImageScenes(ctx context.Context, documentID string) ([]Scene, error) {
-	return gorm.G[Scene](db.db).Where("document_id = ? AND (image_url = ? OR image_url IS NULL)", documentID, "").Order("`index` ASC").Find(ctx)
+	return gorm.G[Scene](db.db).Where("document_id = ? AND (image_url = ? OR image_url IS NULL)", documentID, "").Order(db.quote("index") + " ASC").Find(ctx)
 }
 
 func (db *Database) UpdateSceneImageURL(ctx context.Context, sceneID string, imageURL string) error {
@@ -298,6 +517,21 @@ func (db *Database) UpdateSceneImageURL(ctx context.Context, sceneID string, ima
 	return nil
 }
 
+func (db *Database) UpdateSceneImageVariants(ctx context.Context, sceneID string, thumbnailURL, mediumURL string) error {
+	result := db.db.WithContext(ctx).Model(&Scene{}).Where("id = ?", sceneID).Updates(map[string]interface{}{
+		"thumbnail_url": thumbnailURL,
+		"medium_url":    mediumURL,
+		"updated_at":    time.Now(),
+	})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
 func (db *Database) UpdateSceneVoiceURL(ctx context.Context, sceneID string, voiceURL string) error {
 	result := db.db.WithContext(ctx).Model(&Scene{}).Where("id = ?", sceneID).Updates(map[string]interface{}{
 		"voice_url":  voiceURL,
@@ -333,6 +567,9 @@ func (db *Database) CreateRoles(ctx context.Context, roles []Role) error {
 	if len(roles) == 0 {
 		return nil
 	}
+	if err := setOwners(ctx, db.db, roles, func(x *Role) (string, *int64) { return x.DocumentID, &x.OwnerID }); err != nil {
+		return err
+	}
 	return gorm.G[Role](db.db).CreateInBatches(ctx, &roles, batchSize)
 }
 
@@ -340,45 +577,29 @@ func (db *Database) GetRole(ctx context.Context, id string) (Role, error) {
 	return gorm.G[Role](db.db).Where("id = ?", id).Take(ctx)
 }
 
-func (db *Database) ListRolesByDocument(ctx context.Context, documentID string) ([]Role, error) {
-	return gorm.G[Role](db.db).Where("document_id = ?", documentID).Order("created_at ASC").Find(ctx)
-}
-
-func (db *Database) DeleteRolesByDocument(ctx context.Context, documentID string) error {
-	_, err := gorm.G[Role](db.db).Where("document_id = ?", documentID).Delete(ctx)
-	return err
-}
-
-func (db *Database) UpdateRole(ctx context.Context, id string, args *api.UpdateRoleArgs) error {
-	now := time.Now()
-	role := Role{
-		Name:       args.Name,
-		Gender:     args.Gender,
-		Character:  args.Character,
-		Appearance: args.Appearance,
-		UpdatedAt:  now,
+// ListRolesByDocument 按过滤、排序和分页参数查询文档的角色，同时返回过滤后的总数，args 为 nil 时返回全部角色
+func (db *Database) ListRolesByDocument(ctx context.Context, documentID string, args *api.ListRolesArgs) ([]Role, int64, error) {
+	var page *api.ListArgs
+	if args == nil {
+		args = &api.ListRolesArgs{}
+	} else {
+		page = &args.ListArgs
 	}
-	rowsAffected, err := gorm.G[Role](db.db).Where("id = ?", id).Updates(ctx, role)
+	order, err := db.orderBy(roleSortColumns, args.Sort, args.Order, "created_at ASC", "created_at")
 	if err != nil {
-		return err
+		return nil, 0, err
 	}
-	if rowsAffected == 0 {
-		return gorm.ErrRecordNotFound
+	q := gorm.G[Role](db.db).Where("document_id = ?", documentID)
+	if args.VolumeID != "" {
+		q = q.Where("volume_id = ?", args.VolumeID)
 	}
-	return nil
+	if args.NamePrefix != "" {
+		q = q.Where("name LIKE ? ESCAPE '!'", prefixLike(args.NamePrefix))
+	}
+	return listPage(ctx, q, page, order)
 }
 
-func (db *Database) UpdateScene(ctx context.Context, id string, args *api.UpdateSceneArgs) error {
-	// 仅更新场景内容
-	result := db.db.WithContext(ctx).Model(&Scene{}).Where("id = ?", id).Updates(map[string]interface{}{
-		"content":    args.Content,
-		"updated_at": time.Now(),
-	})
-	if result.Error != nil {
-		return result.Error
-	}
-	if result.RowsAffected == 0 {
-		return gorm.ErrRecordNotFound
-	}
-	return nil
+func (db *Database) DeleteRolesByDocument(ctx context.Context, documentID string) error {
+	_, err := gorm.G[Role](db.db).Where("document_id = ?", documentID).Delete(ctx)
+	return err
 }
@@ -2,29 +2,32 @@ package db
 
 import (
 	"context"
+	"fmt"
 	"testing"
+	"time"
 
 	"imgagent/api"
+	"imgagent/pkg/dbutil"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
-	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
 )
 
 // setupTestDB 创建测试数据库
 func setupTestDB(t *testing.T) *Database {
-	// 使用内存数据库（每次测试使用独立的数据库）
-	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{
-		DisableForeignKeyConstraintWhenMigrating: true,
-	})
+	// 使用内存数据库（每次测试使用独立的数据库），表结构由迁移脚本创建
+	gdb, err := dbutil.NewDatabase(dbutil.Config{Driver: dbutil.DriverSQLite, Database: dbutil.SQLiteMemory})
 	require.NoError(t, err)
 
-	// AutoMigrate (SQLite 不需要表选项)
-	err = db.AutoMigrate(&Document{}, &Chapter{}, &Scene{}, &Role{})
+	migrator, err := NewMigrator(gdb)
+	require.NoError(t, err)
+	_, err = migrator.Up(context.Background())
 	require.NoError(t, err)
 
-	return &Database{db: db}
+	db := &Database{}
+	db.SetDB(gdb)
+	return db
 }
 
 func TestCreateDocument(t *testing.T) {
@@ -37,7 +40,7 @@ func TestCreateDocument(t *testing.T) {
 		Name: "测试文档",
 	}
 
-	doc, err := db.CreateDocument(ctx, docID, "file-id-test", args)
+	doc, err := db.CreateDocument(ctx, docID, DocumentFile{FileID: "file-id-test"}, args)
 	require.NoError(t, err)
 	assert.Equal(t, docID, doc.ID)
 	assert.Equal(t, "测试文档", doc.Name)
@@ -48,6 +51,10 @@ func TestCreateDocument(t *testing.T) {
 	found, err := db.GetDocument(ctx, docID)
 	require.NoError(t, err)
 	assert.Equal(t, doc.Name, found.Name)
+
+	// 同名文档返回通用的唯一索引冲突错误
+	_, err = db.CreateDocument(ctx, MakeUUID(), DocumentFile{FileID: "file-id-test2"}, args)
+	assert.ErrorIs(t, err, gorm.ErrDuplicatedKey)
 }
 
 func TestCreateChapters(t *testing.T) {
@@ -55,18 +62,63 @@ func TestCreateChapters(t *testing.T) {
 	ctx := context.Background()
 
 	docID := MakeUUID()
-	texts := []string{"第一章内容", "第二章内容", "第三章内容"}
+	chapters := []api.CreateChapterArgs{
+		{Title: "第一章 风起", Content: "第一章内容\n\n第二段", Prompt: "第一章内容\n第二段"},
+		{Title: "第二章 云涌", Content: "第二章内容"},
+		{Content: "第三章内容"},
+	}
 
-	err := db.CreateChapters(ctx, docID, texts)
+	err := db.CreateChapters(ctx, docID, chapters)
 	require.NoError(t, err)
 
 	// 查询章节
-	chapters, err := db.ListChapters(ctx, docID)
+	found, _, err := db.ListChapters(ctx, docID, nil)
+	require.NoError(t, err)
+	assert.Equal(t, 3, len(found))
+	assert.Equal(t, 0, found[0].Index)
+	assert.Equal(t, 1, found[1].Index)
+	assert.Equal(t, 2, found[2].Index)
+	assert.Equal(t, "第一章 风起", found[0].Title)
+	assert.Equal(t, "第一章内容\n第二段", found[0].Prompt)
+	assert.Equal(t, "第二章内容", found[1].Content)
+	assert.Equal(t, "", found[2].Title)
+}
+
+func TestVolumes(t *testing.T) {
+	db := setupTestDB(t)
+	ctx := context.Background()
+
+	docID := MakeUUID()
+	volumes := []Volume{
+		{ID: MakeUUID(), DocumentID: docID, Index: 1, Title: "第二卷 云涌", Number: 2},
+		{ID: MakeUUID(), DocumentID: docID, Index: 0, Title: "第一卷 风起", Number: 1},
+	}
+	err := db.CreateVolumes(ctx, volumes)
+	require.NoError(t, err)
+	require.NoError(t, db.CreateVolumes(ctx, nil))
+
+	err = db.CreateChapters(ctx, docID, []api.CreateChapterArgs{
+		{VolumeID: volumes[1].ID, Title: "第一章", Content: "第一章内容"},
+		{VolumeID: volumes[0].ID, Title: "第二章", Content: "第二章内容"},
+	})
+	require.NoError(t, err)
+
+	found, err := db.ListVolumes(ctx, docID)
+	require.NoError(t, err)
+	require.Equal(t, 2, len(found))
+	assert.Equal(t, "第一卷 风起", found[0].Title)
+	assert.Equal(t, 2, found[1].Number)
+
+	chapters, _, err := db.ListChapters(ctx, docID, nil)
+	require.NoError(t, err)
+	assert.Equal(t, volumes[1].ID, chapters[0].VolumeID)
+	assert.Equal(t, volumes[0].ID, chapters[1].VolumeID)
+
+	err = db.DeleteVolumesByDocument(ctx, docID)
 	require.NoError(t, err)
-	assert.Equal(t, 3, len(chapters))
-	assert.Equal(t, 0, chapters[0].Index)
-	assert.Equal(t, 1, chapters[1].Index)
-	assert.Equal(t, 2, chapters[2].Index)
+	found, err = db.ListVolumes(ctx, docID)
+	require.NoError(t, err)
+	assert.Equal(t, 0, len(found))
 }
 
 func TestCreateRoles(t *testing.T) {
@@ -97,7 +149,7 @@ func TestCreateRoles(t *testing.T) {
 	require.NoError(t, err)
 
 	// 查询角色
-	foundRoles, err := db.ListRolesByDocument(ctx, docID)
+	foundRoles, _, err := db.ListRolesByDocument(ctx, docID, nil)
 	require.NoError(t, err)
 	assert.Equal(t, 2, len(foundRoles))
 }
@@ -142,10 +194,10 @@ func TestUpdateChapterSceneIDs(t *testing.T) {
 	docID := MakeUUID()
 
 	// 先创建章节
-	err := db.CreateChapters(ctx, docID, []string{"测试内容"})
+	err := db.CreateChapters(ctx, docID, []api.CreateChapterArgs{{Content: "测试内容"}})
 	require.NoError(t, err)
 
-	chapters, err := db.ListChapters(ctx, docID)
+	chapters, _, err := db.ListChapters(ctx, docID, nil)
 	require.NoError(t, err)
 	chapterID := chapters[0].ID
 
@@ -169,9 +221,9 @@ func TestListChapterReadyDocuments(t *testing.T) {
 	doc2 := MakeUUID()
 	doc3 := MakeUUID()
 
-	db.CreateDocument(ctx, doc1, "file-id-1", &api.CreateDocumentArgs{Name: "doc1"})
-	db.CreateDocument(ctx, doc2, "file-id-2", &api.CreateDocumentArgs{Name: "doc2"})
-	db.CreateDocument(ctx, doc3, "file-id-3", &api.CreateDocumentArgs{Name: "doc3"})
+	db.CreateDocument(ctx, doc1, DocumentFile{FileID: "file-id-1"}, &api.CreateDocumentArgs{Name: "doc1"})
+	db.CreateDocument(ctx, doc2, DocumentFile{FileID: "file-id-2"}, &api.CreateDocumentArgs{Name: "doc2"})
+	db.CreateDocument(ctx, doc3, DocumentFile{FileID: "file-id-3"}, &api.CreateDocumentArgs{Name: "doc3"})
 
 	// 更新 doc2 状态为 sceneReady
 	db.UpdateDocumentStatus(ctx, doc2, DocumentStatusSceneReady)
@@ -266,9 +318,77 @@ func TestListScenesByDocument(t *testing.T) {
 	require.NoError(t, err)
 
 	// 查询文档的所有场景
-	allScenes, err := db.ListScenesByDocument(ctx, docID)
+	allScenes, _, err := db.ListScenesByDocument(ctx, docID, nil)
 	require.NoError(t, err)
 	assert.Equal(t, 3, len(allScenes))
+
+	// 按章节过滤并分页
+	args := &api.ListScenesArgs{ChapterID: chapterID1, ListArgs: api.ListArgs{Limit: 1, Offset: 1}}
+	page, total, err := db.ListScenesByDocument(ctx, docID, args)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), total)
+	require.Len(t, page, 1)
+	assert.Equal(t, "场景2", page[0].Content)
+}
+
+func TestListDocumentsFilter(t *testing.T) {
+	db := setupTestDB(t)
+	ctx := context.Background()
+
+	for _, name := range []string{"骆驼祥子", "骆驼祥子_续", "边城", "50%_off"} {
+		_, err := db.CreateDocument(ctx, MakeUUID(), DocumentFile{}, &api.CreateDocumentArgs{Name: name})
+		require.NoError(t, err)
+	}
+	docs, _, err := db.ListDocuments(ctx, nil)
+	require.NoError(t, err)
+	require.NoError(t, db.UpdateDocumentStatus(ctx, docs[0].ID, DocumentStatusRoleReady))
+
+	names := func(docs []Document) []string {
+		var ret []string
+		for _, d := range docs {
+			ret = append(ret, d.Name)
+		}
+		return ret
+	}
+
+	// 按名称排序分页
+	args := &api.ListDocumentsArgs{Sort: "name", ListArgs: api.ListArgs{Limit: 2}}
+	docs, total, err := db.ListDocuments(ctx, args)
+	require.NoError(t, err)
+	assert.Equal(t, int64(4), total)
+	assert.Equal(t, []string{"50%_off", "边城"}, names(docs))
+	args.Offset = 2
+	docs, _, err = db.ListDocuments(ctx, args)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"骆驼祥子", "骆驼祥子_续"}, names(docs))
+	args.Order = api.OrderDesc
+	args.Offset = 0
+	docs, _, err = db.ListDocuments(ctx, args)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"骆驼祥子_续", "骆驼祥子"}, names(docs))
+
+	// 名称前缀中的通配符按原样匹配
+	docs, total, err = db.ListDocuments(ctx, &api.ListDocumentsArgs{NamePrefix: "50%"})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), total)
+	assert.Equal(t, []string{"50%_off"}, names(docs))
+	_, total, err = db.ListDocuments(ctx, &api.ListDocumentsArgs{NamePrefix: "骆驼祥子_"})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), total)
+
+	// 状态和创建时间
+	_, total, err = db.ListDocuments(ctx, &api.ListDocumentsArgs{Status: DocumentStatusRoleReady})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), total)
+	_, total, err = db.ListDocuments(ctx, &api.ListDocumentsArgs{CreatedAfter: time.Now().Add(-time.Hour)})
+	require.NoError(t, err)
+	assert.Equal(t, int64(4), total)
+	_, total, err = db.ListDocuments(ctx, &api.ListDocumentsArgs{CreatedBefore: time.Now().Add(-time.Hour)})
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), total)
+
+	_, _, err = db.ListDocuments(ctx, &api.ListDocumentsArgs{Sort: "summary"})
+	assert.Error(t, err)
 }
 
 func TestUpdateDocumentFileID(t *testing.T) {
@@ -278,7 +398,7 @@ func TestUpdateDocumentFileID(t *testing.T) {
 	// 创建文档
 	docID := MakeUUID()
 	args := &api.CreateDocumentArgs{Name: "测试文档"}
-	_, err := db.CreateDocument(ctx, docID, "file-id-init", args)
+	_, err := db.CreateDocument(ctx, docID, DocumentFile{FileID: "file-id-init"}, args)
 	require.NoError(t, err)
 
 	// 更新 FileID
@@ -302,10 +422,10 @@ func TestListRoleReadyDocuments(t *testing.T) {
 	doc3 := MakeUUID()
 	doc4 := MakeUUID()
 
-	db.CreateDocument(ctx, doc1, "file-id-1", &api.CreateDocumentArgs{Name: "doc1"})
-	db.CreateDocument(ctx, doc2, "file-id-2", &api.CreateDocumentArgs{Name: "doc2"})
-	db.CreateDocument(ctx, doc3, "file-id-3", &api.CreateDocumentArgs{Name: "doc3"})
-	db.CreateDocument(ctx, doc4, "file-id-4", &api.CreateDocumentArgs{Name: "doc4"})
+	db.CreateDocument(ctx, doc1, DocumentFile{FileID: "file-id-1"}, &api.CreateDocumentArgs{Name: "doc1"})
+	db.CreateDocument(ctx, doc2, DocumentFile{FileID: "file-id-2"}, &api.CreateDocumentArgs{Name: "doc2"})
+	db.CreateDocument(ctx, doc3, DocumentFile{FileID: "file-id-3"}, &api.CreateDocumentArgs{Name: "doc3"})
+	db.CreateDocument(ctx, doc4, DocumentFile{FileID: "file-id-4"}, &api.CreateDocumentArgs{Name: "doc4"})
 
 	// 设置状态
 	db.UpdateDocumentStatus(ctx, doc1, DocumentStatusChapterReady)
@@ -329,9 +449,9 @@ func TestListSceneReadyDocuments(t *testing.T) {
 	doc2 := MakeUUID()
 	doc3 := MakeUUID()
 
-	db.CreateDocument(ctx, doc1, "file-id-1", &api.CreateDocumentArgs{Name: "doc1"})
-	db.CreateDocument(ctx, doc2, "file-id-2", &api.CreateDocumentArgs{Name: "doc2"})
-	db.CreateDocument(ctx, doc3, "file-id-3", &api.CreateDocumentArgs{Name: "doc3"})
+	db.CreateDocument(ctx, doc1, DocumentFile{FileID: "file-id-1"}, &api.CreateDocumentArgs{Name: "doc1"})
+	db.CreateDocument(ctx, doc2, DocumentFile{FileID: "file-id-2"}, &api.CreateDocumentArgs{Name: "doc2"})
+	db.CreateDocument(ctx, doc3, DocumentFile{FileID: "file-id-3"}, &api.CreateDocumentArgs{Name: "doc3"})
 
 	// 设置状态
 	db.UpdateDocumentStatus(ctx, doc1, DocumentStatusChapterReady)
@@ -345,6 +465,29 @@ func TestListSceneReadyDocuments(t *testing.T) {
 	assert.Equal(t, doc2, docs[0].ID)
 }
 
+func TestListFileReleasableDocuments(t *testing.T) {
+	db := setupTestDB(t)
+	ctx := context.Background()
+
+	doc1 := MakeUUID()
+	doc2 := MakeUUID()
+	doc3 := MakeUUID()
+
+	db.CreateDocument(ctx, doc1, DocumentFile{FileID: "file-id-1"}, &api.CreateDocumentArgs{Name: "doc1"})
+	db.CreateDocument(ctx, doc2, DocumentFile{FileID: "file-id-2"}, &api.CreateDocumentArgs{Name: "doc2"})
+	db.CreateDocument(ctx, doc3, DocumentFile{FileID: "file-id-3"}, &api.CreateDocumentArgs{Name: "doc3"})
+
+	// doc1 未完成；doc2 已完成；doc3 已完成且文件已释放
+	db.UpdateDocumentStatus(ctx, doc2, DocumentStatusImgReady)
+	db.UpdateDocumentStatus(ctx, doc3, DocumentStatusImgReady)
+	db.UpdateDocumentFileID(ctx, doc3, "")
+
+	docs, err := db.ListFileReleasableDocuments(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, len(docs))
+	assert.Equal(t, doc2, docs[0].ID)
+}
+
 func TestDeleteRolesByDocument(t *testing.T) {
 	db := setupTestDB(t)
 	ctx := context.Background()
@@ -364,7 +507,7 @@ func TestDeleteRolesByDocument(t *testing.T) {
 	require.NoError(t, err)
 
 	// 验证
-	foundRoles, err := db.ListRolesByDocument(ctx, docID)
+	foundRoles, _, err := db.ListRolesByDocument(ctx, docID, nil)
 	require.NoError(t, err)
 	assert.Equal(t, 0, len(foundRoles))
 }
@@ -389,7 +532,7 @@ func TestDeleteScenesByDocument(t *testing.T) {
 	require.NoError(t, err)
 
 	// 验证
-	foundScenes, err := db.ListScenesByDocument(ctx, docID)
+	foundScenes, _, err := db.ListScenesByDocument(ctx, docID, nil)
 	require.NoError(t, err)
 	assert.Equal(t, 0, len(foundScenes))
 }
@@ -401,15 +544,15 @@ func TestFullFlow(t *testing.T) {
 	// 1. 创建文档
 	docID := MakeUUID()
 	args := &api.CreateDocumentArgs{Name: "完整流程测试"}
-	doc, err := db.CreateDocument(ctx, docID, "file-id-full", args)
+	doc, err := db.CreateDocument(ctx, docID, DocumentFile{FileID: "file-id-full"}, args)
 	require.NoError(t, err)
 	assert.Equal(t, DocumentStatusChapterReady, doc.Status)
 
 	// 2. 创建章节
-	err = db.CreateChapters(ctx, docID, []string{"第一章", "第二章"})
+	err = db.CreateChapters(ctx, docID, []api.CreateChapterArgs{{Title: "第一章", Content: "第一章"}, {Title: "第二章", Content: "第二章"}})
 	require.NoError(t, err)
 
-	chapters, err := db.ListChapters(ctx, docID)
+	chapters, _, err := db.ListChapters(ctx, docID, nil)
 	require.NoError(t, err)
 	assert.Equal(t, 2, len(chapters))
 
@@ -420,7 +563,7 @@ func TestFullFlow(t *testing.T) {
 	err = db.CreateRoles(ctx, roles)
 	require.NoError(t, err)
 
-	foundRoles, err := db.ListRolesByDocument(ctx, docID)
+	foundRoles, _, err := db.ListRolesByDocument(ctx, docID, nil)
 	require.NoError(t, err)
 	assert.Equal(t, 1, len(foundRoles))
 
@@ -452,3 +595,114 @@ func TestFullFlow(t *testing.T) {
 	require.NoError(t, err)
 	assert.Equal(t, DocumentStatusImgReady, finalDoc.Status)
 }
+
+func TestAsset(t *testing.T) {
+	db := setupTestDB(t)
+	ctx := context.Background()
+
+	_, err := db.GetAsset(ctx, "hash-1")
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+
+	err = db.CreateAsset(ctx, &Asset{Hash: "hash-1", Operation: "tts", URL: "https://example.com/a.wav"})
+	require.NoError(t, err)
+
+	// 重复写入保留先写入的记录
+	err = db.CreateAsset(ctx, &Asset{Hash: "hash-1", Operation: "tts", URL: "https://example.com/b.wav"})
+	require.NoError(t, err)
+
+	asset, err := db.GetAsset(ctx, "hash-1")
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com/a.wav", asset.URL)
+}
+
+func TestDeleteDocumentCascade(t *testing.T) {
+	db := setupTestDB(t)
+	ctx := context.Background()
+
+	// 两个文档，只删除第一个
+	docIDs := []string{MakeUUID(), MakeUUID()}
+	for i, docID := range docIDs {
+		_, err := db.CreateDocument(ctx, docID, DocumentFile{}, &api.CreateDocumentArgs{Name: fmt.Sprintf("文档%d", i)})
+		require.NoError(t, err)
+		volumeID := MakeUUID()
+		require.NoError(t, db.CreateVolumes(ctx, []Volume{{ID: volumeID, DocumentID: docID, Title: "第一卷"}}))
+		require.NoError(t, db.CreateChapters(ctx, docID, []api.CreateChapterArgs{{VolumeID: volumeID, Title: "第一章", Content: "内容"}}))
+		chapters, _, err := db.ListChapters(ctx, docID, nil)
+		require.NoError(t, err)
+		require.NoError(t, db.CreateScenes(ctx, []Scene{{ID: MakeUUID(), ChapterID: chapters[0].ID, DocumentID: docID, Content: "场景"}}))
+		require.NoError(t, db.CreateRoles(ctx, []Role{{ID: MakeUUID(), DocumentID: docID, Name: "祥子"}}))
+	}
+	require.NoError(t, db.CreateAsset(ctx, &Asset{Hash: "hash-shared", Operation: "image", URL: "https://example.com/a.png"}))
+
+	require.NoError(t, db.DeleteDocumentCascade(ctx, docIDs[0]))
+
+	_, err := db.GetDocument(ctx, docIDs[0])
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+	chapters, _, err := db.ListChapters(ctx, docIDs[0], nil)
+	require.NoError(t, err)
+	assert.Empty(t, chapters)
+	volumes, err := db.ListVolumes(ctx, docIDs[0])
+	require.NoError(t, err)
+	assert.Empty(t, volumes)
+	scenes, _, err := db.ListScenesByDocument(ctx, docIDs[0], nil)
+	require.NoError(t, err)
+	assert.Empty(t, scenes)
+	roles, _, err := db.ListRolesByDocument(ctx, docIDs[0], nil)
+	require.NoError(t, err)
+	assert.Empty(t, roles)
+
+	// 其他文档的数据和共享的生成资源保留
+	chapters, _, err = db.ListChapters(ctx, docIDs[1], nil)
+	require.NoError(t, err)
+	assert.Len(t, chapters, 1)
+	scenes, _, err = db.ListScenesByDocument(ctx, docIDs[1], nil)
+	require.NoError(t, err)
+	assert.Len(t, scenes, 1)
+	roles, _, err = db.ListRolesByDocument(ctx, docIDs[1], nil)
+	require.NoError(t, err)
+	assert.Len(t, roles, 1)
+	_, err = db.GetAsset(ctx, "hash-shared")
+	assert.NoError(t, err)
+
+	// 文档不存在
+	assert.ErrorIs(t, db.DeleteDocumentCascade(ctx, docIDs[0]), gorm.ErrRecordNotFound)
+}
+
+func TestCompleteDocumentOCR(t *testing.T) {
+	db := setupTestDB(t)
+	ctx := WithOwner(context.Background(), Owner{ID: 2})
+
+	docID := MakeUUID()
+	file := DocumentFile{OriginFile: "origin/scan.pdf", OCRPending: true, ChapterPatterns: []string{`^第.+回`}}
+	doc, err := db.CreateDocument(ctx, docID, file, &api.CreateDocumentArgs{Name: "扫描版"})
+	require.NoError(t, err)
+	assert.Equal(t, DocumentStatusOCRPending, doc.Status)
+
+	// 等待识别的文档不进入后续任务
+	pending, err := db.ListOCRPendingDocuments(context.Background())
+	require.NoError(t, err)
+	require.Len(t, pending, 1)
+	assert.Equal(t, []string{`^第.+回`}, pending[0].ChapterPatterns)
+	ready, err := db.ListChapterReadyDocuments(context.Background())
+	require.NoError(t, err)
+	assert.Empty(t, ready)
+
+	volumes := []Volume{{ID: MakeUUID(), DocumentID: docID, Title: "第一卷"}}
+	chapters := []api.CreateChapterArgs{{Title: "第一回", Content: "话说", VolumeID: volumes[0].ID}}
+	require.NoError(t, db.CompleteDocumentOCR(context.Background(), docID, "origin/scan.txt", volumes, chapters))
+
+	doc2, err := db.GetDocument(ctx, docID)
+	require.NoError(t, err)
+	assert.Equal(t, DocumentStatusChapterReady, doc2.Status)
+	assert.Equal(t, "origin/scan.txt", doc2.OriginFile)
+	list, _, err := db.ListChapters(ctx, docID, nil)
+	require.NoError(t, err)
+	require.Len(t, list, 1)
+	assert.Equal(t, int64(2), list[0].OwnerID)
+
+	// 已完成识别时不重复创建章节
+	assert.ErrorIs(t, db.CompleteDocumentOCR(context.Background(), docID, "origin/scan.txt", nil, chapters), gorm.ErrRecordNotFound)
+	list, _, err = db.ListChapters(ctx, docID, nil)
+	require.NoError(t, err)
+	assert.Len(t, list, 1)
+}
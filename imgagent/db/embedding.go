@@ -0,0 +1,163 @@
+package db
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"math"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Embedding 章节和场景内容的语义检索向量，内容修改时删除，由后台任务重新生成
+type Embedding struct {
+	TargetType  string    `gorm:"primaryKey;size:16;comment:'记录类型 chapter|scene'"`
+	TargetID    string    `gorm:"primaryKey;size:32;comment:'章节或场景 id'"`
+	DocumentID  string    `gorm:"index:idx_embedding_document_id;size:32;comment:'文档 id'"`
+	Model       string    `gorm:"size:64;comment:'向量模型'"`
+	ContentHash string    `gorm:"size:64;comment:'生成向量的文本哈希'"`
+	Vector      []byte    `gorm:"comment:'向量，float32 小端序'"`
+	CreatedAt   time.Time `gorm:"comment:'创建时间'"`
+}
+
+func (Embedding) TableName() string {
+	return "embeddings"
+}
+
+// embeddingTargets 生成向量的章节和场景，角色信息较短，使用全文检索
+var embeddingTargets = []searchTarget{searchTargets[0], searchTargets[1]}
+
+// EncodeVector 将向量编码为 float32 小端序
+func EncodeVector(v []float32) []byte {
+	b := make([]byte, 4*len(v))
+	for i, f := range v {
+		binary.LittleEndian.PutUint32(b[4*i:], math.Float32bits(f))
+	}
+	return b
+}
+
+// DecodeVector 解码 EncodeVector 编码的向量
+func DecodeVector(b []byte) []float32 {
+	v := make([]float32, len(b)/4)
+	for i := range v {
+		v[i] = math.Float32frombits(binary.LittleEndian.Uint32(b[4*i:]))
+	}
+	return v
+}
+
+// ContentHash 生成向量的文本哈希，保存向量时用于确认内容在生成期间没有被修改
+func ContentHash(text string) string {
+	sum := sha256.Sum256([]byte(text))
+	return hex.EncodeToString(sum[:])
+}
+
+// deleteEmbedding 内容修改时删除向量，由后台任务按新内容重新生成
+func deleteEmbedding(ctx context.Context, tx *gorm.DB, targetType, targetID string) error {
+	_, err := gorm.G[Embedding](tx).Where("target_type = ? AND target_id = ?", targetType, targetID).Delete(ctx)
+	return err
+}
+
+// ===== Embedding DAO =====
+
+// ListPendingEmbeddings 查询内容不为空、还没有 model 生成的向量的章节和场景，最多返回 limit 条，回收站中的记录不生成向量
+func (db *Database) ListPendingEmbeddings(ctx context.Context, model string, limit int) ([]SearchHit, error) {
+	var hits []SearchHit
+	for _, st := range embeddingTargets {
+		if len(hits) >= limit {
+			break
+		}
+		sql := "SELECT " + st.selectColumns() + " FROM " + st.table + " t" +
+			" LEFT JOIN embeddings e ON e.target_type = ? AND e.target_id = t.id AND e.model = ?" +
+			" WHERE t.deleted_at IS NULL AND t.content <> '' AND e.target_id IS NULL LIMIT ?"
+		var rows []searchRow
+		if err := db.db.WithContext(ctx).Raw(sql, st.typ, model, limit-len(hits)).Scan(&rows).Error; err != nil {
+			return nil, err
+		}
+		hits = append(hits, makeSearchHits(st, rows)...)
+	}
+	return hits, nil
+}
+
+// SaveEmbeddings 保存向量，已有的向量被覆盖，返回保存的向量
+// 生成期间内容被修改的记录（当前内容的哈希与 ContentHash 不一致）或已被删除的记录不保存
+func (db *Database) SaveEmbeddings(ctx context.Context, embeddings []Embedding) ([]Embedding, error) {
+	var saved []Embedding
+	err := db.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for _, st := range embeddingTargets {
+			var ids []string
+			for _, e := range embeddings {
+				if e.TargetType == st.typ {
+					ids = append(ids, e.TargetID)
+				}
+			}
+			if len(ids) == 0 {
+				continue
+			}
+			hits, err := getSearchHits(ctx, tx, st, ids)
+			if err != nil {
+				return err
+			}
+			hashes := make(map[string]string, len(hits))
+			for _, hit := range hits {
+				hashes[hit.ID] = ContentHash(hit.Text)
+			}
+			for _, e := range embeddings {
+				if e.TargetType == st.typ && hashes[e.TargetID] == e.ContentHash {
+					saved = append(saved, e)
+				}
+			}
+		}
+		if len(saved) == 0 {
+			return nil
+		}
+		return tx.Clauses(clause.OnConflict{UpdateAll: true}).CreateInBatches(&saved, batchSize).Error
+	})
+	return saved, err
+}
+
+// ListEmbeddings 查询 model 生成的全部向量，用于启动时加载向量索引
+func (db *Database) ListEmbeddings(ctx context.Context, model string) ([]Embedding, error) {
+	return gorm.G[Embedding](db.db).Where("model = ?", model).Find(ctx)
+}
+
+// GetSearchHits 按 id 查询章节或场景，回收站中和不存在的记录不返回
+func (db *Database) GetSearchHits(ctx context.Context, targetType string, ids []string) ([]SearchHit, error) {
+	for _, st := range searchTargets {
+		if st.typ == targetType {
+			return getSearchHits(ctx, db.db, st, ids)
+		}
+	}
+	return nil, nil
+}
+
+func getSearchHits(ctx context.Context, tx *gorm.DB, st searchTarget, ids []string) ([]SearchHit, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	cond, vars := ownerCondition(ctx, "t")
+	sql := "SELECT " + st.selectColumns() + " FROM " + st.table + " t WHERE t.id IN ? AND t.deleted_at IS NULL" + cond
+	var rows []searchRow
+	if err := tx.WithContext(ctx).Raw(sql, append([]any{ids}, vars...)...).Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	return makeSearchHits(st, rows), nil
+}
+
+func makeSearchHits(st searchTarget, rows []searchRow) []SearchHit {
+	hits := make([]SearchHit, 0, len(rows))
+	for _, row := range rows {
+		hits = append(hits, SearchHit{
+			Type:       st.typ,
+			ID:         row.ID,
+			DocumentID: row.DocumentID,
+			ChapterID:  row.ChapterID,
+			Title:      row.Title,
+			Text:       row.text(),
+			Score:      row.Score,
+		})
+	}
+	return hits
+}
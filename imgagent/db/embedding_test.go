@@ -0,0 +1,83 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"imgagent/api"
+)
+
+func TestEncodeVector(t *testing.T) {
+	v := []float32{0.5, -1.25, 3}
+	assert.Len(t, EncodeVector(v), 12)
+	assert.Equal(t, v, DecodeVector(EncodeVector(v)))
+}
+
+func TestEmbeddings(t *testing.T) {
+	db := setupTestDB(t)
+	ctx := context.Background()
+
+	docID, chapters, scenes := createTrashTestDocument(t, db, "骆驼祥子")
+	require.NoError(t, db.UpdateChapter(ctx, chapters[0].ID, docID, &api.UpdateChapterArgs{Content: "祥子拉着洋车"}, "editor"))
+
+	// 内容为空的章节不生成向量
+	pending, err := db.ListPendingEmbeddings(ctx, "m1", 10)
+	require.NoError(t, err)
+	require.Len(t, pending, 3)
+	assert.Equal(t, api.SearchTypeChapter, pending[0].Type)
+	assert.Equal(t, chapters[0].ID, pending[0].ID)
+	assert.Equal(t, api.SearchTypeScene, pending[1].Type)
+	pending, err = db.ListPendingEmbeddings(ctx, "m1", 2)
+	require.NoError(t, err)
+	assert.Len(t, pending, 2)
+
+	embed := func(hit SearchHit, text string) Embedding {
+		return Embedding{TargetType: hit.Type, TargetID: hit.ID, DocumentID: hit.DocumentID, Model: "m1",
+			ContentHash: ContentHash(text), Vector: EncodeVector([]float32{1, 2}), CreatedAt: time.Now()}
+	}
+	// 内容已修改的记录不保存
+	saved, err := db.SaveEmbeddings(ctx, []Embedding{embed(pending[0], "祥子拉着洋车"), embed(pending[1], "旧的场景")})
+	require.NoError(t, err)
+	require.Len(t, saved, 1)
+	assert.Equal(t, chapters[0].ID, saved[0].TargetID)
+
+	pending, err = db.ListPendingEmbeddings(ctx, "m1", 10)
+	require.NoError(t, err)
+	assert.Len(t, pending, 2)
+	embeddings, err := db.ListEmbeddings(ctx, "m1")
+	require.NoError(t, err)
+	require.Len(t, embeddings, 1)
+	assert.Equal(t, []float32{1, 2}, DecodeVector(embeddings[0].Vector))
+
+	// 更换模型后重新生成
+	pending, err = db.ListPendingEmbeddings(ctx, "m2", 10)
+	require.NoError(t, err)
+	assert.Len(t, pending, 3)
+
+	// 修改内容后删除向量
+	require.NoError(t, db.UpdateChapter(ctx, chapters[0].ID, docID, &api.UpdateChapterArgs{Content: "祥子买了新车"}, "editor"))
+	embeddings, err = db.ListEmbeddings(ctx, "m1")
+	require.NoError(t, err)
+	assert.Empty(t, embeddings)
+
+	hits, err := db.GetSearchHits(ctx, api.SearchTypeScene, []string{scenes[0].ID, scenes[1].ID, "missing"})
+	require.NoError(t, err)
+	assert.Len(t, hits, 2)
+	require.NoError(t, db.DeleteScene(ctx, scenes[0].ID))
+	hits, err = db.GetSearchHits(ctx, api.SearchTypeScene, []string{scenes[0].ID, scenes[1].ID})
+	require.NoError(t, err)
+	require.Len(t, hits, 1)
+	assert.Equal(t, scenes[1].ID, hits[0].ID)
+
+	// 彻底删除文档时删除向量
+	_, err = db.SaveEmbeddings(ctx, []Embedding{embed(hits[0], hits[0].Text)})
+	require.NoError(t, err)
+	require.NoError(t, db.DeleteDocumentCascade(ctx, docID))
+	embeddings, err = db.ListEmbeddings(ctx, "m1")
+	require.NoError(t, err)
+	assert.Empty(t, embeddings)
+}
@@ -2,6 +2,7 @@ package db
 
 import (
 	"context"
+	"time"
 
 	"imgagent/api"
 )
@@ -9,10 +10,11 @@ import (
 type IDataBase interface {
 	UserToken(ctx context.Context, token string) (UserToken, error)
 	User(ctx context.Context, uid int64) (User, error)
+	ListUsers(ctx context.Context, uids []int64) ([]User, error)
 	GetAdminID(ctx context.Context) (int64, error)
 
 	// Document
-	CreateDocument(ctx context.Context, docID, fileID string, args *api.CreateDocumentArgs) (*Document, error)
+	CreateDocument(ctx context.Context, docID string, file DocumentFile, args *api.CreateDocumentArgs) (*Document, error)
 	GetDocument(ctx context.Context, id string) (Document, error)
 	GetDocumentWithName(ctx context.Context, name string) (Document, error)
 	UpdateDocument(ctx context.Context, id string, args *api.UpdateDocumentArgs) error
@@ -20,29 +22,40 @@ type IDataBase interface {
 	UpdateDocumentFileID(ctx context.Context, id string, fileID string) error
 	UpdateDocumentSummary(ctx context.Context, id string, summary string) error
 	UpdateDocumentSummaryImageURL(ctx context.Context, id string, imageURL string) error
+	UpdateDocumentSummaryImageVariants(ctx context.Context, id string, thumbnailURL, mediumURL string) error
 	DeleteDocument(ctx context.Context, id string) error
-	ListDocuments(ctx context.Context) ([]Document, error)
+	DeleteDocumentCascade(ctx context.Context, id string) error
+	ListDocuments(ctx context.Context, args *api.ListDocumentsArgs) ([]Document, int64, error)
+	ListOCRPendingDocuments(ctx context.Context) ([]Document, error)
+	CompleteDocumentOCR(ctx context.Context, id, originFile string, volumes []Volume, chapters []api.CreateChapterArgs) error
 	ListChapterReadyDocuments(ctx context.Context) ([]Document, error)
 	ListRoleReadyDocuments(ctx context.Context) ([]Document, error)
 	ListSceneReadyDocuments(ctx context.Context) ([]Document, error)
+	ListFileReleasableDocuments(ctx context.Context) ([]Document, error)
 
 	// Chapter
-	CreateChapters(ctx context.Context, documentID string, texts []string) error
+	CreateChapters(ctx context.Context, documentID string, chapters []api.CreateChapterArgs) error
 	GetChapter(ctx context.Context, id, documentID string) (Chapter, error)
-	UpdateChapter(ctx context.Context, id string, args *api.UpdateChapterArgs) error
+	UpdateChapter(ctx context.Context, id, documentID string, args *api.UpdateChapterArgs, author string) error
 	UpdateChapterSceneIDs(ctx context.Context, chapterID string, sceneIDs []string) error
 	DeleteChapter(ctx context.Context, id, documentID string) error
 	DeleteAllChapter(ctx context.Context, documentID string) error
-	ListChapters(ctx context.Context, documentID string) ([]Chapter, error)
+	ListChapters(ctx context.Context, documentID string, args *api.ListChaptersArgs) ([]Chapter, int64, error)
+
+	// Volume
+	CreateVolumes(ctx context.Context, volumes []Volume) error
+	ListVolumes(ctx context.Context, documentID string) ([]Volume, error)
+	DeleteVolumesByDocument(ctx context.Context, documentID string) error
 
 	// Scene
 	CreateScenes(ctx context.Context, scenes []Scene) error
 	GetScene(ctx context.Context, id string) (Scene, error)
 	ListScenesByChapter(ctx context.Context, chapterID string) ([]Scene, error)
-	ListScenesByDocument(ctx context.Context, documentID string) ([]Scene, error)
+	ListScenesByDocument(ctx context.Context, documentID string, args *api.ListScenesArgs) ([]Scene, int64, error)
 	ListPendingImageScenes(ctx context.Context, documentID string) ([]Scene, error)
-	UpdateScene(ctx context.Context, id string, args *api.UpdateSceneArgs) error
+	UpdateScene(ctx context.Context, id string, args *api.UpdateSceneArgs, author string) (int, error)
 	UpdateSceneImageURL(ctx context.Context, sceneID string, imageURL string) error
+	UpdateSceneImageVariants(ctx context.Context, sceneID string, thumbnailURL, mediumURL string) error
 	UpdateSceneVoiceURL(ctx context.Context, sceneID string, voiceURL string) error
 	DeleteScene(ctx context.Context, id string) error
 	DeleteScenesByChapter(ctx context.Context, chapterID string) error
@@ -51,7 +64,48 @@ type IDataBase interface {
 	// Role
 	CreateRoles(ctx context.Context, roles []Role) error
 	GetRole(ctx context.Context, id string) (Role, error)
-	ListRolesByDocument(ctx context.Context, documentID string) ([]Role, error)
-	UpdateRole(ctx context.Context, id string, args *api.UpdateRoleArgs) error
+	ListRolesByDocument(ctx context.Context, documentID string, args *api.ListRolesArgs) ([]Role, int64, error)
+	UpdateRole(ctx context.Context, id string, args *api.UpdateRoleArgs, author string) error
 	DeleteRolesByDocument(ctx context.Context, documentID string) error
+
+	// Trash
+	TrashDocument(ctx context.Context, id string) error
+	RestoreDocument(ctx context.Context, id string) error
+	ListTrashedDocuments(ctx context.Context, args *api.ListArgs) ([]Document, int64, error)
+	ListPurgeableDocuments(ctx context.Context, before time.Time) ([]Document, error)
+	TrashChapter(ctx context.Context, id, documentID string) error
+	RestoreChapter(ctx context.Context, id, documentID string) error
+	RestoreScene(ctx context.Context, id string) error
+	ListTrashedChapters(ctx context.Context, documentID string) ([]Chapter, error)
+	ListTrashedScenes(ctx context.Context, documentID string) ([]Scene, error)
+	PurgeTrash(ctx context.Context, before time.Time) error
+
+	// Member
+	DocumentRole(ctx context.Context, documentID string, userID int64) (string, error)
+	RecordDocumentID(ctx context.Context, targetType, id string) (string, error)
+	SaveMember(ctx context.Context, member *DocumentMember) error
+	DeleteMember(ctx context.Context, documentID string, userID int64) error
+	ListMembers(ctx context.Context, documentID string) ([]DocumentMember, error)
+
+	// Revision
+	ListChapterRevisions(ctx context.Context, chapterID string, args *api.ListArgs) ([]ChapterRevision, int64, error)
+	RestoreChapterRevision(ctx context.Context, id, documentID string, rev int, author string) error
+	ListSceneRevisions(ctx context.Context, sceneID string, args *api.ListArgs) ([]SceneRevision, int64, error)
+	UpdateSceneRevisionMedia(ctx context.Context, sceneID string, rev int) error
+	RestoreSceneRevision(ctx context.Context, id string, rev int, author string) error
+	ListRoleRevisions(ctx context.Context, roleID string, args *api.ListArgs) ([]RoleRevision, int64, error)
+	RestoreRoleRevision(ctx context.Context, id string, rev int, author string) error
+
+	// Search
+	Search(ctx context.Context, args *api.SearchArgs) ([]SearchHit, error)
+
+	// Embedding
+	ListPendingEmbeddings(ctx context.Context, model string, limit int) ([]SearchHit, error)
+	SaveEmbeddings(ctx context.Context, embeddings []Embedding) ([]Embedding, error)
+	ListEmbeddings(ctx context.Context, model string) ([]Embedding, error)
+	GetSearchHits(ctx context.Context, targetType string, ids []string) ([]SearchHit, error)
+
+	// Asset
+	GetAsset(ctx context.Context, hash string) (Asset, error)
+	CreateAsset(ctx context.Context, asset *Asset) error
 }
@@ -0,0 +1,71 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"gorm.io/gorm"
+
+	"imgagent/api"
+)
+
+// 列表可排序的字段，key 为请求参数中的名称
+var (
+	documentSortColumns = map[string]string{"created_at": "created_at", "updated_at": "updated_at", "name": "name"}
+	chapterSortColumns  = map[string]string{"index": "index", "created_at": "created_at", "updated_at": "updated_at"}
+	sceneSortColumns    = map[string]string{"index": "index", "created_at": "created_at", "updated_at": "updated_at"}
+	roleSortColumns     = map[string]string{"created_at": "created_at", "name": "name"}
+)
+
+// quote 按数据库方言转义字段名，index 等字段是 MySQL 的保留字，而 PostgreSQL 不支持反引号
+func (db *Database) quote(name string) string {
+	return db.db.Statement.Quote(name)
+}
+
+// orderBy 生成排序子句，sort 和 order 都为空时使用默认排序 def，只指定 order 时按 defSort 字段排序
+// 以主键作为最后的排序字段保证分页稳定
+func (db *Database) orderBy(columns map[string]string, sort, order, def, defSort string) (string, error) {
+	if sort == "" && order == "" {
+		return def + ", id ASC", nil
+	}
+	if sort == "" {
+		sort = defSort
+	}
+	column, ok := columns[sort]
+	if !ok {
+		return "", fmt.Errorf("invalid sort field: %s", sort)
+	}
+	dir := "ASC"
+	switch order {
+	case "", api.OrderAsc:
+	case api.OrderDesc:
+		dir = "DESC"
+	default:
+		return "", fmt.Errorf("invalid sort order: %s", order)
+	}
+	return db.quote(column) + " " + dir + ", id " + dir, nil
+}
+
+// listPage 统计过滤后的总数，再按排序和分页参数查询一页，args 为 nil 时返回全部记录
+func listPage[T any](ctx context.Context, q gorm.ChainInterface[T], args *api.ListArgs, order string) ([]T, int64, error) {
+	if args == nil {
+		items, err := q.Order(order).Find(ctx)
+		return items, int64(len(items)), err
+	}
+	total, err := q.Count(ctx, "*")
+	if err != nil {
+		return nil, 0, err
+	}
+	limit := args.Limit
+	if limit <= 0 {
+		limit = api.DefaultListLimit
+	}
+	items, err := q.Order(order).Offset(args.Offset).Limit(limit).Find(ctx)
+	return items, total, err
+}
+
+// prefixLike 生成前缀匹配的 LIKE 模式，通配符以 ! 转义，需配合 ESCAPE '!' 使用
+func prefixLike(prefix string) string {
+	return strings.NewReplacer("!", "!!", "%", "!%", "_", "!_").Replace(prefix) + "%"
+}
@@ -0,0 +1,100 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"imgagent/api"
+)
+
+// DocumentMember 文档成员，文档创建者（documents.owner_id）不在成员表中
+type DocumentMember struct {
+	DocumentID string    `gorm:"primaryKey;size:32;comment:'文档 id'"`
+	UserID     int64     `gorm:"primaryKey;index:idx_member_user_id;comment:'用户 id'"`
+	Role       string    `gorm:"size:16;not null;comment:'角色 viewer|editor|owner'"`
+	CreatedAt  time.Time `gorm:"comment:'加入时间'"`
+	UpdatedAt  time.Time `gorm:"comment:'更新时间'"`
+}
+
+func (DocumentMember) TableName() string {
+	return "document_members"
+}
+
+// ===== Member DAO =====
+
+// DocumentRole 返回用户在文档中的角色，文档创建者为 owner，回收站中的文档同样返回角色
+// 文档不存在或用户不是成员时返回 gorm.ErrRecordNotFound
+func (db *Database) DocumentRole(ctx context.Context, documentID string, userID int64) (string, error) {
+	doc, err := gorm.G[Document](db.db).Scopes(unscoped).Select("owner_id").Where("id = ?", documentID).Take(ctx)
+	if err != nil {
+		return "", err
+	}
+	if doc.OwnerID == userID {
+		return api.MemberRoleOwner, nil
+	}
+	member, err := gorm.G[DocumentMember](db.db).Where("document_id = ? AND user_id = ?", documentID, userID).Take(ctx)
+	if err != nil {
+		return "", err
+	}
+	return member.Role, nil
+}
+
+// RecordDocumentID 返回章节、场景或角色所属的文档 id，包括回收站中的记录
+func (db *Database) RecordDocumentID(ctx context.Context, targetType, id string) (string, error) {
+	var documentID string
+	var err error
+	switch targetType {
+	case api.SearchTypeChapter:
+		var chapter Chapter
+		chapter, err = gorm.G[Chapter](db.db).Scopes(unscoped).Select("document_id").Where("id = ?", id).Take(ctx)
+		documentID = chapter.DocumentID
+	case api.SearchTypeScene:
+		var scene Scene
+		scene, err = gorm.G[Scene](db.db).Scopes(unscoped).Select("document_id").Where("id = ?", id).Take(ctx)
+		documentID = scene.DocumentID
+	case api.SearchTypeRole:
+		var role Role
+		role, err = gorm.G[Role](db.db).Scopes(unscoped).Select("document_id").Where("id = ?", id).Take(ctx)
+		documentID = role.DocumentID
+	default:
+		err = errors.New("invalid record type: " + targetType)
+	}
+	return documentID, err
+}
+
+// SaveMember 添加文档成员，用户已是成员时修改角色，保留加入时间
+func (db *Database) SaveMember(ctx context.Context, member *DocumentMember) error {
+	now := time.Now()
+	member.CreatedAt = now
+	member.UpdatedAt = now
+	err := db.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "document_id"}, {Name: "user_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"role", "updated_at"}),
+	}).Create(member).Error
+	if err != nil {
+		return err
+	}
+	*member, err = gorm.G[DocumentMember](db.db).Where("document_id = ? AND user_id = ?", member.DocumentID, member.UserID).Take(ctx)
+	return err
+}
+
+// DeleteMember 移除文档成员，用户不是成员时返回 gorm.ErrRecordNotFound
+func (db *Database) DeleteMember(ctx context.Context, documentID string, userID int64) error {
+	rowsAffected, err := gorm.G[DocumentMember](db.db).Where("document_id = ? AND user_id = ?", documentID, userID).Delete(ctx)
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// ListMembers 查询文档成员，按加入时间排序
+func (db *Database) ListMembers(ctx context.Context, documentID string) ([]DocumentMember, error) {
+	return gorm.G[DocumentMember](db.db).Where("document_id = ?", documentID).Order("created_at ASC, user_id ASC").Find(ctx)
+}
@@ -0,0 +1,80 @@
+package db
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	"imgagent/api"
+)
+
+func TestDocumentMembers(t *testing.T) {
+	db := setupTestDB(t)
+	alice := WithOwner(context.Background(), Owner{ID: 1})
+	bob := WithOwner(context.Background(), Owner{ID: 2})
+
+	docID := MakeUUID()
+	_, err := db.CreateDocument(alice, docID, DocumentFile{}, &api.CreateDocumentArgs{Name: "骆驼祥子"})
+	require.NoError(t, err)
+	require.NoError(t, db.CreateChapters(alice, docID, []api.CreateChapterArgs{{Title: "第一章", Content: "祥子拉车"}}))
+	sceneID := MakeUUID()
+	require.NoError(t, db.CreateScenes(alice, []Scene{{ID: sceneID, DocumentID: docID, Content: "祥子买车"}}))
+
+	role, err := db.DocumentRole(alice, docID, 1)
+	require.NoError(t, err)
+	assert.Equal(t, api.MemberRoleOwner, role)
+	_, err = db.DocumentRole(alice, docID, 2)
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+
+	// 成员可以看到共享的文档及其章节、场景
+	member := DocumentMember{DocumentID: docID, UserID: 2, Role: api.MemberRoleViewer}
+	require.NoError(t, db.SaveMember(alice, &member))
+	joined := member.CreatedAt
+	role, err = db.DocumentRole(bob, docID, 2)
+	require.NoError(t, err)
+	assert.Equal(t, api.MemberRoleViewer, role)
+	docs, _, err := db.ListDocuments(bob, nil)
+	require.NoError(t, err)
+	assert.Len(t, docs, 1)
+	chapters, _, err := db.ListChapters(bob, docID, nil)
+	require.NoError(t, err)
+	assert.Len(t, chapters, 1)
+	hits, err := db.Search(bob, &api.SearchArgs{Q: "祥子"})
+	require.NoError(t, err)
+	assert.Len(t, hits, 2)
+
+	// 修改角色保留加入时间
+	member = DocumentMember{DocumentID: docID, UserID: 2, Role: api.MemberRoleEditor}
+	require.NoError(t, db.SaveMember(alice, &member))
+	assert.Equal(t, api.MemberRoleEditor, member.Role)
+	assert.True(t, joined.Equal(member.CreatedAt))
+	members, err := db.ListMembers(alice, docID)
+	require.NoError(t, err)
+	require.Len(t, members, 1)
+	assert.Equal(t, api.MemberRoleEditor, members[0].Role)
+
+	// 回收站中的场景同样返回文档 id
+	require.NoError(t, db.DeleteScene(alice, sceneID))
+	id, err := db.RecordDocumentID(bob, api.SearchTypeScene, sceneID)
+	require.NoError(t, err)
+	assert.Equal(t, docID, id)
+	_, err = db.RecordDocumentID(bob, api.SearchTypeRole, "missing")
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+
+	require.NoError(t, db.DeleteMember(alice, docID, 2))
+	assert.ErrorIs(t, db.DeleteMember(alice, docID, 2), gorm.ErrRecordNotFound)
+	_, err = db.GetDocument(bob, docID)
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+	_, err = db.RecordDocumentID(bob, api.SearchTypeScene, sceneID)
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+
+	// 彻底删除文档时删除成员
+	require.NoError(t, db.SaveMember(alice, &DocumentMember{DocumentID: docID, UserID: 2, Role: api.MemberRoleViewer}))
+	require.NoError(t, db.DeleteDocumentCascade(context.Background(), docID))
+	members, err = db.ListMembers(context.Background(), docID)
+	require.NoError(t, err)
+	assert.Empty(t, members)
+}
@@ -0,0 +1,314 @@
+package db
+
+import (
+	"context"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// migrationFS 各数据库的迁移脚本，目录名为 gorm 方言名称
+// 已执行的版本不会再次执行，表结构变更需要新增版本，不能修改已有的脚本
+//
+//go:embed migrations
+var migrationFS embed.FS
+
+// migrationFileRegex 迁移脚本文件名：0001_init.up.sql、0001_init.down.sql
+var migrationFileRegex = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+// migrationRequireRegex 脚本中的 -- +requires ENABLE_FTS5 声明依赖的 SQLite 编译选项，未编译该选项时跳过脚本中的语句，只记录版本
+var migrationRequireRegex = regexp.MustCompile(`(?m)^--\s*\+requires\s+(\w+)\s*$`)
+
+// ErrSchemaOutdated 数据库结构版本与程序不一致，需要执行 imgagent migrate up
+var ErrSchemaOutdated = errors.New("database schema is not up to date, run `imgagent migrate up`")
+
+// SchemaVersion 已执行的迁移记录
+type SchemaVersion struct {
+	Version   int       `gorm:"primaryKey;autoIncrement:false;comment:'迁移版本'"`
+	Name      string    `gorm:"size:100;comment:'迁移名称'"`
+	Dirty     bool      `gorm:"comment:'迁移执行中或执行失败，需要人工处理'"`
+	AppliedAt time.Time `gorm:"comment:'执行时间'"`
+}
+
+func (SchemaVersion) TableName() string {
+	return "schema_version"
+}
+
+// Migration 一个版本的迁移脚本
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+// MigrationStatus 迁移的执行状态
+type MigrationStatus struct {
+	Migration
+	Applied   bool
+	Dirty     bool
+	AppliedAt time.Time
+}
+
+// Migrator 按版本顺序执行 SQL 迁移脚本，执行记录保存在 schema_version 表中
+type Migrator struct {
+	db         *gorm.DB
+	migrations []Migration
+}
+
+// NewMigrator 加载 db 所用数据库的迁移脚本
+func NewMigrator(db *gorm.DB) (*Migrator, error) {
+	dialect := db.Dialector.Name()
+	sub, err := fsSubMigrations(dialect)
+	if err != nil {
+		return nil, fmt.Errorf("no migrations for database %s: %w", dialect, err)
+	}
+	migrations, err := loadMigrations(sub)
+	if err != nil {
+		return nil, fmt.Errorf("no migrations for database %s: %w", dialect, err)
+	}
+	if len(migrations) == 0 {
+		return nil, fmt.Errorf("no migrations for database %s", dialect)
+	}
+	return &Migrator{db: db, migrations: migrations}, nil
+}
+
+// fsSubMigrations 返回数据库方言对应的迁移脚本目录
+func fsSubMigrations(dialect string) (fs.FS, error) {
+	return fs.Sub(migrationFS, path.Join("migrations", dialect))
+}
+
+// loadMigrations 读取目录中的迁移脚本，版本号必须从 1 开始连续，且 up、down 脚本成对出现
+func loadMigrations(fsys fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+	byVersion := make(map[int]*Migration)
+	for _, entry := range entries {
+		m := migrationFileRegex.FindStringSubmatch(entry.Name())
+		if entry.IsDir() || m == nil {
+			continue
+		}
+		version, _ := strconv.Atoi(m[1])
+		b, err := fs.ReadFile(fsys, entry.Name())
+		if err != nil {
+			return nil, err
+		}
+		migration := byVersion[version]
+		if migration == nil {
+			migration = &Migration{Version: version, Name: m[2]}
+			byVersion[version] = migration
+		} else if migration.Name != m[2] {
+			return nil, fmt.Errorf("migration %d has different names: %s, %s", version, migration.Name, m[2])
+		}
+		if m[3] == "up" {
+			migration.Up = string(b)
+		} else {
+			migration.Down = string(b)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		if strings.TrimSpace(migration.Up) == "" || strings.TrimSpace(migration.Down) == "" {
+			return nil, fmt.Errorf("migration %d_%s must have both up and down scripts", migration.Version, migration.Name)
+		}
+		migrations = append(migrations, *migration)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	for i, migration := range migrations {
+		if migration.Version != i+1 {
+			return nil, fmt.Errorf("migration versions must be sequential from 1, missing version %d", i+1)
+		}
+	}
+	return migrations, nil
+}
+
+// splitStatements 按行尾的分号拆分 SQL 语句，忽略 -- 开头的注释行
+// 数据库驱动默认不允许一次执行多条语句；触发器的语句体中包含分号，到 END; 结束
+func splitStatements(script string) []string {
+	var statements []string
+	var current strings.Builder
+	for _, line := range strings.Split(script, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "--") {
+			continue
+		}
+		current.WriteString(line)
+		current.WriteByte('\n')
+		if strings.HasPrefix(strings.ToUpper(strings.TrimSpace(current.String())), "CREATE TRIGGER") {
+			if strings.HasSuffix(strings.ToUpper(trimmed), "END;") {
+				statements = append(statements, strings.TrimSuffix(strings.TrimSpace(current.String()), ";"))
+				current.Reset()
+			}
+			continue
+		}
+		if strings.HasSuffix(trimmed, ";") {
+			statements = append(statements, strings.TrimSuffix(strings.TrimSpace(current.String()), ";"))
+			current.Reset()
+		}
+	}
+	if s := strings.TrimSpace(current.String()); s != "" {
+		statements = append(statements, s)
+	}
+	return statements
+}
+
+// Latest 程序需要的数据库结构版本
+func (m *Migrator) Latest() int {
+	return m.migrations[len(m.migrations)-1].Version
+}
+
+// applied 查询已执行的迁移，schema_version 表不存在时返回空
+func (m *Migrator) applied(ctx context.Context) (map[int]SchemaVersion, error) {
+	if !m.db.Migrator().HasTable(&SchemaVersion{}) {
+		return nil, nil
+	}
+	versions, err := gorm.G[SchemaVersion](m.db).Find(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ret := make(map[int]SchemaVersion, len(versions))
+	for _, v := range versions {
+		ret[v.Version] = v
+	}
+	return ret, nil
+}
+
+// Version 返回当前数据库结构版本，以及是否有执行失败的迁移
+func (m *Migrator) Version(ctx context.Context) (version int, dirty bool, err error) {
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return 0, false, err
+	}
+	for _, v := range applied {
+		version = max(version, v.Version)
+		dirty = dirty || v.Dirty
+	}
+	return version, dirty, nil
+}
+
+// Status 返回每个迁移的执行状态
+func (m *Migrator) Status(ctx context.Context) ([]MigrationStatus, error) {
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+	status := make([]MigrationStatus, 0, len(m.migrations))
+	for _, migration := range m.migrations {
+		v, ok := applied[migration.Version]
+		status = append(status, MigrationStatus{Migration: migration, Applied: ok, Dirty: v.Dirty, AppliedAt: v.AppliedAt})
+	}
+	return status, nil
+}
+
+// Check 检查数据库结构是否为程序需要的版本，服务启动时调用
+func (m *Migrator) Check(ctx context.Context) error {
+	version, dirty, err := m.Version(ctx)
+	if err != nil {
+		return err
+	}
+	if dirty {
+		return fmt.Errorf("%w: migration %d failed, fix the schema and the schema_version table manually", ErrSchemaOutdated, version)
+	}
+	if version != m.Latest() {
+		return fmt.Errorf("%w: current version %d, required version %d", ErrSchemaOutdated, version, m.Latest())
+	}
+	return nil
+}
+
+// Up 依次执行所有未执行的迁移，返回执行的迁移
+func (m *Migrator) Up(ctx context.Context) ([]Migration, error) {
+	if err := m.db.WithContext(ctx).AutoMigrate(&SchemaVersion{}); err != nil {
+		return nil, err
+	}
+	version, dirty, err := m.Version(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if dirty {
+		return nil, fmt.Errorf("migration %d is dirty, fix the schema and the schema_version table manually", version)
+	}
+	if version > m.Latest() {
+		return nil, fmt.Errorf("schema version %d is newer than this program (%d)", version, m.Latest())
+	}
+
+	var done []Migration
+	for _, migration := range m.migrations[version:] {
+		record := SchemaVersion{Version: migration.Version, Name: migration.Name, Dirty: true, AppliedAt: time.Now()}
+		if err := gorm.G[SchemaVersion](m.db).Create(ctx, &record); err != nil {
+			return done, err
+		}
+		if err := m.exec(ctx, migration.Up); err != nil {
+			return done, fmt.Errorf("migrate up %d_%s: %w", migration.Version, migration.Name, err)
+		}
+		if _, err := gorm.G[SchemaVersion](m.db).Where("version = ?", migration.Version).Update(ctx, "dirty", false); err != nil {
+			return done, err
+		}
+		done = append(done, migration)
+	}
+	return done, nil
+}
+
+// Down 回滚最近执行的 steps 个迁移，返回回滚的迁移
+func (m *Migrator) Down(ctx context.Context, steps int) ([]Migration, error) {
+	version, dirty, err := m.Version(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if dirty {
+		return nil, fmt.Errorf("migration %d is dirty, fix the schema and the schema_version table manually", version)
+	}
+	if version > m.Latest() {
+		return nil, fmt.Errorf("schema version %d is newer than this program (%d)", version, m.Latest())
+	}
+
+	var done []Migration
+	for ; steps > 0 && version > 0; steps-- {
+		migration := m.migrations[version-1]
+		if _, err := gorm.G[SchemaVersion](m.db).Where("version = ?", migration.Version).Update(ctx, "dirty", true); err != nil {
+			return done, err
+		}
+		if err := m.exec(ctx, migration.Down); err != nil {
+			return done, fmt.Errorf("migrate down %d_%s: %w", migration.Version, migration.Name, err)
+		}
+		if _, err := gorm.G[SchemaVersion](m.db).Where("version = ?", migration.Version).Delete(ctx); err != nil {
+			return done, err
+		}
+		done = append(done, migration)
+		version--
+	}
+	return done, nil
+}
+
+// exec 逐条执行脚本中的语句
+// MySQL 的 DDL 语句会隐式提交，不能放在事务中，执行失败时迁移记录保持 dirty
+func (m *Migrator) exec(ctx context.Context, script string) error {
+	for _, option := range migrationRequireRegex.FindAllStringSubmatch(script, -1) {
+		var used int
+		if err := m.db.WithContext(ctx).Raw("SELECT sqlite_compileoption_used(?)", option[1]).Scan(&used).Error; err != nil {
+			return err
+		}
+		if used == 0 {
+			zap.S().Warnf("SQLite is not compiled with %s, skip the migration statements", option[1])
+			return nil
+		}
+	}
+	for _, statement := range splitStatements(script) {
+		if err := m.db.WithContext(ctx).Exec(statement).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
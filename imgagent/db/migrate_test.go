@@ -0,0 +1,298 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+
+	"imgagent/api"
+	"imgagent/pkg/dbutil"
+)
+
+// setupTestMigrator 使用内存数据库和测试脚本创建 Migrator
+func setupTestMigrator(t *testing.T, fsys fstest.MapFS) *Migrator {
+	gdb, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	migrations, err := loadMigrations(fsys)
+	require.NoError(t, err)
+	return &Migrator{db: gdb, migrations: migrations}
+}
+
+func testMigrationFS() fstest.MapFS {
+	return fstest.MapFS{
+		"0001_init.up.sql":       {Data: []byte("-- 初始表\nCREATE TABLE a (id INTEGER PRIMARY KEY);\nCREATE TABLE b (id INTEGER PRIMARY KEY);\n")},
+		"0001_init.down.sql":     {Data: []byte("DROP TABLE b;\nDROP TABLE a;\n")},
+		"0002_add_name.up.sql":   {Data: []byte("ALTER TABLE a ADD COLUMN name TEXT;\n")},
+		"0002_add_name.down.sql": {Data: []byte("ALTER TABLE a DROP COLUMN name;\n")},
+	}
+}
+
+func TestMigratorUpDown(t *testing.T) {
+	m := setupTestMigrator(t, testMigrationFS())
+	ctx := context.Background()
+
+	// 未迁移的数据库无法通过检查
+	err := m.Check(ctx)
+	assert.True(t, errors.Is(err, ErrSchemaOutdated))
+
+	done, err := m.Up(ctx)
+	require.NoError(t, err)
+	assert.Len(t, done, 2)
+	require.NoError(t, m.Check(ctx))
+	assert.True(t, m.db.Migrator().HasColumn("a", "name"))
+	assert.True(t, m.db.Migrator().HasTable("b"))
+
+	// 重复执行不做任何事
+	done, err = m.Up(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, done)
+
+	// 回滚一个版本
+	done, err = m.Down(ctx, 1)
+	require.NoError(t, err)
+	require.Len(t, done, 1)
+	assert.Equal(t, 2, done[0].Version)
+	assert.False(t, m.db.Migrator().HasColumn("a", "name"))
+	assert.True(t, errors.Is(m.Check(ctx), ErrSchemaOutdated))
+
+	status, err := m.Status(ctx)
+	require.NoError(t, err)
+	require.Len(t, status, 2)
+	assert.True(t, status[0].Applied)
+	assert.False(t, status[1].Applied)
+
+	// 回滚数量超过已执行的版本时全部回滚
+	done, err = m.Down(ctx, 5)
+	require.NoError(t, err)
+	assert.Len(t, done, 1)
+	assert.False(t, m.db.Migrator().HasTable("a"))
+	version, _, err := m.Version(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 0, version)
+}
+
+func TestMigratorDirty(t *testing.T) {
+	fsys := testMigrationFS()
+	fsys["0002_add_name.up.sql"] = &fstest.MapFile{Data: []byte("ALTER TABLE a ADD COLUMN name TEXT;\nALTER TABLE missing ADD COLUMN name TEXT;\n")}
+	m := setupTestMigrator(t, fsys)
+	ctx := context.Background()
+
+	done, err := m.Up(ctx)
+	require.Error(t, err)
+	assert.Len(t, done, 1)
+
+	// 执行失败的迁移保持 dirty，需要人工处理后才能继续
+	version, dirty, err := m.Version(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 2, version)
+	assert.True(t, dirty)
+	assert.True(t, errors.Is(m.Check(ctx), ErrSchemaOutdated))
+	_, err = m.Up(ctx)
+	assert.Error(t, err)
+	_, err = m.Down(ctx, 1)
+	assert.Error(t, err)
+}
+
+func TestLoadMigrations(t *testing.T) {
+	// 缺少 down 脚本
+	_, err := loadMigrations(fstest.MapFS{"0001_init.up.sql": {Data: []byte("SELECT 1;")}})
+	assert.Error(t, err)
+
+	// 版本不连续
+	fsys := testMigrationFS()
+	fsys["0004_skip.up.sql"] = &fstest.MapFile{Data: []byte("SELECT 1;")}
+	fsys["0004_skip.down.sql"] = &fstest.MapFile{Data: []byte("SELECT 1;")}
+	_, err = loadMigrations(fsys)
+	assert.Error(t, err)
+
+	// 内置的迁移脚本，各数据库的版本必须一致
+	var latest []Migration
+	for _, dialect := range []string{"mysql", "postgres", "sqlite"} {
+		sub, err := fsSubMigrations(dialect)
+		require.NoError(t, err)
+		migrations, err := loadMigrations(sub)
+		require.NoError(t, err, dialect)
+		require.NotEmpty(t, migrations, dialect)
+		if latest != nil {
+			require.Len(t, migrations, len(latest), dialect)
+			for i := range migrations {
+				assert.Equal(t, latest[i].Name, migrations[i].Name, dialect)
+			}
+		}
+		latest = migrations
+	}
+
+	// 没有迁移脚本的数据库
+	sub, err := fsSubMigrations("sqlserver")
+	require.NoError(t, err)
+	_, err = loadMigrations(sub)
+	assert.Error(t, err)
+}
+
+func TestSplitStatements(t *testing.T) {
+	statements := splitStatements("-- comment\nCREATE TABLE a (\n  id INT\n);\n\nDROP TABLE b;\nSELECT 1")
+	require.Len(t, statements, 3)
+	assert.Equal(t, "CREATE TABLE a (\n  id INT\n)", statements[0])
+	assert.Equal(t, "DROP TABLE b", statements[1])
+	assert.Equal(t, "SELECT 1", statements[2])
+
+	// 触发器的语句体到 END; 结束
+	statements = splitStatements("CREATE TRIGGER t AFTER UPDATE ON a BEGIN\n  DELETE FROM b;\n  INSERT INTO b VALUES (1);\nEND;\nDROP TABLE c;")
+	require.Len(t, statements, 2)
+	assert.Equal(t, "CREATE TRIGGER t AFTER UPDATE ON a BEGIN\n  DELETE FROM b;\n  INSERT INTO b VALUES (1);\nEND", statements[0])
+	assert.Equal(t, "DROP TABLE c", statements[1])
+}
+
+// openMemoryDB 打开独立的 SQLite 内存数据库
+func openMemoryDB(t *testing.T) *gorm.DB {
+	gdb, err := dbutil.NewDatabase(dbutil.Config{Driver: dbutil.DriverSQLite, Database: dbutil.SQLiteMemory})
+	require.NoError(t, err)
+	return gdb
+}
+
+// sqliteSchema 返回表的字段、索引的字段，用于比较两个数据库的表结构
+func sqliteSchema(t *testing.T, gdb *gorm.DB) []string {
+	var columns []struct {
+		Tbl, Name, Type, Dflt string
+		NotNull, PK           int
+	}
+	require.NoError(t, gdb.Raw(`SELECT m.name AS tbl, p.name, p.type, COALESCE(p.dflt_value, '') AS dflt, p."notnull" AS not_null, p.pk
+		FROM sqlite_master m, pragma_table_info(m.name) p WHERE m.type = 'table' AND m.name != 'schema_version' ORDER BY m.name, p.cid`).Scan(&columns).Error)
+	var indexes []struct{ Tbl, Name, Columns string }
+	require.NoError(t, gdb.Raw(`SELECT m.tbl_name AS tbl, m.name, group_concat(i.name) AS columns
+		FROM sqlite_master m, pragma_index_info(m.name) i WHERE m.type = 'index' GROUP BY m.name ORDER BY m.name`).Scan(&indexes).Error)
+
+	var schema []string
+	for _, c := range columns {
+		schema = append(schema, fmt.Sprintf("%s.%s %s notnull=%d pk=%d default=%s", c.Tbl, c.Name, c.Type, c.NotNull, c.PK, c.Dflt))
+	}
+	for _, i := range indexes {
+		schema = append(schema, fmt.Sprintf("index %s on %s(%s)", i.Name, i.Tbl, i.Columns))
+	}
+	return schema
+}
+
+func TestMigrateFromAutoMigrate(t *testing.T) {
+	ctx := context.Background()
+
+	// 使用 AutoMigrate 建表的数据库，升级前已有数据
+	gdb := openMemoryDB(t)
+	require.NoError(t, gdb.AutoMigrate(&baselineDocument{}, &baselineChapter{}, &baselineScene{}, &baselineRole{}))
+	now := time.Now()
+	require.NoError(t, gdb.Create(&baselineDocument{ID: "doc1", Name: "旧文档", Status: DocumentStatusChapterReady, CreatedAt: now, UpdatedAt: now}).Error)
+	require.NoError(t, gdb.Create(&baselineChapter{ID: "ch1", Index: 0, DocumentID: "doc1", Title: "第一章", Content: "旧内容", CreatedAt: now, UpdatedAt: now}).Error)
+
+	m, err := NewMigrator(gdb)
+	require.NoError(t, err)
+	done, err := m.Up(ctx)
+	require.NoError(t, err)
+	assert.Len(t, done, m.Latest())
+	require.NoError(t, m.Check(ctx))
+
+	// 升级后的表结构与新建的数据库一致
+	fresh := openMemoryDB(t)
+	freshMigrator, err := NewMigrator(fresh)
+	require.NoError(t, err)
+	_, err = freshMigrator.Up(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, sqliteSchema(t, fresh), sqliteSchema(t, gdb))
+
+	// 已有数据在升级后可以正常读写，升级前的文档只有超级管理员可见，回收站中的文档不占用名称
+	db := &Database{}
+	db.SetDB(gdb)
+	adminCtx := WithOwner(ctx, Owner{SuperAdmin: true})
+	doc, err := db.GetDocument(adminCtx, "doc1")
+	require.NoError(t, err)
+	assert.Equal(t, "旧文档", doc.Name)
+	chapter, err := db.GetChapter(adminCtx, "ch1", "doc1")
+	require.NoError(t, err)
+	assert.Equal(t, "旧内容", chapter.Content)
+	hits, err := db.Search(adminCtx, &api.SearchArgs{Q: "旧内容"})
+	require.NoError(t, err)
+	require.Len(t, hits, 1)
+	assert.Equal(t, "ch1", hits[0].ID)
+	require.NoError(t, db.TrashDocument(adminCtx, "doc1"))
+	_, err = db.CreateDocument(adminCtx, MakeUUID(), DocumentFile{}, &api.CreateDocumentArgs{Name: "旧文档"})
+	require.NoError(t, err)
+
+	// 全部回滚后恢复为 AutoMigrate 的表结构，可以重新执行
+	baseline := openMemoryDB(t)
+	require.NoError(t, baseline.AutoMigrate(&baselineDocument{}, &baselineChapter{}, &baselineScene{}, &baselineRole{}))
+	done, err = freshMigrator.Down(ctx, m.Latest()-1)
+	require.NoError(t, err)
+	assert.Len(t, done, m.Latest()-1)
+	assert.Equal(t, sqliteSchema(t, baseline), sqliteSchema(t, fresh))
+	_, err = freshMigrator.Up(ctx)
+	require.NoError(t, err)
+	require.NoError(t, freshMigrator.Check(ctx))
+}
+
+// 最后一个使用 AutoMigrate 建表的版本的表结构，用于验证从该版本升级
+type baselineDocument struct {
+	ID              string    `gorm:"primaryKey;size:32;comment:'主键'"`
+	Name            string    `gorm:"uniqueIndex:uk_name;size:128;comment:'文档名称'"`
+	FileID          string    `gorm:"size:255;comment:'存储在阿里云百炼的 fileid'"`
+	Summary         string    `gorm:"size:1000;comment:'小说摘要'"`
+	SummaryImageURL string    `gorm:"size:500;comment:'小说封面图URL'"`
+	Status          string    `gorm:"size:20;comment:'状态 indexing|ready'"`
+	CreatedAt       time.Time `gorm:"comment:'创建时间'"`
+	UpdatedAt       time.Time `gorm:"comment:'更新时间'"`
+}
+
+func (baselineDocument) TableName() string {
+	return "documents"
+}
+
+type baselineChapter struct {
+	ID         string    `gorm:"primaryKey;size:32;comment:'主键'"`
+	Index      int       `gorm:"uniqueIndex:uk_document_index,priority:2;comment:'章节序号'"`
+	DocumentID string    `gorm:"uniqueIndex:uk_document_index,priority:1;size:32;comment:'文档 id'"`
+	Title      string    `gorm:"size:100;comment:'标题'"`
+	Content    string    `gorm:"size:10000;comment:'章节内容'"`
+	SceneIDs   []string  `gorm:"type:json;serializer:json;comment:'故事场景'"`
+	CreatedAt  time.Time `gorm:"comment:'创建时间'"`
+	UpdatedAt  time.Time `gorm:"comment:'更新时间'"`
+}
+
+func (baselineChapter) TableName() string {
+	return "chapters"
+}
+
+type baselineScene struct {
+	ID         string    `gorm:"primaryKey;size:32;comment:'主键'"`
+	ChapterID  string    `gorm:"index:idx_chapter_id;size:32;comment:'chapter id'"`
+	DocumentID string    `gorm:"index:idx_document_id;size:32;comment:'文档 id'"`
+	Index      int       `gorm:"comment:'场景序号'"`
+	Content    string    `gorm:"size:1000;comment:'场景描述'"`
+	ImageURL   string    `gorm:"size:500;comment:'场景图片url'"`
+	VoiceURL   string    `gorm:"size:500;comment:'音频url'"`
+	CreatedAt  time.Time `gorm:"comment:'创建时间'"`
+	UpdatedAt  time.Time `gorm:"comment:'更新时间'"`
+}
+
+func (baselineScene) TableName() string {
+	return "scenes"
+}
+
+type baselineRole struct {
+	ID         string    `gorm:"primaryKey;size:32;comment:'主键'"`
+	DocumentID string    `gorm:"index:idx_role_document_id;size:32;comment:'文档 id'"`
+	Name       string    `gorm:"size:50;comment:'角色名字'"`
+	Gender     string    `gorm:"size:10;comment:'性别'"`
+	Character  string    `gorm:"size:500;comment:'性格特点'"`
+	Appearance string    `gorm:"size:500;comment:'外貌描述'"`
+	CreatedAt  time.Time `gorm:"comment:'创建时间'"`
+	UpdatedAt  time.Time `gorm:"comment:'更新时间'"`
+}
+
+func (baselineRole) TableName() string {
+	return "roles"
+}
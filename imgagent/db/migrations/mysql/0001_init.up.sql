@@ -0,0 +1,56 @@
+-- 初始表结构，与使用 AutoMigrate 建表的最后一个版本一致
+-- 使用 IF NOT EXISTS，已由该版本建表的数据库执行后只记录版本，之后的迁移在其基础上修改表结构
+
+CREATE TABLE IF NOT EXISTS `documents` (
+  `id` varchar(32) NOT NULL COMMENT '主键',
+  `name` varchar(128) DEFAULT NULL COMMENT '文档名称',
+  `file_id` varchar(255) DEFAULT NULL COMMENT '存储在阿里云百炼的 fileid',
+  `summary` varchar(1000) DEFAULT NULL COMMENT '小说摘要',
+  `summary_image_url` varchar(500) DEFAULT NULL COMMENT '小说封面图URL',
+  `status` varchar(20) DEFAULT NULL COMMENT '状态 indexing|ready',
+  `created_at` datetime(3) DEFAULT NULL COMMENT '创建时间',
+  `updated_at` datetime(3) DEFAULT NULL COMMENT '更新时间',
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `uk_name` (`name`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+CREATE TABLE IF NOT EXISTS `chapters` (
+  `id` varchar(32) NOT NULL COMMENT '主键',
+  `index` bigint DEFAULT NULL COMMENT '章节序号',
+  `document_id` varchar(32) DEFAULT NULL COMMENT '文档 id',
+  `title` varchar(100) DEFAULT NULL COMMENT '标题',
+  `content` varchar(10000) DEFAULT NULL COMMENT '章节内容',
+  `scene_ids` json DEFAULT NULL COMMENT '故事场景',
+  `created_at` datetime(3) DEFAULT NULL COMMENT '创建时间',
+  `updated_at` datetime(3) DEFAULT NULL COMMENT '更新时间',
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `uk_document_index` (`document_id`, `index`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+CREATE TABLE IF NOT EXISTS `scenes` (
+  `id` varchar(32) NOT NULL COMMENT '主键',
+  `chapter_id` varchar(32) DEFAULT NULL COMMENT 'chapter id',
+  `document_id` varchar(32) DEFAULT NULL COMMENT '文档 id',
+  `index` bigint DEFAULT NULL COMMENT '场景序号',
+  `content` varchar(1000) DEFAULT NULL COMMENT '场景描述',
+  `image_url` varchar(500) DEFAULT NULL COMMENT '场景图片url',
+  `voice_url` varchar(500) DEFAULT NULL COMMENT '音频url',
+  `created_at` datetime(3) DEFAULT NULL COMMENT '创建时间',
+  `updated_at` datetime(3) DEFAULT NULL COMMENT '更新时间',
+  PRIMARY KEY (`id`),
+  KEY `idx_chapter_id` (`chapter_id`),
+  KEY `idx_document_id` (`document_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+CREATE TABLE IF NOT EXISTS `roles` (
+  `id` varchar(32) NOT NULL COMMENT '主键',
+  `document_id` varchar(32) DEFAULT NULL COMMENT '文档 id',
+  `name` varchar(50) DEFAULT NULL COMMENT '角色名字',
+  `gender` varchar(10) DEFAULT NULL COMMENT '性别',
+  `character` varchar(500) DEFAULT NULL COMMENT '性格特点',
+  `appearance` varchar(500) DEFAULT NULL COMMENT '外貌描述',
+  `created_at` datetime(3) DEFAULT NULL COMMENT '创建时间',
+  `updated_at` datetime(3) DEFAULT NULL COMMENT '更新时间',
+  PRIMARY KEY (`id`),
+  KEY `idx_role_document_id` (`document_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
@@ -0,0 +1,3 @@
+-- 保存原始文件路径，百炼文件过期后重新上传
+
+ALTER TABLE `documents` ADD COLUMN `origin_file` varchar(255) DEFAULT NULL COMMENT '原始文件路径，用于重新上传百炼' AFTER `file_id`;
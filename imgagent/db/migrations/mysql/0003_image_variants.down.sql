@@ -0,0 +1,2 @@
+ALTER TABLE `scenes` DROP COLUMN `medium_url`, DROP COLUMN `thumbnail_url`;
+ALTER TABLE `documents` DROP COLUMN `medium_url`, DROP COLUMN `thumbnail_url`;
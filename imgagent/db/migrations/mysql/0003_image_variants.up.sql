@@ -0,0 +1,8 @@
+-- 封面和场景图片的缩略图、中图
+
+ALTER TABLE `documents`
+  ADD COLUMN `thumbnail_url` varchar(500) DEFAULT NULL COMMENT '封面缩略图URL' AFTER `summary_image_url`,
+  ADD COLUMN `medium_url` varchar(500) DEFAULT NULL COMMENT '封面中图URL' AFTER `thumbnail_url`;
+ALTER TABLE `scenes`
+  ADD COLUMN `thumbnail_url` varchar(500) DEFAULT NULL COMMENT '场景缩略图url' AFTER `image_url`,
+  ADD COLUMN `medium_url` varchar(500) DEFAULT NULL COMMENT '场景中图url' AFTER `thumbnail_url`;
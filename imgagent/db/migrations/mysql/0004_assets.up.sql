@@ -0,0 +1,12 @@
+-- 语音和图片生成结果的缓存，按请求内容哈希复用
+
+CREATE TABLE IF NOT EXISTS `assets` (
+  `hash` varchar(64) NOT NULL COMMENT '请求内容 sha256',
+  `operation` varchar(20) DEFAULT NULL COMMENT '操作类型 tts|image',
+  `model` varchar(50) DEFAULT NULL COMMENT '模型名称',
+  `url` varchar(500) DEFAULT NULL COMMENT '资源url',
+  `thumbnail_url` varchar(500) DEFAULT NULL COMMENT '缩略图url',
+  `medium_url` varchar(500) DEFAULT NULL COMMENT '中图url',
+  `created_at` datetime(3) DEFAULT NULL COMMENT '创建时间',
+  PRIMARY KEY (`hash`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
@@ -0,0 +1,3 @@
+-- 保存上传文件检测到的编码
+
+ALTER TABLE `documents` ADD COLUMN `encoding` varchar(20) DEFAULT NULL COMMENT '原始文件编码，txt、md 上传时检测' AFTER `origin_file`;
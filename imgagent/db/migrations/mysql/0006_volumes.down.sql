@@ -0,0 +1,3 @@
+ALTER TABLE `roles` DROP COLUMN `volume_id`;
+ALTER TABLE `chapters` DROP INDEX `idx_volume_id`, DROP COLUMN `volume_id`;
+DROP TABLE IF EXISTS `volumes`;
@@ -0,0 +1,17 @@
+-- 章节之上的分卷，角色按卷划分
+
+CREATE TABLE IF NOT EXISTS `volumes` (
+  `id` varchar(32) NOT NULL COMMENT '主键',
+  `index` bigint DEFAULT NULL COMMENT '卷序号',
+  `document_id` varchar(32) DEFAULT NULL COMMENT '文档 id',
+  `title` varchar(100) DEFAULT NULL COMMENT '标题',
+  `number` bigint DEFAULT NULL COMMENT '原文中的卷号，未识别时为 0',
+  `created_at` datetime(3) DEFAULT NULL COMMENT '创建时间',
+  `updated_at` datetime(3) DEFAULT NULL COMMENT '更新时间',
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `uk_volume_document_index` (`document_id`, `index`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+ALTER TABLE `chapters`
+  ADD COLUMN `volume_id` varchar(32) DEFAULT NULL COMMENT '所属卷 id，未分卷时为空' AFTER `document_id`,
+  ADD KEY `idx_volume_id` (`volume_id`);
+ALTER TABLE `roles` ADD COLUMN `volume_id` varchar(32) DEFAULT NULL COMMENT '角色出场的卷 id，为空表示全书角色' AFTER `document_id`;
@@ -0,0 +1,3 @@
+-- 区分正文章节和序章、尾声、番外
+
+ALTER TABLE `chapters` ADD COLUMN `kind` varchar(20) DEFAULT NULL COMMENT '章节类型 chapter|prologue|epilogue|extra' AFTER `title`;
@@ -0,0 +1,3 @@
+ALTER TABLE `chapters`
+  DROP COLUMN `prompt`,
+  MODIFY COLUMN `content` varchar(10000) DEFAULT NULL COMMENT '章节内容';
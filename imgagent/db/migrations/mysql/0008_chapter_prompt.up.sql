@@ -0,0 +1,6 @@
+-- 章节同时保存展示形式和提示词形式的内容
+-- 两个 varchar(10000) 字段超过 InnoDB 的行大小限制，使用 mediumtext
+
+ALTER TABLE `chapters`
+  MODIFY COLUMN `content` mediumtext DEFAULT NULL COMMENT '章节内容，展示形式',
+  ADD COLUMN `prompt` mediumtext DEFAULT NULL COMMENT '章节内容，提示词形式' AFTER `content`;
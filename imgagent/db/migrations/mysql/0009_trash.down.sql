@@ -0,0 +1,9 @@
+ALTER TABLE `roles` DROP INDEX `idx_roles_deleted_at`, DROP COLUMN `deleted_at`;
+ALTER TABLE `scenes` DROP INDEX `idx_scenes_deleted_at`, DROP COLUMN `deleted_at`;
+ALTER TABLE `chapters` DROP INDEX `idx_chapters_deleted_at`, DROP COLUMN `deleted_at`;
+ALTER TABLE `documents`
+  DROP INDEX `idx_documents_deleted_at`,
+  DROP INDEX `uk_name_deleted_key`,
+  ADD UNIQUE KEY `uk_name` (`name`),
+  DROP COLUMN `deleted_at`,
+  DROP COLUMN `deleted_key`;
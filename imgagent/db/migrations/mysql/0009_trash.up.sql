@@ -0,0 +1,18 @@
+-- 软删除：删除的文档、章节、场景和角色进入回收站
+-- 文档名称的唯一索引加上删除标记，回收站中的文档不占用名称
+
+ALTER TABLE `documents`
+  ADD COLUMN `deleted_key` varchar(32) NOT NULL DEFAULT '' COMMENT '删除标记，未删除时为空，回收站中为文档 id，与名称组成唯一索引使回收站中的文档不占用名称',
+  ADD COLUMN `deleted_at` datetime(3) DEFAULT NULL COMMENT '删除时间，不为空表示在回收站中',
+  DROP INDEX `uk_name`,
+  ADD UNIQUE KEY `uk_name_deleted_key` (`name`, `deleted_key`),
+  ADD KEY `idx_documents_deleted_at` (`deleted_at`);
+ALTER TABLE `chapters`
+  ADD COLUMN `deleted_at` datetime(3) DEFAULT NULL COMMENT '删除时间，不为空表示在回收站中',
+  ADD KEY `idx_chapters_deleted_at` (`deleted_at`);
+ALTER TABLE `scenes`
+  ADD COLUMN `deleted_at` datetime(3) DEFAULT NULL COMMENT '删除时间，不为空表示在回收站中',
+  ADD KEY `idx_scenes_deleted_at` (`deleted_at`);
+ALTER TABLE `roles`
+  ADD COLUMN `deleted_at` datetime(3) DEFAULT NULL COMMENT '删除时间，不为空表示在回收站中',
+  ADD KEY `idx_roles_deleted_at` (`deleted_at`);
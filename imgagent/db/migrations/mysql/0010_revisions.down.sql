@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS `role_revisions`;
+DROP TABLE IF EXISTS `scene_revisions`;
+DROP TABLE IF EXISTS `chapter_revisions`;
@@ -0,0 +1,48 @@
+-- 章节、场景和角色的修订记录
+
+CREATE TABLE IF NOT EXISTS `chapter_revisions` (
+  `chapter_id` varchar(32) NOT NULL COMMENT '章节 id',
+  `rev` bigint NOT NULL COMMENT '修订号，从 1 开始',
+  `document_id` varchar(32) DEFAULT NULL COMMENT '文档 id',
+  `author` varchar(64) DEFAULT NULL COMMENT '修改人，为空表示生成的原始版本',
+  `content` mediumtext DEFAULT NULL COMMENT '章节内容，展示形式',
+  `prompt` mediumtext DEFAULT NULL COMMENT '章节内容，提示词形式',
+  `diff` mediumtext COMMENT '与上一修订的差异',
+  `restored_from` bigint DEFAULT NULL COMMENT '从哪个修订恢复，0 表示编辑',
+  `created_at` datetime(3) DEFAULT NULL COMMENT '创建时间',
+  PRIMARY KEY (`chapter_id`, `rev`),
+  KEY `idx_chapter_revision_document_id` (`document_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+CREATE TABLE IF NOT EXISTS `scene_revisions` (
+  `scene_id` varchar(32) NOT NULL COMMENT '场景 id',
+  `rev` bigint NOT NULL COMMENT '修订号，从 1 开始',
+  `document_id` varchar(32) DEFAULT NULL COMMENT '文档 id',
+  `author` varchar(64) DEFAULT NULL COMMENT '修改人，为空表示生成的原始版本',
+  `content` varchar(1000) DEFAULT NULL COMMENT '场景描述',
+  `image_url` varchar(500) DEFAULT NULL COMMENT '场景图片url',
+  `thumbnail_url` varchar(500) DEFAULT NULL COMMENT '场景缩略图url',
+  `medium_url` varchar(500) DEFAULT NULL COMMENT '场景中图url',
+  `voice_url` varchar(500) DEFAULT NULL COMMENT '音频url',
+  `diff` text COMMENT '与上一修订的差异',
+  `restored_from` bigint DEFAULT NULL COMMENT '从哪个修订恢复，0 表示编辑',
+  `created_at` datetime(3) DEFAULT NULL COMMENT '创建时间',
+  PRIMARY KEY (`scene_id`, `rev`),
+  KEY `idx_scene_revision_document_id` (`document_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+CREATE TABLE IF NOT EXISTS `role_revisions` (
+  `role_id` varchar(32) NOT NULL COMMENT '角色 id',
+  `rev` bigint NOT NULL COMMENT '修订号，从 1 开始',
+  `document_id` varchar(32) DEFAULT NULL COMMENT '文档 id',
+  `author` varchar(64) DEFAULT NULL COMMENT '修改人，为空表示生成的原始版本',
+  `name` varchar(50) DEFAULT NULL COMMENT '角色名字',
+  `gender` varchar(10) DEFAULT NULL COMMENT '性别',
+  `character` varchar(500) DEFAULT NULL COMMENT '性格特点',
+  `appearance` varchar(500) DEFAULT NULL COMMENT '外貌描述',
+  `diff` text COMMENT '与上一修订的差异',
+  `restored_from` bigint DEFAULT NULL COMMENT '从哪个修订恢复，0 表示编辑',
+  `created_at` datetime(3) DEFAULT NULL COMMENT '创建时间',
+  PRIMARY KEY (`role_id`, `rev`),
+  KEY `idx_role_revision_document_id` (`document_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
@@ -0,0 +1,3 @@
+ALTER TABLE `roles` DROP INDEX `ft_role_name_appearance`;
+ALTER TABLE `scenes` DROP INDEX `ft_scene_content`;
+ALTER TABLE `chapters` DROP INDEX `ft_chapter_content`;
@@ -0,0 +1,5 @@
+-- 全文检索索引，使用 ngram 分词支持中文，分词长度由 ngram_token_size 控制（默认 2）
+
+ALTER TABLE `chapters` ADD FULLTEXT INDEX `ft_chapter_content` (`content`) WITH PARSER ngram;
+ALTER TABLE `scenes` ADD FULLTEXT INDEX `ft_scene_content` (`content`) WITH PARSER ngram;
+ALTER TABLE `roles` ADD FULLTEXT INDEX `ft_role_name_appearance` (`name`, `appearance`) WITH PARSER ngram;
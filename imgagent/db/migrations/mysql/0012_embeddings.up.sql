@@ -0,0 +1,13 @@
+-- 章节和场景内容的语义检索向量
+
+CREATE TABLE IF NOT EXISTS `embeddings` (
+  `target_type` varchar(16) NOT NULL COMMENT '记录类型 chapter|scene',
+  `target_id` varchar(32) NOT NULL COMMENT '章节或场景 id',
+  `document_id` varchar(32) DEFAULT NULL COMMENT '文档 id',
+  `model` varchar(64) DEFAULT NULL COMMENT '向量模型',
+  `content_hash` varchar(64) DEFAULT NULL COMMENT '生成向量的文本哈希',
+  `vector` blob COMMENT '向量，float32 小端序',
+  `created_at` datetime(3) DEFAULT NULL COMMENT '创建时间',
+  PRIMARY KEY (`target_type`, `target_id`),
+  KEY `idx_embedding_document_id` (`document_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
@@ -0,0 +1,7 @@
+ALTER TABLE `roles` DROP COLUMN `owner_id`;
+ALTER TABLE `scenes` DROP COLUMN `owner_id`;
+ALTER TABLE `chapters` DROP COLUMN `owner_id`;
+ALTER TABLE `documents`
+  DROP INDEX `uk_owner_name_deleted_key`,
+  ADD UNIQUE KEY `uk_name_deleted_key` (`name`, `deleted_key`),
+  DROP COLUMN `owner_id`;
@@ -0,0 +1,10 @@
+-- 文档按用户隔离，章节、场景和角色冗余文档的 owner_id 便于按用户过滤
+-- 升级前创建的文档 owner_id 为 0，只有超级管理员可见
+
+ALTER TABLE `documents`
+  ADD COLUMN `owner_id` bigint NOT NULL DEFAULT 0 COMMENT '所属用户 id' AFTER `id`,
+  DROP INDEX `uk_name_deleted_key`,
+  ADD UNIQUE KEY `uk_owner_name_deleted_key` (`owner_id`, `name`, `deleted_key`);
+ALTER TABLE `chapters` ADD COLUMN `owner_id` bigint NOT NULL DEFAULT 0 COMMENT '所属用户 id，与文档一致' AFTER `document_id`;
+ALTER TABLE `scenes` ADD COLUMN `owner_id` bigint NOT NULL DEFAULT 0 COMMENT '所属用户 id，与文档一致' AFTER `document_id`;
+ALTER TABLE `roles` ADD COLUMN `owner_id` bigint NOT NULL DEFAULT 0 COMMENT '所属用户 id，与文档一致' AFTER `document_id`;
@@ -0,0 +1,11 @@
+-- 文档成员，文档创建者以外的用户按角色访问文档
+
+CREATE TABLE IF NOT EXISTS `document_members` (
+  `document_id` varchar(32) NOT NULL COMMENT '文档 id',
+  `user_id` bigint NOT NULL COMMENT '用户 id',
+  `role` varchar(16) NOT NULL COMMENT '角色 viewer|editor|owner',
+  `created_at` datetime(3) DEFAULT NULL COMMENT '加入时间',
+  `updated_at` datetime(3) DEFAULT NULL COMMENT '更新时间',
+  PRIMARY KEY (`document_id`, `user_id`),
+  KEY `idx_member_user_id` (`user_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
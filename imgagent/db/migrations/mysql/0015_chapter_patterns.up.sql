@@ -0,0 +1,3 @@
+-- 扫描版 PDF 在后台识别文字后分割章节，需要保存上传时指定的章节标题正则
+
+ALTER TABLE `documents` ADD COLUMN `chapter_patterns` json DEFAULT NULL COMMENT '章节标题正则，后台分割章节时使用' AFTER `encoding`;
@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS "roles";
+DROP TABLE IF EXISTS "scenes";
+DROP TABLE IF EXISTS "chapters";
+DROP TABLE IF EXISTS "documents";
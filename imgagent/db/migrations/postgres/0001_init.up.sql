@@ -0,0 +1,56 @@
+-- 初始表结构，字段含义见 mysql/0001_init.up.sql
+-- 使用 IF NOT EXISTS，已由 AutoMigrate 建表的数据库执行后只记录版本
+
+CREATE TABLE IF NOT EXISTS "documents" (
+  "id" varchar(32) NOT NULL,
+  "name" varchar(128),
+  "file_id" varchar(255),
+  "summary" varchar(1000),
+  "summary_image_url" varchar(500),
+  "status" varchar(20),
+  "created_at" timestamptz,
+  "updated_at" timestamptz,
+  PRIMARY KEY ("id")
+);
+CREATE UNIQUE INDEX IF NOT EXISTS "uk_name" ON "documents" ("name");
+
+CREATE TABLE IF NOT EXISTS "chapters" (
+  "id" varchar(32) NOT NULL,
+  "index" bigint,
+  "document_id" varchar(32),
+  "title" varchar(100),
+  "content" varchar(10000),
+  "scene_ids" json,
+  "created_at" timestamptz,
+  "updated_at" timestamptz,
+  PRIMARY KEY ("id")
+);
+CREATE UNIQUE INDEX IF NOT EXISTS "uk_document_index" ON "chapters" ("document_id", "index");
+
+CREATE TABLE IF NOT EXISTS "scenes" (
+  "id" varchar(32) NOT NULL,
+  "chapter_id" varchar(32),
+  "document_id" varchar(32),
+  "index" bigint,
+  "content" varchar(1000),
+  "image_url" varchar(500),
+  "voice_url" varchar(500),
+  "created_at" timestamptz,
+  "updated_at" timestamptz,
+  PRIMARY KEY ("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_chapter_id" ON "scenes" ("chapter_id");
+CREATE INDEX IF NOT EXISTS "idx_document_id" ON "scenes" ("document_id");
+
+CREATE TABLE IF NOT EXISTS "roles" (
+  "id" varchar(32) NOT NULL,
+  "document_id" varchar(32),
+  "name" varchar(50),
+  "gender" varchar(10),
+  "character" varchar(500),
+  "appearance" varchar(500),
+  "created_at" timestamptz,
+  "updated_at" timestamptz,
+  PRIMARY KEY ("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_role_document_id" ON "roles" ("document_id");
@@ -0,0 +1,2 @@
+ALTER TABLE "scenes" DROP COLUMN IF EXISTS "medium_url", DROP COLUMN IF EXISTS "thumbnail_url";
+ALTER TABLE "documents" DROP COLUMN IF EXISTS "medium_url", DROP COLUMN IF EXISTS "thumbnail_url";
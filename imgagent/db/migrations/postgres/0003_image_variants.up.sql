@@ -0,0 +1,4 @@
+-- 封面和场景图片的缩略图、中图，字段含义见 mysql/0003_image_variants.up.sql
+
+ALTER TABLE "documents" ADD COLUMN "thumbnail_url" varchar(500), ADD COLUMN "medium_url" varchar(500);
+ALTER TABLE "scenes" ADD COLUMN "thumbnail_url" varchar(500), ADD COLUMN "medium_url" varchar(500);
@@ -0,0 +1,12 @@
+-- 语音和图片生成结果的缓存，字段含义见 mysql/0004_assets.up.sql
+
+CREATE TABLE IF NOT EXISTS "assets" (
+  "hash" varchar(64) NOT NULL,
+  "operation" varchar(20),
+  "model" varchar(50),
+  "url" varchar(500),
+  "thumbnail_url" varchar(500),
+  "medium_url" varchar(500),
+  "created_at" timestamptz,
+  PRIMARY KEY ("hash")
+);
@@ -0,0 +1,3 @@
+-- 保存上传文件检测到的编码，字段含义见 mysql/0005_encoding.up.sql
+
+ALTER TABLE "documents" ADD COLUMN "encoding" varchar(20);
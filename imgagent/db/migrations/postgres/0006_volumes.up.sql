@@ -0,0 +1,16 @@
+-- 章节之上的分卷，字段含义见 mysql/0006_volumes.up.sql
+
+CREATE TABLE IF NOT EXISTS "volumes" (
+  "id" varchar(32) NOT NULL,
+  "index" bigint,
+  "document_id" varchar(32),
+  "title" varchar(100),
+  "number" bigint,
+  "created_at" timestamptz,
+  "updated_at" timestamptz,
+  PRIMARY KEY ("id")
+);
+CREATE UNIQUE INDEX IF NOT EXISTS "uk_volume_document_index" ON "volumes" ("document_id", "index");
+ALTER TABLE "chapters" ADD COLUMN "volume_id" varchar(32);
+CREATE INDEX IF NOT EXISTS "idx_volume_id" ON "chapters" ("volume_id");
+ALTER TABLE "roles" ADD COLUMN "volume_id" varchar(32);
@@ -0,0 +1,3 @@
+-- 章节类型，字段含义见 mysql/0007_chapter_kind.up.sql
+
+ALTER TABLE "chapters" ADD COLUMN "kind" varchar(20);
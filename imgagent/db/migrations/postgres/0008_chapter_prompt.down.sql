@@ -0,0 +1 @@
+ALTER TABLE "chapters" DROP COLUMN IF EXISTS "prompt", ALTER COLUMN "content" TYPE varchar(10000);
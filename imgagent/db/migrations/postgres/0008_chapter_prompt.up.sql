@@ -0,0 +1,3 @@
+-- 章节提示词形式的内容，字段含义见 mysql/0008_chapter_prompt.up.sql
+
+ALTER TABLE "chapters" ALTER COLUMN "content" TYPE text, ADD COLUMN "prompt" text;
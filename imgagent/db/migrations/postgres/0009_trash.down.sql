@@ -0,0 +1,10 @@
+DROP INDEX IF EXISTS "idx_roles_deleted_at";
+ALTER TABLE "roles" DROP COLUMN IF EXISTS "deleted_at";
+DROP INDEX IF EXISTS "idx_scenes_deleted_at";
+ALTER TABLE "scenes" DROP COLUMN IF EXISTS "deleted_at";
+DROP INDEX IF EXISTS "idx_chapters_deleted_at";
+ALTER TABLE "chapters" DROP COLUMN IF EXISTS "deleted_at";
+DROP INDEX IF EXISTS "idx_documents_deleted_at";
+DROP INDEX IF EXISTS "uk_name_deleted_key";
+ALTER TABLE "documents" DROP COLUMN IF EXISTS "deleted_at", DROP COLUMN IF EXISTS "deleted_key";
+CREATE UNIQUE INDEX IF NOT EXISTS "uk_name" ON "documents" ("name");
@@ -0,0 +1,12 @@
+-- 软删除，字段含义见 mysql/0009_trash.up.sql
+
+ALTER TABLE "documents" ADD COLUMN "deleted_key" varchar(32) NOT NULL DEFAULT '', ADD COLUMN "deleted_at" timestamptz;
+DROP INDEX IF EXISTS "uk_name";
+CREATE UNIQUE INDEX IF NOT EXISTS "uk_name_deleted_key" ON "documents" ("name", "deleted_key");
+CREATE INDEX IF NOT EXISTS "idx_documents_deleted_at" ON "documents" ("deleted_at");
+ALTER TABLE "chapters" ADD COLUMN "deleted_at" timestamptz;
+CREATE INDEX IF NOT EXISTS "idx_chapters_deleted_at" ON "chapters" ("deleted_at");
+ALTER TABLE "scenes" ADD COLUMN "deleted_at" timestamptz;
+CREATE INDEX IF NOT EXISTS "idx_scenes_deleted_at" ON "scenes" ("deleted_at");
+ALTER TABLE "roles" ADD COLUMN "deleted_at" timestamptz;
+CREATE INDEX IF NOT EXISTS "idx_roles_deleted_at" ON "roles" ("deleted_at");
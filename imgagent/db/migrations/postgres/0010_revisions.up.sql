@@ -0,0 +1,48 @@
+-- 章节、场景和角色的修订记录，字段含义见 mysql/0010_revisions.up.sql
+
+CREATE TABLE IF NOT EXISTS "chapter_revisions" (
+  "chapter_id" varchar(32) NOT NULL,
+  "rev" bigint NOT NULL,
+  "document_id" varchar(32),
+  "author" varchar(64),
+  "content" text,
+  "prompt" text,
+  "diff" text,
+  "restored_from" bigint,
+  "created_at" timestamptz,
+  PRIMARY KEY ("chapter_id", "rev")
+);
+CREATE INDEX IF NOT EXISTS "idx_chapter_revision_document_id" ON "chapter_revisions" ("document_id");
+
+CREATE TABLE IF NOT EXISTS "scene_revisions" (
+  "scene_id" varchar(32) NOT NULL,
+  "rev" bigint NOT NULL,
+  "document_id" varchar(32),
+  "author" varchar(64),
+  "content" varchar(1000),
+  "image_url" varchar(500),
+  "thumbnail_url" varchar(500),
+  "medium_url" varchar(500),
+  "voice_url" varchar(500),
+  "diff" text,
+  "restored_from" bigint,
+  "created_at" timestamptz,
+  PRIMARY KEY ("scene_id", "rev")
+);
+CREATE INDEX IF NOT EXISTS "idx_scene_revision_document_id" ON "scene_revisions" ("document_id");
+
+CREATE TABLE IF NOT EXISTS "role_revisions" (
+  "role_id" varchar(32) NOT NULL,
+  "rev" bigint NOT NULL,
+  "document_id" varchar(32),
+  "author" varchar(64),
+  "name" varchar(50),
+  "gender" varchar(10),
+  "character" varchar(500),
+  "appearance" varchar(500),
+  "diff" text,
+  "restored_from" bigint,
+  "created_at" timestamptz,
+  PRIMARY KEY ("role_id", "rev")
+);
+CREATE INDEX IF NOT EXISTS "idx_role_revision_document_id" ON "role_revisions" ("document_id");
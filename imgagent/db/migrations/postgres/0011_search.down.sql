@@ -0,0 +1 @@
+-- 全文检索索引：PostgreSQL 使用 LIKE 检索，无需删除索引
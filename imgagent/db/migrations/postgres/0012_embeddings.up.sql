@@ -0,0 +1,13 @@
+-- 章节和场景内容的语义检索向量，字段含义见 mysql/0012_embeddings.up.sql
+
+CREATE TABLE IF NOT EXISTS "embeddings" (
+  "target_type" varchar(16) NOT NULL,
+  "target_id" varchar(32) NOT NULL,
+  "document_id" varchar(32),
+  "model" varchar(64),
+  "content_hash" varchar(64),
+  "vector" bytea,
+  "created_at" timestamptz,
+  PRIMARY KEY ("target_type", "target_id")
+);
+CREATE INDEX IF NOT EXISTS "idx_embedding_document_id" ON "embeddings" ("document_id");
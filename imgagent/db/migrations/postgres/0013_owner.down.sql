@@ -0,0 +1,6 @@
+ALTER TABLE "roles" DROP COLUMN IF EXISTS "owner_id";
+ALTER TABLE "scenes" DROP COLUMN IF EXISTS "owner_id";
+ALTER TABLE "chapters" DROP COLUMN IF EXISTS "owner_id";
+DROP INDEX IF EXISTS "uk_owner_name_deleted_key";
+ALTER TABLE "documents" DROP COLUMN IF EXISTS "owner_id";
+CREATE UNIQUE INDEX IF NOT EXISTS "uk_name_deleted_key" ON "documents" ("name", "deleted_key");
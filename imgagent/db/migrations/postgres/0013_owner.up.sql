@@ -0,0 +1,8 @@
+-- 文档按用户隔离，字段含义见 mysql/0013_owner.up.sql
+
+ALTER TABLE "documents" ADD COLUMN "owner_id" bigint NOT NULL DEFAULT 0;
+DROP INDEX IF EXISTS "uk_name_deleted_key";
+CREATE UNIQUE INDEX IF NOT EXISTS "uk_owner_name_deleted_key" ON "documents" ("owner_id", "name", "deleted_key");
+ALTER TABLE "chapters" ADD COLUMN "owner_id" bigint NOT NULL DEFAULT 0;
+ALTER TABLE "scenes" ADD COLUMN "owner_id" bigint NOT NULL DEFAULT 0;
+ALTER TABLE "roles" ADD COLUMN "owner_id" bigint NOT NULL DEFAULT 0;
@@ -0,0 +1,11 @@
+-- 文档成员，字段含义见 mysql/0014_document_members.up.sql
+
+CREATE TABLE IF NOT EXISTS "document_members" (
+  "document_id" varchar(32) NOT NULL,
+  "user_id" bigint NOT NULL,
+  "role" varchar(16) NOT NULL,
+  "created_at" timestamptz,
+  "updated_at" timestamptz,
+  PRIMARY KEY ("document_id", "user_id")
+);
+CREATE INDEX IF NOT EXISTS "idx_member_user_id" ON "document_members" ("user_id");
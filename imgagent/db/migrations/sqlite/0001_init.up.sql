@@ -0,0 +1,56 @@
+-- 初始表结构，字段含义见 mysql/0001_init.up.sql
+-- 使用 IF NOT EXISTS，已由 AutoMigrate 建表的数据库执行后只记录版本
+
+CREATE TABLE IF NOT EXISTS `documents` (
+  `id` text,
+  `name` text,
+  `file_id` text,
+  `summary` text,
+  `summary_image_url` text,
+  `status` text,
+  `created_at` datetime,
+  `updated_at` datetime,
+  PRIMARY KEY (`id`)
+);
+CREATE UNIQUE INDEX IF NOT EXISTS `uk_name` ON `documents` (`name`);
+
+CREATE TABLE IF NOT EXISTS `chapters` (
+  `id` text,
+  `index` integer,
+  `document_id` text,
+  `title` text,
+  `content` text,
+  `scene_ids` json,
+  `created_at` datetime,
+  `updated_at` datetime,
+  PRIMARY KEY (`id`)
+);
+CREATE UNIQUE INDEX IF NOT EXISTS `uk_document_index` ON `chapters` (`document_id`, `index`);
+
+CREATE TABLE IF NOT EXISTS `scenes` (
+  `id` text,
+  `chapter_id` text,
+  `document_id` text,
+  `index` integer,
+  `content` text,
+  `image_url` text,
+  `voice_url` text,
+  `created_at` datetime,
+  `updated_at` datetime,
+  PRIMARY KEY (`id`)
+);
+CREATE INDEX IF NOT EXISTS `idx_chapter_id` ON `scenes` (`chapter_id`);
+CREATE INDEX IF NOT EXISTS `idx_document_id` ON `scenes` (`document_id`);
+
+CREATE TABLE IF NOT EXISTS `roles` (
+  `id` text,
+  `document_id` text,
+  `name` text,
+  `gender` text,
+  `character` text,
+  `appearance` text,
+  `created_at` datetime,
+  `updated_at` datetime,
+  PRIMARY KEY (`id`)
+);
+CREATE INDEX IF NOT EXISTS `idx_role_document_id` ON `roles` (`document_id`);
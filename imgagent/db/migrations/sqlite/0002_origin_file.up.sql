@@ -0,0 +1,3 @@
+-- 保存原始文件路径，字段含义见 mysql/0002_origin_file.up.sql
+
+ALTER TABLE `documents` ADD COLUMN `origin_file` text;
@@ -0,0 +1,4 @@
+ALTER TABLE `scenes` DROP COLUMN `medium_url`;
+ALTER TABLE `scenes` DROP COLUMN `thumbnail_url`;
+ALTER TABLE `documents` DROP COLUMN `medium_url`;
+ALTER TABLE `documents` DROP COLUMN `thumbnail_url`;
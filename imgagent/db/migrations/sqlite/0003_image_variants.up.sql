@@ -0,0 +1,6 @@
+-- 封面和场景图片的缩略图、中图，字段含义见 mysql/0003_image_variants.up.sql
+
+ALTER TABLE `documents` ADD COLUMN `thumbnail_url` text;
+ALTER TABLE `documents` ADD COLUMN `medium_url` text;
+ALTER TABLE `scenes` ADD COLUMN `thumbnail_url` text;
+ALTER TABLE `scenes` ADD COLUMN `medium_url` text;
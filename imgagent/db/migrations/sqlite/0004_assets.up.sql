@@ -0,0 +1,12 @@
+-- 语音和图片生成结果的缓存，字段含义见 mysql/0004_assets.up.sql
+
+CREATE TABLE IF NOT EXISTS `assets` (
+  `hash` text NOT NULL,
+  `operation` text,
+  `model` text,
+  `url` text,
+  `thumbnail_url` text,
+  `medium_url` text,
+  `created_at` datetime,
+  PRIMARY KEY (`hash`)
+);
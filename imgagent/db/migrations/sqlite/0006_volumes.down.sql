@@ -0,0 +1,4 @@
+ALTER TABLE `roles` DROP COLUMN `volume_id`;
+DROP INDEX IF EXISTS `idx_volume_id`;
+ALTER TABLE `chapters` DROP COLUMN `volume_id`;
+DROP TABLE IF EXISTS `volumes`;
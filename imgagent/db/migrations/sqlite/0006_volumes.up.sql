@@ -0,0 +1,16 @@
+-- 章节之上的分卷，字段含义见 mysql/0006_volumes.up.sql
+
+CREATE TABLE IF NOT EXISTS `volumes` (
+  `id` text NOT NULL,
+  `index` integer,
+  `document_id` text,
+  `title` text,
+  `number` integer,
+  `created_at` datetime,
+  `updated_at` datetime,
+  PRIMARY KEY (`id`)
+);
+CREATE UNIQUE INDEX IF NOT EXISTS `uk_volume_document_index` ON `volumes` (`document_id`, `index`);
+ALTER TABLE `chapters` ADD COLUMN `volume_id` text;
+CREATE INDEX IF NOT EXISTS `idx_volume_id` ON `chapters` (`volume_id`);
+ALTER TABLE `roles` ADD COLUMN `volume_id` text;
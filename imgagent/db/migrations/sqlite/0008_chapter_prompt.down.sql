@@ -0,0 +1 @@
+ALTER TABLE `chapters` DROP COLUMN `prompt`;
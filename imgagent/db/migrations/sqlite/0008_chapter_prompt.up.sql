@@ -0,0 +1,3 @@
+-- 章节提示词形式的内容，字段含义见 mysql/0008_chapter_prompt.up.sql，content 已是 text 类型
+
+ALTER TABLE `chapters` ADD COLUMN `prompt` text;
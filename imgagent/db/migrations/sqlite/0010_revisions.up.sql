@@ -0,0 +1,48 @@
+-- 章节、场景和角色的修订记录，字段含义见 mysql/0010_revisions.up.sql
+
+CREATE TABLE IF NOT EXISTS `chapter_revisions` (
+  `chapter_id` text NOT NULL,
+  `rev` integer NOT NULL,
+  `document_id` text,
+  `author` text,
+  `content` text,
+  `prompt` text,
+  `diff` text,
+  `restored_from` integer,
+  `created_at` datetime,
+  PRIMARY KEY (`chapter_id`, `rev`)
+);
+CREATE INDEX IF NOT EXISTS `idx_chapter_revision_document_id` ON `chapter_revisions` (`document_id`);
+
+CREATE TABLE IF NOT EXISTS `scene_revisions` (
+  `scene_id` text NOT NULL,
+  `rev` integer NOT NULL,
+  `document_id` text,
+  `author` text,
+  `content` text,
+  `image_url` text,
+  `thumbnail_url` text,
+  `medium_url` text,
+  `voice_url` text,
+  `diff` text,
+  `restored_from` integer,
+  `created_at` datetime,
+  PRIMARY KEY (`scene_id`, `rev`)
+);
+CREATE INDEX IF NOT EXISTS `idx_scene_revision_document_id` ON `scene_revisions` (`document_id`);
+
+CREATE TABLE IF NOT EXISTS `role_revisions` (
+  `role_id` text NOT NULL,
+  `rev` integer NOT NULL,
+  `document_id` text,
+  `author` text,
+  `name` text,
+  `gender` text,
+  `character` text,
+  `appearance` text,
+  `diff` text,
+  `restored_from` integer,
+  `created_at` datetime,
+  PRIMARY KEY (`role_id`, `rev`)
+);
+CREATE INDEX IF NOT EXISTS `idx_role_revision_document_id` ON `role_revisions` (`document_id`);
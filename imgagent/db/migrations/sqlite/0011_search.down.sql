@@ -0,0 +1,12 @@
+DROP TRIGGER IF EXISTS `chapters_fts_ai`;
+DROP TRIGGER IF EXISTS `chapters_fts_ad`;
+DROP TRIGGER IF EXISTS `chapters_fts_au`;
+DROP TRIGGER IF EXISTS `scenes_fts_ai`;
+DROP TRIGGER IF EXISTS `scenes_fts_ad`;
+DROP TRIGGER IF EXISTS `scenes_fts_au`;
+DROP TRIGGER IF EXISTS `roles_fts_ai`;
+DROP TRIGGER IF EXISTS `roles_fts_ad`;
+DROP TRIGGER IF EXISTS `roles_fts_au`;
+DROP TABLE IF EXISTS `chapters_fts`;
+DROP TABLE IF EXISTS `scenes_fts`;
+DROP TABLE IF EXISTS `roles_fts`;
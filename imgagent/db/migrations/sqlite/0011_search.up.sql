@@ -0,0 +1,43 @@
+-- 全文检索索引：FTS5 trigram 分词，触发器同步表的修改，创建时导入已有数据
+-- FTS5 需要 go-sqlite3 使用 -tags sqlite_fts5 编译，未编译时跳过本脚本，检索使用 LIKE
+-- 创建后需要一直使用支持 FTS5 的程序，否则触发器无法写入索引表
+-- +requires ENABLE_FTS5
+
+CREATE VIRTUAL TABLE IF NOT EXISTS `chapters_fts` USING fts5(`id` UNINDEXED, `content`, tokenize = 'trigram');
+CREATE TRIGGER IF NOT EXISTS `chapters_fts_ai` AFTER INSERT ON `chapters` BEGIN
+  INSERT INTO `chapters_fts` (`id`, `content`) VALUES (new.`id`, new.`content`);
+END;
+CREATE TRIGGER IF NOT EXISTS `chapters_fts_ad` AFTER DELETE ON `chapters` BEGIN
+  DELETE FROM `chapters_fts` WHERE `id` = old.`id`;
+END;
+CREATE TRIGGER IF NOT EXISTS `chapters_fts_au` AFTER UPDATE OF `content` ON `chapters` BEGIN
+  DELETE FROM `chapters_fts` WHERE `id` = old.`id`;
+  INSERT INTO `chapters_fts` (`id`, `content`) VALUES (new.`id`, new.`content`);
+END;
+INSERT INTO `chapters_fts` (`id`, `content`) SELECT `id`, `content` FROM `chapters`;
+
+CREATE VIRTUAL TABLE IF NOT EXISTS `scenes_fts` USING fts5(`id` UNINDEXED, `content`, tokenize = 'trigram');
+CREATE TRIGGER IF NOT EXISTS `scenes_fts_ai` AFTER INSERT ON `scenes` BEGIN
+  INSERT INTO `scenes_fts` (`id`, `content`) VALUES (new.`id`, new.`content`);
+END;
+CREATE TRIGGER IF NOT EXISTS `scenes_fts_ad` AFTER DELETE ON `scenes` BEGIN
+  DELETE FROM `scenes_fts` WHERE `id` = old.`id`;
+END;
+CREATE TRIGGER IF NOT EXISTS `scenes_fts_au` AFTER UPDATE OF `content` ON `scenes` BEGIN
+  DELETE FROM `scenes_fts` WHERE `id` = old.`id`;
+  INSERT INTO `scenes_fts` (`id`, `content`) VALUES (new.`id`, new.`content`);
+END;
+INSERT INTO `scenes_fts` (`id`, `content`) SELECT `id`, `content` FROM `scenes`;
+
+CREATE VIRTUAL TABLE IF NOT EXISTS `roles_fts` USING fts5(`id` UNINDEXED, `name`, `appearance`, tokenize = 'trigram');
+CREATE TRIGGER IF NOT EXISTS `roles_fts_ai` AFTER INSERT ON `roles` BEGIN
+  INSERT INTO `roles_fts` (`id`, `name`, `appearance`) VALUES (new.`id`, new.`name`, new.`appearance`);
+END;
+CREATE TRIGGER IF NOT EXISTS `roles_fts_ad` AFTER DELETE ON `roles` BEGIN
+  DELETE FROM `roles_fts` WHERE `id` = old.`id`;
+END;
+CREATE TRIGGER IF NOT EXISTS `roles_fts_au` AFTER UPDATE OF `name`, `appearance` ON `roles` BEGIN
+  DELETE FROM `roles_fts` WHERE `id` = old.`id`;
+  INSERT INTO `roles_fts` (`id`, `name`, `appearance`) VALUES (new.`id`, new.`name`, new.`appearance`);
+END;
+INSERT INTO `roles_fts` (`id`, `name`, `appearance`) SELECT `id`, `name`, `appearance` FROM `roles`;
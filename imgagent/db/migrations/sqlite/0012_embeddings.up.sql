@@ -0,0 +1,13 @@
+-- 章节和场景内容的语义检索向量，字段含义见 mysql/0012_embeddings.up.sql
+
+CREATE TABLE IF NOT EXISTS `embeddings` (
+  `target_type` text NOT NULL,
+  `target_id` text NOT NULL,
+  `document_id` text,
+  `model` text,
+  `content_hash` text,
+  `vector` blob,
+  `created_at` datetime,
+  PRIMARY KEY (`target_type`, `target_id`)
+);
+CREATE INDEX IF NOT EXISTS `idx_embedding_document_id` ON `embeddings` (`document_id`);
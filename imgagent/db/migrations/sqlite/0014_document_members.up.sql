@@ -0,0 +1,11 @@
+-- 文档成员，字段含义见 mysql/0014_document_members.up.sql
+
+CREATE TABLE IF NOT EXISTS `document_members` (
+  `document_id` text NOT NULL,
+  `user_id` integer NOT NULL,
+  `role` text NOT NULL,
+  `created_at` datetime,
+  `updated_at` datetime,
+  PRIMARY KEY (`document_id`, `user_id`)
+);
+CREATE INDEX IF NOT EXISTS `idx_member_user_id` ON `document_members` (`user_id`);
@@ -0,0 +1,3 @@
+-- 扫描版 PDF 在后台分割章节使用的章节标题正则，见 mysql/0015_chapter_patterns.up.sql
+
+ALTER TABLE `documents` ADD COLUMN `chapter_patterns` json;
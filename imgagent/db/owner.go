@@ -0,0 +1,129 @@
+package db
+
+import (
+	"context"
+	"errors"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Owner 发起请求的用户，文档及其章节、场景和角色按 owner_id 隔离，共享给用户的文档按 document_members 可见
+type Owner struct {
+	ID int64
+	// SuperAdmin 超级管理员可以访问所有用户的文档
+	SuperAdmin bool
+}
+
+type ownerKey struct{}
+
+// WithOwner 返回携带用户信息的 ctx，使用该 ctx 的查询、更新和删除只作用于用户自己的和共享给用户的记录
+func WithOwner(ctx context.Context, owner Owner) context.Context {
+	return context.WithValue(ctx, ownerKey{}, owner)
+}
+
+// OwnerFromContext 返回 ctx 中的用户信息，后台任务没有用户信息
+func OwnerFromContext(ctx context.Context) (Owner, bool) {
+	owner, ok := ctx.Value(ownerKey{}).(Owner)
+	return owner, ok
+}
+
+// filterOwner 返回需要过滤的 owner_id，没有用户信息或者为超级管理员时不过滤
+func filterOwner(ctx context.Context) (int64, bool) {
+	owner, ok := OwnerFromContext(ctx)
+	if !ok || owner.SuperAdmin {
+		return 0, false
+	}
+	return owner.ID, true
+}
+
+// memberDocuments 用户作为成员可以访问的文档 id 子查询
+const memberDocuments = "SELECT document_id FROM document_members WHERE user_id = ?"
+
+// ownerCondition 返回原生 SQL 中按 owner_id 和文档成员过滤的条件，table 为章节、场景或角色表的别名
+func ownerCondition(ctx context.Context, table string) (string, []any) {
+	ownerID, ok := filterOwner(ctx)
+	if !ok {
+		return "", nil
+	}
+	return " AND (" + table + ".owner_id = ? OR " + table + ".document_id IN (" + memberDocuments + "))", []any{ownerID, ownerID}
+}
+
+// registerOwnerCallbacks 为有 owner_id 字段的表的查询、更新和删除自动添加 owner_id 和文档成员条件
+// 原生 SQL 不经过该条件，需要使用 ownerCondition
+func registerOwnerCallbacks(gdb *gorm.DB) error {
+	cb := gdb.Callback()
+	if err := cb.Query().Before("gorm:query").Register("imgagent:owner", ownerScope); err != nil {
+		return err
+	}
+	if err := cb.Row().Before("gorm:row").Register("imgagent:owner", ownerScope); err != nil {
+		return err
+	}
+	if err := cb.Update().Before("gorm:update").Register("imgagent:owner", ownerScope); err != nil {
+		return err
+	}
+	return cb.Delete().Before("gorm:delete").Register("imgagent:owner", ownerScope)
+}
+
+func ownerScope(tx *gorm.DB) {
+	if tx.Statement.Schema == nil || tx.Statement.Schema.LookUpField("OwnerID") == nil {
+		return
+	}
+	ownerID, ok := filterOwner(tx.Statement.Context)
+	if !ok {
+		return
+	}
+	// 文档表按主键，章节、场景和角色表按 document_id 匹配成员
+	documentColumn := "document_id"
+	if tx.Statement.Schema.LookUpField("DocumentID") == nil {
+		documentColumn = "id"
+	}
+	tx.Statement.AddClause(clause.Where{Exprs: []clause.Expression{clause.Expr{
+		SQL: "(? = ? OR ? IN (" + memberDocuments + "))",
+		Vars: []any{
+			clause.Column{Table: clause.CurrentTable, Name: "owner_id"}, ownerID,
+			clause.Column{Table: clause.CurrentTable, Name: documentColumn}, ownerID,
+		},
+	}}})
+}
+
+// documentOwner 返回文档的 owner_id，创建章节、场景和角色时与文档保持一致
+// 用户请求时文档必须对该用户可见；文档尚未创建时（先分割章节再创建文档）属于发起请求的用户，后台任务为 0
+func documentOwner(ctx context.Context, gdb *gorm.DB, documentID string) (int64, error) {
+	doc, err := gorm.G[Document](gdb).Scopes(unscoped).Select("owner_id").Where("id = ?", documentID).Take(ctx)
+	if err == nil {
+		return doc.OwnerID, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return 0, err
+	}
+	// 区分文档不存在和对该用户不可见
+	if _, ok := filterOwner(ctx); ok {
+		all := WithOwner(ctx, Owner{SuperAdmin: true})
+		if _, err := gorm.G[Document](gdb).Scopes(unscoped).Select("id").Where("id = ?", documentID).Take(all); err == nil {
+			return 0, gorm.ErrRecordNotFound
+		} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return 0, err
+		}
+	}
+	owner, _ := OwnerFromContext(ctx)
+	return owner.ID, nil
+}
+
+// setOwners 按记录所属的文档设置 owner_id
+func setOwners[T any](ctx context.Context, gdb *gorm.DB, records []T, fields func(*T) (string, *int64)) error {
+	owners := make(map[string]int64)
+	for i := range records {
+		documentID, ownerID := fields(&records[i])
+		id, ok := owners[documentID]
+		if !ok {
+			var err error
+			if id, err = documentOwner(ctx, gdb, documentID); err != nil {
+				return err
+			}
+			owners[documentID] = id
+		}
+		*ownerID = id
+	}
+	return nil
+}
@@ -0,0 +1,75 @@
+package db
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	"imgagent/api"
+)
+
+func TestOwner(t *testing.T) {
+	db := setupTestDB(t)
+	alice := WithOwner(context.Background(), Owner{ID: 1})
+	bob := WithOwner(context.Background(), Owner{ID: 2})
+	admin := WithOwner(context.Background(), Owner{ID: 3, SuperAdmin: true})
+
+	docID := MakeUUID()
+	_, err := db.CreateDocument(alice, docID, DocumentFile{}, &api.CreateDocumentArgs{Name: "骆驼祥子"})
+	require.NoError(t, err)
+	require.NoError(t, db.CreateChapters(alice, docID, []api.CreateChapterArgs{{Title: "第一章", Content: "祥子拉车"}}))
+	// 后台任务创建的场景和角色与文档属于同一用户
+	require.NoError(t, db.CreateRoles(context.Background(), []Role{{ID: MakeUUID(), DocumentID: docID, Name: "祥子"}}))
+	roles, _, err := db.ListRolesByDocument(alice, docID, nil)
+	require.NoError(t, err)
+	require.Len(t, roles, 1)
+	assert.Equal(t, int64(1), roles[0].OwnerID)
+
+	// 其他用户查询、修改和创建都找不到文档
+	_, err = db.GetDocument(bob, docID)
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+	docs, total, err := db.ListDocuments(bob, &api.ListDocumentsArgs{})
+	require.NoError(t, err)
+	assert.Empty(t, docs)
+	assert.Zero(t, total)
+	assert.ErrorIs(t, db.TrashDocument(bob, docID), gorm.ErrRecordNotFound)
+	assert.ErrorIs(t, db.CreateChapters(bob, docID, []api.CreateChapterArgs{{Title: "第二章"}}), gorm.ErrRecordNotFound)
+	hits, err := db.Search(bob, &api.SearchArgs{Q: "祥子"})
+	require.NoError(t, err)
+	assert.Empty(t, hits)
+	hits, err = db.Search(alice, &api.SearchArgs{Q: "祥子"})
+	require.NoError(t, err)
+	assert.Len(t, hits, 2)
+
+	// 创建文档前先创建章节时，章节属于发起请求的用户
+	newDocID := MakeUUID()
+	require.NoError(t, db.CreateChapters(bob, newDocID, []api.CreateChapterArgs{{Title: "第一章"}}))
+	_, err = db.CreateDocument(bob, newDocID, DocumentFile{}, &api.CreateDocumentArgs{Name: "离婚"})
+	require.NoError(t, err)
+	chapters, _, err := db.ListChapters(bob, newDocID, nil)
+	require.NoError(t, err)
+	require.Len(t, chapters, 1)
+	assert.Equal(t, int64(2), chapters[0].OwnerID)
+
+	// 超级管理员和后台任务不过滤
+	for _, ctx := range []context.Context{admin, context.Background()} {
+		_, err = db.GetDocument(ctx, docID)
+		assert.NoError(t, err)
+		chapters, _, err := db.ListChapters(ctx, docID, nil)
+		require.NoError(t, err)
+		assert.Len(t, chapters, 1)
+	}
+
+	// 名称在同一用户下唯一
+	_, err = db.GetDocumentWithName(bob, "骆驼祥子")
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+	_, err = db.GetDocumentWithName(admin, "骆驼祥子")
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+	_, err = db.CreateDocument(bob, MakeUUID(), DocumentFile{}, &api.CreateDocumentArgs{Name: "骆驼祥子"})
+	require.NoError(t, err)
+	_, err = db.CreateDocument(alice, MakeUUID(), DocumentFile{}, &api.CreateDocumentArgs{Name: "骆驼祥子"})
+	assert.ErrorIs(t, err, gorm.ErrDuplicatedKey)
+}
@@ -0,0 +1,435 @@
+package db
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"imgagent/api"
+)
+
+// 修订记录在首次编辑时先保存原始版本为修订 1，之后每次编辑或恢复追加一个修订，历史不会被改写
+
+// ChapterRevision 章节内容的修订记录
+type ChapterRevision struct {
+	ChapterID    string    `gorm:"primaryKey;size:32;comment:'章节 id'"`
+	Rev          int       `gorm:"primaryKey;autoIncrement:false;comment:'修订号，从 1 开始'"`
+	DocumentID   string    `gorm:"index:idx_chapter_revision_document_id;size:32;comment:'文档 id'"`
+	Author       string    `gorm:"size:64;comment:'修改人，为空表示生成的原始版本'"`
+	Content      string    `gorm:"type:mediumtext;comment:'章节内容，展示形式'"`
+	Prompt       string    `gorm:"type:mediumtext;comment:'章节内容，提示词形式'"`
+	Diff         string    `gorm:"type:text;comment:'与上一修订的差异'"`
+	RestoredFrom int       `gorm:"comment:'从哪个修订恢复，0 表示编辑'"`
+	CreatedAt    time.Time `gorm:"comment:'创建时间'"`
+}
+
+// SceneRevision 场景内容的修订记录，包含该版本内容生成的图片和语音
+type SceneRevision struct {
+	SceneID      string    `gorm:"primaryKey;size:32;comment:'场景 id'"`
+	Rev          int       `gorm:"primaryKey;autoIncrement:false;comment:'修订号，从 1 开始'"`
+	DocumentID   string    `gorm:"index:idx_scene_revision_document_id;size:32;comment:'文档 id'"`
+	Author       string    `gorm:"size:64;comment:'修改人，为空表示生成的原始版本'"`
+	Content      string    `gorm:"size:1000;comment:'场景描述'"`
+	ImageURL     string    `gorm:"size:500;comment:'场景图片url'"`
+	ThumbnailURL string    `gorm:"size:500;comment:'场景缩略图url'"`
+	MediumURL    string    `gorm:"size:500;comment:'场景中图url'"`
+	VoiceURL     string    `gorm:"size:500;comment:'音频url'"`
+	Diff         string    `gorm:"type:text;comment:'与上一修订的差异'"`
+	RestoredFrom int       `gorm:"comment:'从哪个修订恢复，0 表示编辑'"`
+	CreatedAt    time.Time `gorm:"comment:'创建时间'"`
+}
+
+// RoleRevision 角色信息的修订记录
+type RoleRevision struct {
+	RoleID       string    `gorm:"primaryKey;size:32;comment:'角色 id'"`
+	Rev          int       `gorm:"primaryKey;autoIncrement:false;comment:'修订号，从 1 开始'"`
+	DocumentID   string    `gorm:"index:idx_role_revision_document_id;size:32;comment:'文档 id'"`
+	Author       string    `gorm:"size:64;comment:'修改人，为空表示生成的原始版本'"`
+	Name         string    `gorm:"size:50;comment:'角色名字'"`
+	Gender       string    `gorm:"size:10;comment:'性别'"`
+	Character    string    `gorm:"size:500;comment:'性格特点'"`
+	Appearance   string    `gorm:"size:500;comment:'外貌描述'"`
+	Diff         string    `gorm:"type:text;comment:'与上一修订的差异'"`
+	RestoredFrom int       `gorm:"comment:'从哪个修订恢复，0 表示编辑'"`
+	CreatedAt    time.Time `gorm:"comment:'创建时间'"`
+}
+
+// maxDiffCells 行差异使用 LCS 计算，行数乘积超过该值时整体替换
+const maxDiffCells = 4_000_000
+
+// lineDiff 按行比较两个版本，只输出变化的行，删除的行以 "-" 开头，新增的行以 "+" 开头
+func lineDiff(old, new string) string {
+	if old == new {
+		return ""
+	}
+	a := strings.Split(old, "\n")
+	b := strings.Split(new, "\n")
+	if old == "" {
+		a = nil
+	}
+	if new == "" {
+		b = nil
+	}
+
+	var sb strings.Builder
+	write := func(prefix byte, lines []string) {
+		for _, line := range lines {
+			sb.WriteByte(prefix)
+			sb.WriteString(line)
+			sb.WriteByte('\n')
+		}
+	}
+	if len(a)*len(b) > maxDiffCells {
+		write('-', a)
+		write('+', b)
+		return sb.String()
+	}
+
+	// lcs[i][j] 为 a[i:] 与 b[j:] 的最长公共子序列长度
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			write('-', a[i:i+1])
+			i++
+		default:
+			write('+', b[j:j+1])
+			j++
+		}
+	}
+	write('-', a[i:])
+	write('+', b[j:])
+	return sb.String()
+}
+
+// roleText 角色信息的文本形式，用于生成差异
+func roleText(name, gender, character, appearance string) string {
+	return "名字：" + name + "\n性别：" + gender + "\n性格：" + character + "\n外貌：" + appearance
+}
+
+// lockForUpdate 修改前在事务中锁定章节、场景或角色，同一记录的并发修改依次计算修订号
+// SQLite 不支持行锁，只使用一个连接，写事务本身是串行的
+var lockForUpdate = clause.Locking{Strength: "UPDATE"}
+
+// nextRev 返回目标的下一个修订号，没有修订时返回 1，调用前需用 lockForUpdate 锁定目标
+func nextRev[T any](ctx context.Context, tx *gorm.DB, column, id string) (int, error) {
+	var rev int
+	err := tx.WithContext(ctx).Model(new(T)).Where(column+" = ?", id).Select("COALESCE(MAX(rev), 0)").Scan(&rev).Error
+	return rev + 1, err
+}
+
+// ===== Revision DAO =====
+
+// UpdateChapter 更新章节内容并追加修订记录，章节不存在或不属于该文档时返回 gorm.ErrRecordNotFound
+func (db *Database) UpdateChapter(ctx context.Context, id, documentID string, args *api.UpdateChapterArgs, author string) error {
+	return db.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		chapter, err := gorm.G[Chapter](tx, lockForUpdate).Where("id = ? AND document_id = ?", id, documentID).Take(ctx)
+		if err != nil {
+			return err
+		}
+		return reviseChapter(ctx, tx, &chapter, args.Content, args.Prompt, author, 0)
+	})
+}
+
+// RestoreChapterRevision 将章节内容恢复为指定修订，并追加一个恢复的修订记录
+// 章节或修订不存在时返回 gorm.ErrRecordNotFound
+func (db *Database) RestoreChapterRevision(ctx context.Context, id, documentID string, rev int, author string) error {
+	return db.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		chapter, err := gorm.G[Chapter](tx, lockForUpdate).Where("id = ? AND document_id = ?", id, documentID).Take(ctx)
+		if err != nil {
+			return err
+		}
+		revision, err := gorm.G[ChapterRevision](tx).Where("chapter_id = ? AND rev = ?", id, rev).Take(ctx)
+		if err != nil {
+			return err
+		}
+		return reviseChapter(ctx, tx, &chapter, revision.Content, revision.Prompt, author, rev)
+	})
+}
+
+// reviseChapter 更新章节内容，首次修改时先保存原始版本
+func reviseChapter(ctx context.Context, tx *gorm.DB, chapter *Chapter, content, prompt, author string, restoredFrom int) error {
+	rev, err := nextRev[ChapterRevision](ctx, tx, "chapter_id", chapter.ID)
+	if err != nil {
+		return err
+	}
+	if rev == 1 {
+		origin := ChapterRevision{
+			ChapterID:  chapter.ID,
+			Rev:        rev,
+			DocumentID: chapter.DocumentID,
+			Content:    chapter.Content,
+			Prompt:     chapter.Prompt,
+			CreatedAt:  chapter.UpdatedAt,
+		}
+		if err := gorm.G[ChapterRevision](tx).Create(ctx, &origin); err != nil {
+			return err
+		}
+		rev++
+	}
+
+	now := time.Now()
+	err = tx.Model(&Chapter{}).Where("id = ?", chapter.ID).Updates(map[string]any{
+		"content":    content,
+		"prompt":     prompt,
+		"updated_at": now,
+	}).Error
+	if err != nil {
+		return err
+	}
+	if err := deleteEmbedding(ctx, tx, api.SearchTypeChapter, chapter.ID); err != nil {
+		return err
+	}
+	revision := ChapterRevision{
+		ChapterID:    chapter.ID,
+		Rev:          rev,
+		DocumentID:   chapter.DocumentID,
+		Author:       author,
+		Content:      content,
+		Prompt:       prompt,
+		Diff:         lineDiff(chapter.Content, content),
+		RestoredFrom: restoredFrom,
+		CreatedAt:    now,
+	}
+	return gorm.G[ChapterRevision](tx).Create(ctx, &revision)
+}
+
+// ListChapterRevisions 查询章节的修订记录，按修订号倒序，args 为 nil 时返回全部
+func (db *Database) ListChapterRevisions(ctx context.Context, chapterID string, args *api.ListArgs) ([]ChapterRevision, int64, error) {
+	q := gorm.G[ChapterRevision](db.db).Where("chapter_id = ?", chapterID)
+	return listPage(ctx, q, args, "rev DESC")
+}
+
+// UpdateScene 更新场景内容并追加修订记录，返回新的修订号，场景不存在时返回 gorm.ErrRecordNotFound
+// 新内容的图片和语音生成后由 UpdateSceneRevisionMedia 记录到修订中
+func (db *Database) UpdateScene(ctx context.Context, id string, args *api.UpdateSceneArgs, author string) (int, error) {
+	var rev int
+	err := db.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		scene, err := gorm.G[Scene](tx, lockForUpdate).Where("id = ?", id).Take(ctx)
+		if err != nil {
+			return err
+		}
+		// 仅更新场景内容
+		revision := SceneRevision{Content: args.Content, Author: author}
+		rev, err = reviseScene(ctx, tx, &scene, &revision)
+		return err
+	})
+	return rev, err
+}
+
+// UpdateSceneRevisionMedia 将场景当前的图片和语音记录到修订中
+func (db *Database) UpdateSceneRevisionMedia(ctx context.Context, sceneID string, rev int) error {
+	scene, err := gorm.G[Scene](db.db).Where("id = ?", sceneID).Take(ctx)
+	if err != nil {
+		return err
+	}
+	result := db.db.WithContext(ctx).Model(&SceneRevision{}).Where("scene_id = ? AND rev = ?", sceneID, rev).Updates(map[string]any{
+		"image_url":     scene.ImageURL,
+		"thumbnail_url": scene.ThumbnailURL,
+		"medium_url":    scene.MediumURL,
+		"voice_url":     scene.VoiceURL,
+	})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// RestoreSceneRevision 将场景内容以及图片和语音恢复为指定修订，并追加一个恢复的修订记录
+// 场景或修订不存在时返回 gorm.ErrRecordNotFound
+func (db *Database) RestoreSceneRevision(ctx context.Context, id string, rev int, author string) error {
+	return db.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		scene, err := gorm.G[Scene](tx, lockForUpdate).Where("id = ?", id).Take(ctx)
+		if err != nil {
+			return err
+		}
+		revision, err := gorm.G[SceneRevision](tx).Where("scene_id = ? AND rev = ?", id, rev).Take(ctx)
+		if err != nil {
+			return err
+		}
+		revision.Author = author
+		revision.RestoredFrom = rev
+		_, err = reviseScene(ctx, tx, &scene, &revision)
+		return err
+	})
+}
+
+// reviseScene 按 revision 更新场景，首次修改时先保存原始版本，返回新的修订号
+// 恢复修订时同时恢复图片和语音，编辑时图片和语音在重新生成后更新
+func reviseScene(ctx context.Context, tx *gorm.DB, scene *Scene, revision *SceneRevision) (int, error) {
+	rev, err := nextRev[SceneRevision](ctx, tx, "scene_id", scene.ID)
+	if err != nil {
+		return 0, err
+	}
+	if rev == 1 {
+		origin := SceneRevision{
+			SceneID:      scene.ID,
+			Rev:          rev,
+			DocumentID:   scene.DocumentID,
+			Content:      scene.Content,
+			ImageURL:     scene.ImageURL,
+			ThumbnailURL: scene.ThumbnailURL,
+			MediumURL:    scene.MediumURL,
+			VoiceURL:     scene.VoiceURL,
+			CreatedAt:    scene.UpdatedAt,
+		}
+		if err := gorm.G[SceneRevision](tx).Create(ctx, &origin); err != nil {
+			return 0, err
+		}
+		rev++
+	}
+
+	now := time.Now()
+	updates := map[string]any{
+		"content":    revision.Content,
+		"updated_at": now,
+	}
+	if revision.RestoredFrom > 0 {
+		updates["image_url"] = revision.ImageURL
+		updates["thumbnail_url"] = revision.ThumbnailURL
+		updates["medium_url"] = revision.MediumURL
+		updates["voice_url"] = revision.VoiceURL
+	}
+	if err := tx.Model(&Scene{}).Where("id = ?", scene.ID).Updates(updates).Error; err != nil {
+		return 0, err
+	}
+	if err := deleteEmbedding(ctx, tx, api.SearchTypeScene, scene.ID); err != nil {
+		return 0, err
+	}
+	created := SceneRevision{
+		SceneID:      scene.ID,
+		Rev:          rev,
+		DocumentID:   scene.DocumentID,
+		Author:       revision.Author,
+		Content:      revision.Content,
+		ImageURL:     revision.ImageURL,
+		ThumbnailURL: revision.ThumbnailURL,
+		MediumURL:    revision.MediumURL,
+		VoiceURL:     revision.VoiceURL,
+		Diff:         lineDiff(scene.Content, revision.Content),
+		RestoredFrom: revision.RestoredFrom,
+		CreatedAt:    now,
+	}
+	return rev, gorm.G[SceneRevision](tx).Create(ctx, &created)
+}
+
+// ListSceneRevisions 查询场景的修订记录，按修订号倒序，args 为 nil 时返回全部
+func (db *Database) ListSceneRevisions(ctx context.Context, sceneID string, args *api.ListArgs) ([]SceneRevision, int64, error) {
+	q := gorm.G[SceneRevision](db.db).Where("scene_id = ?", sceneID)
+	return listPage(ctx, q, args, "rev DESC")
+}
+
+// UpdateRole 更新角色信息并追加修订记录，角色不存在时返回 gorm.ErrRecordNotFound
+func (db *Database) UpdateRole(ctx context.Context, id string, args *api.UpdateRoleArgs, author string) error {
+	return db.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		role, err := gorm.G[Role](tx, lockForUpdate).Where("id = ?", id).Take(ctx)
+		if err != nil {
+			return err
+		}
+		revision := RoleRevision{
+			Author:     author,
+			Name:       args.Name,
+			Gender:     args.Gender,
+			Character:  args.Character,
+			Appearance: args.Appearance,
+		}
+		return reviseRole(ctx, tx, &role, &revision)
+	})
+}
+
+// RestoreRoleRevision 将角色信息恢复为指定修订，并追加一个恢复的修订记录
+// 角色或修订不存在时返回 gorm.ErrRecordNotFound
+func (db *Database) RestoreRoleRevision(ctx context.Context, id string, rev int, author string) error {
+	return db.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		role, err := gorm.G[Role](tx, lockForUpdate).Where("id = ?", id).Take(ctx)
+		if err != nil {
+			return err
+		}
+		revision, err := gorm.G[RoleRevision](tx).Where("role_id = ? AND rev = ?", id, rev).Take(ctx)
+		if err != nil {
+			return err
+		}
+		revision.Author = author
+		revision.RestoredFrom = rev
+		return reviseRole(ctx, tx, &role, &revision)
+	})
+}
+
+// reviseRole 按 revision 更新角色，首次修改时先保存原始版本
+func reviseRole(ctx context.Context, tx *gorm.DB, role *Role, revision *RoleRevision) error {
+	rev, err := nextRev[RoleRevision](ctx, tx, "role_id", role.ID)
+	if err != nil {
+		return err
+	}
+	if rev == 1 {
+		origin := RoleRevision{
+			RoleID:     role.ID,
+			Rev:        rev,
+			DocumentID: role.DocumentID,
+			Name:       role.Name,
+			Gender:     role.Gender,
+			Character:  role.Character,
+			Appearance: role.Appearance,
+			CreatedAt:  role.UpdatedAt,
+		}
+		if err := gorm.G[RoleRevision](tx).Create(ctx, &origin); err != nil {
+			return err
+		}
+		rev++
+	}
+
+	now := time.Now()
+	err = tx.Model(&Role{}).Where("id = ?", role.ID).Updates(map[string]any{
+		"name":       revision.Name,
+		"gender":     revision.Gender,
+		"character":  revision.Character,
+		"appearance": revision.Appearance,
+		"updated_at": now,
+	}).Error
+	if err != nil {
+		return err
+	}
+	created := RoleRevision{
+		RoleID:     role.ID,
+		Rev:        rev,
+		DocumentID: role.DocumentID,
+		Author:     revision.Author,
+		Name:       revision.Name,
+		Gender:     revision.Gender,
+		Character:  revision.Character,
+		Appearance: revision.Appearance,
+		Diff: lineDiff(roleText(role.Name, role.Gender, role.Character, role.Appearance),
+			roleText(revision.Name, revision.Gender, revision.Character, revision.Appearance)),
+		RestoredFrom: revision.RestoredFrom,
+		CreatedAt:    now,
+	}
+	return gorm.G[RoleRevision](tx).Create(ctx, &created)
+}
+
+// ListRoleRevisions 查询角色的修订记录，按修订号倒序，args 为 nil 时返回全部
+func (db *Database) ListRoleRevisions(ctx context.Context, roleID string, args *api.ListArgs) ([]RoleRevision, int64, error) {
+	q := gorm.G[RoleRevision](db.db).Where("role_id = ?", roleID)
+	return listPage(ctx, q, args, "rev DESC")
+}
@@ -0,0 +1,204 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	"imgagent/api"
+)
+
+func TestLineDiff(t *testing.T) {
+	assert.Equal(t, "", lineDiff("a\nb", "a\nb"))
+	assert.Equal(t, "-b\n+c\n", lineDiff("a\nb", "a\nc"))
+	assert.Equal(t, "+b\n", lineDiff("a\nc", "a\nb\nc"))
+	assert.Equal(t, "-a\n", lineDiff("a\nb", "b"))
+	assert.Equal(t, "+a\n", lineDiff("", "a"))
+}
+
+func TestChapterRevisions(t *testing.T) {
+	db := setupTestDB(t)
+	ctx := context.Background()
+
+	docID, chapters, _ := createTrashTestDocument(t, db, "骆驼祥子")
+	id := chapters[0].ID
+
+	// 未编辑的章节没有修订
+	revisions, total, err := db.ListChapterRevisions(ctx, id, nil)
+	require.NoError(t, err)
+	assert.Empty(t, revisions)
+	assert.Equal(t, int64(0), total)
+
+	// 首次编辑同时保存原始版本
+	require.NoError(t, db.UpdateChapter(ctx, id, docID, &api.UpdateChapterArgs{Content: "第一段\n\n第二段", Prompt: "第一段\n第二段"}, "editor"))
+	require.NoError(t, db.UpdateChapter(ctx, id, docID, &api.UpdateChapterArgs{Content: "第一段\n\n第三段", Prompt: "第一段\n第三段"}, "editor2"))
+	assert.ErrorIs(t, db.UpdateChapter(ctx, "missing", docID, &api.UpdateChapterArgs{Content: "x"}, "editor"), gorm.ErrRecordNotFound)
+	// 章节不属于路径中的文档
+	otherID, _, _ := createTrashTestDocument(t, db, "四世同堂")
+	assert.ErrorIs(t, db.UpdateChapter(ctx, id, otherID, &api.UpdateChapterArgs{Content: "x"}, "editor"), gorm.ErrRecordNotFound)
+
+	revisions, total, err = db.ListChapterRevisions(ctx, id, &api.ListArgs{Limit: 2})
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), total)
+	require.Len(t, revisions, 2)
+	assert.Equal(t, 3, revisions[0].Rev)
+	assert.Equal(t, "editor2", revisions[0].Author)
+	assert.Equal(t, "-第二段\n+第三段\n", revisions[0].Diff)
+	assert.Equal(t, 2, revisions[1].Rev)
+
+	// 恢复到原始版本，追加一个恢复的修订
+	require.NoError(t, db.RestoreChapterRevision(ctx, id, docID, 1, "editor"))
+	chapter, err := db.GetChapter(ctx, id, docID)
+	require.NoError(t, err)
+	assert.Equal(t, "", chapter.Content)
+	assert.Equal(t, "", chapter.Prompt)
+	revisions, _, err = db.ListChapterRevisions(ctx, id, nil)
+	require.NoError(t, err)
+	require.Len(t, revisions, 4)
+	assert.Equal(t, 4, revisions[0].Rev)
+	assert.Equal(t, 1, revisions[0].RestoredFrom)
+	assert.Equal(t, "", revisions[3].Author)
+
+	assert.ErrorIs(t, db.RestoreChapterRevision(ctx, id, docID, 10, "editor"), gorm.ErrRecordNotFound)
+	assert.ErrorIs(t, db.RestoreChapterRevision(ctx, id, "other", 1, "editor"), gorm.ErrRecordNotFound)
+
+	// 彻底删除文档时删除修订记录
+	require.NoError(t, db.DeleteDocumentCascade(ctx, docID))
+	revisions, _, err = db.ListChapterRevisions(ctx, id, nil)
+	require.NoError(t, err)
+	assert.Empty(t, revisions)
+}
+
+func TestSceneRevisions(t *testing.T) {
+	db := setupTestDB(t)
+	ctx := context.Background()
+
+	_, _, scenes := createTrashTestDocument(t, db, "骆驼祥子")
+	id := scenes[0].ID
+	require.NoError(t, db.UpdateSceneImageURL(ctx, id, "image-1"))
+	require.NoError(t, db.UpdateSceneVoiceURL(ctx, id, "voice-1"))
+
+	// 编辑后重新生成图片和语音，记录到新修订中
+	rev, err := db.UpdateScene(ctx, id, &api.UpdateSceneArgs{Content: "新场景"}, "editor")
+	require.NoError(t, err)
+	assert.Equal(t, 2, rev)
+	require.NoError(t, db.UpdateSceneImageURL(ctx, id, "image-2"))
+	require.NoError(t, db.UpdateSceneVoiceURL(ctx, id, "voice-2"))
+	require.NoError(t, db.UpdateSceneRevisionMedia(ctx, id, rev))
+
+	revisions, _, err := db.ListSceneRevisions(ctx, id, nil)
+	require.NoError(t, err)
+	require.Len(t, revisions, 2)
+	assert.Equal(t, "新场景", revisions[0].Content)
+	assert.Equal(t, "image-2", revisions[0].ImageURL)
+	assert.Equal(t, "voice-2", revisions[0].VoiceURL)
+	assert.Equal(t, "image-1", revisions[1].ImageURL)
+	assert.Equal(t, "voice-1", revisions[1].VoiceURL)
+
+	// 恢复原始版本时图片和语音一起恢复
+	require.NoError(t, db.RestoreSceneRevision(ctx, id, 1, "editor"))
+	scene, err := db.GetScene(ctx, id)
+	require.NoError(t, err)
+	assert.Equal(t, scenes[0].Content, scene.Content)
+	assert.Equal(t, "image-1", scene.ImageURL)
+	assert.Equal(t, "voice-1", scene.VoiceURL)
+	assert.ErrorIs(t, db.RestoreSceneRevision(ctx, id, 9, "editor"), gorm.ErrRecordNotFound)
+}
+
+func TestRoleRevisions(t *testing.T) {
+	db := setupTestDB(t)
+	ctx := context.Background()
+
+	docID, _, _ := createTrashTestDocument(t, db, "骆驼祥子")
+	roles, _, err := db.ListRolesByDocument(ctx, docID, nil)
+	require.NoError(t, err)
+	require.Len(t, roles, 1)
+	id := roles[0].ID
+
+	args := &api.UpdateRoleArgs{Name: "祥子", Gender: "男", Character: "老实", Appearance: "高大"}
+	require.NoError(t, db.UpdateRole(ctx, id, args, "editor"))
+	revisions, _, err := db.ListRoleRevisions(ctx, id, nil)
+	require.NoError(t, err)
+	require.Len(t, revisions, 2)
+	assert.Equal(t, "-性别：\n-性格：\n-外貌：\n+性别：男\n+性格：老实\n+外貌：高大\n", revisions[0].Diff)
+
+	// 恢复原始版本时空字段也会恢复
+	require.NoError(t, db.RestoreRoleRevision(ctx, id, 1, "editor"))
+	role, err := db.GetRole(ctx, id)
+	require.NoError(t, err)
+	assert.Equal(t, "祥子", role.Name)
+	assert.Equal(t, "", role.Gender)
+
+	// 回收站中超过保留期的角色连同修订一起删除
+	require.NoError(t, db.TrashDocument(ctx, docID))
+	require.NoError(t, db.PurgeTrash(ctx, time.Now().Add(time.Hour)))
+	revisions, _, err = db.ListRoleRevisions(ctx, id, nil)
+	require.NoError(t, err)
+	assert.Empty(t, revisions)
+}
+
+func TestConcurrentRevisions(t *testing.T) {
+	db := setupTestDB(t)
+	ctx := context.Background()
+
+	docID, chapters, scenes := createTrashTestDocument(t, db, "骆驼祥子")
+	roles, _, err := db.ListRolesByDocument(ctx, docID, nil)
+	require.NoError(t, err)
+	require.NotEmpty(t, roles)
+
+	// 同一记录的并发修改都成功，修订号依次递增，原始版本只保存一次
+	const n = 8
+	var wg sync.WaitGroup
+	errs := make(chan error, n*3)
+	for i := range n {
+		wg.Add(3)
+		go func() {
+			defer wg.Done()
+			errs <- db.UpdateChapter(ctx, chapters[0].ID, docID, &api.UpdateChapterArgs{Content: fmt.Sprint("内容", i)}, "editor")
+		}()
+		go func() {
+			defer wg.Done()
+			_, err := db.UpdateScene(ctx, scenes[0].ID, &api.UpdateSceneArgs{Content: fmt.Sprint("场景", i)}, "editor")
+			errs <- err
+		}()
+		go func() {
+			defer wg.Done()
+			errs <- db.UpdateRole(ctx, roles[0].ID, &api.UpdateRoleArgs{Name: fmt.Sprint("祥子", i)}, "editor")
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		require.NoError(t, err)
+	}
+
+	chapterRevisions, _, err := db.ListChapterRevisions(ctx, chapters[0].ID, nil)
+	require.NoError(t, err)
+	sceneRevisions, _, err := db.ListSceneRevisions(ctx, scenes[0].ID, nil)
+	require.NoError(t, err)
+	roleRevisions, _, err := db.ListRoleRevisions(ctx, roles[0].ID, nil)
+	require.NoError(t, err)
+	for _, got := range [][]int{revs(chapterRevisions, func(r ChapterRevision) int { return r.Rev }),
+		revs(sceneRevisions, func(r SceneRevision) int { return r.Rev }),
+		revs(roleRevisions, func(r RoleRevision) int { return r.Rev })} {
+		require.Len(t, got, n+1)
+		for i, rev := range got {
+			assert.Equal(t, n+1-i, rev)
+		}
+	}
+}
+
+// revs 返回修订记录的修订号
+func revs[T any](revisions []T, rev func(T) int) []int {
+	ret := make([]int, len(revisions))
+	for i, r := range revisions {
+		ret[i] = rev(r)
+	}
+	return ret
+}
@@ -0,0 +1,263 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	"imgagent/api"
+)
+
+// maxSearchCandidates LIKE 检索没有相关度排序，每个表最多取出的候选记录数，在内存中计算得分
+const maxSearchCandidates = 500
+
+var errFTS5Unavailable = errors.New("sqlite FTS5 index is unavailable")
+
+// SearchHit 全文检索命中的记录，Text 为被检索的完整文本
+type SearchHit struct {
+	Type       string
+	ID         string
+	DocumentID string
+	ChapterID  string
+	Title      string
+	Text       string
+	Score      float64
+}
+
+// searchTarget 可检索的表
+type searchTarget struct {
+	typ     string
+	table   string
+	columns []string // 检索的字段，角色同时检索名字和外貌
+	title   string   // 作为标题的字段，为空表示没有标题
+	chapter string   // 章节 id 字段，为空表示不属于章节
+}
+
+var searchTargets = []searchTarget{
+	{typ: api.SearchTypeChapter, table: "chapters", columns: []string{"content"}, title: "title", chapter: "id"},
+	{typ: api.SearchTypeScene, table: "scenes", columns: []string{"content"}, chapter: "chapter_id"},
+	{typ: api.SearchTypeRole, table: "roles", columns: []string{"name", "appearance"}, title: "name"},
+}
+
+// selectColumns 查询字段，表的别名为 t，检索字段依次为 text0、text1
+func (st searchTarget) selectColumns() string {
+	cols := []string{"t.id AS id", "t.document_id AS document_id"}
+	for _, field := range []struct{ column, alias string }{{st.chapter, "chapter_id"}, {st.title, "title"}} {
+		if field.column == "" {
+			cols = append(cols, "'' AS "+field.alias)
+		} else {
+			cols = append(cols, "COALESCE(t."+field.column+", '') AS "+field.alias)
+		}
+	}
+	for i, column := range st.columns {
+		cols = append(cols, "COALESCE(t."+column+", '') AS text"+string(rune('0'+i)))
+	}
+	return strings.Join(cols, ", ")
+}
+
+// searchRow 检索的查询结果
+type searchRow struct {
+	ID         string
+	DocumentID string
+	ChapterID  string
+	Title      string
+	Text0      string
+	Text1      string
+	Score      float64
+}
+
+func (r *searchRow) text() string {
+	if r.Text1 == "" {
+		return r.Text0
+	}
+	return r.Text0 + "\n" + r.Text1
+}
+
+// searcher 不同数据库的全文检索实现，返回按得分倒序的记录
+type searcher interface {
+	search(ctx context.Context, db *gorm.DB, st searchTarget, q, documentID string, limit int) ([]searchRow, error)
+}
+
+// newSearcher 按数据库选择检索方式：MySQL 使用 ngram 全文索引，SQLite 在编译了 FTS5 时使用 trigram 全文索引，其他使用 LIKE
+func newSearcher(db *gorm.DB) searcher {
+	switch db.Dialector.Name() {
+	case "mysql":
+		return mysqlSearcher{}
+	case "sqlite":
+		if err := checkFTS5(context.Background(), db); err != nil {
+			zap.S().Warnf("SQLite FTS5 is unavailable, search falls back to LIKE, err: %v", err)
+			return likeSearcher{}
+		}
+		return fts5Searcher{}
+	default:
+		return likeSearcher{}
+	}
+}
+
+// searchConditions 返回按文档和当前用户过滤的条件
+func searchConditions(ctx context.Context, documentID string) (string, []any) {
+	cond, vars := ownerCondition(ctx, "t")
+	if documentID != "" {
+		cond += " AND t.document_id = ?"
+		vars = append(vars, documentID)
+	}
+	return cond, vars
+}
+
+// likeSearcher 使用 LIKE 子串匹配，得分为检索词出现的次数
+type likeSearcher struct{}
+
+func (likeSearcher) search(ctx context.Context, db *gorm.DB, st searchTarget, q, documentID string, limit int) ([]searchRow, error) {
+	pattern := "%" + prefixLike(q)
+	var conds []string
+	var vars []any
+	for _, column := range st.columns {
+		conds = append(conds, "t."+column+" LIKE ? ESCAPE '!'")
+		vars = append(vars, pattern)
+	}
+	sql := "SELECT " + st.selectColumns() + " FROM " + st.table + " t WHERE t.deleted_at IS NULL AND (" + strings.Join(conds, " OR ") + ")"
+	cond, condVars := searchConditions(ctx, documentID)
+	sql += cond
+	vars = append(vars, condVars...)
+	sql += " LIMIT ?"
+	vars = append(vars, maxSearchCandidates)
+
+	var rows []searchRow
+	if err := db.WithContext(ctx).Raw(sql, vars...).Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	lower := strings.ToLower(q)
+	for i := range rows {
+		rows[i].Score = float64(strings.Count(strings.ToLower(rows[i].text()), lower))
+	}
+	sort.SliceStable(rows, func(i, j int) bool { return rows[i].Score > rows[j].Score })
+	if len(rows) > limit {
+		rows = rows[:limit]
+	}
+	return rows, nil
+}
+
+// mysqlSearcher 使用 FULLTEXT ngram 索引的布尔模式短语匹配，得分为 MATCH 的相关度
+// 检索词短于 ngram_token_size（默认 2）时无法使用索引，改用 LIKE
+type mysqlSearcher struct{}
+
+func (mysqlSearcher) search(ctx context.Context, db *gorm.DB, st searchTarget, q, documentID string, limit int) ([]searchRow, error) {
+	if utf8.RuneCountInString(q) < 2 {
+		return likeSearcher{}.search(ctx, db, st, q, documentID, limit)
+	}
+	match := "MATCH(t." + strings.Join(st.columns, ", t.") + ") AGAINST (? IN BOOLEAN MODE)"
+	phrase := `"` + strings.ReplaceAll(q, `"`, " ") + `"`
+	sql := "SELECT " + st.selectColumns() + ", " + match + " AS score FROM " + st.table + " t WHERE t.deleted_at IS NULL AND " + match
+	vars := []any{phrase, phrase}
+	cond, condVars := searchConditions(ctx, documentID)
+	sql += cond
+	vars = append(vars, condVars...)
+	sql += " ORDER BY score DESC LIMIT ?"
+	vars = append(vars, limit)
+
+	var rows []searchRow
+	err := db.WithContext(ctx).Raw(sql, vars...).Scan(&rows).Error
+	return rows, err
+}
+
+// fts5Searcher 使用 SQLite FTS5 trigram 索引的短语匹配，得分为 bm25 取反
+// trigram 索引要求检索词至少 3 个字符，更短时改用 LIKE
+type fts5Searcher struct{}
+
+func (fts5Searcher) search(ctx context.Context, db *gorm.DB, st searchTarget, q, documentID string, limit int) ([]searchRow, error) {
+	if utf8.RuneCountInString(q) < 3 {
+		return likeSearcher{}.search(ctx, db, st, q, documentID, limit)
+	}
+	fts := st.table + "_fts"
+	sql := "SELECT " + st.selectColumns() + ", -bm25(" + fts + ") AS score FROM " + fts + " JOIN " + st.table + " t ON t.id = " + fts + ".id" +
+		" WHERE " + fts + " MATCH ? AND t.deleted_at IS NULL"
+	vars := []any{`"` + strings.ReplaceAll(q, `"`, `""`) + `"`}
+	cond, condVars := searchConditions(ctx, documentID)
+	sql += cond
+	vars = append(vars, condVars...)
+	sql += " ORDER BY score DESC LIMIT ?"
+	vars = append(vars, limit)
+
+	var rows []searchRow
+	err := db.WithContext(ctx).Raw(sql, vars...).Scan(&rows).Error
+	return rows, err
+}
+
+// checkFTS5 检查 FTS5 索引表是否可用，索引表和同步触发器由迁移脚本 0011_search 创建
+// go-sqlite3 需要使用 -tags sqlite_fts5 编译才支持 FTS5，未编译时执行迁移会跳过索引表
+func checkFTS5(ctx context.Context, db *gorm.DB) error {
+	var enabled int
+	if err := db.WithContext(ctx).Raw("SELECT sqlite_compileoption_used('ENABLE_FTS5')").Scan(&enabled).Error; err != nil {
+		return err
+	}
+	if enabled == 0 {
+		return fmt.Errorf("%w: sqlite is not compiled with FTS5", errFTS5Unavailable)
+	}
+	for _, st := range searchTargets {
+		var exists int64
+		if err := db.WithContext(ctx).Raw("SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = ?", st.table+"_fts").Scan(&exists).Error; err != nil {
+			return err
+		}
+		if exists == 0 {
+			return fmt.Errorf("%w: table %s_fts does not exist, run migrate up with a binary built with -tags sqlite_fts5", errFTS5Unavailable, st.table)
+		}
+	}
+	return nil
+}
+
+// normalizeScores 将一次检索的得分按最高、最低得分归一化到 0~1，得分都相同时都为 1
+// 不同表和不同检索方式的得分（MATCH 相关度、bm25、出现次数）范围不同，不能直接比较
+func normalizeScores(rows []searchRow) {
+	if len(rows) == 0 {
+		return
+	}
+	lo, hi := rows[0].Score, rows[0].Score
+	for _, row := range rows {
+		lo, hi = min(lo, row.Score), max(hi, row.Score)
+	}
+	for i := range rows {
+		if hi == lo {
+			rows[i].Score = 1
+		} else {
+			rows[i].Score = (rows[i].Score - lo) / (hi - lo)
+		}
+	}
+}
+
+// ===== Search DAO =====
+
+// Search 全文检索章节内容、场景描述和角色名字、外貌，按得分倒序返回，回收站中的记录不参与检索
+// 各类记录的得分分别归一化后再合并排序
+func (db *Database) Search(ctx context.Context, args *api.SearchArgs) ([]SearchHit, error) {
+	db.searchOnce.Do(func() {
+		db.searcher = newSearcher(db.db)
+	})
+
+	limit := args.Limit
+	if limit <= 0 {
+		limit = api.DefaultSearchLimit
+	}
+	var hits []SearchHit
+	for _, st := range searchTargets {
+		if args.Type != "" && args.Type != st.typ {
+			continue
+		}
+		rows, err := db.searcher.search(ctx, db.db, st, args.Q, args.DocumentID, limit)
+		if err != nil {
+			return nil, err
+		}
+		normalizeScores(rows)
+		hits = append(hits, makeSearchHits(st, rows)...)
+	}
+	sort.SliceStable(hits, func(i, j int) bool { return hits[i].Score > hits[j].Score })
+	if len(hits) > limit {
+		hits = hits[:limit]
+	}
+	return hits, nil
+}
@@ -0,0 +1,110 @@
+package db
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"imgagent/api"
+)
+
+func TestSearch(t *testing.T) {
+	db := setupTestDB(t)
+	ctx := context.Background()
+
+	docID, chapters, scenes := createTrashTestDocument(t, db, "骆驼祥子")
+	otherID, _, _ := createTrashTestDocument(t, db, "四世同堂")
+	require.NoError(t, db.UpdateChapter(ctx, chapters[0].ID, docID, &api.UpdateChapterArgs{Content: "祥子拉着洋车，祥子很高兴"}, "editor"))
+	_, err := db.UpdateScene(ctx, scenes[0].ID, &api.UpdateSceneArgs{Content: "祥子在雨中拉车"}, "editor")
+	require.NoError(t, err)
+
+	// 章节内容中出现两次，得分最高
+	hits, err := db.Search(ctx, &api.SearchArgs{Q: "祥子", DocumentID: docID})
+	require.NoError(t, err)
+	require.Len(t, hits, 3)
+	assert.Equal(t, api.SearchTypeChapter, hits[0].Type)
+	assert.Equal(t, chapters[0].ID, hits[0].ID)
+	assert.Equal(t, chapters[0].ID, hits[0].ChapterID)
+	assert.Equal(t, "第一章", hits[0].Title)
+	assert.Equal(t, "祥子拉着洋车，祥子很高兴", hits[0].Text)
+	for _, hit := range hits {
+		assert.Equal(t, docID, hit.DocumentID)
+	}
+
+	// 按类型过滤，不指定文档时检索所有文档
+	hits, err = db.Search(ctx, &api.SearchArgs{Q: "祥子", Type: api.SearchTypeRole})
+	require.NoError(t, err)
+	require.Len(t, hits, 2)
+	assert.Equal(t, "祥子", hits[0].Title)
+	assert.Empty(t, hits[0].ChapterID)
+
+	hits, err = db.Search(ctx, &api.SearchArgs{Q: "祥子", Limit: 1})
+	require.NoError(t, err)
+	assert.Len(t, hits, 1)
+
+	// 检索后修改的内容也能检索到
+	_, err = db.UpdateScene(ctx, scenes[0].ID, &api.UpdateSceneArgs{Content: "祥子在烈日下拉车"}, "editor")
+	require.NoError(t, err)
+	hits, err = db.Search(ctx, &api.SearchArgs{Q: "在烈日下"})
+	require.NoError(t, err)
+	require.Len(t, hits, 1)
+	assert.Equal(t, scenes[0].ID, hits[0].ID)
+	hits, err = db.Search(ctx, &api.SearchArgs{Q: "在雨中"})
+	require.NoError(t, err)
+	assert.Empty(t, hits)
+
+	// LIKE 通配符按普通字符匹配
+	hits, err = db.Search(ctx, &api.SearchArgs{Q: "%"})
+	require.NoError(t, err)
+	assert.Empty(t, hits)
+
+	// 回收站中的记录不参与检索
+	require.NoError(t, db.TrashDocument(ctx, otherID))
+	hits, err = db.Search(ctx, &api.SearchArgs{Q: "祥子", Type: api.SearchTypeRole})
+	require.NoError(t, err)
+	require.Len(t, hits, 1)
+	assert.Equal(t, docID, hits[0].DocumentID)
+}
+
+func TestSearchNormalizesScores(t *testing.T) {
+	db := setupTestDB(t)
+	ctx := context.Background()
+
+	docID, chapters, _ := createTrashTestDocument(t, db, "骆驼祥子")
+	require.NoError(t, db.UpdateChapter(ctx, chapters[0].ID, docID, &api.UpdateChapterArgs{Content: strings.Repeat("祥子拉车。", 5)}, "editor"))
+	require.NoError(t, db.UpdateChapter(ctx, chapters[1].ID, docID, &api.UpdateChapterArgs{Content: strings.Repeat("祥子拉车。", 4)}, "editor"))
+
+	// 章节的出现次数更多，但得分按类型分别归一化，角色不会被章节挤出结果
+	hits, err := db.Search(ctx, &api.SearchArgs{Q: "祥子", DocumentID: docID, Limit: 2})
+	require.NoError(t, err)
+	require.Len(t, hits, 2)
+	assert.Equal(t, chapters[0].ID, hits[0].ID)
+	assert.Equal(t, 1.0, hits[0].Score)
+	assert.Equal(t, api.SearchTypeRole, hits[1].Type)
+	assert.Equal(t, 1.0, hits[1].Score)
+
+	hits, err = db.Search(ctx, &api.SearchArgs{Q: "祥子", DocumentID: docID, Type: api.SearchTypeChapter})
+	require.NoError(t, err)
+	require.Len(t, hits, 2)
+	assert.Equal(t, 0.0, hits[1].Score)
+}
+
+func TestCheckFTS5(t *testing.T) {
+	db := setupTestDB(t)
+	ctx := context.Background()
+
+	var enabled int
+	require.NoError(t, db.db.Raw("SELECT sqlite_compileoption_used('ENABLE_FTS5')").Scan(&enabled).Error)
+	if enabled == 0 {
+		// 未编译 FTS5 时迁移跳过索引表，检索使用 LIKE
+		assert.ErrorIs(t, checkFTS5(ctx, db.db), errFTS5Unavailable)
+		assert.IsType(t, likeSearcher{}, newSearcher(db.db))
+		return
+	}
+	// 索引表和触发器由迁移脚本创建
+	require.NoError(t, checkFTS5(ctx, db.db))
+	assert.IsType(t, fts5Searcher{}, newSearcher(db.db))
+}
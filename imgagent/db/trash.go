@@ -0,0 +1,177 @@
+package db
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+
+	"imgagent/api"
+)
+
+// unscoped 查询包含回收站中的记录，删除时彻底删除
+func unscoped(stmt *gorm.Statement) {
+	stmt.Unscoped = true
+}
+
+// ===== Trash DAO =====
+
+// TrashDocument 在一个事务中将文档及其章节、场景和角色移入回收站，子记录与文档使用相同的删除时间，恢复时一起恢复
+// 文档不存在或已在回收站中时返回 gorm.ErrRecordNotFound
+func (db *Database) TrashDocument(ctx context.Context, id string) error {
+	now := time.Now()
+	return db.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if _, err := gorm.G[Scene](tx).Where("document_id = ?", id).Update(ctx, "deleted_at", now); err != nil {
+			return err
+		}
+		if _, err := gorm.G[Role](tx).Where("document_id = ?", id).Update(ctx, "deleted_at", now); err != nil {
+			return err
+		}
+		if _, err := gorm.G[Chapter](tx).Where("document_id = ?", id).Update(ctx, "deleted_at", now); err != nil {
+			return err
+		}
+		result := tx.Model(&Document{}).Where("id = ?", id).Updates(map[string]any{
+			"deleted_at":  now,
+			"deleted_key": id,
+		})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return nil
+	})
+}
+
+// RestoreDocument 从回收站恢复文档，以及与文档一起移入回收站的章节、场景和角色，单独删除的记录仍留在回收站中
+// 文档不在回收站中时返回 gorm.ErrRecordNotFound，已有同名文档时返回唯一索引冲突错误
+func (db *Database) RestoreDocument(ctx context.Context, id string) error {
+	return db.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if _, err := gorm.G[Document](tx).Scopes(unscoped).Where("id = ? AND deleted_at IS NOT NULL", id).Take(ctx); err != nil {
+			return err
+		}
+		sameTime := "document_id = ? AND deleted_at = (SELECT deleted_at FROM documents WHERE id = ?)"
+		if _, err := gorm.G[Scene](tx).Scopes(unscoped).Where(sameTime, id, id).Update(ctx, "deleted_at", nil); err != nil {
+			return err
+		}
+		if _, err := gorm.G[Role](tx).Scopes(unscoped).Where(sameTime, id, id).Update(ctx, "deleted_at", nil); err != nil {
+			return err
+		}
+		if _, err := gorm.G[Chapter](tx).Scopes(unscoped).Where(sameTime, id, id).Update(ctx, "deleted_at", nil); err != nil {
+			return err
+		}
+		return tx.Unscoped().Model(&Document{}).Where("id = ?", id).Updates(map[string]any{
+			"deleted_at":  nil,
+			"deleted_key": "",
+		}).Error
+	})
+}
+
+// ListTrashedDocuments 查询回收站中的文档，按删除时间倒序，args 为 nil 时返回全部
+func (db *Database) ListTrashedDocuments(ctx context.Context, args *api.ListArgs) ([]Document, int64, error) {
+	q := gorm.G[Document](db.db).Scopes(unscoped).Where("deleted_at IS NOT NULL")
+	return listPage(ctx, q, args, "deleted_at DESC, id ASC")
+}
+
+// ListPurgeableDocuments 查询在回收站中超过保留期（删除时间早于 before）的文档
+func (db *Database) ListPurgeableDocuments(ctx context.Context, before time.Time) ([]Document, error) {
+	return gorm.G[Document](db.db).Scopes(unscoped).Where("deleted_at IS NOT NULL AND deleted_at < ?", before).Order("deleted_at ASC").Find(ctx)
+}
+
+// TrashChapter 在一个事务中将章节及其场景移入回收站
+func (db *Database) TrashChapter(ctx context.Context, id, documentID string) error {
+	now := time.Now()
+	return db.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if _, err := gorm.G[Scene](tx).Where("chapter_id = ?", id).Update(ctx, "deleted_at", now); err != nil {
+			return err
+		}
+		rowsAffected, err := gorm.G[Chapter](tx).Where("id = ? AND document_id = ?", id, documentID).Update(ctx, "deleted_at", now)
+		if err != nil {
+			return err
+		}
+		if rowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return nil
+	})
+}
+
+// RestoreChapter 从回收站恢复章节，以及与章节一起移入回收站的场景
+// 章节不在回收站中或所属文档在回收站中时返回 gorm.ErrRecordNotFound
+func (db *Database) RestoreChapter(ctx context.Context, id, documentID string) error {
+	return db.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if _, err := gorm.G[Document](tx).Where("id = ?", documentID).Take(ctx); err != nil {
+			return err
+		}
+		if _, err := gorm.G[Chapter](tx).Scopes(unscoped).Where("id = ? AND document_id = ? AND deleted_at IS NOT NULL", id, documentID).Take(ctx); err != nil {
+			return err
+		}
+		sameTime := "chapter_id = ? AND deleted_at = (SELECT deleted_at FROM chapters WHERE id = ?)"
+		if _, err := gorm.G[Scene](tx).Scopes(unscoped).Where(sameTime, id, id).Update(ctx, "deleted_at", nil); err != nil {
+			return err
+		}
+		_, err := gorm.G[Chapter](tx).Scopes(unscoped).Where("id = ?", id).Update(ctx, "deleted_at", nil)
+		return err
+	})
+}
+
+// RestoreScene 从回收站恢复场景，场景不在回收站中或所属章节在回收站中时返回 gorm.ErrRecordNotFound
+func (db *Database) RestoreScene(ctx context.Context, id string) error {
+	return db.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		scene, err := gorm.G[Scene](tx).Scopes(unscoped).Where("id = ? AND deleted_at IS NOT NULL", id).Take(ctx)
+		if err != nil {
+			return err
+		}
+		if _, err := gorm.G[Chapter](tx).Where("id = ?", scene.ChapterID).Take(ctx); err != nil {
+			return err
+		}
+		_, err = gorm.G[Scene](tx).Scopes(unscoped).Where("id = ?", id).Update(ctx, "deleted_at", nil)
+		return err
+	})
+}
+
+// ListTrashedChapters 查询文档在回收站中的章节
+func (db *Database) ListTrashedChapters(ctx context.Context, documentID string) ([]Chapter, error) {
+	return gorm.G[Chapter](db.db).Scopes(unscoped).Where("document_id = ? AND deleted_at IS NOT NULL", documentID).Order(db.quote("index") + " ASC").Find(ctx)
+}
+
+// ListTrashedScenes 查询文档在回收站中且可以单独恢复（所属章节不在回收站中）的场景
+func (db *Database) ListTrashedScenes(ctx context.Context, documentID string) ([]Scene, error) {
+	return gorm.G[Scene](db.db).Scopes(unscoped).
+		Where("document_id = ? AND deleted_at IS NOT NULL", documentID).
+		Where("chapter_id NOT IN (SELECT id FROM chapters WHERE document_id = ? AND deleted_at IS NOT NULL)", documentID).
+		Order("chapter_id ASC, " + db.quote("index") + " ASC").Find(ctx)
+}
+
+// PurgeTrash 彻底删除在回收站中超过保留期的章节、场景、角色及其修订记录和向量，回收站中的文档由 DeleteDocumentCascade 删除
+func (db *Database) PurgeTrash(ctx context.Context, before time.Time) error {
+	return db.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		purged := func(table string) string {
+			return "IN (SELECT id FROM " + table + " WHERE deleted_at < ?)"
+		}
+		if _, err := gorm.G[Embedding](tx).Where("target_type = ? AND target_id "+purged("scenes"), api.SearchTypeScene, before).Delete(ctx); err != nil {
+			return err
+		}
+		if _, err := gorm.G[Embedding](tx).Where("target_type = ? AND target_id "+purged("chapters"), api.SearchTypeChapter, before).Delete(ctx); err != nil {
+			return err
+		}
+		if _, err := gorm.G[SceneRevision](tx).Where("scene_id "+purged("scenes"), before).Delete(ctx); err != nil {
+			return err
+		}
+		if _, err := gorm.G[RoleRevision](tx).Where("role_id "+purged("roles"), before).Delete(ctx); err != nil {
+			return err
+		}
+		if _, err := gorm.G[ChapterRevision](tx).Where("chapter_id "+purged("chapters"), before).Delete(ctx); err != nil {
+			return err
+		}
+		if _, err := gorm.G[Scene](tx).Scopes(unscoped).Where("deleted_at < ?", before).Delete(ctx); err != nil {
+			return err
+		}
+		if _, err := gorm.G[Role](tx).Scopes(unscoped).Where("deleted_at < ?", before).Delete(ctx); err != nil {
+			return err
+		}
+		_, err := gorm.G[Chapter](tx).Scopes(unscoped).Where("deleted_at < ?", before).Delete(ctx)
+		return err
+	})
+}
@@ -0,0 +1,157 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	"imgagent/api"
+)
+
+// createTrashTestDocument 创建包含两个章节、每章一个场景和一个角色的文档
+func createTrashTestDocument(t *testing.T, db *Database, name string) (docID string, chapters []Chapter, scenes []Scene) {
+	t.Helper()
+	ctx := context.Background()
+
+	docID = MakeUUID()
+	_, err := db.CreateDocument(ctx, docID, DocumentFile{}, &api.CreateDocumentArgs{Name: name})
+	require.NoError(t, err)
+	require.NoError(t, db.CreateChapters(ctx, docID, []api.CreateChapterArgs{{Title: "第一章"}, {Title: "第二章"}}))
+	chapters, _, err = db.ListChapters(ctx, docID, nil)
+	require.NoError(t, err)
+	for i, chapter := range chapters {
+		scenes = append(scenes, Scene{ID: MakeUUID(), ChapterID: chapter.ID, DocumentID: docID, Index: i, Content: chapter.Title})
+	}
+	require.NoError(t, db.CreateScenes(ctx, scenes))
+	require.NoError(t, db.CreateRoles(ctx, []Role{{ID: MakeUUID(), DocumentID: docID, Name: "祥子"}}))
+	return docID, chapters, scenes
+}
+
+func TestTrashAndRestoreDocument(t *testing.T) {
+	db := setupTestDB(t)
+	ctx := context.Background()
+
+	docID, chapters, scenes := createTrashTestDocument(t, db, "骆驼祥子")
+
+	// 单独删除的场景在恢复文档后仍在回收站中
+	require.NoError(t, db.DeleteScene(ctx, scenes[1].ID))
+	time.Sleep(10 * time.Millisecond)
+
+	require.NoError(t, db.TrashDocument(ctx, docID))
+	assert.ErrorIs(t, db.TrashDocument(ctx, docID), gorm.ErrRecordNotFound)
+
+	_, err := db.GetDocument(ctx, docID)
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+	_, err = db.GetDocumentWithName(ctx, "骆驼祥子")
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+	found, _, err := db.ListChapters(ctx, docID, nil)
+	require.NoError(t, err)
+	assert.Empty(t, found)
+	trashed, total, err := db.ListTrashedDocuments(ctx, nil)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), total)
+	require.Len(t, trashed, 1)
+	assert.True(t, trashed[0].DeletedAt.Valid)
+	assert.Equal(t, docID, trashed[0].DeletedKey)
+
+	// 回收站中的文档不占用名称，同名文档存在时无法恢复
+	otherID := MakeUUID()
+	_, err = db.CreateDocument(ctx, otherID, DocumentFile{}, &api.CreateDocumentArgs{Name: "骆驼祥子"})
+	require.NoError(t, err)
+	assert.Error(t, db.RestoreDocument(ctx, docID))
+	require.NoError(t, db.TrashDocument(ctx, otherID))
+
+	require.NoError(t, db.RestoreDocument(ctx, docID))
+	assert.ErrorIs(t, db.RestoreDocument(ctx, docID), gorm.ErrRecordNotFound)
+	doc, err := db.GetDocumentWithName(ctx, "骆驼祥子")
+	require.NoError(t, err)
+	assert.Equal(t, docID, doc.ID)
+	assert.Empty(t, doc.DeletedKey)
+	found, _, err = db.ListChapters(ctx, docID, nil)
+	require.NoError(t, err)
+	assert.Len(t, found, len(chapters))
+	roles, _, err := db.ListRolesByDocument(ctx, docID, nil)
+	require.NoError(t, err)
+	assert.Len(t, roles, 1)
+	foundScenes, _, err := db.ListScenesByDocument(ctx, docID, nil)
+	require.NoError(t, err)
+	require.Len(t, foundScenes, 1)
+	assert.Equal(t, scenes[0].ID, foundScenes[0].ID)
+
+	trashedScenes, err := db.ListTrashedScenes(ctx, docID)
+	require.NoError(t, err)
+	require.Len(t, trashedScenes, 1)
+	require.NoError(t, db.RestoreScene(ctx, scenes[1].ID))
+	foundScenes, _, err = db.ListScenesByDocument(ctx, docID, nil)
+	require.NoError(t, err)
+	assert.Len(t, foundScenes, 2)
+}
+
+func TestTrashAndRestoreChapter(t *testing.T) {
+	db := setupTestDB(t)
+	ctx := context.Background()
+
+	docID, chapters, scenes := createTrashTestDocument(t, db, "边城")
+	require.NoError(t, db.TrashChapter(ctx, chapters[0].ID, docID))
+	assert.ErrorIs(t, db.TrashChapter(ctx, chapters[0].ID, docID), gorm.ErrRecordNotFound)
+
+	_, err := db.GetChapter(ctx, chapters[0].ID, docID)
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+	trashedChapters, err := db.ListTrashedChapters(ctx, docID)
+	require.NoError(t, err)
+	require.Len(t, trashedChapters, 1)
+	// 随章节删除的场景不能单独恢复
+	trashedScenes, err := db.ListTrashedScenes(ctx, docID)
+	require.NoError(t, err)
+	assert.Empty(t, trashedScenes)
+	assert.ErrorIs(t, db.RestoreScene(ctx, scenes[0].ID), gorm.ErrRecordNotFound)
+
+	require.NoError(t, db.RestoreChapter(ctx, chapters[0].ID, docID))
+	_, err = db.GetChapter(ctx, chapters[0].ID, docID)
+	require.NoError(t, err)
+	found, err := db.ListScenesByChapter(ctx, chapters[0].ID)
+	require.NoError(t, err)
+	assert.Len(t, found, 1)
+
+	// 文档在回收站中时不能单独恢复章节
+	require.NoError(t, db.TrashChapter(ctx, chapters[0].ID, docID))
+	require.NoError(t, db.TrashDocument(ctx, docID))
+	assert.ErrorIs(t, db.RestoreChapter(ctx, chapters[0].ID, docID), gorm.ErrRecordNotFound)
+}
+
+func TestPurgeTrash(t *testing.T) {
+	db := setupTestDB(t)
+	ctx := context.Background()
+
+	docID, chapters, _ := createTrashTestDocument(t, db, "呐喊")
+	otherID, otherChapters, _ := createTrashTestDocument(t, db, "彷徨")
+	require.NoError(t, db.TrashDocument(ctx, docID))
+	require.NoError(t, db.TrashChapter(ctx, otherChapters[0].ID, otherID))
+
+	// 未超过保留期
+	docs, err := db.ListPurgeableDocuments(ctx, time.Now().Add(-time.Hour))
+	require.NoError(t, err)
+	assert.Empty(t, docs)
+
+	before := time.Now().Add(time.Second)
+	docs, err = db.ListPurgeableDocuments(ctx, before)
+	require.NoError(t, err)
+	require.Len(t, docs, 1)
+	require.NoError(t, db.DeleteDocumentCascade(ctx, docs[0].ID))
+	require.NoError(t, db.PurgeTrash(ctx, before))
+
+	trashed, _, err := db.ListTrashedDocuments(ctx, nil)
+	require.NoError(t, err)
+	assert.Empty(t, trashed)
+	assert.ErrorIs(t, db.RestoreChapter(ctx, chapters[0].ID, docID), gorm.ErrRecordNotFound)
+	trashedChapters, err := db.ListTrashedChapters(ctx, otherID)
+	require.NoError(t, err)
+	assert.Empty(t, trashedChapters)
+	found, _, err := db.ListChapters(ctx, otherID, nil)
+	require.NoError(t, err)
+	assert.Len(t, found, 1)
+}
@@ -47,6 +47,10 @@ func (db *Database) User(ctx context.Context, uid int64) (User, error) {
 	return gorm.G[User](db.db).Where("id = ?", uid).Take(ctx)
 }
 
+func (db *Database) ListUsers(ctx context.Context, uids []int64) ([]User, error) {
+	return gorm.G[User](db.db).Where("id IN ?", uids).Find(ctx)
+}
+
 func (db *Database) GetAdminID(ctx context.Context) (int64, error) {
 	admin, err := gorm.G[User](db.db).Where("super_admin = ?", 1).Take(ctx)
 	if err != nil {
@@ -0,0 +1,103 @@
+package embedding
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	"imgagent/pkg/logger"
+)
+
+// Client OpenAI 兼容的 embeddings 接口客户端，DashScope 使用兼容模式地址
+// https://dashscope.aliyuncs.com/compatible-mode/v1/embeddings
+type Client struct {
+	URL        string       // embeddings 接口地址
+	Model      string       // 向量模型，如 text-embedding-v4、text-embedding-3-small
+	APIKey     string       // API 密钥
+	Dimensions int          // 向量维度，为 0 时使用模型默认维度
+	HTTPClient *http.Client // 为 nil 时使用 http.DefaultClient
+}
+
+// EmbeddingRequest embeddings 请求
+type EmbeddingRequest struct {
+	Model          string   `json:"model"`
+	Input          []string `json:"input"`
+	Dimensions     int      `json:"dimensions,omitempty"`
+	EncodingFormat string   `json:"encoding_format"`
+}
+
+// EmbeddingResponse embeddings 响应
+type EmbeddingResponse struct {
+	Data []EmbeddingData `json:"data"`
+}
+
+// EmbeddingData 单个输入的向量，Index 为输入中的序号
+type EmbeddingData struct {
+	Index     int       `json:"index"`
+	Embedding []float32 `json:"embedding"`
+}
+
+// Embed 生成 texts 的向量，按输入顺序返回，单次请求的文本数受接口限制（DashScope 最多 10 条）
+func (c *Client) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	log := logger.FromContext(ctx)
+
+	reqBody, err := json.Marshal(EmbeddingRequest{
+		Model:          c.Model,
+		Input:          texts,
+		Dimensions:     c.Dimensions,
+		EncodingFormat: "float",
+	})
+	if err != nil {
+		return nil, fmt.Errorf("marshal request failed: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, c.URL, bytes.NewReader(reqBody))
+	if err != nil {
+		return nil, fmt.Errorf("create request failed: %w", err)
+	}
+	httpReq.Header.Set("Authorization", fmt.Sprintf("Bearer %s", c.APIKey))
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	httpClient := c.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	resp, err := httpClient.Do(httpReq)
+	if err != nil {
+		log.Errorf("Failed to send embedding request, err: %v", err)
+		return nil, fmt.Errorf("send request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("read response failed: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		log.Errorf("Embedding failed, status: %d, body: %s", resp.StatusCode, string(respBody))
+		return nil, fmt.Errorf("embedding failed, status: %d, body: %s", resp.StatusCode, string(respBody))
+	}
+
+	var embResp EmbeddingResponse
+	if err := json.Unmarshal(respBody, &embResp); err != nil {
+		log.Errorf("Failed to parse embedding response, err: %v, body: %s", err, string(respBody))
+		return nil, fmt.Errorf("parse response failed: %w", err)
+	}
+
+	vectors := make([][]float32, len(texts))
+	for _, d := range embResp.Data {
+		if d.Index < 0 || d.Index >= len(texts) || len(d.Embedding) == 0 {
+			return nil, fmt.Errorf("invalid embedding index: %d", d.Index)
+		}
+		vectors[d.Index] = d.Embedding
+	}
+	for i, v := range vectors {
+		if v == nil {
+			return nil, fmt.Errorf("missing embedding for input %d", i)
+		}
+	}
+	return vectors, nil
+}
@@ -0,0 +1,41 @@
+package embedding
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClientEmbed(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer key", r.Header.Get("Authorization"))
+		var req EmbeddingRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		assert.Equal(t, "text-embedding-v4", req.Model)
+		assert.Equal(t, 4, req.Dimensions)
+		if req.Input[0] == "error" {
+			http.Error(w, `{"error":{"message":"bad input"}}`, http.StatusBadRequest)
+			return
+		}
+		// 乱序返回
+		var resp EmbeddingResponse
+		for i := len(req.Input) - 1; i >= 0; i-- {
+			resp.Data = append(resp.Data, EmbeddingData{Index: i, Embedding: []float32{float32(len([]rune(req.Input[i]))), 0, 0, 1}})
+		}
+		json.NewEncoder(w).Encode(resp)
+	}))
+	defer server.Close()
+
+	client := &Client{URL: server.URL, Model: "text-embedding-v4", APIKey: "key", Dimensions: 4}
+	vectors, err := client.Embed(context.Background(), []string{"祥子", "拉车的祥子"})
+	require.NoError(t, err)
+	assert.Equal(t, [][]float32{{2, 0, 0, 1}, {5, 0, 0, 1}}, vectors)
+
+	_, err = client.Embed(context.Background(), []string{"error"})
+	assert.ErrorContains(t, err, "status: 400")
+}
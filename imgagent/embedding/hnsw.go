@@ -0,0 +1,284 @@
+package embedding
+
+import (
+	"container/heap"
+	"math"
+	"math/rand/v2"
+	"sort"
+	"sync"
+)
+
+// HNSWConfig HNSW 索引参数
+type HNSWConfig struct {
+	M              int // 每层连接的邻居数，第 0 层最多 2M
+	EfConstruction int // 插入时的候选数，越大图质量越高、插入越慢
+	EfSearch       int // 检索时的候选数，越大召回率越高、检索越慢，不小于 K
+}
+
+var DefaultHNSWConfig = HNSWConfig{M: 16, EfConstruction: 200, EfSearch: 64}
+
+// hnswMaxLevel 节点的最大层数
+const hnswMaxLevel = 16
+
+// HNSWIndex 每个文档一个 HNSW 图的近似检索索引
+// 替换的向量在图中标记删除，仍用于导航，删除的节点超过一半时重建该文档的图
+type HNSWIndex struct {
+	conf HNSWConfig
+	ml   float64 // 层数分布的系数 1/ln(M)
+
+	mu     sync.RWMutex
+	graphs map[string]*hnswGraph
+	rng    *rand.Rand
+	n      int
+}
+
+type hnswGraph struct {
+	dim      int
+	nodes    []hnswNode
+	keys     map[string]int32 // key -> 未删除的节点
+	entry    int32
+	maxLevel int
+	deleted  int
+}
+
+type hnswNode struct {
+	item    Item      // Vector 为单位向量
+	links   [][]int32 // 每层的邻居
+	deleted bool
+}
+
+func NewHNSWIndex(conf HNSWConfig) *HNSWIndex {
+	if conf.M < 2 {
+		conf.M = DefaultHNSWConfig.M
+	}
+	if conf.EfConstruction < conf.M {
+		conf.EfConstruction = DefaultHNSWConfig.EfConstruction
+	}
+	if conf.EfSearch <= 0 {
+		conf.EfSearch = DefaultHNSWConfig.EfSearch
+	}
+	return &HNSWIndex{
+		conf:   conf,
+		ml:     1 / math.Log(float64(conf.M)),
+		graphs: make(map[string]*hnswGraph),
+		rng:    rand.New(rand.NewPCG(1, 2)), // 固定种子使图结构可复现
+	}
+}
+
+func newHNSWGraph(dim int) *hnswGraph {
+	return &hnswGraph{dim: dim, keys: make(map[string]int32), entry: -1}
+}
+
+func (x *HNSWIndex) Add(items ...Item) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	for _, item := range items {
+		g := x.graphs[item.DocumentID]
+		if g == nil {
+			g = newHNSWGraph(len(item.Vector))
+			x.graphs[item.DocumentID] = g
+		}
+		if len(item.Vector) != g.dim {
+			continue
+		}
+		key := itemKey(item.Type, item.ID)
+		if old, ok := g.keys[key]; ok {
+			g.nodes[old].deleted = true
+			g.deleted++
+		} else {
+			x.n++
+		}
+		item.Vector = normalize(item.Vector)
+		x.insert(g, item)
+
+		if g.deleted > len(g.nodes)/2 {
+			x.graphs[item.DocumentID] = x.rebuild(g)
+		}
+	}
+}
+
+func (x *HNSWIndex) DeleteDocument(documentID string) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	if g := x.graphs[documentID]; g != nil {
+		x.n -= len(g.keys)
+		delete(x.graphs, documentID)
+	}
+}
+
+func (x *HNSWIndex) Search(q Query) []Hit {
+	vector := normalize(q.Vector)
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+
+	g := x.graphs[q.DocumentID]
+	if g == nil || g.entry < 0 || len(vector) != g.dim || q.K <= 0 {
+		return nil
+	}
+	ep := g.entry
+	for level := g.maxLevel; level > 0; level-- {
+		ep = g.greedy(vector, ep, level)
+	}
+	// 删除的节点和其他类型的记录会被过滤，扩大候选数
+	ef := max(x.conf.EfSearch, q.K)
+	if q.Type != "" || g.deleted > 0 {
+		ef *= 2
+	}
+	var hits []Hit
+	for _, c := range g.searchLayer(vector, ep, ef, 0) {
+		node := &g.nodes[c.id]
+		if node.deleted || (q.Type != "" && node.item.Type != q.Type) {
+			continue
+		}
+		hits = append(hits, Hit{Type: node.item.Type, ID: node.item.ID, Score: 1 - c.dist})
+	}
+	return topHits(hits, q.K)
+}
+
+func (x *HNSWIndex) Len() int {
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+	return x.n
+}
+
+func (x *HNSWIndex) randomLevel() int {
+	level := int(-math.Log(1-x.rng.Float64()) * x.ml)
+	return min(level, hnswMaxLevel)
+}
+
+// maxLinks 每层邻居数的上限
+func (x *HNSWIndex) maxLinks(level int) int {
+	if level == 0 {
+		return 2 * x.conf.M
+	}
+	return x.conf.M
+}
+
+// insert 将单位向量插入图中
+func (x *HNSWIndex) insert(g *hnswGraph, item Item) {
+	level := x.randomLevel()
+	id := int32(len(g.nodes))
+	g.nodes = append(g.nodes, hnswNode{item: item, links: make([][]int32, level+1)})
+	g.keys[itemKey(item.Type, item.ID)] = id
+	if g.entry < 0 {
+		g.entry = id
+		g.maxLevel = level
+		return
+	}
+
+	ep := g.entry
+	for l := g.maxLevel; l > level; l-- {
+		ep = g.greedy(item.Vector, ep, l)
+	}
+	for l := min(level, g.maxLevel); l >= 0; l-- {
+		candidates := g.searchLayer(item.Vector, ep, x.conf.EfConstruction, l)
+		neighbors := candidates[:min(len(candidates), x.conf.M)]
+		links := make([]int32, 0, len(neighbors))
+		for _, nb := range neighbors {
+			links = append(links, nb.id)
+			nbLinks := append(g.nodes[nb.id].links[l], id)
+			if len(nbLinks) > x.maxLinks(l) {
+				nbLinks = g.prune(nb.id, nbLinks, x.maxLinks(l))
+			}
+			g.nodes[nb.id].links[l] = nbLinks
+		}
+		g.nodes[id].links[l] = links
+		ep = candidates[0].id
+	}
+	if level > g.maxLevel {
+		g.entry = id
+		g.maxLevel = level
+	}
+}
+
+// rebuild 只使用未删除的节点重建图
+func (x *HNSWIndex) rebuild(g *hnswGraph) *hnswGraph {
+	rebuilt := newHNSWGraph(g.dim)
+	for _, node := range g.nodes {
+		if !node.deleted {
+			x.insert(rebuilt, node.item)
+		}
+	}
+	return rebuilt
+}
+
+func (g *hnswGraph) dist(q []float32, id int32) float32 {
+	return 1 - dot(q, g.nodes[id].item.Vector)
+}
+
+// greedy 在一层中从 ep 出发，贪心地移动到离 q 最近的节点
+func (g *hnswGraph) greedy(q []float32, ep int32, level int) int32 {
+	cur, curDist := ep, g.dist(q, ep)
+	for changed := true; changed; {
+		changed = false
+		for _, nb := range g.nodes[cur].links[level] {
+			if d := g.dist(q, nb); d < curDist {
+				cur, curDist, changed = nb, d, true
+			}
+		}
+	}
+	return cur
+}
+
+// searchLayer 在一层中从 ep 出发检索离 q 最近的 ef 个节点，按距离升序返回
+func (g *hnswGraph) searchLayer(q []float32, ep int32, ef, level int) []candidate {
+	visited := map[int32]bool{ep: true}
+	start := candidate{id: ep, dist: g.dist(q, ep)}
+	candidates := &candidateHeap{items: []candidate{start}}
+	results := &candidateHeap{items: []candidate{start}, farthest: true}
+	for candidates.Len() > 0 {
+		c := heap.Pop(candidates).(candidate)
+		if results.Len() >= ef && c.dist > results.items[0].dist {
+			break
+		}
+		for _, nb := range g.nodes[c.id].links[level] {
+			if visited[nb] {
+				continue
+			}
+			visited[nb] = true
+			d := g.dist(q, nb)
+			if results.Len() < ef || d < results.items[0].dist {
+				heap.Push(candidates, candidate{id: nb, dist: d})
+				heap.Push(results, candidate{id: nb, dist: d})
+				if results.Len() > ef {
+					heap.Pop(results)
+				}
+			}
+		}
+	}
+	sort.Slice(results.items, func(i, j int) bool { return results.items[i].dist < results.items[j].dist })
+	return results.items
+}
+
+// prune 保留离节点 id 最近的 m 个邻居
+func (g *hnswGraph) prune(id int32, links []int32, m int) []int32 {
+	v := g.nodes[id].item.Vector
+	sort.Slice(links, func(i, j int) bool { return g.dist(v, links[i]) < g.dist(v, links[j]) })
+	return links[:m]
+}
+
+type candidate struct {
+	id   int32
+	dist float32
+}
+
+// candidateHeap 按距离排序的堆，farthest 为 true 时堆顶为最远的节点
+type candidateHeap struct {
+	items    []candidate
+	farthest bool
+}
+
+func (h *candidateHeap) Len() int { return len(h.items) }
+func (h *candidateHeap) Less(i, j int) bool {
+	if h.farthest {
+		return h.items[i].dist > h.items[j].dist
+	}
+	return h.items[i].dist < h.items[j].dist
+}
+func (h *candidateHeap) Swap(i, j int) { h.items[i], h.items[j] = h.items[j], h.items[i] }
+func (h *candidateHeap) Push(x any)    { h.items = append(h.items, x.(candidate)) }
+func (h *candidateHeap) Pop() any {
+	last := h.items[len(h.items)-1]
+	h.items = h.items[:len(h.items)-1]
+	return last
+}
@@ -0,0 +1,161 @@
+package embedding
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+)
+
+// 向量索引类型
+const (
+	IndexFlat = "flat" // 暴力检索，逐个计算相似度
+	IndexHNSW = "hnsw" // HNSW 近似检索
+)
+
+// Item 索引中的向量，Type 和 ID 一起唯一标识一条记录
+type Item struct {
+	Type       string
+	ID         string
+	DocumentID string
+	Vector     []float32
+}
+
+// Query 在一个文档中检索与 Vector 最相似的 K 条记录，Type 不为空时只检索该类型
+type Query struct {
+	DocumentID string
+	Vector     []float32
+	K          int
+	Type       string
+}
+
+// Hit 检索结果，Score 为余弦相似度
+type Hit struct {
+	Type  string
+	ID    string
+	Score float32
+}
+
+// Index 向量索引，按文档分组，检索限定在一个文档内
+// 实现需要并发安全，向量维度与查询不一致的记录不参与检索
+type Index interface {
+	// Add 添加向量，Type 和 ID 相同的记录被替换
+	Add(items ...Item)
+	// DeleteDocument 删除文档的全部向量
+	DeleteDocument(documentID string)
+	// Search 返回按相似度倒序的结果
+	Search(q Query) []Hit
+	// Len 返回向量数
+	Len() int
+}
+
+// NewIndex 按类型创建向量索引，kind 为空时使用 IndexFlat
+func NewIndex(kind string) (Index, error) {
+	switch kind {
+	case "", IndexFlat:
+		return NewFlatIndex(), nil
+	case IndexHNSW:
+		return NewHNSWIndex(DefaultHNSWConfig), nil
+	default:
+		return nil, fmt.Errorf("unknown vector index: %s", kind)
+	}
+}
+
+// normalize 返回单位向量的副本，余弦相似度即为点积
+func normalize(v []float32) []float32 {
+	var sum float64
+	for _, f := range v {
+		sum += float64(f) * float64(f)
+	}
+	n := make([]float32, len(v))
+	if sum == 0 {
+		return n
+	}
+	norm := float32(math.Sqrt(sum))
+	for i, f := range v {
+		n[i] = f / norm
+	}
+	return n
+}
+
+func dot(a, b []float32) float32 {
+	var sum float32
+	for i := range a {
+		sum += a[i] * b[i]
+	}
+	return sum
+}
+
+func itemKey(typ, id string) string {
+	return typ + "/" + id
+}
+
+// FlatIndex 暴力检索的向量索引，检索时计算文档中每个向量的相似度，适合单个文档向量数不多的场景
+type FlatIndex struct {
+	mu   sync.RWMutex
+	docs map[string]map[string]Item // documentID -> key -> 单位向量
+	n    int
+}
+
+func NewFlatIndex() *FlatIndex {
+	return &FlatIndex{docs: make(map[string]map[string]Item)}
+}
+
+func (x *FlatIndex) Add(items ...Item) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	for _, item := range items {
+		doc := x.docs[item.DocumentID]
+		if doc == nil {
+			doc = make(map[string]Item)
+			x.docs[item.DocumentID] = doc
+		}
+		key := itemKey(item.Type, item.ID)
+		if _, ok := doc[key]; !ok {
+			x.n++
+		}
+		item.Vector = normalize(item.Vector)
+		doc[key] = item
+	}
+}
+
+func (x *FlatIndex) DeleteDocument(documentID string) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	x.n -= len(x.docs[documentID])
+	delete(x.docs, documentID)
+}
+
+func (x *FlatIndex) Search(q Query) []Hit {
+	vector := normalize(q.Vector)
+	x.mu.RLock()
+	var hits []Hit
+	for _, item := range x.docs[q.DocumentID] {
+		if (q.Type != "" && item.Type != q.Type) || len(item.Vector) != len(vector) {
+			continue
+		}
+		hits = append(hits, Hit{Type: item.Type, ID: item.ID, Score: dot(item.Vector, vector)})
+	}
+	x.mu.RUnlock()
+	return topHits(hits, q.K)
+}
+
+func (x *FlatIndex) Len() int {
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+	return x.n
+}
+
+// topHits 按相似度倒序返回前 k 条，相似度相同时按 id 排序保证结果稳定
+func topHits(hits []Hit, k int) []Hit {
+	sort.Slice(hits, func(i, j int) bool {
+		if hits[i].Score != hits[j].Score {
+			return hits[i].Score > hits[j].Score
+		}
+		return itemKey(hits[i].Type, hits[i].ID) < itemKey(hits[j].Type, hits[j].ID)
+	})
+	if len(hits) > k {
+		hits = hits[:k]
+	}
+	return hits
+}
@@ -0,0 +1,111 @@
+package embedding
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testIndexBasic(t *testing.T, x Index) {
+	x.Add(
+		Item{Type: "chapter", ID: "c1", DocumentID: "d1", Vector: []float32{1, 0, 0}},
+		Item{Type: "chapter", ID: "c2", DocumentID: "d1", Vector: []float32{0, 2, 0}},
+		Item{Type: "scene", ID: "s1", DocumentID: "d1", Vector: []float32{1, 1, 0}},
+		Item{Type: "chapter", ID: "c3", DocumentID: "d2", Vector: []float32{1, 0, 0}},
+	)
+	assert.Equal(t, 4, x.Len())
+
+	// 只检索指定文档，相似度为余弦相似度
+	hits := x.Search(Query{DocumentID: "d1", Vector: []float32{3, 0, 0}, K: 2})
+	require.Len(t, hits, 2)
+	assert.Equal(t, Hit{Type: "chapter", ID: "c1", Score: 1}, hits[0])
+	assert.Equal(t, "s1", hits[1].ID)
+	assert.InDelta(t, 0.7071, hits[1].Score, 1e-4)
+
+	hits = x.Search(Query{DocumentID: "d1", Vector: []float32{1, 0, 0}, K: 10, Type: "scene"})
+	require.Len(t, hits, 1)
+	assert.Equal(t, "s1", hits[0].ID)
+
+	// 替换向量
+	x.Add(Item{Type: "chapter", ID: "c1", DocumentID: "d1", Vector: []float32{0, 0, 1}})
+	assert.Equal(t, 4, x.Len())
+	hits = x.Search(Query{DocumentID: "d1", Vector: []float32{0, 0, 1}, K: 1})
+	require.Len(t, hits, 1)
+	assert.Equal(t, "c1", hits[0].ID)
+	assert.InDelta(t, 1, hits[0].Score, 1e-6)
+
+	// 维度不一致的查询没有结果
+	assert.Empty(t, x.Search(Query{DocumentID: "d1", Vector: []float32{1, 0}, K: 1}))
+
+	x.DeleteDocument("d1")
+	assert.Equal(t, 1, x.Len())
+	assert.Empty(t, x.Search(Query{DocumentID: "d1", Vector: []float32{1, 0, 0}, K: 1}))
+}
+
+func TestFlatIndex(t *testing.T) {
+	testIndexBasic(t, NewFlatIndex())
+}
+
+func TestHNSWIndex(t *testing.T) {
+	testIndexBasic(t, NewHNSWIndex(DefaultHNSWConfig))
+}
+
+func TestNewIndex(t *testing.T) {
+	x, err := NewIndex("")
+	require.NoError(t, err)
+	assert.IsType(t, &FlatIndex{}, x)
+	x, err = NewIndex(IndexHNSW)
+	require.NoError(t, err)
+	assert.IsType(t, &HNSWIndex{}, x)
+	_, err = NewIndex("ivf")
+	assert.Error(t, err)
+}
+
+func randomVector(rng *rand.Rand, dim int) []float32 {
+	v := make([]float32, dim)
+	for i := range v {
+		v[i] = float32(rng.NormFloat64())
+	}
+	return v
+}
+
+// HNSW 的检索结果与暴力检索比较，召回率应接近 1
+func TestHNSWRecall(t *testing.T) {
+	const n, dim, k, queries = 2000, 32, 10, 50
+	rng := rand.New(rand.NewPCG(3, 4))
+	flat := NewFlatIndex()
+	hnsw := NewHNSWIndex(DefaultHNSWConfig)
+	for i := range n {
+		item := Item{Type: "scene", ID: fmt.Sprint(i), DocumentID: "d", Vector: randomVector(rng, dim)}
+		flat.Add(item)
+		hnsw.Add(item)
+	}
+	// 替换一半以上的向量，触发重建
+	for i := range n * 3 / 5 {
+		item := Item{Type: "scene", ID: fmt.Sprint(i), DocumentID: "d", Vector: randomVector(rng, dim)}
+		flat.Add(item)
+		hnsw.Add(item)
+	}
+	assert.Equal(t, n, hnsw.Len())
+
+	found := 0
+	for range queries {
+		q := Query{DocumentID: "d", Vector: randomVector(rng, dim), K: k}
+		expected := make(map[string]bool)
+		for _, hit := range flat.Search(q) {
+			expected[hit.ID] = true
+		}
+		hits := hnsw.Search(q)
+		require.Len(t, hits, k)
+		for _, hit := range hits {
+			if expected[hit.ID] {
+				found++
+			}
+		}
+	}
+	recall := float64(found) / float64(queries*k)
+	assert.Greater(t, recall, 0.9, "recall: %f", recall)
+}
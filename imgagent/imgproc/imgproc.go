@@ -0,0 +1,103 @@
+package imgproc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"image/jpeg"
+	_ "image/png"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp"
+
+	"imgagent/pkg/logger"
+)
+
+const (
+	VariantThumbnail = "thumbnail"
+	VariantMedium    = "medium"
+)
+
+// Variant 图片尺寸规格
+type Variant struct {
+	Name     string // 规格名称，作为输出文件名后缀
+	MaxWidth int    // 最大宽度，原图更小时不放大
+	Quality  int    // JPEG 质量 1-100
+}
+
+// DefaultVariants 漫画阅读器使用的缩略图和中图规格
+var DefaultVariants = []Variant{
+	{Name: VariantThumbnail, MaxWidth: 320, Quality: 75},
+	{Name: VariantMedium, MaxWidth: 800, Quality: 85},
+}
+
+// Process 为原图生成各规格的 JPEG 图片，输出文件与原图放在同一目录
+// 返回规格名称到输出文件路径的映射
+func Process(ctx context.Context, filename string, variants []Variant) (map[string]string, error) {
+	start := time.Now()
+	log := logger.FromContext(ctx)
+
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	src, format, err := image.Decode(f)
+	if err != nil {
+		return nil, fmt.Errorf("decode image failed: %w", err)
+	}
+	log.Infof("Decode image, filename: %s, format: %s, size: %v", filename, format, src.Bounds().Size())
+
+	base := strings.TrimSuffix(filename, filepath.Ext(filename))
+	files := make(map[string]string, len(variants))
+	for _, v := range variants {
+		if v.Name == "" || v.MaxWidth <= 0 {
+			return nil, errors.New("invalid variant")
+		}
+		out := base + "_" + v.Name + ".jpg"
+		if err := writeJPEG(out, Resize(src, v.MaxWidth), v.Quality); err != nil {
+			return nil, err
+		}
+		files[v.Name] = out
+	}
+	log.Infof("Process image costMS: %d, variants: %d", time.Since(start).Milliseconds(), len(files))
+	return files, nil
+}
+
+// Resize 按最大宽度等比缩放图片，原图宽度不超过 maxWidth 时直接返回原图
+func Resize(src image.Image, maxWidth int) image.Image {
+	b := src.Bounds()
+	if b.Dx() <= maxWidth {
+		return src
+	}
+	height := b.Dy() * maxWidth / b.Dx()
+	if height < 1 {
+		height = 1
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, maxWidth, height))
+	draw.CatmullRom.Scale(dst, dst.Bounds(), src, b, draw.Over, nil)
+	return dst
+}
+
+func writeJPEG(filename string, img image.Image, quality int) error {
+	if quality <= 0 || quality > 100 {
+		quality = jpeg.DefaultQuality
+	}
+	f, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	err = jpeg.Encode(f, img, &jpeg.Options{Quality: quality})
+	if err != nil {
+		f.Close()
+		os.Remove(filename)
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,77 @@
+package imgproc
+
+import (
+	"context"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func writeTestPNG(t *testing.T, filename string, width, height int) {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for x := 0; x < width; x++ {
+		for y := 0; y < height; y++ {
+			img.Set(x, y, color.RGBA{R: uint8(x), G: uint8(y), B: 128, A: 255})
+		}
+	}
+	f, err := os.Create(filename)
+	require.NoError(t, err)
+	defer f.Close()
+	require.NoError(t, png.Encode(f, img))
+}
+
+func TestResize(t *testing.T) {
+	t.Parallel()
+
+	src := image.NewRGBA(image.Rect(0, 0, 1328, 664))
+	dst := Resize(src, 320)
+	require.Equal(t, 320, dst.Bounds().Dx())
+	require.Equal(t, 160, dst.Bounds().Dy())
+
+	// 小图不放大
+	small := image.NewRGBA(image.Rect(0, 0, 100, 100))
+	require.Equal(t, small, Resize(small, 320))
+}
+
+func TestProcess(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "scene.png")
+	writeTestPNG(t, filename, 1000, 500)
+
+	files, err := Process(ctx, filename, DefaultVariants)
+	require.NoError(t, err)
+	require.Len(t, files, 2)
+	require.Equal(t, filepath.Join(dir, "scene_thumbnail.jpg"), files[VariantThumbnail])
+	require.Equal(t, filepath.Join(dir, "scene_medium.jpg"), files[VariantMedium])
+
+	for name, want := range map[string]int{VariantThumbnail: 320, VariantMedium: 800} {
+		f, err := os.Open(files[name])
+		require.NoError(t, err)
+		cfg, err := jpeg.DecodeConfig(f)
+		f.Close()
+		require.NoError(t, err)
+		require.Equal(t, want, cfg.Width, "variant %s", name)
+	}
+}
+
+func TestProcess_InvalidImage(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "bad.png")
+	require.NoError(t, os.WriteFile(filename, []byte("not an image"), 0o600))
+
+	_, err := Process(ctx, filename, DefaultVariants)
+	require.Error(t, err)
+}
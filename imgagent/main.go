@@ -46,6 +46,14 @@ func main() {
 	}
 	log.Println("conf: ", conf)
 
+	// 子命令 migrate 只维护表结构，不启动服务
+	if flag.Arg(0) == "migrate" {
+		if err := runMigrate(conf.DB, flag.Args()[1:]); err != nil {
+			log.Fatalf("Failed to migrate, err: %v", err)
+		}
+		return
+	}
+
 	_, err = logger.New(conf.LogConf)
 	if err != nil {
 		log.Fatalf("Failed to new logger, err: %v", err)
@@ -90,6 +98,7 @@ func main() {
 	}()
 
 	SetupGracefulShutdown(server)
+	svr.Close()
 }
 
 func SetupGracefulShutdown(server *http.Server) {
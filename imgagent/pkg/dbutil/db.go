@@ -3,19 +3,37 @@ package dbutil
 import (
 	"errors"
 	"fmt"
+	"strings"
 	"time"
 
 	"gorm.io/driver/mysql"
+	"gorm.io/driver/postgres"
+	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
 	glogger "gorm.io/gorm/logger"
 )
 
+// 支持的数据库驱动
+const (
+	DriverMySQL    = "mysql"
+	DriverPostgres = "postgres"
+	DriverSQLite   = "sqlite"
+)
+
+// SQLiteMemory SQLite 内存数据库，进程退出后数据丢失，用于演示和测试
+const SQLiteMemory = ":memory:"
+
 type Config struct {
-	Host           string `json:"host"`
-	Port           int    `json:"port"`
-	User           string `json:"user"`
-	Password       string `json:"password"`
-	Database       string `json:"database"`
+	// Driver 数据库驱动 mysql|postgres|sqlite，默认 mysql
+	Driver   string `json:"driver"`
+	Host     string `json:"host"`
+	Port     int    `json:"port"`
+	User     string `json:"user"`
+	Password string `json:"password"`
+	// Database 数据库名称，sqlite 为数据库文件路径或 :memory:
+	Database string `json:"database"`
+	// SSLMode postgres 的 sslmode，默认 disable
+	SSLMode        string `json:"ssl_mode"`
 	MaxIdleConns   int    `json:"max_idle_conns"`
 	MaxIdleTimeSec int    `json:"max_idle_time_sec"`
 	EnableLog      bool   `json:"enable_log"`
@@ -23,29 +41,21 @@ type Config struct {
 
 // NewDatabase 初始化数据库
 func NewDatabase(conf Config) (*gorm.DB, error) {
-	if conf.Host == "" || conf.User == "" || conf.Database == "" {
-		return nil, errors.New("invalid host or user or database")
-	}
-
-	if conf.Port == 0 {
-		conf.Port = 3306
+	dialector, err := newDialector(conf)
+	if err != nil {
+		return nil, err
 	}
-	dsn := fmt.Sprintf("%s:%s@tcp(%s:%d)/%s?charset=utf8mb4&parseTime=True&loc=Local",
-		conf.User,
-		conf.Password,
-		conf.Host,
-		conf.Port,
-		conf.Database,
-	)
 
 	gormConfig := &gorm.Config{
 		// 默认不打印 gorm 日志
 		Logger: glogger.Default.LogMode(glogger.Silent),
+		// 将各数据库的唯一索引冲突等错误转换为 gorm.ErrDuplicatedKey 等通用错误
+		TranslateError: true,
 	}
 	if conf.EnableLog {
 		gormConfig.Logger = glogger.Default.LogMode(glogger.Info)
 	}
-	db, err := gorm.Open(mysql.Open(dsn), gormConfig)
+	db, err := gorm.Open(dialector, gormConfig)
 	if err != nil {
 		return nil, err
 	}
@@ -56,5 +66,69 @@ func NewDatabase(conf Config) (*gorm.DB, error) {
 	sqlDB.SetMaxIdleConns(conf.MaxIdleConns)
 	sqlDB.SetConnMaxIdleTime(time.Duration(conf.MaxIdleTimeSec) * time.Second)
 	sqlDB.SetConnMaxLifetime(time.Hour)
+	if dialector.Name() == DriverSQLite {
+		// SQLite 同一时间只允许一个写入，内存数据库每个连接是独立的库，只使用一个连接
+		sqlDB.SetMaxOpenConns(1)
+		sqlDB.SetMaxIdleConns(1)
+		sqlDB.SetConnMaxLifetime(0)
+		sqlDB.SetConnMaxIdleTime(0)
+	}
 	return db, nil
 }
+
+// newDialector 根据驱动生成 DSN
+func newDialector(conf Config) (gorm.Dialector, error) {
+	switch conf.Driver {
+	case "", DriverMySQL:
+		if conf.Host == "" || conf.User == "" || conf.Database == "" {
+			return nil, errors.New("invalid host or user or database")
+		}
+		if conf.Port == 0 {
+			conf.Port = 3306
+		}
+		dsn := fmt.Sprintf("%s:%s@tcp(%s:%d)/%s?charset=utf8mb4&parseTime=True&loc=Local",
+			conf.User,
+			conf.Password,
+			conf.Host,
+			conf.Port,
+			conf.Database,
+		)
+		return mysql.Open(dsn), nil
+	case DriverPostgres:
+		if conf.Host == "" || conf.User == "" || conf.Database == "" {
+			return nil, errors.New("invalid host or user or database")
+		}
+		if conf.Port == 0 {
+			conf.Port = 5432
+		}
+		if conf.SSLMode == "" {
+			conf.SSLMode = "disable"
+		}
+		dsn := fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=%s",
+			pgQuote(conf.Host),
+			conf.Port,
+			pgQuote(conf.User),
+			pgQuote(conf.Password),
+			pgQuote(conf.Database),
+			pgQuote(conf.SSLMode),
+		)
+		return postgres.Open(dsn), nil
+	case DriverSQLite:
+		if conf.Database == "" {
+			return nil, errors.New("invalid database")
+		}
+		dsn := conf.Database
+		if dsn != SQLiteMemory && !strings.Contains(dsn, "?") {
+			// 并发写入时等待锁而不是直接返回 database is locked
+			dsn += "?_busy_timeout=5000&_journal_mode=WAL"
+		}
+		return sqlite.Open(dsn), nil
+	default:
+		return nil, fmt.Errorf("unsupported driver: %s", conf.Driver)
+	}
+}
+
+// pgQuote 转义 postgres DSN 中的值，值中可能包含空格和引号
+func pgQuote(s string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(s) + "'"
+}
@@ -0,0 +1,260 @@
+package spliter
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// maxTitleRunes 章节标题的最大长度，标题行超过该长度时只取章节序号部分
+const maxTitleRunes = 50
+
+// maxTitleLen 保存的章节标题最大字符数，与 db.Chapter.Title 的长度一致
+const maxTitleLen = 100
+
+// 章节类型
+const (
+	KindChapter  = "chapter"  // 正文章节
+	KindPrologue = "prologue" // 序章、楔子、引子
+	KindEpilogue = "epilogue" // 尾声、后记、终章
+	KindExtra    = "extra"    // 番外
+)
+
+// Chunk 分割后的章节
+type Chunk struct {
+	Title   string // 章节标题，未识别时为空
+	Content string // 章节正文，展示形式，段落之间以空行分隔
+	Number  int    // 原文中的章节序号，如“第十二章”为 12，未识别时为 0
+	Kind    string // 章节类型，见 KindChapter 等
+	Start   int    // 在原文纯文本中的起始字节偏移，未能定位时为 -1
+	End     int    // 在原文纯文本中的结束字节偏移（不含），未能定位时为 -1
+	Part    int    // 超长章节拆分后的分段序号，从 1 开始，未拆分时为 0
+
+	Paragraphs []string // 章节段落
+	Prompt     string   // 章节正文的提示词形式，见 PromptText
+
+	Volume       string // 所属卷标题，如“第一卷 风起”，未分卷时为空
+	VolumeNumber int    // 原文中的卷号，未识别时为 0
+}
+
+// mark 章节在全文中的起始位置
+type mark struct {
+	Title  string
+	Offset int
+}
+
+// splitByMarks 按起始位置切分章节，第一个位置之前的内容作为无标题章节保留
+// 只有标题没有正文的章节（如卷名）合并到下一章节开头
+func splitByMarks(content string, marks []mark) []Chunk {
+	var sorted []mark
+	for _, m := range marks {
+		// 位置需递增，同一位置只保留第一个
+		if len(sorted) == 0 || m.Offset > sorted[len(sorted)-1].Offset {
+			sorted = append(sorted, m)
+		}
+	}
+	if len(sorted) == 0 {
+		return nil
+	}
+
+	var chunks []Chunk
+	if front := strings.TrimSpace(content[:sorted[0].Offset]); front != "" {
+		chunks = append(chunks, Chunk{Content: front, Start: 0, End: sorted[0].Offset})
+	}
+	pending, pendingStart := "", -1
+	for i, m := range sorted {
+		end := len(content)
+		if i+1 < len(sorted) {
+			end = sorted[i+1].Offset
+		}
+		text := strings.TrimSpace(content[m.Offset:end])
+		if text == "" {
+			continue
+		}
+		// 正文通常以标题开头，否则补充标题
+		if m.Title != "" && !strings.HasPrefix(text, m.Title) {
+			text = m.Title + "\n" + text
+		}
+		if text == m.Title {
+			if pendingStart < 0 {
+				pendingStart = m.Offset
+			}
+			pending += text + "\n"
+			continue
+		}
+		start := m.Offset
+		if pendingStart >= 0 {
+			start = pendingStart
+		}
+		chunks = append(chunks, Chunk{Title: m.Title, Content: pending + text, Start: start, End: end})
+		pending, pendingStart = "", -1
+	}
+	if pending != "" {
+		pending = strings.TrimSpace(pending)
+		if len(chunks) > 0 {
+			last := &chunks[len(chunks)-1]
+			last.Content += "\n" + pending
+			last.End = len(content)
+		} else {
+			chunks = append(chunks, Chunk{Content: pending, Start: pendingStart, End: len(content)})
+		}
+	}
+	return chunks
+}
+
+// locateChunks 在原文中依次查找分割结果的位置，用于无法直接得到偏移的分割方式
+func locateChunks(content string, texts []string) []Chunk {
+	chunks := make([]Chunk, 0, len(texts))
+	pos := 0
+	for _, text := range texts {
+		chunk := Chunk{Content: text, Start: -1, End: -1}
+		if i := strings.Index(content[pos:], strings.TrimSpace(text)); i >= 0 {
+			chunk.Start = pos + i
+			chunk.End = chunk.Start + len(strings.TrimSpace(text))
+			// 分割结果可能重叠，下一段从本段起始之后查找
+			pos = chunk.Start + 1
+		}
+		chunks = append(chunks, chunk)
+	}
+	return chunks
+}
+
+// chapterTitle 返回章节标题：匹配位置所在行较短时取整行，否则只取匹配到的章节序号
+// 去掉 Markdown 标题前的 #
+func chapterTitle(content string, start, end int) string {
+	line := content[headingStart(content, start):]
+	if i := strings.IndexAny(line, "\r\n"); i >= 0 {
+		line = line[:i]
+	}
+	line = strings.TrimSpace(strings.TrimLeft(strings.TrimSpace(line), "#"))
+	if utf8.RuneCountInString(line) <= maxTitleRunes {
+		return line
+	}
+	return strings.TrimSpace(content[start:end])
+}
+
+// specialChapterRegex 序章、楔子、尾声、番外等特殊章节标题，需独占一行
+var specialChapterRegex = regexp.MustCompile(`(?m)^[ \t\x{3000}]*(?:#{1,6}[ \t]*)?[【\[]?(序章|序幕|序言|楔子|引子|尾声|后记|终章|番外)(?:[】\][ \t\x{3000}:：·\-—零一二三四五六七八九十\d篇][^\r\n]{0,30})?\r?$`)
+
+var specialKinds = map[string]string{
+	"序章": KindPrologue, "序幕": KindPrologue, "序言": KindPrologue, "楔子": KindPrologue, "引子": KindPrologue,
+	"尾声": KindEpilogue, "后记": KindEpilogue, "终章": KindEpilogue,
+	"番外": KindExtra,
+}
+
+// chapterKind 根据标题判断章节类型
+func chapterKind(title string) string {
+	if m := specialChapterRegex.FindStringSubmatch(strings.TrimSpace(title)); m != nil {
+		return specialKinds[m[1]]
+	}
+	return KindChapter
+}
+
+// truncateRunes 按字符截断字符串
+func truncateRunes(s string, n int) string {
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	return string([]rune(s)[:n])
+}
+
+var (
+	chapterNumberRegex = regexp.MustCompile(`第\s*([零〇一二两三四五六七八九十百千万\d]+|[IVXLCDM]+)\s*[章节回卷部篇集]`)
+	englishNumberRegex = regexp.MustCompile(`\b(?i:chapter|part|book|volume)\s+(\d+|[IVXLCDM]+)\b`)
+)
+
+// parseChapterNumber 从标题中解析章节序号，支持中文数字、阿拉伯数字和罗马数字，无法解析时返回 0
+func parseChapterNumber(title string) int {
+	var s string
+	if m := chapterNumberRegex.FindStringSubmatch(title); m != nil {
+		s = m[1]
+	} else if m := englishNumberRegex.FindStringSubmatch(title); m != nil {
+		s = m[1]
+	} else {
+		return 0
+	}
+	return parseNumber(s)
+}
+
+// parseNumber 解析阿拉伯数字、罗马数字或中文数字，无法解析时返回 0
+func parseNumber(s string) int {
+	if n, err := strconv.Atoi(s); err == nil {
+		return n
+	}
+	if n := parseRoman(strings.ToUpper(s)); n > 0 {
+		return n
+	}
+	return parseChineseNumber(s)
+}
+
+var chineseDigits = map[rune]int{
+	'零': 0, '〇': 0, '一': 1, '二': 2, '两': 2, '三': 3, '四': 4,
+	'五': 5, '六': 6, '七': 7, '八': 8, '九': 9,
+}
+
+var chineseUnits = map[rune]int{'十': 10, '百': 100, '千': 1000}
+
+// parseChineseNumber 解析中文数字，如“十二”“一百零五”“一二三”，无法解析时返回 0
+func parseChineseNumber(s string) int {
+	total, section, digit := 0, 0, -1
+	hasUnit := false
+	for _, r := range s {
+		if d, ok := chineseDigits[r]; ok {
+			if digit >= 0 && !hasUnit {
+				// 没有单位的逐位写法，如“一二三”
+				section = section*10 + digit
+			}
+			digit = d
+			continue
+		}
+		if u, ok := chineseUnits[r]; ok {
+			hasUnit = true
+			if digit < 0 {
+				digit = 1 // “十二”省略了“一”
+			}
+			section += digit * u
+			digit = -1
+			continue
+		}
+		if r == '万' {
+			hasUnit = true
+			if digit > 0 {
+				section += digit
+			}
+			total += section * 10000
+			section, digit = 0, -1
+			continue
+		}
+		if r >= '0' && r <= '9' {
+			return 0
+		}
+	}
+	if digit > 0 {
+		if hasUnit {
+			section += digit
+		} else {
+			section = section*10 + digit
+		}
+	}
+	return total + section
+}
+
+var romanValues = map[byte]int{'I': 1, 'V': 5, 'X': 10, 'L': 50, 'C': 100, 'D': 500, 'M': 1000}
+
+// parseRoman 解析罗马数字，无法解析时返回 0
+func parseRoman(s string) int {
+	total := 0
+	for i := 0; i < len(s); i++ {
+		v, ok := romanValues[s[i]]
+		if !ok {
+			return 0
+		}
+		if i+1 < len(s) && romanValues[s[i+1]] > v {
+			total -= v
+		} else {
+			total += v
+		}
+	}
+	return total
+}
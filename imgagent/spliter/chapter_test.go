@@ -0,0 +1,205 @@
+package spliter
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSplitByMarks(t *testing.T) {
+	t.Parallel()
+
+	content := "前言\n卷一\n第一章\n正文一\n第二章\n正文二\n附录"
+	marks := []mark{
+		{Title: "卷一", Offset: strings.Index(content, "卷一")},
+		{Title: "第一章", Offset: strings.Index(content, "第一章")},
+		{Title: "重复", Offset: strings.Index(content, "第一章")},
+		{Title: "第二章", Offset: strings.Index(content, "第二章")},
+		{Title: "附录", Offset: strings.Index(content, "附录")},
+	}
+	chapters := splitByMarks(content, marks)
+	require.Len(t, chapters, 3)
+	assert.Equal(t, "", chapters[0].Title)
+	assert.Equal(t, "前言", chapters[0].Content)
+	// 只有标题的卷名合并到下一章节
+	assert.Equal(t, "第一章", chapters[1].Title)
+	assert.Equal(t, "卷一\n第一章\n正文一", chapters[1].Content)
+	// 末尾只有标题的内容合并到上一章节
+	assert.Equal(t, "第二章", chapters[2].Title)
+	assert.Equal(t, "第二章\n正文二\n附录", chapters[2].Content)
+	// 偏移对应原文中的位置
+	assert.Equal(t, 0, chapters[0].Start)
+	assert.Equal(t, strings.Index(content, "卷一"), chapters[1].Start)
+	assert.Equal(t, strings.Index(content, "第二章"), chapters[1].End)
+	assert.Equal(t, len(content), chapters[2].End)
+	assert.Nil(t, splitByMarks(content, nil))
+}
+
+func TestParseChapterNumber(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]int{
+		"第十二章 风起":       12,
+		"第一百零五回":        105,
+		"第一二三章":         123,
+		"第两千零一十章":       2010,
+		"第一万二千章":        12000,
+		"第 3 章 开始":      3,
+		"第IV节":          4,
+		"Chapter 7":     7,
+		"CHAPTER XII":   12,
+		"Book III: End": 3,
+		"序章":            0,
+		"Chapter one":   0,
+	}
+	for title, want := range cases {
+		assert.Equal(t, want, parseChapterNumber(title), title)
+	}
+}
+
+func TestChapterTitle(t *testing.T) {
+	t.Parallel()
+
+	content := "第一章 风起\n正文\n第二章" + strings.Repeat("长", 60) + "\n正文"
+	idx := chapterNumberRegex.FindAllStringIndex(content, -1)
+	require.Len(t, idx, 2)
+	assert.Equal(t, "第一章 风起", chapterTitle(content, idx[0][0], idx[0][1]))
+	// 标题行过长时只取章节序号
+	assert.Equal(t, "第二章", chapterTitle(content, idx[1][0], idx[1][1]))
+	assert.Equal(t, "一二", truncateRunes("一二三", 2))
+}
+
+func TestSplit_ChapterTitles(t *testing.T) {
+	t.Parallel()
+
+	content := "上回说到\n\n第十二章 风起\n天色暗了。\n\n第十三章 云涌\n雨下了起来。"
+	file := writeTempFile(t, t.TempDir(), "book.txt", content)
+	chunks, err := Split(context.Background(), file, Option{ChunkSize: 5000, ChunkOverlap: 100, Separator: "\n\n"})
+	require.NoError(t, err)
+	require.Len(t, chunks, 2)
+
+	assert.Equal(t, "第十二章 风起", chunks[0].Title)
+	assert.Equal(t, 12, chunks[0].Number)
+	assert.Equal(t, "第十二章 风起\n\n天色暗了。", chunks[0].Content)
+	assert.Equal(t, []string{"第十二章 风起", "天色暗了。"}, chunks[0].Paragraphs)
+	assert.Equal(t, "第十二章 风起\n天色暗了。", chunks[0].Prompt)
+	assert.Equal(t, strings.Index(content, "第十二章"), chunks[0].Start)
+	assert.Equal(t, strings.Index(content, "第十三章"), chunks[0].End)
+
+	assert.Equal(t, "第十三章 云涌", chunks[1].Title)
+	assert.Equal(t, 13, chunks[1].Number)
+	assert.Equal(t, len(content), chunks[1].End)
+}
+
+func TestSplit_LocateChunks(t *testing.T) {
+	t.Parallel()
+
+	content := "第一段内容\n\n第二段内容"
+	file := writeTempFile(t, t.TempDir(), "plain.txt", content)
+	chunks, err := Split(context.Background(), file, Option{ChunkSize: 5000, ChunkOverlap: 100, Separator: "\n\n", MinChapterRunes: -1})
+	require.NoError(t, err)
+	require.Len(t, chunks, 2)
+	for _, c := range chunks {
+		assert.Empty(t, c.Title)
+		assert.Zero(t, c.Number)
+		assert.Equal(t, c.Content, content[c.Start:c.End])
+	}
+
+	located := locateChunks(content, []string{"第二段内容", "不存在"})
+	assert.Equal(t, strings.Index(content, "第二段"), located[0].Start)
+	assert.Equal(t, -1, located[1].Start)
+	assert.Equal(t, -1, located[1].End)
+}
+
+func TestChapterKind(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]string{
+		"序章 雪夜":   KindPrologue,
+		"【楔子】":    KindPrologue,
+		"## 引子":   KindPrologue,
+		"尾声":      KindEpilogue,
+		"后记：写在最后": KindEpilogue,
+		"番外一 重逢":  KindExtra,
+		"番外篇":     KindExtra,
+		"第一章 风起":  KindChapter,
+		"序言中提到的事": KindChapter,
+		"":        KindChapter,
+	}
+	for title, want := range cases {
+		assert.Equal(t, want, chapterKind(title), title)
+	}
+}
+
+func TestSplitByChapters_Formats(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	cases := []struct {
+		name    string
+		content string
+		titles  []string
+	}{
+		{
+			name:    "方括号章节",
+			content: "【第一章】风起\n天色暗了。\n【第二章】云涌\n雨下了起来。",
+			titles:  []string{"【第一章】风起", "【第二章】云涌"},
+		},
+		{
+			name:    "数字编号",
+			content: "001. 开端\n天色暗了。\n1.5 公斤的东西。\n002. 发展\n雨下了起来。\n003.\n雨停了。",
+			titles:  []string{"001. 开端", "002. 发展", "003."},
+		},
+		{
+			name:    "Markdown 标题",
+			content: "# 书名\n\n## 第一章 风起\n天色暗了。\n\n## 第二章 云涌\n雨下了起来。",
+			titles:  []string{"第一章 风起", "第二章 云涌"},
+		},
+		{
+			name:    "特殊章节",
+			content: "楔子\n很久以前。\n第一章 风起\n天色暗了。\n第二章 云涌\n雨下了起来。\n尾声\n雨停了。\n番外 重逢\n又见面了。",
+			titles:  []string{"楔子", "第一章 风起", "第二章 云涌", "尾声", "番外 重逢"},
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			chunks := splitByChapters(ctx, tc.content, nil)
+			var titles []string
+			for _, c := range chunks {
+				titles = append(titles, c.Title)
+			}
+			assert.Equal(t, tc.titles, titles)
+			// 标题前的【、# 等符号归入本章节
+			assert.NotContains(t, chunks[0].Content, "\n【")
+			assert.False(t, strings.HasSuffix(chunks[0].Content, "#"))
+		})
+	}
+}
+
+func TestSplit_CustomPatterns(t *testing.T) {
+	t.Parallel()
+
+	content := "卷首语\n\n=== 一 ===\n天色暗了。\n\n=== 二 ===\n雨下了起来。"
+	file := writeTempFile(t, t.TempDir(), "custom.txt", content)
+
+	// 默认规则识别不到章节时按段落分割，过短的段落合并为一个片段
+	chunks, err := Split(context.Background(), file, Option{ChunkSize: 5000, ChunkOverlap: 100, Separator: "\n\n"})
+	require.NoError(t, err)
+	require.Len(t, chunks, 1)
+	assert.Empty(t, chunks[0].Title)
+
+	chunks, err = Split(context.Background(), file, Option{
+		ChunkSize: 5000, ChunkOverlap: 100, Separator: "\n\n",
+		ChapterPatterns: []string{`(?m)^=== .+ ===$`},
+	})
+	require.NoError(t, err)
+	require.Len(t, chunks, 2)
+	assert.Equal(t, "=== 一 ===", chunks[0].Title)
+	assert.Equal(t, KindChapter, chunks[0].Kind)
+
+	_, err = CompilePatterns([]string{`(?m)^=== .+ ===$`, `第(`})
+	assert.Error(t, err)
+}
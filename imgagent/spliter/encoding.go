@@ -0,0 +1,278 @@
+package spliter
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/simplifiedchinese"
+	"golang.org/x/text/encoding/traditionalchinese"
+	xunicode "golang.org/x/text/encoding/unicode"
+)
+
+const (
+	EncodingUTF8    = "utf-8"
+	EncodingUTF16LE = "utf-16le"
+	EncodingUTF16BE = "utf-16be"
+	EncodingGBK     = "gbk"
+	EncodingGB18030 = "gb18030"
+	EncodingBig5    = "big5"
+
+	// 检测编码时读取的最大字节数
+	detectSampleSize = 1 << 20
+	// 解码后无效字符占比超过该值视为编码错误
+	maxInvalidRatio = 0.001
+)
+
+var (
+	ErrUnknownEncoding = errors.New("unknown text encoding")
+	ErrInvalidText     = errors.New("text does not match encoding")
+)
+
+var (
+	bomUTF8    = []byte{0xef, 0xbb, 0xbf}
+	bomUTF16LE = []byte{0xff, 0xfe}
+	bomUTF16BE = []byte{0xfe, 0xff}
+)
+
+// encodingAliases 支持的编码名称及别名
+var encodingAliases = map[string]string{
+	"utf-8":    EncodingUTF8,
+	"utf8":     EncodingUTF8,
+	"utf-16le": EncodingUTF16LE,
+	"utf16le":  EncodingUTF16LE,
+	"utf-16be": EncodingUTF16BE,
+	"utf16be":  EncodingUTF16BE,
+	"gbk":      EncodingGBK,
+	"gb2312":   EncodingGBK,
+	"cp936":    EncodingGBK,
+	"gb18030":  EncodingGB18030,
+	"big5":     EncodingBig5,
+	"big-5":    EncodingBig5,
+	"cp950":    EncodingBig5,
+}
+
+// commonHanChars 简繁体常用汉字，用于判断 GBK 与 Big5 哪个解码结果更像正常文本
+const commonHanChars = "的一是不了在人有我他这個个们們中来來上大为為和国國地到以说說时時要就出会會可也你对對生能而子那得于着著下自之年过過发發后後作里裡用道行所然家种種事成方多经經么麼去法学學如都同现現当當没沒动動面起看定天分还還进進好小部其些主样樣理心她本前开開但因只从從想实實日军軍者意无無力它与與长長把机機十民第公此已工使情明性知全三又关關点點正业業外将將两兩高间間由问問很最重并物手应應战戰向头頭文体體政美相见見被利什二等产產或新己制身果加西月话話合回特代内信表化老给給世位次度门門任常先海通教儿兒原东東声聲提立及比员員解水名真论論处處走义義各入几幾口认認条條平系气氣题題活更别別打女变變四神总總何电電数數安少报報才结結反受目太量再感建务務做接必场場件计計管期市直德资資命山金指克许許统統区區保至队隊形社便空决決治展马馬科司五基眼书書非则則听聽白却卻界达達光放强強即像难難且权權思王象完设設式色路记記南品住告类類求据據程北边邊死张張该該交规規万萬取拉格望觉覺术術领領共确確传傳师師观觀清今切院让讓识識候带帶导導争爭运運笑飞飛风風步改收根干造言联聯持组組每车車亲親极極林服快办辦议議往元士证證近失转轉夫令准布始怎呢存未远遠叫台单單影具罗羅字爱愛击擊流备備兵连連调調深商算质質团團集百需价價花华華城石级級整府离離况況请請技际際约約示复復病息究线線似官火断斷精满滿支视視消越器容照须須九增研写寫称稱企八功吗嗎包片史委乎查轻輕易早曾除农農找装裝广廣显顯吧阿李标標谈談吃图圖念六引历歷首医醫局突专專费費号號尽盡另周较較注语語仅僅考落青随隨选選列武红紅响響虽雖推势勢参參希古众眾构構房半节節土投某案黑维維革划劃敌敵致陈陳律足态態护護七兴興派孩验驗责責营營星够夠章音跟志底站严嚴巴例防族供效续續施留讲講型料终終答紧緊黄黃绝絕奇察母京段依批群项項故按河米围圍江织織害斗双雙境客纪紀采举舉杀殺攻父苏蘇密低朝友诉訴止细細愿願千值仍男钱錢破网網热熱助倒育属屬坐帝限船脸臉职職速刻乐樂否刚剛威毛状狀率甚独獨球般普怕弹彈校苦创創假久错錯承印晚试試股拿脑腦预預谁誰益阳陽若哪微继繼送急血惊驚伤傷素药藥适適波夜省初喜卫衛源食险險待述陆陸习習置居劳勞财財环環排福纳納欢歡雷警获獲模充负負云雲停木游龙龍树樹疑层層冷冲衝射略范竟句室异異激村哈策演简簡卡罪判担擔州静靜退既衣您宗积積余痛检檢差富灵靈协協角占配征修皮挥揮胜勝降阶階审審沉坚堅善妈媽刘劉读讀啊超免压壓银銀买買皇养養怀懷执執副乱亂抗犯追帮幫宣佛岁歲航优優怪香田铁鐵控左右份穿艺藝背阵陣草脚腳概恶惡块塊顿頓敢守酒岛島托央户戶烈洋哥索胡款靠评評版宝寶座释釋景顾顧弟登货貨互付伯慢欧歐换換闻聞危忙核暗姐介坏壞讨討丽麗良序升监監临臨亮露永呼味野架域沙掉括鱼魚杂雜误誤吉减減编編楚肯测測败敗屋跑梦夢散温溫困剑劍渐漸封救贵貴枪槍缺楼樓县縣尚毫移娘朋画畫班智亦耳恩短掌恐遗遺固席松秘谢謝遇康虑慮幸均钟鐘诗詩藏赶趕剧劇票损損忽巨旧舊端探湖录錄叶葉春乡鄉附吸予礼禮港雨呀板庭妇婦归歸睛饭飯额額含顺順输輸摇搖招婚脱脫补補谓謂督毒油旅材灭滅逐莫笔筆亡鲜鮮词詞圣聖择擇寻尋睡博烟煙诺諾岸唐卖賣载載健堂旁宫宮喝借君禁阴陰园園谋謀避抓荣榮姑孙孫逃牙束跳顶頂玉镇鎮雪午练練迫爷爺肉嘴馆館遍凡洞卷牛宁寧纸紙诸諸私庄莊祖丝絲翻暴森塔默握戏戲隐隱熟骨访訪弱歌店鬼软軟典欲伙遭盘盤爸扩擴盖蓋弄雄稳穩忘刺拥擁徒杨楊齐齊赛賽趣曲刀床迎冰虚虛玩窗醒妻透购購替塞努休虎途侵刑绿綠兄迅套迹跡尤竞競街促延震弃棄甲伟偉麻川缓緩潜潛闪閃灯燈针針抵抱鼓植纯純夏忍页頁折秀混臣雅振染盛怒舞圆圓搞狂措姓残殘秋迷诚誠宽寬猛摆擺梅毁毀伸悲拍，。！？“”：；、"
+
+var commonHanSet = func() map[rune]bool {
+	m := make(map[rune]bool)
+	for _, r := range commonHanChars {
+		m[r] = true
+	}
+	return m
+}()
+
+// NormalizeEncoding 返回编码的规范名称，不支持的编码返回 ErrUnknownEncoding
+func NormalizeEncoding(name string) (string, error) {
+	enc, ok := encodingAliases[strings.ToLower(strings.TrimSpace(name))]
+	if !ok {
+		return "", ErrUnknownEncoding
+	}
+	return enc, nil
+}
+
+// DetectEncoding 检测文本编码：优先识别 BOM，其次为合法 UTF-8，再比较 GB18030 与 Big5 的解码结果
+// 无法识别时返回空字符串
+func DetectEncoding(data []byte) string {
+	switch {
+	case bytes.HasPrefix(data, bomUTF8):
+		return EncodingUTF8
+	case bytes.HasPrefix(data, bomUTF16LE):
+		return EncodingUTF16LE
+	case bytes.HasPrefix(data, bomUTF16BE):
+		return EncodingUTF16BE
+	}
+	if enc := detectUTF16(data); enc != "" {
+		return enc
+	}
+	if validUTF8(data) {
+		return EncodingUTF8
+	}
+
+	// 简体与繁体编码的字节范围大量重叠，按无效字符数和常用字占比选择
+	best, bestInvalid, bestScore := "", 0.0, -1.0
+	for _, enc := range []string{EncodingGB18030, EncodingBig5} {
+		text, _ := decodeBytes(data, enc)
+		invalid, score := scoreText(text)
+		if best == "" || invalid < bestInvalid || (invalid == bestInvalid && score > bestScore) {
+			best, bestInvalid, bestScore = enc, invalid, score
+		}
+	}
+	if bestInvalid > maxInvalidRatio {
+		return ""
+	}
+	if best == EncodingGB18030 && !hasGB18030FourBytes(data) {
+		return EncodingGBK
+	}
+	return best
+}
+
+// DetectFileEncoding 检测文件的文本编码
+func DetectFileEncoding(filename string) (string, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	data, err := io.ReadAll(io.LimitReader(f, detectSampleSize))
+	if err != nil {
+		return "", err
+	}
+	enc := DetectEncoding(data)
+	if enc == "" {
+		return "", ErrUnknownEncoding
+	}
+	return enc, nil
+}
+
+// DecodeText 按指定编码将文本转换为 UTF-8 并去掉 BOM，enc 为空时自动检测
+// 无效字符过多时返回 ErrInvalidText
+func DecodeText(data []byte, enc string) (string, error) {
+	if enc == "" {
+		enc = DetectEncoding(data)
+		if enc == "" {
+			return "", ErrUnknownEncoding
+		}
+	}
+	text, err := decodeBytes(data, enc)
+	if err != nil {
+		return "", err
+	}
+	if invalid, _ := scoreText(text); invalid > maxInvalidRatio {
+		return "", ErrInvalidText
+	}
+	return text, nil
+}
+
+// TranscodeFile 将文本文件按指定编码原地转换为不带 BOM 的 UTF-8
+func TranscodeFile(filename, enc string) error {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return err
+	}
+	text, err := DecodeText(data, enc)
+	if err != nil {
+		return err
+	}
+	if text == string(data) {
+		return nil
+	}
+	tmp := filename + ".tmp"
+	if err := os.WriteFile(tmp, []byte(text), 0666); err != nil {
+		return err
+	}
+	return os.Rename(tmp, filename)
+}
+
+func decodeBytes(data []byte, enc string) (string, error) {
+	var e encoding.Encoding
+	switch enc {
+	case EncodingUTF8:
+		data = bytes.TrimPrefix(data, bomUTF8)
+		return strings.ToValidUTF8(string(data), string(utf8.RuneError)), nil
+	case EncodingUTF16LE:
+		e = xunicode.UTF16(xunicode.LittleEndian, xunicode.UseBOM)
+	case EncodingUTF16BE:
+		e = xunicode.UTF16(xunicode.BigEndian, xunicode.UseBOM)
+	case EncodingGBK, EncodingGB18030:
+		// GB18030 兼容 GBK
+		e = simplifiedchinese.GB18030
+	case EncodingBig5:
+		e = traditionalchinese.Big5
+	default:
+		return "", ErrUnknownEncoding
+	}
+	b, err := e.NewDecoder().Bytes(data)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+// scoreText 返回无效字符占比和常用汉字在汉字中的占比
+func scoreText(text string) (float64, float64) {
+	total, invalid, han, common := 0, 0, 0, 0
+	for _, r := range text {
+		total++
+		switch {
+		case r == utf8.RuneError:
+			invalid++
+		case commonHanSet[r]:
+			han++
+			common++
+		case unicode.Is(unicode.Han, r):
+			han++
+		}
+	}
+	if total == 0 {
+		return 0, 0
+	}
+	score := 0.0
+	if han > 0 {
+		score = float64(common) / float64(han)
+	}
+	return float64(invalid) / float64(total), score
+}
+
+// validUTF8 判断是否为合法 UTF-8，允许采样截断在多字节字符中间
+func validUTF8(data []byte) bool {
+	if utf8.Valid(data) {
+		return true
+	}
+	if len(data) < detectSampleSize {
+		return false
+	}
+	for i := 1; i < utf8.UTFMax && i < len(data); i++ {
+		if utf8.Valid(data[:len(data)-i]) {
+			return true
+		}
+	}
+	return false
+}
+
+// detectUTF16 识别不带 BOM 的 UTF-16：ASCII 字符的零字节集中在奇数或偶数位
+func detectUTF16(data []byte) string {
+	if len(data) < 4 {
+		return ""
+	}
+	n := min(len(data), 4096) &^ 1
+	even, odd := 0, 0
+	for i := 0; i < n; i += 2 {
+		if data[i] == 0 {
+			even++
+		}
+		if data[i+1] == 0 {
+			odd++
+		}
+	}
+	pairs := n / 2
+	switch {
+	case odd*5 > pairs && even*10 < odd:
+		return EncodingUTF16LE
+	case even*5 > pairs && odd*10 < even:
+		return EncodingUTF16BE
+	}
+	return ""
+}
+
+// hasGB18030FourBytes 判断是否包含 GB18030 四字节编码，不包含时即为 GBK
+func hasGB18030FourBytes(data []byte) bool {
+	for i := 0; i < len(data); {
+		if data[i] < 0x80 {
+			i++
+			continue
+		}
+		if i+1 < len(data) && data[i+1] >= 0x30 && data[i+1] <= 0x39 {
+			return true
+		}
+		i += 2
+	}
+	return false
+}
@@ -0,0 +1,87 @@
+package spliter
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/simplifiedchinese"
+	"golang.org/x/text/encoding/traditionalchinese"
+	xunicode "golang.org/x/text/encoding/unicode"
+)
+
+const (
+	simplifiedSample  = "第一章 祥子\n我们所要介绍的是祥子，不是骆驼，因为“骆驼”只是个外号；那么，我们就先说祥子，随手儿把骆驼与祥子那点关系说过去，也就算了。"
+	traditionalSample = "第一章 祥子\n我們所要介紹的是祥子，不是駱駝，因為「駱駝」只是個外號；那麼，我們就先說祥子，隨手兒把駱駝與祥子那點關係說過去，也就算了。"
+)
+
+func encode(t *testing.T, e encoding.Encoding, s string) []byte {
+	t.Helper()
+	b, err := e.NewEncoder().Bytes([]byte(s))
+	require.NoError(t, err)
+	return b
+}
+
+func TestDetectEncoding(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name string
+		data []byte
+		want string
+	}{
+		{"UTF-8", []byte(simplifiedSample), EncodingUTF8},
+		{"UTF-8 BOM", append([]byte("\xef\xbb\xbf"), simplifiedSample...), EncodingUTF8},
+		{"UTF-16LE BOM", encode(t, xunicode.UTF16(xunicode.LittleEndian, xunicode.UseBOM), simplifiedSample), EncodingUTF16LE},
+		{"UTF-16BE BOM", encode(t, xunicode.UTF16(xunicode.BigEndian, xunicode.UseBOM), simplifiedSample), EncodingUTF16BE},
+		{"UTF-16LE 无 BOM", encode(t, xunicode.UTF16(xunicode.LittleEndian, xunicode.IgnoreBOM), "Chapter 1\nHello world"), EncodingUTF16LE},
+		{"GBK", encode(t, simplifiedchinese.GBK, simplifiedSample), EncodingGBK},
+		{"GB18030", encode(t, simplifiedchinese.GB18030, simplifiedSample+"€𠀀"), EncodingGB18030},
+		{"Big5", encode(t, traditionalchinese.Big5, traditionalSample), EncodingBig5},
+		{"二进制", []byte{0x81, 0x7f, 0xff, 0x80, 0xfe, 0x20, 0x81}, ""},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			assert.Equal(t, c.want, DetectEncoding(c.data))
+		})
+	}
+}
+
+func TestDecodeText(t *testing.T) {
+	t.Parallel()
+
+	text, err := DecodeText(encode(t, simplifiedchinese.GBK, simplifiedSample), "")
+	require.NoError(t, err)
+	assert.Equal(t, simplifiedSample, text)
+
+	text, err = DecodeText(encode(t, traditionalchinese.Big5, traditionalSample), EncodingBig5)
+	require.NoError(t, err)
+	assert.Equal(t, traditionalSample, text)
+
+	// 指定的编码与内容不一致
+	_, err = DecodeText(encode(t, simplifiedchinese.GBK, simplifiedSample), EncodingUTF8)
+	assert.ErrorIs(t, err, ErrInvalidText)
+
+	_, err = DecodeText([]byte("abc"), "latin9")
+	assert.ErrorIs(t, err, ErrUnknownEncoding)
+
+	enc, err := NormalizeEncoding(" CP936 ")
+	require.NoError(t, err)
+	assert.Equal(t, EncodingGBK, enc)
+}
+
+func TestSplitGBK(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	content := "第一章 风起\n\n祥子来到了北平。\n\n第二章 买车\n\n他凑足了一百块钱。"
+	file := writeTempFile(t, t.TempDir(), "gbk.txt", string(encode(t, simplifiedchinese.GBK, content)))
+	chunks, err := Split(ctx, file, Option{ChunkSize: 5000, ChunkOverlap: 100, Separator: "\n\n"})
+	require.NoError(t, err)
+	require.Len(t, chunks, 2)
+	assert.True(t, strings.HasPrefix(chunks[0].Content, "第一章 风起"))
+	assert.True(t, strings.HasPrefix(chunks[1].Content, "第二章 买车"))
+}
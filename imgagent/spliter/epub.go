@@ -0,0 +1,277 @@
+package spliter
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"errors"
+	"net/url"
+	"path"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+type epubContainer struct {
+	Rootfiles []struct {
+		FullPath string `xml:"full-path,attr"`
+	} `xml:"rootfiles>rootfile"`
+}
+
+type epubPackage struct {
+	Manifest []struct {
+		ID         string `xml:"id,attr"`
+		Href       string `xml:"href,attr"`
+		MediaType  string `xml:"media-type,attr"`
+		Properties string `xml:"properties,attr"`
+	} `xml:"manifest>item"`
+	Spine struct {
+		Toc      string `xml:"toc,attr"`
+		Itemrefs []struct {
+			IDRef  string `xml:"idref,attr"`
+			Linear string `xml:"linear,attr"`
+		} `xml:"itemref"`
+	} `xml:"spine"`
+}
+
+type ncxNavPoint struct {
+	Label   string `xml:"navLabel>text"`
+	Content struct {
+		Src string `xml:"src,attr"`
+	} `xml:"content"`
+	Children []ncxNavPoint `xml:"navPoint"`
+}
+
+type ncxDocument struct {
+	NavPoints []ncxNavPoint `xml:"navMap>navPoint"`
+}
+
+// tocEntry 目录项，Path 为 zip 内的文件路径，Fragment 为文件内的锚点
+type tocEntry struct {
+	Title    string
+	Path     string
+	Fragment string
+}
+
+// spineText 书脊中单个文档转换后的文本，anchors 记录锚点在文本中的偏移
+type spineText struct {
+	path    string
+	start   int
+	anchors map[string]int
+}
+
+// readEPUB 按 OPF 书脊顺序读取 EPUB 文本，并使用 nav/NCX 目录划分章节
+// 没有可用目录时返回包含全文的单个章节
+func readEPUB(filename string) ([]Chunk, error) {
+	r, err := zip.OpenReader(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	files := make(map[string]*zip.File, len(r.File))
+	for _, f := range r.File {
+		files[f.Name] = f
+	}
+
+	var container epubContainer
+	if err := readZipXML(files, "META-INF/container.xml", &container); err != nil {
+		return nil, err
+	}
+	if len(container.Rootfiles) == 0 {
+		return nil, errors.New("epub rootfile not found")
+	}
+	opfPath := container.Rootfiles[0].FullPath
+	var pkg epubPackage
+	if err := readZipXML(files, opfPath, &pkg); err != nil {
+		return nil, err
+	}
+
+	hrefs := make(map[string]string, len(pkg.Manifest))
+	var navPath, ncxPath string
+	for _, item := range pkg.Manifest {
+		p := resolveHref(opfPath, item.Href)
+		hrefs[item.ID] = p
+		if strings.Contains(" "+item.Properties+" ", " nav ") {
+			navPath = p
+		}
+		if item.ID == pkg.Spine.Toc || (ncxPath == "" && item.MediaType == "application/x-dtbncx+xml") {
+			ncxPath = p
+		}
+	}
+
+	// 按书脊顺序拼接全文
+	var sb strings.Builder
+	var spine []spineText
+	for _, ref := range pkg.Spine.Itemrefs {
+		if ref.Linear == "no" {
+			continue
+		}
+		p, ok := hrefs[ref.IDRef]
+		if !ok {
+			continue
+		}
+		f, ok := files[p]
+		if !ok {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return nil, err
+		}
+		ht := htmlToText(rc)
+		rc.Close()
+		text, anchors := ht.Text, ht.Anchors
+		if text == "" {
+			continue
+		}
+		if sb.Len() > 0 {
+			sb.WriteString("\n\n")
+		}
+		spine = append(spine, spineText{path: p, start: sb.Len(), anchors: anchors})
+		sb.WriteString(text)
+	}
+	content := sb.String()
+	if content == "" {
+		return nil, errors.New("empty content")
+	}
+
+	// EPUB3 优先使用 nav，EPUB2 使用 NCX
+	var toc []tocEntry
+	if navPath != "" {
+		toc = readNavToc(files, navPath)
+	}
+	if len(toc) == 0 && ncxPath != "" {
+		toc = readNCXToc(files, ncxPath)
+	}
+
+	chapters := splitByToc(content, spine, toc)
+	if len(chapters) == 0 {
+		return []Chunk{{Content: content, Start: 0, End: len(content)}}, nil
+	}
+	return chapters, nil
+}
+
+// splitByToc 将目录项映射到全文偏移后切分章节
+func splitByToc(content string, spine []spineText, toc []tocEntry) []Chunk {
+	var marks []mark
+	for _, entry := range toc {
+		for _, st := range spine {
+			if st.path != entry.Path {
+				continue
+			}
+			offset := st.start
+			if entry.Fragment != "" {
+				if o, ok := st.anchors[entry.Fragment]; ok {
+					offset += o
+				}
+			}
+			marks = append(marks, mark{Title: entry.Title, Offset: offset})
+			break
+		}
+	}
+	return splitByMarks(content, marks)
+}
+
+func readNCXToc(files map[string]*zip.File, ncxPath string) []tocEntry {
+	var ncx ncxDocument
+	if err := readZipXML(files, ncxPath, &ncx); err != nil {
+		return nil
+	}
+	var toc []tocEntry
+	var walk func(points []ncxNavPoint)
+	walk = func(points []ncxNavPoint) {
+		for _, p := range points {
+			toc = append(toc, makeTocEntry(ncxPath, p.Label, p.Content.Src))
+			walk(p.Children)
+		}
+	}
+	walk(ncx.NavPoints)
+	return toc
+}
+
+// readNavToc 读取 EPUB3 nav 文档中 epub:type="toc" 的目录链接
+func readNavToc(files map[string]*zip.File, navPath string) []tocEntry {
+	f, ok := files[navPath]
+	if !ok {
+		return nil
+	}
+	rc, err := f.Open()
+	if err != nil {
+		return nil
+	}
+	defer rc.Close()
+
+	z := html.NewTokenizer(rc)
+	var toc []tocEntry
+	navDepth := 0 // 当前所在 toc nav 的嵌套深度，0 表示不在 toc 中
+	var href string
+	var label strings.Builder
+	inLink := false
+	for {
+		tt := z.Next()
+		if tt == html.ErrorToken {
+			break
+		}
+		t := z.Token()
+		switch tt {
+		case html.StartTagToken:
+			switch {
+			case t.Data == "nav" && navDepth == 0:
+				if tokenAttr(t, "type") == "toc" {
+					navDepth = 1
+				}
+			case t.Data == "nav":
+				navDepth++
+			case t.Data == "a" && navDepth > 0:
+				inLink = true
+				href = tokenAttr(t, "href")
+				label.Reset()
+			}
+		case html.EndTagToken:
+			switch {
+			case t.Data == "nav" && navDepth > 0:
+				navDepth--
+			case t.Data == "a" && inLink:
+				inLink = false
+				if href != "" {
+					toc = append(toc, makeTocEntry(navPath, label.String(), href))
+				}
+			}
+		case html.TextToken:
+			if inLink {
+				label.WriteString(t.Data)
+			}
+		}
+	}
+	return toc
+}
+
+func makeTocEntry(base, label, href string) tocEntry {
+	href, fragment, _ := strings.Cut(href, "#")
+	return tocEntry{
+		Title:    strings.Join(strings.Fields(label), " "),
+		Path:     resolveHref(base, href),
+		Fragment: fragment,
+	}
+}
+
+// resolveHref 将相对于 base 文件的链接转换为 zip 内的路径
+func resolveHref(base, href string) string {
+	if unescaped, err := url.PathUnescape(href); err == nil {
+		href = unescaped
+	}
+	return path.Join(path.Dir(base), href)
+}
+
+func readZipXML(files map[string]*zip.File, name string, v any) error {
+	f, ok := files[name]
+	if !ok {
+		return errors.New("epub file not found: " + name)
+	}
+	rc, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	return xml.NewDecoder(rc).Decode(v)
+}
@@ -0,0 +1,171 @@
+package spliter
+
+import (
+	"archive/zip"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type epubEntry struct {
+	name    string
+	content string
+}
+
+func writeTestEPUB(t *testing.T, dir string, entries []epubEntry) string {
+	t.Helper()
+	filename := filepath.Join(dir, "book.epub")
+	f, err := os.Create(filename)
+	require.NoError(t, err)
+	defer f.Close()
+
+	w := zip.NewWriter(f)
+	// mimetype 必须是第一个且不压缩
+	mw, err := w.CreateHeader(&zip.FileHeader{Name: "mimetype", Method: zip.Store})
+	require.NoError(t, err)
+	_, err = mw.Write([]byte("application/epub+zip"))
+	require.NoError(t, err)
+
+	entries = append([]epubEntry{{
+		name: "META-INF/container.xml",
+		content: `<?xml version="1.0"?>
+<container version="1.0" xmlns="urn:oasis:names:tc:opendocument:xmlns:container">
+  <rootfiles><rootfile full-path="OEBPS/content.opf" media-type="application/oebps-package+xml"/></rootfiles>
+</container>`,
+	}}, entries...)
+	for _, e := range entries {
+		ew, err := w.Create(e.name)
+		require.NoError(t, err)
+		_, err = ew.Write([]byte(e.content))
+		require.NoError(t, err)
+	}
+	require.NoError(t, w.Close())
+	return filename
+}
+
+func xhtml(body string) string {
+	return `<?xml version="1.0" encoding="utf-8"?>
+<!DOCTYPE html>
+<html xmlns="http://www.w3.org/1999/xhtml"><head><title>忽略</title><style>p{}</style></head>
+<body>` + body + `</body></html>`
+}
+
+const testOPF = `<?xml version="1.0" encoding="utf-8"?>
+<package xmlns="http://www.idpf.org/2007/opf" version="3.0">
+  <manifest>
+    <item id="nav" href="nav.xhtml" media-type="application/xhtml+xml" properties="nav"/>
+    <item id="ncx" href="toc.ncx" media-type="application/x-dtbncx+xml"/>
+    <item id="cover" href="Text/cover.xhtml" media-type="application/xhtml+xml"/>
+    <item id="c1" href="Text/chapter%201.xhtml" media-type="application/xhtml+xml"/>
+    <item id="c2" href="Text/chapter2.xhtml" media-type="application/xhtml+xml"/>
+  </manifest>
+  <spine toc="ncx">
+    <itemref idref="cover"/>
+    <itemref idref="nav" linear="no"/>
+    <itemref idref="c2"/>
+    <itemref idref="c1"/>
+  </spine>
+</package>`
+
+var testChapters = []epubEntry{
+	{name: "OEBPS/Text/cover.xhtml", content: xhtml(`<p>骆驼祥子</p><p>老舍 著</p>`)},
+	{name: "OEBPS/Text/chapter2.xhtml", content: xhtml(`<h1>第一章 祥子</h1><p>我们所要介绍的是祥子，</p><p>不是骆驼。</p>`)},
+	{name: "OEBPS/Text/chapter 1.xhtml", content: xhtml(`<h1 id="c2">第二章 买车</h1><p>恰好用了三年，&nbsp;他凑足了一百块钱！</p>` +
+		`<h1 id="c3">第三章 逃走</h1><p>祥子已经跑出二里多地去。<br/>可还不敢放慢。</p>`)},
+}
+
+func TestReadEPUB_Nav(t *testing.T) {
+	t.Parallel()
+
+	nav := xhtml(`<nav epub:type="landmarks"><ol><li><a href="Text/cover.xhtml">封面</a></li></ol></nav>
+<nav epub:type="toc"><ol>
+  <li><a href="Text/chapter2.xhtml">第一章  祥子</a></li>
+  <li><a href="Text/chapter%201.xhtml#c2">第二章 买车</a>
+    <ol><li><a href="Text/chapter%201.xhtml#c3">第三章 逃走</a></li></ol>
+  </li>
+</ol></nav>`)
+	entries := append([]epubEntry{
+		{name: "OEBPS/content.opf", content: testOPF},
+		{name: "OEBPS/nav.xhtml", content: nav},
+	}, testChapters...)
+	filename := writeTestEPUB(t, t.TempDir(), entries)
+
+	chapters, err := readEPUB(filename)
+	require.NoError(t, err)
+	require.Len(t, chapters, 4)
+
+	// 目录之前的内容保留为无标题章节
+	assert.Equal(t, "", chapters[0].Title)
+	assert.Equal(t, "骆驼祥子\n老舍 著", chapters[0].Content)
+
+	// 按书脊顺序，标题取自目录
+	assert.Equal(t, "第一章 祥子", chapters[1].Title)
+	assert.Equal(t, "第一章 祥子\n我们所要介绍的是祥子，\n不是骆驼。", chapters[1].Content)
+	assert.Equal(t, "第二章 买车", chapters[2].Title)
+	assert.Equal(t, "第二章 买车\n恰好用了三年， 他凑足了一百块钱！", chapters[2].Content)
+	assert.Equal(t, "第三章 逃走", chapters[3].Title)
+	assert.Equal(t, "第三章 逃走\n祥子已经跑出二里多地去。\n可还不敢放慢。", chapters[3].Content)
+	for _, c := range chapters {
+		assert.NotContains(t, c.Content, "忽略")
+	}
+}
+
+func TestReadEPUB_NCX(t *testing.T) {
+	t.Parallel()
+
+	opf := strings.Replace(testOPF, `properties="nav"`, ``, 1)
+	ncx := `<?xml version="1.0" encoding="UTF-8"?>
+<ncx xmlns="http://www.daisy.org/z3986/2005/ncx/" version="2005-1">
+  <navMap>
+    <navPoint id="p1"><navLabel><text>卷一</text></navLabel><content src="Text/chapter2.xhtml"/>
+      <navPoint id="p2"><navLabel><text>祥子</text></navLabel><content src="Text/chapter2.xhtml"/></navPoint>
+      <navPoint id="p3"><navLabel><text>买车</text></navLabel><content src="Text/chapter%201.xhtml"/></navPoint>
+    </navPoint>
+  </navMap>
+</ncx>`
+	entries := append([]epubEntry{
+		{name: "OEBPS/content.opf", content: opf},
+		{name: "OEBPS/toc.ncx", content: ncx},
+	}, testChapters...)
+	filename := writeTestEPUB(t, t.TempDir(), entries)
+
+	chapters, err := readEPUB(filename)
+	require.NoError(t, err)
+	require.Len(t, chapters, 3)
+
+	// 同一位置的目录项只保留第一个
+	assert.Equal(t, "卷一", chapters[1].Title)
+	assert.True(t, strings.HasPrefix(chapters[1].Content, "卷一\n第一章 祥子"))
+	// 标题不在正文开头时补充标题
+	assert.Equal(t, "买车", chapters[2].Title)
+	assert.True(t, strings.HasPrefix(chapters[2].Content, "买车\n第二章 买车"))
+	assert.Contains(t, chapters[2].Content, "第三章 逃走")
+}
+
+func TestSplitEPUB(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	// 没有目录时按普通文本分割
+	opf := strings.Replace(strings.Replace(testOPF, `properties="nav"`, ``, 1), `toc="ncx"`, ``, 1)
+	opf = strings.Replace(opf, `<item id="ncx" href="toc.ncx" media-type="application/x-dtbncx+xml"/>`, ``, 1)
+	entries := append([]epubEntry{{name: "OEBPS/content.opf", content: opf}}, testChapters...)
+	filename := writeTestEPUB(t, t.TempDir(), entries)
+
+	chunks, err := Split(ctx, filename, Option{ChunkSize: 5000, ChunkOverlap: 100, Separator: "\n\n"})
+	require.NoError(t, err)
+	require.Len(t, chunks, 3)
+	assert.True(t, strings.HasPrefix(chunks[0].Content, "第一章 祥子"))
+	assert.True(t, strings.HasPrefix(chunks[1].Content, "第二章 买车"))
+	assert.True(t, strings.HasPrefix(chunks[2].Content, "第三章 逃走"))
+
+	// 非 EPUB 文件
+	bad := writeTempFile(t, t.TempDir(), "bad.epub", "not a zip")
+	_, err = Split(ctx, bad, Option{ChunkSize: 5000})
+	require.Error(t, err)
+}
@@ -0,0 +1,120 @@
+package spliter
+
+import (
+	"encoding/xml"
+	"io"
+	"os"
+	"strings"
+
+	"golang.org/x/net/html/charset"
+)
+
+// fb2Paragraphs FB2 中按段落输出的元素
+var fb2Paragraphs = map[string]bool{
+	"p": true, "v": true, "subtitle": true, "text-author": true, "empty-line": true,
+}
+
+// readFB2 读取 FictionBook 2 文件，按 section 结构划分章节，section 标题作为章节标题
+// 跳过 description、binary 以及注释等带 name 属性的 body
+func readFB2(filename string) ([]Chunk, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	d := xml.NewDecoder(f)
+	d.Strict = false
+	d.CharsetReader = charset.NewReaderLabel
+
+	var b textBuilder
+	var marks []mark
+	var sections []int // 当前嵌套的 section 对应的 marks 下标
+	skip := 0
+	inBody := false
+	titleDepth := 0
+	titleStart := 0
+	for {
+		tok, err := d.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			name := t.Name.Local
+			switch {
+			case skip > 0:
+				skip++
+			case name == "description" || name == "binary":
+				skip = 1
+			case name == "body":
+				if xmlAttr(t, "name") != "" {
+					skip = 1
+					continue
+				}
+				inBody = true
+			case !inBody:
+			case name == "section":
+				b.Newline()
+				sections = append(sections, len(marks))
+				marks = append(marks, mark{Offset: b.Len()})
+			case name == "title":
+				b.Newline()
+				if titleDepth == 0 {
+					titleStart = b.Len()
+				}
+				titleDepth++
+			case fb2Paragraphs[name]:
+				fb2Break(&b, titleDepth)
+			}
+		case xml.EndElement:
+			name := t.Name.Local
+			switch {
+			case skip > 0:
+				skip--
+			case name == "body":
+				inBody = false
+			case !inBody:
+			case name == "section":
+				if len(sections) > 0 {
+					sections = sections[:len(sections)-1]
+				}
+			case name == "title":
+				titleDepth--
+				if titleDepth == 0 && len(sections) > 0 {
+					title := strings.TrimSpace(string(b.buf[titleStart:]))
+					marks[sections[len(sections)-1]].Title = title
+				}
+				b.Newline()
+			case fb2Paragraphs[name]:
+				fb2Break(&b, titleDepth)
+			}
+		case xml.CharData:
+			if skip == 0 && inBody {
+				b.WriteText(string(t))
+			}
+		}
+	}
+
+	content := b.String()
+	if content == "" {
+		return nil, nil
+	}
+	chapters := splitByMarks(content, marks)
+	if len(chapters) < 2 {
+		return []Chunk{{Content: content, Start: 0, End: len(content)}}, nil
+	}
+	return chapters, nil
+}
+
+// fb2Break 段落之间换行，标题中的多个段落以空格连接为一行
+func fb2Break(b *textBuilder, titleDepth int) {
+	if titleDepth > 0 {
+		b.WriteText(" ")
+		return
+	}
+	b.Newline()
+}
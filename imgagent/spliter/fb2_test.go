@@ -0,0 +1,64 @@
+package spliter
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/text/encoding/charmap"
+)
+
+func TestReadFB2(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+
+	book := `<?xml version="1.0" encoding="utf-8"?>
+<FictionBook xmlns="http://www.gribuser.ru/xml/fictionbook/2.0" xmlns:l="http://www.w3.org/1999/xlink">
+  <description><title-info><book-title>忽略</book-title></title-info></description>
+  <body>
+    <title><p>骆驼祥子</p></title>
+    <section>
+      <title><p>第一部</p></title>
+      <section>
+        <title><p>第一章</p><p>祥子</p></title>
+        <p>我们所要介绍的是祥子，</p>
+        <empty-line/>
+        <p>不是骆驼。<a l:href="#n1">[1]</a></p>
+      </section>
+      <section>
+        <title><p>第二章</p></title>
+        <poem><stanza><v>第一行</v><v>第二行</v></stanza></poem>
+      </section>
+    </section>
+  </body>
+  <body name="notes"><section id="n1"><p>注释</p></section></body>
+  <binary id="cover.jpg" content-type="image/jpeg">AAAA</binary>
+</FictionBook>`
+	chapters, err := readFB2(writeTempFile(t, dir, "book.fb2", book))
+	require.NoError(t, err)
+	require.Len(t, chapters, 3)
+	assert.Equal(t, "", chapters[0].Title)
+	assert.Equal(t, "骆驼祥子", chapters[0].Content)
+	assert.Equal(t, "第一章 祥子", chapters[1].Title)
+	assert.Equal(t, "第一部\n第一章 祥子\n我们所要介绍的是祥子，\n不是骆驼。[1]", chapters[1].Content)
+	assert.Equal(t, "第二章", chapters[2].Title)
+	assert.Equal(t, "第二章\n第一行\n第二行", chapters[2].Content)
+}
+
+func TestReadFB2_Charset(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+
+	book := `<?xml version="1.0" encoding="windows-1251"?>
+<FictionBook><body><section><title><p>Глава 1</p></title><p>Начало</p></section>` +
+		`<section><title><p>Глава 2</p></title><p>Конец</p></section></body></FictionBook>`
+	encoded, err := charmap.Windows1251.NewEncoder().String(book)
+	require.NoError(t, err)
+	chapters, err := readFB2(writeTempFile(t, dir, "ru.fb2", encoded))
+	require.NoError(t, err)
+	require.Len(t, chapters, 2)
+	assert.Equal(t, "Глава 1", chapters[0].Title)
+	assert.Equal(t, "Глава 1\nНачало", chapters[0].Content)
+	assert.Equal(t, "Глава 2", chapters[1].Title)
+	assert.Equal(t, "Глава 2\nКонец", chapters[1].Content)
+}
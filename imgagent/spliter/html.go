@@ -0,0 +1,217 @@
+package spliter
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/charset"
+)
+
+// htmlText HTML 转换后的纯文本及结构信息，偏移均为在 Text 中的字节位置
+type htmlText struct {
+	Text     string
+	Anchors  map[string]int // 带 id 的元素
+	Headings []heading      // h1-h6 标题
+	Sections []int          // 最外层 section
+}
+
+type heading struct {
+	Level  int
+	Title  string
+	Offset int
+}
+
+// htmlBlockElements 块级元素，前后换行以保留段落结构
+var htmlBlockElements = map[string]bool{
+	"p": true, "div": true, "br": true, "li": true, "tr": true,
+	"h1": true, "h2": true, "h3": true, "h4": true, "h5": true, "h6": true,
+	"section": true, "article": true, "blockquote": true, "pre": true, "hr": true,
+}
+
+// htmlSkipElements 不包含正文的元素
+var htmlSkipElements = map[string]bool{
+	"head": true, "script": true, "style": true, "title": true, "noscript": true, "template": true,
+}
+
+// textBuilder 拼接纯文本，合并连续空白，行首行尾不保留空白
+type textBuilder struct {
+	buf []byte
+}
+
+func (b *textBuilder) Len() int {
+	return len(b.buf)
+}
+
+func (b *textBuilder) WriteText(s string) {
+	for _, c := range s {
+		if unicode.IsSpace(c) {
+			if len(b.buf) > 0 && b.buf[len(b.buf)-1] != ' ' && b.buf[len(b.buf)-1] != '\n' {
+				b.buf = append(b.buf, ' ')
+			}
+			continue
+		}
+		b.buf = utf8.AppendRune(b.buf, c)
+	}
+}
+
+// Newline 结束当前段落，连续调用只保留一个换行
+func (b *textBuilder) Newline() {
+	b.buf = bytes.TrimRight(b.buf, " ")
+	if len(b.buf) > 0 && b.buf[len(b.buf)-1] != '\n' {
+		b.buf = append(b.buf, '\n')
+	}
+}
+
+func (b *textBuilder) String() string {
+	return string(bytes.TrimRight(b.buf, " \n"))
+}
+
+// htmlToText 将 HTML/XHTML 转换为纯文本，块级元素之间以换行分隔
+func htmlToText(r io.Reader) htmlText {
+	z := html.NewTokenizer(r)
+	var b textBuilder
+	result := htmlText{Anchors: make(map[string]int)}
+	skip := 0
+	sectionDepth := 0
+	headingLevel := 0
+	headingStart := 0
+	for {
+		tt := z.Next()
+		if tt == html.ErrorToken {
+			break
+		}
+		t := z.Token()
+		name := t.Data
+		switch tt {
+		case html.StartTagToken, html.SelfClosingTagToken:
+			if htmlSkipElements[name] {
+				if tt == html.StartTagToken {
+					skip++
+				}
+				continue
+			}
+			if htmlBlockElements[name] {
+				b.Newline()
+			}
+			if id := tokenAttr(t, "id"); id != "" {
+				if _, ok := result.Anchors[id]; !ok {
+					result.Anchors[id] = b.Len()
+				}
+			}
+			if name == "section" && tt == html.StartTagToken {
+				if sectionDepth == 0 {
+					result.Sections = append(result.Sections, b.Len())
+				}
+				sectionDepth++
+			}
+			if level := headingTagLevel(name); level > 0 && tt == html.StartTagToken {
+				headingLevel = level
+				headingStart = b.Len()
+			}
+		case html.EndTagToken:
+			if htmlSkipElements[name] {
+				if skip > 0 {
+					skip--
+				}
+				continue
+			}
+			if name == "section" && sectionDepth > 0 {
+				sectionDepth--
+			}
+			if level := headingTagLevel(name); level > 0 && level == headingLevel {
+				title := strings.TrimSpace(string(b.buf[headingStart:]))
+				if title != "" {
+					result.Headings = append(result.Headings, heading{Level: level, Title: title, Offset: headingStart})
+				}
+				headingLevel = 0
+			}
+			if htmlBlockElements[name] {
+				b.Newline()
+			}
+		case html.TextToken:
+			if skip > 0 {
+				continue
+			}
+			b.WriteText(t.Data)
+		}
+	}
+	result.Text = b.String()
+	return result
+}
+
+func headingTagLevel(name string) int {
+	if len(name) == 2 && name[0] == 'h' && name[1] >= '1' && name[1] <= '6' {
+		return int(name[1] - '0')
+	}
+	return 0
+}
+
+// tokenAttr 返回属性值，带命名空间前缀的属性（如 epub:type）按本地名匹配
+func tokenAttr(t html.Token, key string) string {
+	for _, a := range t.Attr {
+		if a.Key == key || strings.HasSuffix(a.Key, ":"+key) {
+			return a.Val
+		}
+	}
+	return ""
+}
+
+// readHTML 读取 HTML 文件，按页面编码声明转换为 UTF-8，并按标题或 section 划分章节
+// 无法识别结构时返回包含全文的单个章节
+func readHTML(filename string) ([]Chunk, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	r, err := charset.NewReader(f, "text/html")
+	if err != nil {
+		return nil, err
+	}
+	ht := htmlToText(r)
+	if ht.Text == "" {
+		return nil, nil
+	}
+
+	chapters := splitByMarks(ht.Text, htmlChapterMarks(ht))
+	if len(chapters) < 2 {
+		return []Chunk{{Content: ht.Text, Start: 0, End: len(ht.Text)}}, nil
+	}
+	return chapters, nil
+}
+
+// htmlChapterMarks 选择出现至少两次的最高级标题作为章节标题，例如 h1 为书名、h2 为章节名时按 h2 划分
+// 没有合适的标题时按最外层 section 划分
+func htmlChapterMarks(ht htmlText) []mark {
+	counts := make(map[int]int)
+	for _, h := range ht.Headings {
+		counts[h.Level]++
+	}
+	for level := 1; level <= 6; level++ {
+		if counts[level] < 2 {
+			continue
+		}
+		var marks []mark
+		for _, h := range ht.Headings {
+			if h.Level == level {
+				marks = append(marks, mark{Title: h.Title, Offset: h.Offset})
+			}
+		}
+		return marks
+	}
+
+	if len(ht.Sections) >= 2 {
+		marks := make([]mark, 0, len(ht.Sections))
+		for _, offset := range ht.Sections {
+			marks = append(marks, mark{Offset: offset})
+		}
+		return marks
+	}
+	return nil
+}
@@ -0,0 +1,79 @@
+package spliter
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHTMLToText(t *testing.T) {
+	t.Parallel()
+
+	ht := htmlToText(strings.NewReader(`<html><head><title>标题</title><script>var a = "<p>";</script></head>
+<body><div id="top">  第一段
+  继续&amp;结束 </div><p>第二段<br>换行</p><ul><li>列表</li></ul></body></html>`))
+	assert.Equal(t, "第一段 继续&结束\n第二段\n换行\n列表", ht.Text)
+	assert.Equal(t, 0, ht.Anchors["top"])
+}
+
+func TestReadHTML_Headings(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+
+	// h1 只有一个（书名），按 h2 划分章节
+	page := `<html><head><meta charset="utf-8"></head><body>
+<h1>骆驼祥子</h1><p>老舍 著</p>
+<h2>第一章 祥子</h2><p>我们所要介绍的是祥子。</p>
+<h2>第二章 买车</h2><p>恰好用了三年。</p><h3>小节</h3><p>他凑足了一百块钱。</p>
+</body></html>`
+	chapters, err := readHTML(writeTempFile(t, dir, "book.html", page))
+	require.NoError(t, err)
+	require.Len(t, chapters, 3)
+	assert.Equal(t, "骆驼祥子\n老舍 著", chapters[0].Content)
+	assert.Equal(t, "第一章 祥子", chapters[1].Title)
+	assert.Equal(t, "第一章 祥子\n我们所要介绍的是祥子。", chapters[1].Content)
+	assert.Equal(t, "第二章 买车", chapters[2].Title)
+	assert.Equal(t, "第二章 买车\n恰好用了三年。\n小节\n他凑足了一百块钱。", chapters[2].Content)
+}
+
+func TestReadHTML_SectionsAndCharset(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+
+	// GBK 编码的“第一节”“第二节”，按 section 划分
+	gbk := "<html><head><meta charset=\"gbk\"></head><body>" +
+		"<section><p>\xb5\xda\xd2\xbb\xbd\xda</p><p>A</p></section>" +
+		"<section><p>\xb5\xda\xb6\xfe\xbd\xda</p><p>B</p></section></body></html>"
+	chapters, err := readHTML(writeTempFile(t, dir, "gbk.htm", gbk))
+	require.NoError(t, err)
+	require.Len(t, chapters, 2)
+	assert.Equal(t, "第一节\nA", chapters[0].Content)
+	assert.Equal(t, "第二节\nB", chapters[1].Content)
+
+	// 没有结构时按普通文本分割
+	chunks, err := Split(context.Background(), writeTempFile(t, dir, "plain.html", "<p>第一章 开始</p><p>内容</p><p>第二章 结束</p><p>内容</p>"),
+		Option{ChunkSize: 5000, ChunkOverlap: 100, Separator: "\n\n"})
+	require.NoError(t, err)
+	require.Len(t, chunks, 2)
+}
+
+func TestReadText(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+
+	// 按结构划分章节的格式按章节顺序拼接
+	page := `<html><body><h2>第一章 祥子</h2><p>我们所要介绍的是祥子。</p><h2>第二章 买车</h2><p>恰好用了三年。</p></body></html>`
+	text, err := ReadText(context.Background(), writeTempFile(t, dir, "book.html", page), Option{})
+	require.NoError(t, err)
+	assert.Equal(t, "第一章 祥子\n我们所要介绍的是祥子。\n\n第二章 买车\n恰好用了三年。", text)
+
+	text, err = ReadText(context.Background(), writeTempFile(t, dir, "book.rtf", `{\rtf1\ansi\ansicpg936 \'b5\'da\'d2\'bb\'d5\'c2\par}`), Option{})
+	require.NoError(t, err)
+	assert.Equal(t, "第一章", text)
+
+	_, err = ReadText(context.Background(), writeTempFile(t, dir, "empty.html", "<html></html>"), Option{})
+	assert.Error(t, err)
+}
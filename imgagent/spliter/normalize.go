@@ -0,0 +1,191 @@
+package spliter
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	// DefaultMaxChapterRunes 单个章节的最大字符数，与 api.UpdateChapterArgs.Content 的长度限制一致
+	DefaultMaxChapterRunes = 6000
+	// DefaultMinChapterRunes 无标题片段小于该字符数时合并到相邻章节
+	DefaultMinChapterRunes = 200
+)
+
+// sentenceEnds 段落过长时优先在句末切分
+const sentenceEnds = "。！？；…!?;"
+
+// normalizeChunks 规范章节长度：无标题的过短片段（作者的话、段落碎片等）合并到相邻章节，
+// 超长章节在段落边界拆分为多个分段，分段沿用原标题并以 Part 编号
+// 长度按展示形式（DisplayText，段落之间以空行分隔）计算，保证保存后的正文不超过 maxRunes
+func normalizeChunks(content string, chunks []Chunk, maxRunes, minRunes int) []Chunk {
+	if maxRunes <= 0 {
+		maxRunes = DefaultMaxChapterRunes
+	}
+	if minRunes == 0 {
+		minRunes = DefaultMinChapterRunes
+	}
+
+	merged := mergeFragments(chunks, maxRunes, minRunes)
+	result := make([]Chunk, 0, len(merged))
+	for _, chunk := range merged {
+		result = append(result, splitOversized(content, chunk, maxRunes)...)
+	}
+	return result
+}
+
+// mergeFragments 合并无标题的过短片段，优先并入前一章节，放不下时并入后一章节，不跨卷合并
+func mergeFragments(chunks []Chunk, maxRunes, minRunes int) []Chunk {
+	isFragment := func(c Chunk) bool {
+		return c.Title == "" && displayRunes(c.Content) < minRunes
+	}
+	canMerge := func(a, b Chunk) bool {
+		return a.Volume == b.Volume && displayRunes(a.Content)+displayRunes(b.Content)+len(paragraphSeparator) <= maxRunes
+	}
+
+	result := make([]Chunk, 0, len(chunks))
+	for _, chunk := range chunks {
+		if n := len(result); n > 0 {
+			last := &result[n-1]
+			if isFragment(chunk) && canMerge(*last, chunk) {
+				*last = joinChunks(*last, chunk)
+				continue
+			}
+			if isFragment(*last) && canMerge(*last, chunk) {
+				*last = joinChunks(*last, chunk)
+				continue
+			}
+		}
+		result = append(result, chunk)
+	}
+	return result
+}
+
+// joinChunks 拼接相邻的两个章节，保留其中的标题
+func joinChunks(a, b Chunk) Chunk {
+	joined := a
+	if joined.Title == "" {
+		joined.Title = b.Title
+		joined.Volume, joined.VolumeNumber = b.Volume, b.VolumeNumber
+	}
+	joined.Content = strings.TrimSpace(a.Content) + "\n\n" + strings.TrimSpace(b.Content)
+	if a.Start >= 0 && b.End >= 0 {
+		joined.End = b.End
+	} else {
+		joined.Start, joined.End = -1, -1
+	}
+	return joined
+}
+
+// splitOversized 将超过 maxRunes 的章节在段落边界拆分，各分段长度尽量均匀
+// 单个段落超长时在句末切分，仍然超长时按字符切分
+func splitOversized(content string, chunk Chunk, maxRunes int) []Chunk {
+	text := chunk.Content
+	total := displayRunes(text)
+	if total <= maxRunes {
+		return []Chunk{chunk}
+	}
+
+	// 正文在原文中的位置，无法定位时分段偏移为 -1
+	base := -1
+	if chunk.Start >= 0 && chunk.End <= len(content) && chunk.Start <= chunk.End {
+		if i := strings.Index(content[chunk.Start:chunk.End], text); i >= 0 {
+			base = chunk.Start + i
+		}
+	}
+
+	parts := (total + maxRunes - 1) / maxRunes
+	target := (total + parts - 1) / parts
+
+	var ranges [][2]int
+	start, runes := 0, 0
+	for _, seg := range paragraphSegments(text, maxRunes) {
+		segRunes := utf8.RuneCountInString(strings.TrimSpace(text[seg[0]:seg[1]]))
+		if segRunes == 0 {
+			continue
+		}
+		// 同一分段内的段落之间需要加上分隔符的长度
+		if runes > 0 && (runes+len(paragraphSeparator)+segRunes > maxRunes || runes >= target) {
+			ranges = append(ranges, [2]int{start, seg[0]})
+			start, runes = seg[0], 0
+		}
+		if runes > 0 {
+			runes += len(paragraphSeparator)
+		}
+		runes += segRunes
+	}
+	ranges = append(ranges, [2]int{start, len(text)})
+
+	result := make([]Chunk, 0, len(ranges))
+	for _, r := range ranges {
+		part := text[r[0]:r[1]]
+		trimmed := strings.TrimSpace(part)
+		if trimmed == "" {
+			continue
+		}
+		c := chunk
+		c.Content = trimmed
+		c.Start, c.End = -1, -1
+		if base >= 0 {
+			c.Start = base + r[0] + len(part) - len(strings.TrimLeftFunc(part, unicode.IsSpace))
+			c.End = c.Start + len(trimmed)
+		}
+		result = append(result, c)
+	}
+	if len(result) > 1 {
+		for i := range result {
+			result[i].Part = i + 1
+		}
+	}
+	return result
+}
+
+// displayRunes 返回文本按 DisplayText 展示时的字符数
+func displayRunes(text string) int {
+	return utf8.RuneCountInString(DisplayText(Paragraphs(text)))
+}
+
+// paragraphSegments 按行切分文本（与 Paragraphs 一致，\r 也作为换行），返回各段的字节区间（含换行符），超过 maxRunes 的行继续在句末切分
+func paragraphSegments(text string, maxRunes int) [][2]int {
+	var segs [][2]int
+	for start := 0; start < len(text); {
+		end := len(text)
+		if i := strings.IndexAny(text[start:], "\r\n"); i >= 0 {
+			end = start + i + 1
+		}
+		if utf8.RuneCountInString(text[start:end]) > maxRunes {
+			segs = append(segs, sentenceSegments(text, start, end, maxRunes)...)
+		} else {
+			segs = append(segs, [2]int{start, end})
+		}
+		start = end
+	}
+	return segs
+}
+
+// sentenceSegments 将 text[start:end] 切分为不超过 maxRunes 的片段，优先在句末切分
+func sentenceSegments(text string, start, end, maxRunes int) [][2]int {
+	var segs [][2]int
+	segStart, lastEnd, runes := start, -1, 0
+	for i, r := range text[start:end] {
+		pos := start + i
+		if runes == maxRunes {
+			cut := pos
+			if lastEnd > segStart {
+				cut = lastEnd
+			}
+			segs = append(segs, [2]int{segStart, cut})
+			runes = utf8.RuneCountInString(text[cut:pos])
+			segStart, lastEnd = cut, -1
+		}
+		runes++
+		if strings.ContainsRune(sentenceEnds, r) {
+			lastEnd = pos + utf8.RuneLen(r)
+		}
+	}
+	if segStart < end {
+		segs = append(segs, [2]int{segStart, end})
+	}
+	return segs
+}
@@ -0,0 +1,131 @@
+package spliter
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNormalizeChunks_SplitOversized(t *testing.T) {
+	t.Parallel()
+
+	para := strings.Repeat("字", 30)
+	body := strings.Join([]string{para, para, para, para, para}, "\n")
+	content := "前文\n" + body + "\n后文"
+	start := strings.Index(content, body)
+	chunks := []Chunk{{Title: "第一章 风起", Content: body, Start: start, End: start + len(body), Volume: "第一卷", VolumeNumber: 1}}
+
+	result := normalizeChunks(content, chunks, 70, -1)
+	require.Len(t, result, 3)
+	for i, c := range result {
+		assert.Equal(t, "第一章 风起", c.Title)
+		assert.Equal(t, i+1, c.Part)
+		assert.Equal(t, "第一卷", c.Volume)
+		assert.LessOrEqual(t, utf8.RuneCountInString(c.Content), 70)
+		// 只在段落边界拆分
+		assert.True(t, strings.HasPrefix(c.Content, para))
+		assert.Equal(t, c.Content, content[c.Start:c.End])
+	}
+
+	// 未超长时不拆分
+	result = normalizeChunks(content, chunks, 1000, -1)
+	require.Len(t, result, 1)
+	assert.Zero(t, result[0].Part)
+}
+
+func TestNormalizeChunks_LongParagraph(t *testing.T) {
+	t.Parallel()
+
+	sentence := strings.Repeat("字", 9) + "。"
+	body := strings.Repeat(sentence, 5) + strings.Repeat("长", 25)
+	result := normalizeChunks("", []Chunk{{Content: body, Start: -1, End: -1}}, 25, -1)
+	require.Greater(t, len(result), 1)
+	var joined string
+	for _, c := range result {
+		assert.LessOrEqual(t, utf8.RuneCountInString(c.Content), 25)
+		assert.Equal(t, -1, c.Start)
+		joined += c.Content
+	}
+	assert.Equal(t, body, joined)
+	// 优先在句末切分
+	assert.True(t, strings.HasSuffix(result[0].Content, "。"))
+}
+
+func TestNormalizeChunks_MergeFragments(t *testing.T) {
+	t.Parallel()
+
+	long := strings.Repeat("文", 50)
+	chunks := []Chunk{
+		{Content: "作者的话", Start: 0, End: 12},
+		{Title: "第一章", Content: "第一章\n" + long, Start: 12, End: 100},
+		{Content: "求月票", Start: 100, End: 109},
+		{Title: "第二章", Content: "第二章\n短", Start: 109, End: 120},
+		{Content: "新卷前言", Start: 120, End: 132, Volume: "第二卷"},
+		{Title: "第三章", Content: "第三章\n" + long, Start: 132, End: 200, Volume: "第二卷"},
+	}
+
+	result := normalizeChunks("", chunks, 1000, 20)
+	require.Len(t, result, 3)
+	// 开头的片段并入下一章节
+	assert.Equal(t, "第一章", result[0].Title)
+	assert.True(t, strings.HasPrefix(result[0].Content, "作者的话\n\n第一章"))
+	assert.True(t, strings.HasSuffix(result[0].Content, "求月票"))
+	assert.Equal(t, 0, result[0].Start)
+	assert.Equal(t, 109, result[0].End)
+	// 有标题的短章节保留
+	assert.Equal(t, "第二章", result[1].Title)
+	// 不跨卷合并
+	assert.Equal(t, "第三章", result[2].Title)
+	assert.Equal(t, "第二卷", result[2].Volume)
+	assert.True(t, strings.HasPrefix(result[2].Content, "新卷前言"))
+
+	// 小于 0 时不合并
+	assert.Len(t, normalizeChunks("", chunks, 1000, -1), len(chunks))
+	// 合并后超长时并入后一章节
+	result = normalizeChunks("", chunks, 58, 20)
+	require.Len(t, result, 5)
+	assert.Equal(t, "第二章", result[2].Title)
+	assert.True(t, strings.HasPrefix(result[2].Content, "求月票\n\n第二章"))
+}
+
+func TestSplit_NormalizeChapters(t *testing.T) {
+	t.Parallel()
+
+	para := strings.Repeat("字", 40)
+	content := "第一章 风起\n" + strings.Repeat(para+"\n", 6) + "第二章 云涌\n" + para
+	file := writeTempFile(t, t.TempDir(), "long.txt", content)
+
+	chunks, err := Split(context.Background(), file, Option{ChunkSize: 5000, ChunkOverlap: 100, Separator: "\n\n", MaxChapterRunes: 150})
+	require.NoError(t, err)
+	var titles []string
+	for _, c := range chunks {
+		titles = append(titles, c.Title)
+		assert.LessOrEqual(t, utf8.RuneCountInString(c.Content), 150)
+	}
+	assert.Equal(t, []string{"第一章 风起（1）", "第一章 风起（2）", "第二章 云涌"}, titles)
+	assert.Equal(t, 1, chunks[1].Number)
+	assert.Equal(t, 2, chunks[1].Part)
+	assert.Equal(t, KindChapter, chunks[1].Kind)
+}
+
+func TestSplit_DisplayTextWithinLimit(t *testing.T) {
+	t.Parallel()
+
+	// 大量短段落以单个换行分隔，展示形式以空行分隔后长度增加
+	para := "走。"
+	content := "第一章 风起\n" + strings.Repeat(para+"\n", 3990) + "第二章 云涌\n" + para
+	file := writeTempFile(t, t.TempDir(), "short_paragraphs.txt", content)
+
+	chunks, err := Split(context.Background(), file, Option{ChunkSize: 5000, ChunkOverlap: 100, Separator: "\n\n"})
+	require.NoError(t, err)
+	require.Greater(t, len(chunks), 1)
+	for _, c := range chunks {
+		// 保存后原样提交不会超过 api.UpdateChapterArgs.Content 的长度限制
+		assert.LessOrEqual(t, utf8.RuneCountInString(c.Content), DefaultMaxChapterRunes)
+		assert.Equal(t, c.Content, DisplayText(Paragraphs(c.Content)))
+	}
+}
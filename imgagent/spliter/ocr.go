@@ -0,0 +1,74 @@
+package spliter
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// DefaultOCRDPI 渲染 PDF 页面的默认分辨率
+const DefaultOCRDPI = 150
+
+// OCRProvider 识别没有文字层的 PDF 页面（扫描件），page 从 1 开始
+type OCRProvider interface {
+	RecognizePage(ctx context.Context, filename string, page int) (string, error)
+}
+
+// ImageRecognizer 识别 PNG 图片中的文字
+type ImageRecognizer func(ctx context.Context, image []byte) (string, error)
+
+// ImageOCR 将 PDF 页面渲染为图片后交给 Recognize 识别
+type ImageOCR struct {
+	DPI       int // 渲染分辨率，为 0 时使用 DefaultOCRDPI
+	Recognize ImageRecognizer
+}
+
+func (o *ImageOCR) RecognizePage(ctx context.Context, filename string, page int) (string, error) {
+	dpi := o.DPI
+	if dpi <= 0 {
+		dpi = DefaultOCRDPI
+	}
+	image, err := RenderPDFPage(ctx, filename, page, dpi)
+	if err != nil {
+		return "", err
+	}
+	return o.Recognize(ctx, image)
+}
+
+// RenderPDFPage 使用 poppler 的 pdftoppm 将 PDF 的一页渲染为 PNG
+func RenderPDFPage(ctx context.Context, filename string, page, dpi int) ([]byte, error) {
+	dir, err := os.MkdirTemp("", "pdfpage-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+
+	root := filepath.Join(dir, "page")
+	n := strconv.Itoa(page)
+	cmd := exec.CommandContext(ctx, "pdftoppm", "-f", n, "-l", n, "-r", strconv.Itoa(dpi), "-png", "-singlefile", filename, root)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return nil, fmt.Errorf("render pdf page %d: %w, output: %s", page, err, strings.TrimSpace(string(out)))
+	}
+	return os.ReadFile(root + ".png")
+}
+
+// CommandRecognizer 调用本地 OCR 命令识别图片，图片从标准输入传入，识别结果从标准输出读取，
+// 如 tesseract stdin stdout -l chi_sim
+func CommandRecognizer(command string, args ...string) ImageRecognizer {
+	return func(ctx context.Context, image []byte) (string, error) {
+		var stdout, stderr bytes.Buffer
+		cmd := exec.CommandContext(ctx, command, args...)
+		cmd.Stdin = bytes.NewReader(image)
+		cmd.Stdout = &stdout
+		cmd.Stderr = &stderr
+		if err := cmd.Run(); err != nil {
+			return "", fmt.Errorf("run ocr command %s: %w, stderr: %s", command, err, strings.TrimSpace(stderr.String()))
+		}
+		return stdout.String(), nil
+	}
+}
@@ -0,0 +1,21 @@
+package spliter
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCommandRecognizer(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	text, err := CommandRecognizer("cat")(ctx, []byte("第一章 扫描\n正文"))
+	require.NoError(t, err)
+	assert.Equal(t, "第一章 扫描\n正文", text)
+
+	_, err = CommandRecognizer("false")(ctx, []byte("x"))
+	assert.Error(t, err)
+}
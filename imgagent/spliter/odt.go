@@ -0,0 +1,95 @@
+package spliter
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// readODT 读取 OpenDocument 文本文件 content.xml 中的正文，text:p 和 text:h 各为一段
+// 跳过批注和脚注
+func readODT(filename string) (string, error) {
+	r, err := zip.OpenReader(filename)
+	if err != nil {
+		return "", err
+	}
+	defer r.Close()
+
+	var content *zip.File
+	for _, f := range r.File {
+		if f.Name == "content.xml" {
+			content = f
+			break
+		}
+	}
+	if content == nil {
+		return "", errors.New("odt content.xml not found")
+	}
+	rc, err := content.Open()
+	if err != nil {
+		return "", err
+	}
+	defer rc.Close()
+
+	d := xml.NewDecoder(rc)
+	var b textBuilder
+	skip := 0
+	para := 0
+	for {
+		tok, err := d.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			if skip > 0 {
+				skip++
+				continue
+			}
+			switch t.Name.Local {
+			case "annotation", "note", "tracked-changes":
+				skip = 1
+			case "p", "h":
+				b.Newline()
+				para++
+			case "s":
+				// text:s 表示 c 个连续空格，合并为一个
+				n, _ := strconv.Atoi(xmlAttr(t, "c"))
+				b.WriteText(strings.Repeat(" ", max(n, 1)))
+			case "tab":
+				b.WriteText(" ")
+			case "line-break":
+				b.Newline()
+			}
+		case xml.EndElement:
+			if skip > 0 {
+				skip--
+				continue
+			}
+			if t.Name.Local == "p" || t.Name.Local == "h" {
+				b.Newline()
+				para--
+			}
+		case xml.CharData:
+			if skip == 0 && para > 0 {
+				b.WriteText(string(t))
+			}
+		}
+	}
+	return b.String(), nil
+}
+
+func xmlAttr(e xml.StartElement, local string) string {
+	for _, a := range e.Attr {
+		if a.Name.Local == local {
+			return a.Value
+		}
+	}
+	return ""
+}
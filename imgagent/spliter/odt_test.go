@@ -0,0 +1,41 @@
+package spliter
+
+import (
+	"archive/zip"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadODT(t *testing.T) {
+	t.Parallel()
+
+	filename := filepath.Join(t.TempDir(), "book.odt")
+	f, err := os.Create(filename)
+	require.NoError(t, err)
+	w := zip.NewWriter(f)
+	cw, err := w.Create("content.xml")
+	require.NoError(t, err)
+	_, err = cw.Write([]byte(`<?xml version="1.0" encoding="UTF-8"?>
+<office:document-content xmlns:office="urn:oasis:names:tc:opendocument:xmlns:office:1.0"
+  xmlns:text="urn:oasis:names:tc:opendocument:xmlns:text:1.0">
+  <office:automatic-styles><style>忽略</style></office:automatic-styles>
+  <office:body><office:text>
+    <text:h text:outline-level="1">第一章 祥子</text:h>
+    <text:p>我们<text:s text:c="3"/>所要介绍的<text:span>是祥子</text:span>，<text:line-break/>不是骆驼。</text:p>
+    <text:p>批注<office:annotation><text:p>不输出</text:p></office:annotation>之后<text:note><text:note-body><text:p>脚注</text:p></text:note-body></text:note></text:p>
+    <text:p/>
+    <text:h>第二章 买车</text:h><text:p>恰好<text:tab/>三年。</text:p>
+  </office:text></office:body>
+</office:document-content>`))
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+	require.NoError(t, f.Close())
+
+	text, err := readODT(filename)
+	require.NoError(t, err)
+	assert.Equal(t, "第一章 祥子\n我们 所要介绍的是祥子，\n不是骆驼。\n批注之后\n第二章 买车\n恰好 三年。", text)
+}
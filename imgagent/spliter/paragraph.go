@@ -0,0 +1,34 @@
+package spliter
+
+import "strings"
+
+// Paragraphs 将文本按行拆分为段落，去掉首尾空白（含全角缩进）和空行
+func Paragraphs(text string) []string {
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	text = strings.ReplaceAll(text, "\r", "\n")
+	var paragraphs []string
+	for _, line := range strings.Split(text, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			paragraphs = append(paragraphs, line)
+		}
+	}
+	return paragraphs
+}
+
+// paragraphSeparator 展示形式中段落之间的分隔符
+const paragraphSeparator = "\n\n"
+
+// DisplayText 用于展示的章节正文，段落之间以空行分隔
+func DisplayText(paragraphs []string) string {
+	return strings.Join(paragraphs, paragraphSeparator)
+}
+
+// PromptText 用于大模型提示词的章节正文，段落之间以单个换行分隔，段内连续空白合并为一个空格
+// 保留段落和对话的边界，同时减少 token 消耗
+func PromptText(paragraphs []string) string {
+	lines := make([]string, 0, len(paragraphs))
+	for _, p := range paragraphs {
+		lines = append(lines, strings.Join(strings.Fields(p), " "))
+	}
+	return strings.Join(lines, "\n")
+}
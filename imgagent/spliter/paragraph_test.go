@@ -0,0 +1,21 @@
+package spliter
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParagraphs(t *testing.T) {
+	t.Parallel()
+
+	text := "　　“你来了。”他说。\r\n\r\n\r\n　　“嗯，  来了。”\r\n  \n最后一段"
+	paragraphs := Paragraphs(text)
+	assert.Equal(t, []string{"“你来了。”他说。", "“嗯，  来了。”", "最后一段"}, paragraphs)
+	assert.Equal(t, "“你来了。”他说。\n\n“嗯，  来了。”\n\n最后一段", DisplayText(paragraphs))
+	assert.Equal(t, "“你来了。”他说。\n“嗯， 来了。”\n最后一段", PromptText(paragraphs))
+
+	// 展示形式再次拆分得到相同的段落
+	assert.Equal(t, paragraphs, Paragraphs(DisplayText(paragraphs)))
+	assert.Empty(t, Paragraphs(" \n\r\n"))
+}
@@ -0,0 +1,378 @@
+package spliter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/ledongthuc/pdf"
+
+	"imgagent/pkg/logger"
+)
+
+const (
+	// pdfEdgeLines 每页首尾参与页眉、页脚识别的行数
+	pdfEdgeLines = 2
+	// pdfMinRepeat 页眉、页脚至少重复出现的页数
+	pdfMinRepeat = 3
+	// pdfRowTolerance 纵坐标相差小于该值的文字视为同一行
+	pdfRowTolerance = 2.0
+	// pdfIndentTolerance 行首横坐标超出左边距该值时视为段首缩进
+	pdfIndentTolerance = 5.0
+	// pdfShortLineRatio 行长度小于本页最长行的该比例时视为段落末行
+	pdfShortLineRatio = 0.6
+)
+
+// ErrNoPDFText PDF 没有文字层（扫描件）且未配置 OCR
+var ErrNoPDFText = errors.New("pdf has no text layer")
+
+// pdfLine PDF 页面中的一行文字
+type pdfLine struct {
+	Text string
+	X    float64 // 行首横坐标，OCR 识别的行为 -1
+}
+
+// pageNumberRegex 独占一行的页码：12、- 12 -、第 12 页、Page 12、12 / 300、Page 3 of 10
+var pageNumberRegex = regexp.MustCompile(`^(?i:page)?\s*[-—–·]?\s*第?\s*\d{1,5}\s*页?\s*(?:(?:/|(?i:of))\s*\d{1,5})?\s*[-—–·]?$`)
+
+// readPDF 逐页提取 PDF 文字，去掉重复的页眉、页脚和页码，按版面将折行合并为段落
+// 没有文字层的页面（扫描件）在 ocr 不为空时调用 OCR 识别
+func readPDF(ctx context.Context, filename string, ocr OCRProvider) (string, error) {
+	log := logger.FromContext(ctx)
+
+	f, r, err := pdf.Open(filename)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	pages := make([][]pdfLine, 0, r.NumPage())
+	ocrPages := 0
+	for i := 1; i <= r.NumPage(); i++ {
+		page := r.Page(i)
+		var lines []pdfLine
+		if !page.V.IsNull() {
+			lines, err = pageLines(page)
+			if err != nil {
+				log.Warnf("Failed to extract pdf page text, page: %d, err: %v", i, err)
+			}
+		}
+		if len(lines) == 0 && ocr != nil {
+			text, err := ocr.RecognizePage(ctx, filename, i)
+			if err != nil {
+				log.Warnf("Failed to recognize pdf page, page: %d, err: %v", i, err)
+			} else {
+				ocrPages++
+				for _, p := range Paragraphs(text) {
+					lines = append(lines, pdfLine{Text: p, X: -1})
+				}
+			}
+		}
+		pages = append(pages, lines)
+	}
+	if ocrPages > 0 {
+		log.Infof("Recognized %d pdf pages by ocr, file: %s", ocrPages, filename)
+	}
+	content := joinPDFLines(cleanPDFPages(pages))
+	if content == "" && ocr == nil {
+		return "", ErrNoPDFText
+	}
+	return content, nil
+}
+
+// PDFNeedsOCR 判断 PDF 是否有没有文字层的页面（扫描件），这些页面需要 OCR 识别
+func PDFNeedsOCR(filename string) (bool, error) {
+	f, r, err := pdf.Open(filename)
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+
+	for i := 1; i <= r.NumPage(); i++ {
+		page := r.Page(i)
+		if page.V.IsNull() {
+			return true, nil
+		}
+		if lines, _ := pageLines(page); len(lines) == 0 {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// pdfText 带坐标的文字片段
+type pdfText struct {
+	S    string
+	X, Y float64
+}
+
+// pageLines 解析页面内容流，按坐标将文字片段归并为行，行从上到下排列
+func pageLines(page pdf.Page) (lines []pdfLine, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			lines, err = nil, fmt.Errorf("parse page content: %v", r)
+		}
+	}()
+
+	fonts := make(map[string]pdf.TextEncoding)
+	for _, name := range page.Fonts() {
+		fonts[name] = page.Font(name).Encoder()
+	}
+
+	var texts []pdfText
+	var enc pdf.TextEncoding
+	var x, y, leading float64
+	scaleX, scaleY := 1.0, 1.0
+	nextLine := func() { y -= leading * scaleY }
+	show := func(s string) {
+		if enc != nil {
+			s = enc.Decode(s)
+		}
+		if s != "" {
+			texts = append(texts, pdfText{S: s, X: x, Y: y})
+		}
+	}
+	interpret := func(stk *pdf.Stack, op string) {
+		args := make([]pdf.Value, stk.Len())
+		for i := len(args) - 1; i >= 0; i-- {
+			args[i] = stk.Pop()
+		}
+		switch op {
+		case "BT":
+			x, y, scaleX, scaleY = 0, 0, 1, 1
+		case "Tf":
+			if len(args) == 2 {
+				enc = fonts[args[0].Name()]
+			}
+		case "TL":
+			if len(args) == 1 {
+				leading = args[0].Float64()
+			}
+		case "Tm":
+			if len(args) == 6 {
+				scaleX, scaleY = math.Abs(args[0].Float64()), math.Abs(args[3].Float64())
+				if scaleX == 0 {
+					scaleX = 1
+				}
+				if scaleY == 0 {
+					scaleY = 1
+				}
+				x, y = args[4].Float64(), args[5].Float64()
+			}
+		case "Td", "TD":
+			if len(args) == 2 {
+				x += args[0].Float64() * scaleX
+				y += args[1].Float64() * scaleY
+				if op == "TD" {
+					leading = -args[1].Float64()
+				}
+			}
+		case "T*":
+			nextLine()
+		case "Tj":
+			if len(args) == 1 {
+				show(args[0].RawString())
+			}
+		case "'", "\"":
+			nextLine()
+			if len(args) > 0 {
+				show(args[len(args)-1].RawString())
+			}
+		case "TJ":
+			if len(args) == 1 {
+				for i := 0; i < args[0].Len(); i++ {
+					if v := args[0].Index(i); v.Kind() == pdf.String {
+						show(v.RawString())
+					}
+				}
+			}
+		}
+	}
+
+	contents := page.V.Key("Contents")
+	if contents.Kind() == pdf.Array {
+		for i := 0; i < contents.Len(); i++ {
+			pdf.Interpret(contents.Index(i), interpret)
+		}
+	} else {
+		pdf.Interpret(contents, interpret)
+	}
+	return groupLines(texts), nil
+}
+
+// groupLines 将纵坐标相近的文字片段归为一行，行内按横坐标排序
+func groupLines(texts []pdfText) []pdfLine {
+	sort.SliceStable(texts, func(i, j int) bool { return texts[i].Y > texts[j].Y })
+
+	var lines []pdfLine
+	for start := 0; start < len(texts); {
+		end := start + 1
+		for end < len(texts) && texts[start].Y-texts[end].Y < pdfRowTolerance {
+			end++
+		}
+		row := texts[start:end]
+		sort.SliceStable(row, func(i, j int) bool { return row[i].X < row[j].X })
+		var b strings.Builder
+		for _, t := range row {
+			b.WriteString(t.S)
+		}
+		if text := strings.TrimSpace(b.String()); text != "" {
+			lines = append(lines, pdfLine{Text: text, X: row[0].X})
+		}
+		start = end
+	}
+	return lines
+}
+
+// cleanPDFPages 去掉每页首尾的页码和重复出现的页眉、页脚
+// 页眉中的章节名在正文中作为章节标题出现时保留第一次出现的位置
+func cleanPDFPages(pages [][]pdfLine) [][]pdfLine {
+	isEdge := func(page []pdfLine, i int) bool {
+		return i < pdfEdgeLines || i >= len(page)-pdfEdgeLines
+	}
+
+	// 统计首尾行在多少页中出现，数字不同的页眉（如“书名 12”）按去掉数字后的内容统计
+	exact := make(map[string]int)
+	masked := make(map[string]int)
+	textPages := 0
+	for _, page := range pages {
+		if len(page) > 0 {
+			textPages++
+		}
+		seenExact, seenMasked := make(map[string]bool), make(map[string]bool)
+		for i, line := range page {
+			if !isEdge(page, i) || line.X < 0 {
+				continue
+			}
+			if key := edgeKey(line.Text); !seenExact[key] {
+				seenExact[key] = true
+				exact[key]++
+			}
+			if key := maskedEdgeKey(line.Text); key != "" && !seenMasked[key] {
+				seenMasked[key] = true
+				masked[key]++
+			}
+		}
+	}
+
+	kept := make(map[string]bool)
+	result := make([][]pdfLine, 0, len(pages))
+	for _, page := range pages {
+		var lines []pdfLine
+		for i, line := range page {
+			if isEdge(page, i) && line.X >= 0 {
+				if pageNumberRegex.MatchString(strings.TrimSpace(line.Text)) {
+					continue
+				}
+				key := edgeKey(line.Text)
+				if textPages >= pdfMinRepeat && exact[key] >= pdfMinRepeat {
+					if !isHeadingLine(line.Text) || kept[key] {
+						continue
+					}
+					kept[key] = true
+				}
+				if mk := maskedEdgeKey(line.Text); mk != "" && masked[mk] >= pdfMinRepeat && masked[mk]*10 >= textPages*3 {
+					continue
+				}
+			}
+			lines = append(lines, line)
+		}
+		result = append(result, lines)
+	}
+	return result
+}
+
+// edgeKey 比较页眉、页脚时忽略空白
+func edgeKey(text string) string {
+	return strings.Join(strings.Fields(text), "")
+}
+
+// maskedEdgeKey 去掉数字后的页眉、页脚内容，不含数字或本身是章节标题时返回空
+func maskedEdgeKey(text string) string {
+	if !strings.ContainsAny(text, "0123456789") || isHeadingLine(text) {
+		return ""
+	}
+	return strings.Map(func(r rune) rune {
+		if r >= '0' && r <= '9' {
+			return '#'
+		}
+		return r
+	}, edgeKey(text))
+}
+
+// isHeadingLine 判断一行是否为章节或卷标题
+func isHeadingLine(text string) bool {
+	return parseChapterNumber(text) > 0 || chapterKind(text) != KindChapter || volumeRegex.MatchString(text)
+}
+
+// joinPDFLines 按版面将折行合并为段落：章节标题单独成段，段首缩进、上一行明显较短或以句末标点结尾时另起一段
+// 本页有缩进的行时只按缩进和行长判断，否则按句末标点判断
+func joinPDFLines(pages [][]pdfLine) string {
+	var paragraphs []string
+	var current strings.Builder
+	flush := func() {
+		if current.Len() > 0 {
+			paragraphs = append(paragraphs, current.String())
+			current.Reset()
+		}
+	}
+
+	for _, page := range pages {
+		margin, maxRunes := math.MaxFloat64, 0
+		for _, line := range page {
+			if line.X >= 0 {
+				margin = math.Min(margin, line.X)
+			}
+			maxRunes = max(maxRunes, utf8.RuneCountInString(line.Text))
+		}
+		indented := false
+		for _, line := range page {
+			if line.X >= 0 && line.X > margin+pdfIndentTolerance {
+				indented = true
+				break
+			}
+		}
+
+		for _, line := range page {
+			heading := isHeadingLine(line.Text)
+			if line.X < 0 || heading || line.X > margin+pdfIndentTolerance {
+				flush()
+			}
+			joinLine(&current, line.Text)
+			short := float64(utf8.RuneCountInString(line.Text)) < float64(maxRunes)*pdfShortLineRatio
+			if line.X < 0 || heading || short || (!indented && endsSentence(line.Text)) {
+				flush()
+			}
+		}
+	}
+	flush()
+	return strings.Join(paragraphs, "\n")
+}
+
+// joinLine 将折行拼接到段落中，英文单词之间补空格
+func joinLine(b *strings.Builder, line string) {
+	if b.Len() > 0 {
+		last, _ := utf8.DecodeLastRuneInString(b.String())
+		first, _ := utf8.DecodeRuneInString(line)
+		if isLatin(last) && isLatin(first) {
+			b.WriteByte(' ')
+		}
+	}
+	b.WriteString(line)
+}
+
+func isLatin(r rune) bool {
+	return r < utf8.RuneSelf && (unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.IsPunct(r))
+}
+
+// endsSentence 判断一行是否以句末标点结尾
+func endsSentence(line string) bool {
+	r, _ := utf8.DecodeLastRuneInString(strings.TrimSpace(line))
+	return strings.ContainsRune("。！？…”」』.!?\"", r)
+}
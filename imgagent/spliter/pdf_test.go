@@ -0,0 +1,207 @@
+package spliter
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// pdfTextLine 测试 PDF 中的一行文字
+type pdfTextLine struct {
+	X, Y float64
+	Text string
+}
+
+// writeTestPDF 生成只包含 ASCII 文字的 PDF，每页的 lines 为空时生成没有文字层的页面
+func writeTestPDF(t *testing.T, dir, name string, pages [][]pdfTextLine) string {
+	t.Helper()
+
+	var objects []string
+	kids := make([]string, len(pages))
+	// 1: Catalog, 2: Pages, 3: Font，之后每页两个对象：Page 和 Contents
+	for i, lines := range pages {
+		pageID, contentID := 4+i*2, 5+i*2
+		kids[i] = fmt.Sprintf("%d 0 R", pageID)
+		var stream strings.Builder
+		for _, l := range lines {
+			fmt.Fprintf(&stream, "BT /F1 12 Tf 1 0 0 1 %.1f %.1f Tm (%s) Tj ET\n", l.X, l.Y, l.Text)
+		}
+		objects = append(objects,
+			fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 600 800] /Resources << /Font << /F1 3 0 R >> >> /Contents %d 0 R >>", contentID),
+			fmt.Sprintf("<< /Length %d >>\nstream\n%sendstream", stream.Len(), stream.String()),
+		)
+	}
+	objects = append([]string{
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(pages)),
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>",
+	}, objects...)
+
+	var buf bytes.Buffer
+	buf.WriteString("%PDF-1.4\n")
+	offsets := make([]int, len(objects))
+	for i, obj := range objects {
+		offsets[i] = buf.Len()
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", i+1, obj)
+	}
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, off := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", off)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, xref)
+
+	filename := filepath.Join(dir, name)
+	require.NoError(t, os.WriteFile(filename, buf.Bytes(), 0o644))
+	return filename
+}
+
+// fakeOCR 按页返回固定文字
+type fakeOCR map[int]string
+
+func (o fakeOCR) RecognizePage(ctx context.Context, filename string, page int) (string, error) {
+	if text, ok := o[page]; ok {
+		return text, nil
+	}
+	return "", errors.New("no text")
+}
+
+func TestCleanPDFPages(t *testing.T) {
+	t.Parallel()
+
+	line := func(text string) pdfLine { return pdfLine{Text: text, X: 72} }
+	pages := [][]pdfLine{
+		{line("The Long Road"), line("Chapter 1 Home"), line("body one"), line("body two"), line("- 1 -")},
+		{line("The Long Road"), line("body three"), line("body four"), line("Page 2 of 4")},
+		{line("The  Long Road"), line("body five"), line("body six"), line("3")},
+		{line("Chapter 2 Away"), line("body seven"), line("The Long Road 4")},
+	}
+	cleaned := cleanPDFPages(pages)
+
+	var texts []string
+	for _, page := range cleaned {
+		for _, l := range page {
+			texts = append(texts, l.Text)
+		}
+	}
+	assert.Equal(t, []string{
+		"Chapter 1 Home", "body one", "body two",
+		"body three", "body four",
+		"body five", "body six",
+		"Chapter 2 Away", "body seven", "The Long Road 4",
+	}, texts)
+
+	// 书名带页码的页眉按去掉数字后的内容识别
+	pages = [][]pdfLine{
+		{line("Road Notes 1"), line("alpha")},
+		{line("Road Notes 2"), line("beta")},
+		{line("Road Notes 3"), line("gamma")},
+	}
+	cleaned = cleanPDFPages(pages)
+	for _, page := range cleaned {
+		require.Len(t, page, 1)
+		assert.NotContains(t, page[0].Text, "Road Notes")
+	}
+
+	// 作为页眉重复出现的章节名保留第一次出现的位置
+	pages = [][]pdfLine{
+		{line("第一章 风起"), line("正文一")},
+		{line("第一章 风起"), line("正文二")},
+		{line("第一章 风起"), line("正文三")},
+	}
+	cleaned = cleanPDFPages(pages)
+	assert.Equal(t, "第一章 风起", cleaned[0][0].Text)
+	assert.Equal(t, []pdfLine{line("正文二")}, cleaned[1])
+	assert.Equal(t, []pdfLine{line("正文三")}, cleaned[2])
+}
+
+func TestJoinPDFLines(t *testing.T) {
+	t.Parallel()
+
+	// 有段首缩进：按缩进和行长分段，跨页的段落合并
+	pages := [][]pdfLine{
+		{
+			{Text: "第一章 风起", X: 200},
+			{Text: "天色暗了下来，街上的行人越来越", X: 96},
+			{Text: "少。他站在路口，不知道该往哪走", X: 72},
+		},
+		{
+			{Text: "才好。", X: 72},
+			{Text: "“走吧。”她说道，声音很轻很轻", X: 96},
+			{Text: "的。", X: 72},
+		},
+	}
+	assert.Equal(t, "第一章 风起\n天色暗了下来，街上的行人越来越少。他站在路口，不知道该往哪走才好。\n“走吧。”她说道，声音很轻很轻的。", joinPDFLines(pages))
+
+	// 没有缩进：按句末标点分段，英文单词之间补空格
+	pages = [][]pdfLine{{
+		{Text: "It was a dark and stormy night and", X: 72},
+		{Text: "the rain fell in torrents.", X: 72},
+		{Text: "Nobody came to the door that night", X: 72},
+		{Text: "or the next one either, not at all.", X: 72},
+	}}
+	assert.Equal(t, "It was a dark and stormy night and the rain fell in torrents.\nNobody came to the door that night or the next one either, not at all.", joinPDFLines(pages))
+
+	// OCR 识别的行各自成段
+	pages = [][]pdfLine{{{Text: "第一段", X: -1}, {Text: "第二段", X: -1}}}
+	assert.Equal(t, "第一段\n第二段", joinPDFLines(pages))
+}
+
+func TestReadPDF(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	page := func(n int, body ...string) []pdfTextLine {
+		lines := []pdfTextLine{{X: 72, Y: 760, Text: "The Long Road"}}
+		for i, text := range body {
+			lines = append(lines, pdfTextLine{X: 72, Y: 700 - float64(i)*20, Text: text})
+		}
+		return append(lines, pdfTextLine{X: 290, Y: 40, Text: fmt.Sprint(n)})
+	}
+	filename := writeTestPDF(t, dir, "book.pdf", [][]pdfTextLine{
+		page(1, "Chapter 1 Home", "He walked home.", "It was late."),
+		page(2, "She waited for him", "at the door."),
+		nil,
+		page(4, "Chapter 2 Away", "They left at dawn."),
+	})
+
+	ctx := context.Background()
+	content, err := readPDF(ctx, filename, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "Chapter 1 Home\nHe walked home.\nIt was late.\nShe waited for him at the door.\nChapter 2 Away\nThey left at dawn.", content)
+
+	// 没有文字层的页面交给 OCR 识别
+	content, err = readPDF(ctx, filename, fakeOCR{3: "Scanned page one.\n\nScanned page two."})
+	require.NoError(t, err)
+	assert.Contains(t, content, "at the door.\nScanned page one.\nScanned page two.\nChapter 2 Away")
+
+	// 扫描件未配置 OCR
+	scanned := writeTestPDF(t, dir, "scanned.pdf", [][]pdfTextLine{nil, nil})
+	_, err = readPDF(ctx, scanned, nil)
+	assert.ErrorIs(t, err, ErrNoPDFText)
+	content, err = readPDF(ctx, scanned, fakeOCR{1: "第一章 扫描", 2: "正文"})
+	require.NoError(t, err)
+	assert.Equal(t, "第一章 扫描\n正文", content)
+
+	needs, err := PDFNeedsOCR(filename)
+	require.NoError(t, err)
+	assert.True(t, needs)
+	text := writeTestPDF(t, dir, "text.pdf", [][]pdfTextLine{page(1, "Chapter 1 Home", "He walked home.")})
+	needs, err = PDFNeedsOCR(text)
+	require.NoError(t, err)
+	assert.False(t, needs)
+
+	chunks, err := Split(ctx, filename, Option{ChunkSize: 5000, ChunkOverlap: 100, Separator: "\n\n"})
+	require.NoError(t, err)
+	require.Len(t, chunks, 2)
+	assert.Equal(t, "Chapter 1 Home", chunks[0].Title)
+	assert.NotContains(t, chunks[0].Content, "The Long Road")
+}
@@ -0,0 +1,228 @@
+package spliter
+
+import (
+	"os"
+	"strconv"
+	"strings"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/japanese"
+	"golang.org/x/text/encoding/korean"
+	"golang.org/x/text/encoding/simplifiedchinese"
+	"golang.org/x/text/encoding/traditionalchinese"
+	"golang.org/x/text/encoding/unicode"
+)
+
+// rtfSkipDestinations 不包含正文的 RTF 目标组
+var rtfSkipDestinations = map[string]bool{
+	"fonttbl": true, "colortbl": true, "stylesheet": true, "info": true, "pict": true,
+	"header": true, "headerl": true, "headerr": true, "headerf": true,
+	"footer": true, "footerl": true, "footerr": true, "footerf": true,
+	"object": true, "listtable": true, "listoverridetable": true, "rsidtbl": true,
+	"generator": true, "themedata": true, "colorschememapping": true, "datastore": true,
+	"latentstyles": true, "xmlnstbl": true, "fldinst": true, "filetbl": true,
+	"revtbl": true, "pgdsctbl": true, "footnote": true,
+}
+
+// rtfSymbols 输出为字符的控制字
+var rtfSymbols = map[string]string{
+	"par": "\n", "line": "\n", "sect": "\n", "page": "\n", "row": "\n", "cell": " ", "tab": " ",
+	"emdash": "—", "endash": "–", "emspace": " ", "enspace": " ", "bullet": "•",
+	"lquote": "‘", "rquote": "’", "ldblquote": "“", "rdblquote": "”",
+}
+
+// rtfCodepages ansicpg 代码页对应的编码
+var rtfCodepages = map[int]encoding.Encoding{
+	932:   japanese.ShiftJIS,
+	936:   simplifiedchinese.GBK,
+	949:   korean.EUCKR,
+	950:   traditionalchinese.Big5,
+	1250:  charmap.Windows1250,
+	1251:  charmap.Windows1251,
+	1252:  charmap.Windows1252,
+	1253:  charmap.Windows1253,
+	1254:  charmap.Windows1254,
+	1255:  charmap.Windows1255,
+	1256:  charmap.Windows1256,
+	1257:  charmap.Windows1257,
+	1258:  charmap.Windows1258,
+	65001: unicode.UTF8,
+}
+
+// readRTF 读取 RTF 文件并转换为纯文本，\par 等控制字转换为换行
+func readRTF(filename string) (string, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return "", err
+	}
+	return rtfToText(data), nil
+}
+
+type rtfState struct {
+	skip bool // 当前组为不输出的目标组
+	uc   int  // \u 之后需要跳过的替代字符数
+}
+
+// rtfParser 解析 RTF 控制字与组，\'hh 字节按代码页解码
+type rtfParser struct {
+	data    []byte
+	pos     int
+	state   rtfState
+	stack   []rtfState
+	enc     encoding.Encoding
+	out     strings.Builder
+	pending []byte // 待按代码页解码的字节
+	skipN   int    // \u 之后剩余需要跳过的替代字符数
+}
+
+func rtfToText(data []byte) string {
+	p := &rtfParser{
+		data:  data,
+		state: rtfState{uc: 1},
+		enc:   charmap.Windows1252,
+	}
+	p.parse()
+
+	lines := strings.Split(p.out.String(), "\n")
+	var b textBuilder
+	for _, line := range lines {
+		b.WriteText(line)
+		b.Newline()
+	}
+	return b.String()
+}
+
+func (p *rtfParser) parse() {
+	for p.pos < len(p.data) {
+		c := p.data[p.pos]
+		p.pos++
+		switch c {
+		case '{':
+			p.stack = append(p.stack, p.state)
+		case '}':
+			if len(p.stack) > 0 {
+				p.state = p.stack[len(p.stack)-1]
+				p.stack = p.stack[:len(p.stack)-1]
+			}
+			p.skipN = 0
+		case '\\':
+			p.control()
+		case '\r', '\n':
+		default:
+			p.writeByte(c)
+		}
+	}
+	p.flush()
+}
+
+// control 解析反斜杠之后的控制符号或控制字
+func (p *rtfParser) control() {
+	if p.pos >= len(p.data) {
+		return
+	}
+	c := p.data[p.pos]
+	if !isASCIILetter(c) {
+		p.pos++
+		switch c {
+		case '\'':
+			if p.pos+2 <= len(p.data) {
+				if v, err := strconv.ParseUint(string(p.data[p.pos:p.pos+2]), 16, 8); err == nil {
+					p.writeByte(byte(v))
+				}
+				p.pos += 2
+			}
+		case '*':
+			p.state.skip = true
+		case '\\', '{', '}':
+			p.writeByte(c)
+		case '~':
+			p.writeString(" ")
+		case '_':
+			p.writeString("-")
+		case '\r', '\n':
+			p.writeString("\n")
+		}
+		return
+	}
+
+	start := p.pos
+	for p.pos < len(p.data) && isASCIILetter(p.data[p.pos]) {
+		p.pos++
+	}
+	word := string(p.data[start:p.pos])
+	paramStart := p.pos
+	if p.pos < len(p.data) && p.data[p.pos] == '-' {
+		p.pos++
+	}
+	for p.pos < len(p.data) && p.data[p.pos] >= '0' && p.data[p.pos] <= '9' {
+		p.pos++
+	}
+	param, hasParam := 0, false
+	if p.pos > paramStart {
+		if v, err := strconv.Atoi(string(p.data[paramStart:p.pos])); err == nil {
+			param, hasParam = v, true
+		}
+	}
+	// 控制字后的单个空格是分隔符
+	if p.pos < len(p.data) && p.data[p.pos] == ' ' {
+		p.pos++
+	}
+
+	switch {
+	case rtfSkipDestinations[word]:
+		p.state.skip = true
+	case word == "ansicpg" && hasParam:
+		if enc, ok := rtfCodepages[param]; ok {
+			p.enc = enc
+		}
+	case word == "uc" && hasParam:
+		p.state.uc = param
+	case word == "u" && hasParam:
+		if param < 0 {
+			param += 65536
+		}
+		p.writeString(string(rune(param)))
+		p.skipN = p.state.uc
+	default:
+		if s, ok := rtfSymbols[word]; ok {
+			p.writeString(s)
+		}
+	}
+}
+
+func (p *rtfParser) writeByte(c byte) {
+	if p.skipN > 0 {
+		p.skipN--
+		return
+	}
+	if p.state.skip {
+		return
+	}
+	p.pending = append(p.pending, c)
+}
+
+func (p *rtfParser) writeString(s string) {
+	if p.state.skip {
+		return
+	}
+	p.flush()
+	p.out.WriteString(s)
+}
+
+// flush 按代码页解码累积的字节
+func (p *rtfParser) flush() {
+	if len(p.pending) == 0 {
+		return
+	}
+	b, err := p.enc.NewDecoder().Bytes(p.pending)
+	if err != nil {
+		b = p.pending
+	}
+	p.out.Write(b)
+	p.pending = p.pending[:0]
+}
+
+func isASCIILetter(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
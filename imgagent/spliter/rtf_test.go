@@ -0,0 +1,45 @@
+package spliter
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRTFToText(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name string
+		rtf  string
+		want string
+	}{
+		{
+			name: "段落与目标组",
+			rtf: `{\rtf1\ansi\deff0{\fonttbl{\f0 Times;}}{\colortbl;\red0\green0\blue0;}` +
+				`{\*\generator Writer;}{\info{\title Ignored}}` +
+				`\pard\plain First \b bold\b0  paragraph.\par Second\line line \{x\}\par}`,
+			want: "First bold paragraph.\nSecond\nline {x}",
+		},
+		{
+			name: "代码页",
+			rtf:  `{\rtf1\ansi\ansicpg936 \'b5\'da\'d2\'bb\'d5\'c2\par}`,
+			want: "第一章",
+		},
+		{
+			name: "Unicode 与替代字符",
+			rtf:  `{\rtf1\ansi\uc1\u31532?\u20108?\u31456?\par\uc2\u8212\'97\'97 end}`,
+			want: "第二章\n— end",
+		},
+		{
+			name: "字段只保留结果",
+			rtf:  `{\rtf1{\field{\*\fldinst HYPERLINK "http://x"}{\fldrslt link}}\tab text}`,
+			want: "link text",
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			assert.Equal(t, c.want, rtfToText([]byte(c.rtf)))
+		})
+	}
+}
@@ -1,52 +1,115 @@
 package spliter
 
 import (
-	"bytes"
 	"context"
 	"errors"
-	"io"
+	"fmt"
 	"os"
 	"path/filepath"
 	"regexp"
+	"sort"
 	"strings"
 	"time"
 	"unicode/utf8"
 
 	worddoc "baliance.com/gooxml/document"
-	"github.com/ledongthuc/pdf"
 	"github.com/tmc/langchaingo/textsplitter"
 
 	"imgagent/pkg/logger"
 )
 
+// structuredReaders 可以从目录、标题或 section 结构识别章节的格式
+var structuredReaders = map[string]func(filename string) ([]Chunk, error){
+	".epub": readEPUB,
+	".html": readHTML,
+	".htm":  readHTML,
+	".fb2":  readFB2,
+}
+
 type Option struct {
-	ChunkSize    int
-	ChunkOverlap int
+	ChunkSize    int        // 未识别到章节时每块的最大长度，按 LengthFunc 计算
+	ChunkOverlap int        // 相邻块的重叠长度，按 LengthFunc 计算
+	LengthFunc   LengthFunc // 长度计算方式，为空时按字符计算，见 TokenLength
 	Separator    string
-}
+	Encoding     string // txt、md 的文本编码，为空时自动检测
 
-func Split(ctx context.Context, filename string, opt Option) ([]string, error) {
-	var content string
+	ChapterPatterns []string // 章节标题正则，为空时使用 DefaultChapterPatterns
+	MaxChapterRunes int      // 单个章节的最大字符数，超过时按段落拆分，为 0 时使用 DefaultMaxChapterRunes
+	MinChapterRunes int      // 无标题片段的最小字符数，不足时合并到相邻章节，为 0 时使用 DefaultMinChapterRunes，小于 0 时不合并
+
+	OCR OCRProvider // 识别 PDF 中没有文字层的页面，为空时跳过这些页面
+}
 
+func Split(ctx context.Context, filename string, opt Option) ([]Chunk, error) {
 	start := time.Now()
 	log := logger.FromContext(ctx)
+	if opt.LengthFunc == nil {
+		opt.LengthFunc = RuneLength
+	}
 	separators := []string{"\n\n", "\n", " ", ""}
 	if opt.Separator == "\n" {
 		separators = []string{"\n", " ", ""}
 	}
 
 	ext := filepath.Ext(filename)
-	switch ext {
+	content, chunks, err := readContent(ctx, filename, opt)
+	if err != nil {
+		return nil, err
+	}
+
+	// 3. 创建文本分割器，已按目录划分章节时跳过
+	if len(chunks) == 0 {
+		chunks, err = splitContent(ctx, content, ext, separators, opt)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// 识别卷标题
+	assignVolumes(chunks)
+	// 合并过短片段、拆分超长章节
+	chunks = normalizeChunks(content, chunks, opt.MaxChapterRunes, opt.MinChapterRunes)
+
+	// 数据清洗
+	for i := range chunks {
+		chunk := &chunks[i]
+		// 保留段落结构，去掉段落首尾空白和空行
+		chunk.Paragraphs = Paragraphs(chunk.Content)
+		chunk.Content = DisplayText(chunk.Paragraphs)
+		chunk.Prompt = PromptText(chunk.Paragraphs)
+		chunk.Title = truncateRunes(strings.TrimSpace(chunk.Title), maxTitleLen)
+		chunk.Number = parseChapterNumber(chunk.Title)
+		chunk.Kind = chapterKind(chunk.Title)
+		if chunk.Part > 0 && chunk.Title != "" {
+			suffix := fmt.Sprintf("（%d）", chunk.Part)
+			chunk.Title = truncateRunes(chunk.Title, maxTitleLen-utf8.RuneCountInString(suffix)) + suffix
+		}
+		log.Debugf("Splite content, i: %d, title: %s, len: %d,  %s", i, chunk.Title, len(chunk.Content), chunk.Content[:min(48, len(chunk.Content))])
+	}
+	log.Infof("Split costMS: %d", time.Since(start).Milliseconds())
+	return chunks, nil
+}
+
+// readContent 提取文件的纯文本，能识别文档结构的格式（epub、html、fb2）同时返回按结构划分的章节
+func readContent(ctx context.Context, filename string, opt Option) (string, []Chunk, error) {
+	var content string
+	var chunks []Chunk
+
+	log := logger.FromContext(ctx)
+	switch ext := filepath.Ext(filename); ext {
 	case ".txt", ".md":
 		bytes, err := os.ReadFile(filename)
 		if err != nil {
-			return nil, err
+			return "", nil, err
+		}
+		content, err = DecodeText(bytes, opt.Encoding)
+		if err != nil {
+			return "", nil, err
 		}
-		content = string(bytes)
 	case ".doc", ".docx":
 		d, err := worddoc.Open(filename)
 		if err != nil {
-			return nil, err
+			return "", nil, err
 		}
 		for _, para := range d.Paragraphs() {
 			for _, run := range para.Runs() {
@@ -55,74 +118,98 @@ func Split(ctx context.Context, filename string, opt Option) ([]string, error) {
 			content += "\n"
 		}
 	case ".pdf":
-		f, r, err := pdf.Open(filename)
+		// 逐页提取，去掉页眉、页脚和页码
+		text, err := readPDF(ctx, filename, opt.OCR)
 		if err != nil {
-			return nil, err
+			return "", nil, err
 		}
-		defer f.Close()
-		var buf bytes.Buffer
-		// 获取 pdf 文本数据
-		pt, err := r.GetPlainText()
+		content = text
+	case ".rtf":
+		text, err := readRTF(filename)
 		if err != nil {
-			return nil, err
+			return "", nil, err
 		}
-		if _, err := io.Copy(&buf, pt); err != nil {
-			return nil, err
+		content = text
+	case ".odt":
+		text, err := readODT(filename)
+		if err != nil {
+			return "", nil, err
+		}
+		content = text
+	case ".epub", ".html", ".htm", ".fb2":
+		chapters, err := structuredReaders[ext](filename)
+		if err != nil {
+			return "", nil, err
+		}
+		// 能识别文档结构时直接按结构划分章节，否则按普通文本分割
+		if len(chapters) > 1 {
+			log.Infof("按文档结构分割成功，共 %d 个章节", len(chapters))
+			chunks = chapters
+		} else if len(chapters) == 1 {
+			content = chapters[0].Content
 		}
-		content = buf.String()
 	default:
-		return nil, errors.New("unknown file ext")
+		return "", nil, errors.New("unknown file ext")
 	}
-	if content == "" {
-		return nil, errors.New("empty content")
+	if content == "" && len(chunks) == 0 {
+		return "", nil, errors.New("empty content")
 	}
+	return content, chunks, nil
+}
 
-	// 3. 创建文本分割器
-	var err error
-	var texts []string
-	var splitter textsplitter.TextSplitter
+// ReadText 提取文件的纯文本，按结构划分章节的格式按章节顺序拼接
+// 用于将大模型不支持的格式（rtf、odt、html、fb2 等）转换为 txt 上传
+func ReadText(ctx context.Context, filename string, opt Option) (string, error) {
+	content, chunks, err := readContent(ctx, filename, opt)
+	if err != nil {
+		return "", err
+	}
+	if content != "" {
+		return content, nil
+	}
+	texts := make([]string, 0, len(chunks))
+	for _, c := range chunks {
+		texts = append(texts, c.Content)
+	}
+	return strings.Join(texts, "\n\n"), nil
+}
+
+func splitContent(ctx context.Context, content, ext string, separators []string, opt Option) ([]Chunk, error) {
 	if ext == ".md" {
+		// Markdown 标题中带章节序号时按章节分割
+		if chunks := splitByChapters(ctx, content, opt.ChapterPatterns); len(chunks) > 1 {
+			return chunks, nil
+		}
 		mdSparators := []string{"#", "##", "###", "####"}
 		mdSparators = append(mdSparators, separators...)
-		splitter = textsplitter.NewMarkdownTextSplitter(
+		splitter := textsplitter.NewMarkdownTextSplitter(
 			textsplitter.WithChunkSize(opt.ChunkSize),
 			textsplitter.WithChunkOverlap(opt.ChunkOverlap),
 			textsplitter.WithSeparators(mdSparators),
+			textsplitter.WithLenFunc(opt.LengthFunc),
 		)
-		texts, err = splitter.SplitText(content)
-		if err != nil {
-			return nil, err
-		}
-	} else {
-		splitter = textsplitter.NewRecursiveCharacter(
-			textsplitter.WithChunkSize(opt.ChunkSize),
-			textsplitter.WithChunkOverlap(opt.ChunkOverlap),
-			textsplitter.WithSeparators(separators),
-		)
-		// 使用 SplitText 方法分割文本内容
-		texts, err = splitText(ctx, splitter, content, opt.Separator, opt.ChunkSize)
+		texts, err := splitter.SplitText(content)
 		if err != nil {
 			return nil, err
 		}
+		return locateChunks(content, texts), nil
 	}
 
-	// 数据清洗
-	for i, text := range texts {
-		// 去掉空白符号
-		text = strings.TrimSpace(text)
-		// 替换中间换行符
-		texts[i] = strings.ReplaceAll(text, "\n", ",")
-		log.Debugf("Splite content, i: %d, len: %d,  %s", i, len(texts[i]), texts[i][:min(48, len(texts[i]))])
-	}
-	log.Infof("Split costMS: %d", time.Since(start).Milliseconds())
-	return texts, nil
+	splitter := textsplitter.NewRecursiveCharacter(
+		textsplitter.WithChunkSize(opt.ChunkSize),
+		textsplitter.WithChunkOverlap(opt.ChunkOverlap),
+		textsplitter.WithSeparators(separators),
+		textsplitter.WithLenFunc(opt.LengthFunc),
+	)
+	// 使用 SplitText 方法分割文本内容
+	return splitText(ctx, splitter, content, opt.Separator, opt.ChunkSize, opt.LengthFunc, opt.ChapterPatterns)
 }
 
-func splitText(ctx context.Context, splitter textsplitter.TextSplitter, content string, separator string, chunkSize int) ([]string, error) {
+func splitText(ctx context.Context, splitter textsplitter.TextSplitter, content string, separator string, chunkSize int, length LengthFunc, patterns []string) ([]Chunk, error) {
 	log := logger.FromContext(ctx)
 
 	// 优先按章节分割
-	chapterChunks := splitByChapters(ctx, content)
+	chapterChunks := splitByChapters(ctx, content, patterns)
 	if len(chapterChunks) > 1 {
 		log.Infof("按章节分割成功，共 %d 个章节", len(chapterChunks))
 		return chapterChunks, nil
@@ -146,7 +233,7 @@ func splitText(ctx context.Context, splitter textsplitter.TextSplitter, content
 		if split == "" {
 			continue
 		}
-		if utf8.RuneCountInString(split) > chunkSize {
+		if length(split) > chunkSize {
 			texts, err := splitter.SplitText(split)
 			if err != nil {
 				finalChunks = append(finalChunks, split)
@@ -159,31 +246,55 @@ func splitText(ctx context.Context, splitter textsplitter.TextSplitter, content
 
 		finalChunks = append(finalChunks, split)
 	}
-	return finalChunks, nil
+	return locateChunks(content, finalChunks), nil
+}
+
+// DefaultChapterPatterns 默认的章节标题正则，分割时选择匹配数量最多的一个，数量相同时取靠前的
+var DefaultChapterPatterns = []string{
+	// 【第X章】、[第X章] 标题
+	`(?m)^[ \t\x{3000}]*[【\[]第[零〇一二两三四五六七八九十百千万\d]+[章节回][】\]][^\r\n]*`,
+	// Markdown 标题中的章节：## 第X章、## Chapter N、## 1. 标题
+	`(?m)^#{1,6}[ \t]*(?:第[零〇一二两三四五六七八九十百千万\d]+[章节回]|(?i:chapter)\s+\d+|\d{1,4}[.．、])[^\r\n]*`,
+	// 第X章、第X回、第X节
+	`(?i)(第[一二三四五六七八九十百千万\d]+[章节回节])`,
+	// 第X章 标题
+	`(?i)(第[一二三四五六七八九十百千万\d]+章\s*[^\n]*)`,
+	// 第X回 标题
+	`(?i)(第[一二三四五六七八九十百千万\d]+回\s*[^\n]*)`,
+	// 第X节 标题
+	`(?i)(第[一二三四五六七八九十百千万\d]+节\s*[^\n]*)`,
+	// 数字章节
+	`(?i)(第\d+[章节回节])`,
+	// 纯数字章节
+	`(?i)(第\d+章\s*[^\n]*)`,
+	// 英文章节
+	`(?i)(Chapter\s+\d+)`,
+	// 罗马数字章节
+	`(?i)(第[IVX]+[章节回节])`,
+	// 独占一行的数字编号：001.、12、
+	`(?m)^[ \t\x{3000}]*\d{1,4}[.．、](?:[ \t\x{3000}]*[^\r\n\d][^\r\n]{0,29})?[ \t\x{3000}]*\r?$`,
+}
+
+// CompilePatterns 编译章节标题正则，用于校验用户配置
+func CompilePatterns(patterns []string) ([]*regexp.Regexp, error) {
+	regexes := make([]*regexp.Regexp, 0, len(patterns))
+	for _, pattern := range patterns {
+		regex, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid chapter pattern %q: %w", pattern, err)
+		}
+		regexes = append(regexes, regex)
+	}
+	return regexes, nil
 }
 
-// splitByChapters 按章节分割文本
-func splitByChapters(ctx context.Context, content string) []string {
+// splitByChapters 按章节分割文本，patterns 为空时使用 DefaultChapterPatterns
+// 识别到章节时，序章、尾声、番外等特殊章节也单独分割
+func splitByChapters(ctx context.Context, content string, patterns []string) []Chunk {
 	log := logger.FromContext(ctx)
 
-	// 定义章节匹配的正则表达式
-	chapterPatterns := []string{
-		// 第X章、第X回、第X节
-		`(?i)(第[一二三四五六七八九十百千万\d]+[章节回节])`,
-		// 第X章 标题
-		`(?i)(第[一二三四五六七八九十百千万\d]+章\s*[^\n]*)`,
-		// 第X回 标题
-		`(?i)(第[一二三四五六七八九十百千万\d]+回\s*[^\n]*)`,
-		// 第X节 标题
-		`(?i)(第[一二三四五六七八九十百千万\d]+节\s*[^\n]*)`,
-		// 数字章节
-		`(?i)(第\d+[章节回节])`,
-		// 纯数字章节
-		`(?i)(第\d+章\s*[^\n]*)`,
-		// 英文章节
-		`(?i)(Chapter\s+\d+)`,
-		// 罗马数字章节
-		`(?i)(第[IVX]+[章节回节])`,
+	if len(patterns) == 0 {
+		patterns = DefaultChapterPatterns
 	}
 
 	var chapterRegex *regexp.Regexp
@@ -191,9 +302,10 @@ func splitByChapters(ctx context.Context, content string) []string {
 
 	// 尝试不同的章节模式，找到匹配最多的
 	maxMatches := 0
-	for _, pattern := range chapterPatterns {
+	for _, pattern := range patterns {
 		regex, err := regexp.Compile(pattern)
 		if err != nil {
+			log.Warnf("Invalid chapter pattern: %s, err: %v", pattern, err)
 			continue
 		}
 
@@ -216,9 +328,9 @@ func splitByChapters(ctx context.Context, content string) []string {
 		}
 
 		// 按章节分割 - 简单直接的方法
-		// 找到所有章节标题的位置
-		indices := chapterRegex.FindAllStringIndex(content, -1)
-		var result []string
+		// 找到所有章节标题的位置，加入不与章节标题重叠的特殊章节标题
+		indices := mergeIndices(chapterRegex.FindAllStringIndex(content, -1), specialChapterRegex.FindAllStringIndex(content, -1))
+		var result []Chunk
 
 		log.Infof("找到 %d 个章节标题位置", len(indices))
 		for i, idx := range indices {
@@ -226,15 +338,29 @@ func splitByChapters(ctx context.Context, content string) []string {
 		}
 
 		if len(indices) == 0 {
-			return []string{content}
+			return []Chunk{{Content: content, Start: 0, End: len(content)}}
+		}
+
+		// 标题所在行前只有【、# 等符号时从行首开始分割；紧挨章节标题之前的卷标题归入该章节，作为新一卷的开始
+		volumes := volumeMarks(content)
+		starts := make([]int, len(indices))
+		prevEnd := 0
+		for i, idx := range indices {
+			starts[i] = headingStart(content, idx[0])
+			for _, v := range volumes {
+				if v.Offset >= prevEnd && v.Offset < starts[i] {
+					starts[i] = v.Offset
+				}
+			}
+			prevEnd = idx[1]
 		}
 
 		// 从第一个章节标题开始分割
-		start := indices[0][0]
 		for i := 0; i < len(indices); i++ {
+			start := starts[i]
 			var end int
 			if i+1 < len(indices) {
-				end = indices[i+1][0] // 下一个章节标题开始位置
+				end = starts[i+1] // 下一个章节开始位置
 			} else {
 				end = len(content) // 最后一个章节到结尾
 			}
@@ -242,14 +368,46 @@ func splitByChapters(ctx context.Context, content string) []string {
 			chapter := strings.TrimSpace(content[start:end])
 			log.Infof("章节 %d: 位置 %d-%d, 内容: %s", i+1, start, end, chapter[:min(50, len(chapter))])
 			if chapter != "" {
-				result = append(result, chapter)
+				result = append(result, Chunk{
+					Title:   chapterTitle(content, indices[i][0], indices[i][1]),
+					Content: chapter,
+					Start:   start,
+					End:     end,
+				})
 			}
-			start = end
 		}
 
 		return result
 	}
 
 	log.Infof("未找到有效的章节模式，匹配数量: %d", maxMatches)
-	return []string{content}
+	return []Chunk{{Content: content, Start: 0, End: len(content)}}
+}
+
+// mergeIndices 合并特殊章节标题位置，与章节标题重叠的忽略，结果按位置排序
+func mergeIndices(chapters, specials [][]int) [][]int {
+	merged := append([][]int{}, chapters...)
+	for _, sp := range specials {
+		overlap := false
+		for _, ch := range chapters {
+			if sp[0] < ch[1] && ch[0] < sp[1] {
+				overlap = true
+				break
+			}
+		}
+		if !overlap {
+			merged = append(merged, sp)
+		}
+	}
+	sort.Slice(merged, func(i, j int) bool { return merged[i][0] < merged[j][0] })
+	return merged
+}
+
+// headingStart 标题所在行在标题之前只有空白、#、【 等符号时返回行首，否则返回标题位置
+func headingStart(content string, pos int) int {
+	lineStart := strings.LastIndexByte(content[:pos], '\n') + 1
+	if strings.Trim(content[lineStart:pos], " \t　#【[") == "" {
+		return lineStart
+	}
+	return pos
 }
@@ -33,8 +33,9 @@ func TestSplitTXT_Basic(t *testing.T) {
 	require.NoError(t, err)
 	require.NotEmpty(t, chunks)
 	for _, c := range chunks {
-		require.False(t, strings.Contains(c, "\n"), "chunk should not contain raw newline after cleaning: %q", c)
-		require.NotEmpty(t, strings.TrimSpace(c), "chunk should not be empty after trim")
+		require.NotContains(t, c.Content, "\n\n\n", "chunk should not contain blank paragraphs after cleaning: %q", c.Content)
+		require.Equal(t, Paragraphs(c.Content), c.Paragraphs)
+		require.NotEmpty(t, strings.TrimSpace(c.Content), "chunk should not be empty after trim")
 	}
 }
 
@@ -47,7 +48,7 @@ func TestSplitMD_Headings(t *testing.T) {
 	file := writeTempFile(t, dir, "doc.md", md)
 
 	// Use small chunk to encourage splitting by headings/separators
-	opts := Option{ChunkSize: 40, ChunkOverlap: 0, Separator: "\n"}
+	opts := Option{ChunkSize: 40, ChunkOverlap: 0, Separator: "\n", MinChapterRunes: -1}
 	chunks, err := Split(ctx, file, opts)
 	require.NoError(t, err)
 	require.GreaterOrEqual(t, len(chunks), 2, "expected multiple chunks for markdown")
@@ -64,14 +65,14 @@ func TestSplitText_SeparatorChoiceAndChunking(t *testing.T) {
 		textsplitter.WithChunkOverlap(0),
 		textsplitter.WithSeparators([]string{" ", ""}),
 	)
-	chunks, err := splitText(ctx, splitter1, content1, " ", 10)
+	chunks, err := splitText(ctx, splitter1, content1, " ", 10, RuneLength, nil)
 	require.NoError(t, err)
 	require.NotEmpty(t, chunks)
 	for _, c := range chunks {
-		require.False(t, strings.Contains(c, "\n"), "unexpected newline in chunk: %q", c)
-		utfLen := len([]rune(c))
+		require.False(t, strings.Contains(c.Content, "\n"), "unexpected newline in chunk: %q", c.Content)
+		utfLen := len([]rune(c.Content))
 		require.Greater(t, utfLen, 0, "chunk should not be empty")
-		require.LessOrEqual(t, utfLen, 10, "chunk size out of bound: %q (len=%d)", c, utfLen)
+		require.LessOrEqual(t, utfLen, 10, "chunk size out of bound: %q (len=%d)", c.Content, utfLen)
 	}
 
 	// Case 2: contains newlines, set separator to "\n"
@@ -81,11 +82,11 @@ func TestSplitText_SeparatorChoiceAndChunking(t *testing.T) {
 		textsplitter.WithChunkOverlap(0),
 		textsplitter.WithSeparators([]string{"\n", " ", ""}),
 	)
-	chunks, err = splitText(ctx, splitter2, content2, "\n", 8)
+	chunks, err = splitText(ctx, splitter2, content2, "\n", 8, RuneLength, nil)
 	require.NoError(t, err)
 	require.NotEmpty(t, chunks)
 	for _, c := range chunks {
-		utfLen := len([]rune(c))
+		utfLen := len([]rune(c.Content))
 		require.Greater(t, utfLen, 0)
 		require.LessOrEqual(t, utfLen, 8)
 	}
@@ -98,11 +99,11 @@ func TestSplitText_SeparatorChoiceAndChunking(t *testing.T) {
 		textsplitter.WithChunkOverlap(0),
 		textsplitter.WithSeparators([]string{""}),
 	)
-	chunks, err = splitText(ctx, splitter3, content3, "", 5)
+	chunks, err = splitText(ctx, splitter3, content3, "", 5, RuneLength, nil)
 	require.NoError(t, err)
 	require.NotEmpty(t, chunks)
 	for _, c := range chunks {
-		utfLen := len([]rune(c))
+		utfLen := len([]rune(c.Content))
 		require.Greater(t, utfLen, 0)
 		require.LessOrEqual(t, utfLen, 5)
 	}
@@ -114,11 +115,11 @@ func TestSplitText_SeparatorChoiceAndChunking(t *testing.T) {
 		textsplitter.WithChunkOverlap(0),
 		textsplitter.WithSeparators([]string{"\n\n", "\n", " ", ""}),
 	)
-	chunks, err = splitText(ctx, splitter4, content4, "", 20)
+	chunks, err = splitText(ctx, splitter4, content4, "", 20, RuneLength, nil)
 	require.NoError(t, err)
 	require.NotEmpty(t, chunks)
 	for _, c := range chunks {
-		require.NotEmpty(t, strings.TrimSpace(c))
+		require.NotEmpty(t, strings.TrimSpace(c.Content))
 	}
 }
 
@@ -210,12 +211,13 @@ func TestSplitBooks_ChapterDetection(t *testing.T) {
 
 			// 检查每个块的内容
 			for i, chunk := range chunks {
-				require.NotEmpty(t, strings.TrimSpace(chunk), "第 %d 个块为空", i+1)
-				require.False(t, strings.Contains(chunk, "\n"), "块 %d 包含未处理的换行符", i+1)
+				require.NotEmpty(t, strings.TrimSpace(chunk.Content), "第 %d 个块为空", i+1)
+				require.NotContains(t, chunk.Content, "\r", "块 %d 包含未处理的换行符", i+1)
+				require.Equal(t, PromptText(chunk.Paragraphs), chunk.Prompt, "块 %d 的提示词形式不一致", i+1)
 
 				// 打印前几个块的内容预览
 				if i < 3 {
-					preview := chunk
+					preview := chunk.Content
 					if len(preview) > 100 {
 						preview = preview[:100] + "..."
 					}
@@ -316,14 +318,14 @@ func TestSplitBooks_SpecificNovels(t *testing.T) {
 			chapterIndicators := 0
 			for i, chunk := range chunks {
 				// 检查是否包含章节关键词
-				chunkLower := strings.ToLower(chunk)
+				chunkLower := strings.ToLower(chunk.Content)
 				if strings.Contains(chunkLower, "第") &&
 					(strings.Contains(chunkLower, "章") ||
 						strings.Contains(chunkLower, "回") ||
 						strings.Contains(chunkLower, "节")) {
 					chapterIndicators++
 					if i < 5 { // 只打印前5个可能的章节
-						preview := strings.TrimSpace(chunk)
+						preview := strings.TrimSpace(chunk.Content)
 						if len(preview) > 200 {
 							preview = preview[:200] + "..."
 						}
@@ -383,12 +385,12 @@ func TestSplitByChapters_Unit(t *testing.T) {
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
 			t.Logf("测试内容: %s", tc.content)
-			chunks := splitByChapters(ctx, tc.content)
+			chunks := splitByChapters(ctx, tc.content, nil)
 			t.Logf("实际分割结果: %d 个章节，期望: %d 个", len(chunks), tc.expected)
 
 			for i, chunk := range chunks {
-				require.NotEmpty(t, strings.TrimSpace(chunk), "章节 %d 为空", i+1)
-				chunkPreview := strings.TrimSpace(chunk)
+				require.NotEmpty(t, strings.TrimSpace(chunk.Content), "章节 %d 为空", i+1)
+				chunkPreview := strings.TrimSpace(chunk.Content)
 				if len(chunkPreview) > 100 {
 					chunkPreview = chunkPreview[:100] + "..."
 				}
@@ -0,0 +1,43 @@
+package spliter
+
+import (
+	"unicode"
+	"unicode/utf8"
+)
+
+// LengthFunc 计算文本长度，用于控制分割块大小
+type LengthFunc func(text string) int
+
+// RuneLength 按字符计算长度
+func RuneLength(text string) int {
+	return utf8.RuneCountInString(text)
+}
+
+// TokenLength 近似计算 Qwen 分词后的 token 数：
+// 汉字、假名、谚文约 1.5 个字一个 token，英文单词约 4 个字母一个 token，
+// 数字逐位计算，标点符号各算一个 token，空白并入相邻的词
+func TokenLength(text string) int {
+	cjk, tokens, word, other := 0, 0, 0, 0
+	flushWord := func() {
+		tokens += (word + 3) / 4
+		word = 0
+	}
+	for _, r := range text {
+		switch {
+		case r < utf8.RuneSelf && (unicode.IsLetter(r) || r == '\''):
+			word++
+			continue
+		case unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul):
+			cjk++
+		case unicode.IsDigit(r), unicode.IsPunct(r), unicode.IsSymbol(r):
+			tokens++
+		case unicode.IsSpace(r):
+		default:
+			// 其他文字（西里尔字母等）约 2 个字符一个 token
+			other++
+		}
+		flushWord()
+	}
+	flushWord()
+	return tokens + (cjk*2+2)/3 + (other+1)/2
+}
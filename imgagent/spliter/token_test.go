@@ -0,0 +1,60 @@
+package spliter
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTokenLength(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		text   string
+		tokens int
+	}{
+		{"", 0},
+		{"天色暗了", 3},
+		{"天色暗了下来", 4},
+		{"hello world", 4},
+		{"It's 2024.", 6},
+		{"第12章 风起", 5},
+		{"Привет", 3},
+	}
+	for _, tc := range cases {
+		assert.Equal(t, tc.tokens, TokenLength(tc.text), tc.text)
+	}
+
+	// 同样字符数的中文比英文占用更多 token
+	zh := strings.Repeat("字", 400)
+	en := strings.Repeat("word ", 80)
+	assert.Equal(t, RuneLength(zh), RuneLength(en))
+	assert.Greater(t, TokenLength(zh), TokenLength(en))
+}
+
+func TestSplit_TokenLength(t *testing.T) {
+	t.Parallel()
+
+	// 没有章节标题的长文本按 token 数分块
+	para := strings.Repeat("天色暗了下来。", 30)
+	content := strings.Repeat(para+"\n\n", 10)
+	file := writeTempFile(t, t.TempDir(), "plain.txt", content)
+
+	opt := Option{ChunkSize: 200, ChunkOverlap: 0, Separator: "\n\n", MinChapterRunes: -1}
+	byRunes, err := Split(context.Background(), file, opt)
+	require.NoError(t, err)
+
+	opt.LengthFunc = TokenLength
+	byTokens, err := Split(context.Background(), file, opt)
+	require.NoError(t, err)
+
+	// 每段 210 字、150 token：按字符计算时段落超长需继续分割，按 token 计算时一段一块
+	assert.Greater(t, len(byRunes), 10)
+	require.Len(t, byTokens, 10)
+	for _, c := range byTokens {
+		assert.Equal(t, 150, TokenLength(c.Content))
+	}
+}
@@ -0,0 +1,62 @@
+package spliter
+
+import (
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+var (
+	// volumeRegex 卷标题：第X卷、第X部、卷X、Part N、Book N、Volume N，需位于行首，排除“第X部分”
+	volumeRegex = regexp.MustCompile(`(?m)^[ \t\x{3000}]*(?:第\s*[零〇一二两三四五六七八九十百千万\d]+\s*[卷部]|卷\s*[零〇一二两三四五六七八九十百千万\d]+|(?i:part|book|volume)\s+(?:\d+|[IVXLCDM]+)\b)(?:[^分\r\n][^\r\n]*)?\r?$`)
+	// volumeNumberRegex 没有“第”字的卷号写法，如“卷三”
+	volumeNumberRegex = regexp.MustCompile(`^卷\s*([零〇一二两三四五六七八九十百千万\d]+)`)
+)
+
+// volumeMarks 查找全文中的卷标题，标题行超过 maxTitleRunes 的视为正文
+func volumeMarks(content string) []mark {
+	var marks []mark
+	for _, idx := range volumeRegex.FindAllStringIndex(content, -1) {
+		title := strings.TrimSpace(content[idx[0]:idx[1]])
+		if utf8.RuneCountInString(title) > maxTitleRunes {
+			continue
+		}
+		marks = append(marks, mark{Title: title, Offset: idx[0]})
+	}
+	return marks
+}
+
+// volumeTitle 返回正文第一行的卷标题，不是卷标题时返回空
+func volumeTitle(text string) string {
+	line := strings.TrimSpace(text)
+	if i := strings.IndexAny(line, "\r\n"); i >= 0 {
+		line = line[:i]
+	}
+	if utf8.RuneCountInString(line) > maxTitleRunes {
+		return ""
+	}
+	if loc := volumeRegex.FindStringIndex(line); loc == nil || loc[0] != 0 {
+		return ""
+	}
+	return strings.TrimSpace(line)
+}
+
+// parseVolumeNumber 从卷标题中解析卷号，无法解析时返回 0
+func parseVolumeNumber(title string) int {
+	if m := volumeNumberRegex.FindStringSubmatch(title); m != nil {
+		return parseNumber(m[1])
+	}
+	return parseChapterNumber(title)
+}
+
+// assignVolumes 以卷标题开头的章节开始新的一卷，之后的章节都归属该卷
+func assignVolumes(chunks []Chunk) {
+	volume, number := "", 0
+	for i := range chunks {
+		if title := volumeTitle(chunks[i].Content); title != "" {
+			volume, number = truncateRunes(title, maxTitleLen), parseVolumeNumber(title)
+		}
+		chunks[i].Volume = volume
+		chunks[i].VolumeNumber = number
+	}
+}
@@ -0,0 +1,77 @@
+package spliter
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVolumeTitle(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]string{
+		"第一卷 风起\n第一章":       "第一卷 风起",
+		"  第二部：云涌\r\n":      "第二部：云涌",
+		"卷三\n正文":            "卷三",
+		"Part 2\nChapter 1": "Part 2",
+		"BOOK IV":           "BOOK IV",
+		"第二部分的内容":           "",
+		"第一章 开始":            "",
+		"他读完了第一卷":           "",
+		"Part of the plan":  "",
+	}
+	for text, want := range cases {
+		assert.Equal(t, want, volumeTitle(text), text)
+	}
+
+	assert.Equal(t, 1, parseVolumeNumber("第一卷 风起"))
+	assert.Equal(t, 3, parseVolumeNumber("卷三"))
+	assert.Equal(t, 4, parseVolumeNumber("Book IV"))
+	assert.Equal(t, 0, parseVolumeNumber("终卷"))
+}
+
+func TestSplit_Volumes(t *testing.T) {
+	t.Parallel()
+
+	content := "第一卷 风起\n\n第一章 开始\n天色暗了。\n\n第二章 出发\n他走了。\n\n第二卷 云涌\n\n第三章 归来\n雨停了。"
+	file := writeTempFile(t, t.TempDir(), "volumes.txt", content)
+	chunks, err := Split(context.Background(), file, Option{ChunkSize: 5000, ChunkOverlap: 100, Separator: "\n\n"})
+	require.NoError(t, err)
+	require.Len(t, chunks, 3)
+
+	// 卷标题归入下一卷的第一章，不留在上一章末尾
+	assert.Equal(t, "第一章 开始", chunks[0].Title)
+	assert.True(t, strings.HasPrefix(chunks[0].Content, "第一卷 风起\n\n"))
+	assert.Equal(t, 0, chunks[0].Start)
+	assert.Equal(t, "第一卷 风起", chunks[0].Volume)
+	assert.Equal(t, 1, chunks[0].VolumeNumber)
+
+	assert.Equal(t, "第二章 出发\n\n他走了。", chunks[1].Content)
+	assert.Equal(t, "第一卷 风起", chunks[1].Volume)
+
+	assert.Equal(t, "第三章 归来", chunks[2].Title)
+	assert.Equal(t, strings.Index(content, "第二卷"), chunks[2].Start)
+	assert.Equal(t, "第二卷 云涌", chunks[2].Volume)
+	assert.Equal(t, 2, chunks[2].VolumeNumber)
+}
+
+func TestAssignVolumes(t *testing.T) {
+	t.Parallel()
+
+	chunks := []Chunk{
+		{Content: "前言"},
+		{Content: "卷一\n第一章 祥子"},
+		{Content: "第二章 买车"},
+		{Content: "Part 2\nChapter 3"},
+	}
+	assignVolumes(chunks)
+	assert.Equal(t, "", chunks[0].Volume)
+	assert.Equal(t, "卷一", chunks[1].Volume)
+	assert.Equal(t, "卷一", chunks[2].Volume)
+	assert.Equal(t, 1, chunks[2].VolumeNumber)
+	assert.Equal(t, "Part 2", chunks[3].Volume)
+	assert.Equal(t, 2, chunks[3].VolumeNumber)
+}
@@ -1,9 +1,16 @@
 package storage
 
 import (
+	"bytes"
 	"context"
 	"errors"
 	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"os"
+	"path/filepath"
 	"time"
 
 	"github.com/qiniu/go-sdk/v7/storagev2/credentials"
@@ -16,6 +23,7 @@ type Config struct {
 	Bucket      string `json:"bucket"`
 	ExpiresHour int    `json:"expires_hour"`
 	Domain      string `json:"domain"`
+	UpHost      string `json:"up_host"` // 表单上传域名，默认华东区域
 }
 
 type Storage struct {
@@ -29,6 +37,9 @@ func NewStorage(conf Config) (*Storage, error) {
 	if conf.ExpiresHour == 0 {
 		conf.ExpiresHour = 2
 	}
+	if conf.UpHost == "" {
+		conf.UpHost = "https://up.qiniup.com"
+	}
 	return &Storage{
 		conf: conf,
 	}, nil
@@ -47,6 +58,64 @@ func (s *Storage) GenerateUploadToken(userID int64) (string, error) {
 	return uptoken.NewSigner(policy, mac).GetUpToken(context.Background())
 }
 
+// UploadFile 以表单方式上传本地文件到指定 key，返回访问 URL
+func (s *Storage) UploadFile(ctx context.Context, key, filename, contentType string) (string, error) {
+	mac := credentials.NewCredentials(s.conf.AccessKey, s.conf.SecretKey)
+	policy, err := uptoken.NewPutPolicyWithKey(s.conf.Bucket, key, time.Now().Add(time.Duration(s.conf.ExpiresHour)*time.Hour))
+	if err != nil {
+		return "", err
+	}
+	token, err := uptoken.NewSigner(policy, mac).GetUpToken(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	file, err := os.Open(filename)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	if err := writer.WriteField("token", token); err != nil {
+		return "", err
+	}
+	if err := writer.WriteField("key", key); err != nil {
+		return "", err
+	}
+	header := make(textproto.MIMEHeader)
+	header.Set("Content-Disposition", fmt.Sprintf(`form-data; name="file"; filename="%s"`, filepath.Base(filename)))
+	header.Set("Content-Type", contentType)
+	part, err := writer.CreatePart(header)
+	if err != nil {
+		return "", err
+	}
+	if _, err := io.Copy(part, file); err != nil {
+		return "", err
+	}
+	if err := writer.Close(); err != nil {
+		return "", err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", s.conf.UpHost, body)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		respBody, _ := io.ReadAll(resp.Body)
+		return "", fmt.Errorf("upload file failed, status: %d, body: %s", resp.StatusCode, string(respBody))
+	}
+	return s.MakeURL(key), nil
+}
+
 func (s *Storage) MakeURL(key string) string {
 	return "https://" + s.conf.Domain + "/" + key
 }
@@ -0,0 +1,144 @@
+package svr
+
+import (
+	"context"
+	"errors"
+	"mime"
+	"os"
+	"path/filepath"
+
+	"gorm.io/gorm"
+
+	"imgagent/bailian"
+	"imgagent/db"
+	"imgagent/pkg/logger"
+	"imgagent/storage"
+)
+
+// AssetCache 生成资源的内容寻址缓存
+// 相同请求内容（操作、模型、音色、规范化后的输入）命中缓存时直接复用已转存的资源，不再调用百炼
+type AssetCache struct {
+	db            db.IDataBase
+	stg           *storage.Storage
+	temp          string
+	bailianClient *bailian.Client
+}
+
+func newAssetCache(d db.IDataBase, stg *storage.Storage, temp string, bailianClient *bailian.Client) *AssetCache {
+	return &AssetCache{
+		db:            d,
+		stg:           stg,
+		temp:          temp,
+		bailianClient: bailianClient,
+	}
+}
+
+// GenerateTTS 生成语音，音频转存到存储后写入缓存
+func (a *AssetCache) GenerateTTS(ctx context.Context, text string) (string, error) {
+	log := logger.FromContext(ctx)
+
+	hash := a.bailianClient.TTSHash(text)
+	if asset, ok := a.lookup(ctx, hash); ok {
+		log.Infof("TTS cache hit, hash: %s, URL: %s", hash, asset.URL)
+		return asset.URL, nil
+	}
+
+	voiceURL, err := a.bailianClient.GenerateTTS(ctx, text)
+	if err != nil {
+		return "", err
+	}
+
+	// 百炼返回的音频 URL 有时效，转存成功后才缓存
+	storedURL, err := storeFile(ctx, a.stg, a.temp, voiceURL, "tts/"+hash)
+	if err != nil {
+		log.Warnf("Failed to store TTS audio, url: %s, err: %v", voiceURL, err)
+		return voiceURL, nil
+	}
+	a.save(ctx, &db.Asset{
+		Hash:      hash,
+		Operation: bailian.AssetOperationTTS,
+		Model:     bailian.TTSModel,
+		URL:       storedURL,
+	})
+	return storedURL, nil
+}
+
+// GenerateImage 生成场景图片及缩略图，key 为图片在存储中的对象名（不含扩展名）
+// 仅在配置了固定 seed 时缓存，否则每次都重新生成
+func (a *AssetCache) GenerateImage(ctx context.Context, sceneContent string, summary string, roles []bailian.RoleInfo, key string) (imageVariants, error) {
+	log := logger.FromContext(ctx)
+
+	hash := a.bailianClient.ImageHash(sceneContent, summary, roles)
+	if hash != "" {
+		if asset, ok := a.lookup(ctx, hash); ok {
+			log.Infof("Image cache hit, hash: %s, URL: %s", hash, asset.URL)
+			return imageVariants{
+				ImageURL:     asset.URL,
+				ThumbnailURL: asset.ThumbnailURL,
+				MediumURL:    asset.MediumURL,
+			}, nil
+		}
+	}
+
+	imageURL, err := a.bailianClient.GenerateImage(ctx, sceneContent, summary, roles)
+	if err != nil {
+		return imageVariants{}, err
+	}
+
+	// 可缓存的图片按内容哈希存储，供不同场景复用
+	if hash != "" {
+		key = "images/" + hash
+	}
+
+	variants := storeImage(ctx, a.stg, a.temp, imageURL, key)
+	// 后处理失败时 URL 仍为百炼临时地址，不缓存
+	if hash != "" && variants.ThumbnailURL != "" {
+		a.save(ctx, &db.Asset{
+			Hash:         hash,
+			Operation:    bailian.AssetOperationImage,
+			Model:        bailian.ImageModel,
+			URL:          variants.ImageURL,
+			ThumbnailURL: variants.ThumbnailURL,
+			MediumURL:    variants.MediumURL,
+		})
+	}
+	return variants, nil
+}
+
+func (a *AssetCache) lookup(ctx context.Context, hash string) (db.Asset, bool) {
+	log := logger.FromContext(ctx)
+
+	asset, err := a.db.GetAsset(ctx, hash)
+	if err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			log.Warnf("Failed to get asset, hash: %s, err: %v", hash, err)
+		}
+		return db.Asset{}, false
+	}
+	return asset, true
+}
+
+func (a *AssetCache) save(ctx context.Context, asset *db.Asset) {
+	log := logger.FromContext(ctx)
+
+	if err := a.db.CreateAsset(ctx, asset); err != nil {
+		// 缓存写入失败不影响生成结果
+		log.Warnf("Failed to create asset, hash: %s, err: %v", asset.Hash, err)
+	}
+}
+
+// storeFile 下载文件并上传到存储，key 为不含扩展名的对象名
+func storeFile(ctx context.Context, stg *storage.Storage, temp, fileURL, key string) (string, error) {
+	if stg == nil {
+		return "", errors.New("storage not configured")
+	}
+
+	filename, err := downloadFile(ctx, temp, fileURL)
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(filename)
+
+	ext := filepath.Ext(filename)
+	return stg.UploadFile(ctx, key+ext, filename, mime.TypeByExtension(ext))
+}
@@ -7,6 +7,7 @@ import (
 
 	"github.com/gin-gonic/gin"
 
+	"imgagent/db"
 	hutil "imgagent/httputil"
 	"imgagent/pkg/logger"
 )
@@ -77,6 +78,8 @@ func (s *Service) Auth() gin.HandlerFunc {
 			ui.SuperAdmin = true
 		}
 		c.Set(userInfoKey, ui)
+		// 数据库查询按用户过滤，超级管理员不过滤
+		c.Request = c.Request.WithContext(db.WithOwner(ctx, db.Owner{ID: ui.ID, SuperAdmin: ui.SuperAdmin}))
 
 		c.Next()
 	}
@@ -91,3 +94,11 @@ func (s *Service) NilAuth() gin.HandlerFunc {
 func GetUserInfo(c *gin.Context) UserInfo {
 	return c.MustGet(userInfoKey).(UserInfo)
 }
+
+// authorName 返回当前请求的用户名，未认证时为空，用于记录修订的修改人
+func authorName(c *gin.Context) string {
+	if v, ok := c.Get(userInfoKey); ok {
+		return v.(UserInfo).Name
+	}
+	return ""
+}
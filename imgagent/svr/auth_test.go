@@ -0,0 +1,122 @@
+package svr
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	"imgagent/api"
+	"imgagent/db"
+	"imgagent/proto"
+)
+
+func TestAuth(t *testing.T) {
+	service, cleanup := setupTestService(t)
+	defer cleanup()
+
+	router := service.RegisterRouter(os.Stdout)
+	get := func(auth string) int {
+		req := httptest.NewRequest(http.MethodGet, "/v1/documents", nil)
+		if auth != "" {
+			req.Header.Set("Authorization", auth)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		var resp proto.BaseResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		return resp.Code
+	}
+
+	assert.Equal(t, http.StatusUnauthorized, get(""))
+	assert.Equal(t, http.StatusUnauthorized, get(testAdminToken))
+	assert.Equal(t, http.StatusUnauthorized, get("Bearer missing"))
+	assert.Equal(t, http.StatusForbidden, get("Bearer "+testExpiredToken))
+	assert.Equal(t, http.StatusOK, get("Bearer "+testAdminToken))
+}
+
+func TestOwnerIsolation(t *testing.T) {
+	service, cleanup := setupTestService(t)
+	defer cleanup()
+	service.conf.Upload.setDefault()
+
+	router2 := testRouter(service, testUserToken)
+	router3 := testRouter(service, testOtherToken)
+	admin := testRouter(service, testAdminToken)
+
+	do := func(router http.Handler, method, path string, body any) proto.BaseResponse {
+		var b []byte
+		if body != nil {
+			b, _ = json.Marshal(body)
+		}
+		req := httptest.NewRequest(method, path, bytes.NewReader(b))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		var resp proto.BaseResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		return resp
+	}
+	listDocuments := func(router http.Handler) []api.Document {
+		resp := do(router, http.MethodGet, "/v1/documents", nil)
+		require.Equal(t, http.StatusOK, resp.Code)
+		var result api.ListDocumentsResult
+		b, _ := json.Marshal(resp.Data)
+		require.NoError(t, json.Unmarshal(b, &result))
+		return result.Documents
+	}
+
+	// 用户 2 的文档
+	ctx := db.WithOwner(t.Context(), db.Owner{ID: 2})
+	docID := db.MakeUUID()
+	doc, err := service.db.CreateDocument(ctx, docID, db.DocumentFile{}, &api.CreateDocumentArgs{Name: "骆驼祥子"})
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), doc.OwnerID)
+	require.NoError(t, service.db.CreateChapters(ctx, docID, []api.CreateChapterArgs{{Title: "第一章", Content: "祥子拉车"}}))
+	chapters, _, err := service.db.ListChapters(ctx, docID, nil)
+	require.NoError(t, err)
+	require.Len(t, chapters, 1)
+	assert.Equal(t, int64(2), chapters[0].OwnerID)
+	chapterPath := "/v1/documents/" + docID + "/chapters/" + chapters[0].ID
+
+	assert.Len(t, listDocuments(router2), 1)
+	assert.Equal(t, http.StatusOK, do(router2, http.MethodGet, "/v1/documents/"+docID, nil).Code)
+	assert.Equal(t, http.StatusOK, do(router2, http.MethodGet, chapterPath, nil).Code)
+
+	// 其他用户看不到、不能修改
+	assert.Empty(t, listDocuments(router3))
+	assert.Equal(t, ErrNoSuchDocumentCode, do(router3, http.MethodGet, "/v1/documents/"+docID, nil).Code)
+	assert.Equal(t, ErrNoSuchDocumentCode, do(router3, http.MethodGet, chapterPath, nil).Code)
+	assert.Equal(t, ErrNoSuchDocumentCode, do(router3, http.MethodGet, "/v1/documents/"+docID+"/volumes", nil).Code)
+	assert.Equal(t, ErrNoSuchDocumentCode, do(router3, http.MethodPut, chapterPath, api.UpdateChapterArgs{Content: "虎妞"}).Code)
+	assert.Equal(t, ErrNoSuchDocumentCode, do(router3, http.MethodDelete, "/v1/documents/"+docID, nil).Code)
+	resp := do(router3, http.MethodGet, "/v1/search?q=祥子", nil)
+	require.Equal(t, http.StatusOK, resp.Code)
+	assert.Empty(t, resp.Data.(map[string]any)["records"])
+	resp = do(router2, http.MethodGet, "/v1/search?q=祥子", nil)
+	require.Equal(t, http.StatusOK, resp.Code)
+	assert.Len(t, resp.Data.(map[string]any)["records"], 1)
+
+	// 超级管理员可以看到所有用户的文档
+	assert.Len(t, listDocuments(admin), 1)
+	assert.Equal(t, http.StatusOK, do(admin, http.MethodGet, chapterPath, nil).Code)
+
+	// 文档名称在同一用户下唯一，其他用户的上传不可见
+	initArgs := api.InitUploadArgs{Name: "骆驼祥子", Filename: "a.txt", Size: 10}
+	assert.Equal(t, ErrExistingDocumentCode, do(router2, http.MethodPost, "/v1/uploads", initArgs).Code)
+	resp = do(router3, http.MethodPost, "/v1/uploads", initArgs)
+	require.Equal(t, http.StatusOK, resp.Code)
+	uploadID := resp.Data.(map[string]any)["id"].(string)
+	assert.Equal(t, ErrNoSuchUploadCode, do(router2, http.MethodGet, "/v1/uploads/"+uploadID, nil).Code)
+	assert.Equal(t, http.StatusOK, do(router3, http.MethodGet, "/v1/uploads/"+uploadID, nil).Code)
+	_, err = service.db.CreateDocument(db.WithOwner(t.Context(), db.Owner{ID: 3}), db.MakeUUID(), db.DocumentFile{}, &api.CreateDocumentArgs{Name: "骆驼祥子"})
+	require.NoError(t, err)
+	_, err = service.db.CreateDocument(ctx, db.MakeUUID(), db.DocumentFile{}, &api.CreateDocumentArgs{Name: "骆驼祥子"})
+	assert.ErrorIs(t, err, gorm.ErrDuplicatedKey)
+}
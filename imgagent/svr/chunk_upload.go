@@ -0,0 +1,491 @@
+package svr
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"imgagent/api"
+	"imgagent/db"
+	hutil "imgagent/httputil"
+	"imgagent/pkg/logger"
+	"imgagent/proto"
+)
+
+const (
+	ErrNoSuchUploadCode     = 622
+	ErrUploadIncompleteCode = 623
+	ErrChecksumMismatchCode = 624
+	ErrNoSuchUpload         = "no such upload"
+	ErrUploadIncomplete     = "upload incomplete"
+	ErrChecksumMismatch     = "checksum mismatch"
+
+	// HeaderContentSHA256 分片内容的 sha256 十六进制值，上传分片时必须携带
+	HeaderContentSHA256 = "X-Content-Sha256"
+)
+
+const (
+	uploadDirName      = "uploads"
+	uploadMetaFile     = "meta.json"
+	uploadPartSuffix   = ".part"
+	uploadTempSuffix   = ".tmp"
+	maxUploadPartCount = 10000
+)
+
+var uploadIDRegexp = regexp.MustCompile(`^[0-9a-f]{32}$`)
+
+// uploadMeta 分片上传的元信息，初始化后不再修改；已上传的分片以目录中的分片文件为准
+type uploadMeta struct {
+	ID        string    `json:"id"`
+	OwnerID   int64     `json:"owner_id"`
+	Name      string    `json:"name"`
+	Filename  string    `json:"filename"`
+	Ext       string    `json:"ext"`
+	Size      int64     `json:"size"`
+	PartSize  int64     `json:"part_size"`
+	SHA256    string    `json:"sha256"`
+	Encoding  string    `json:"encoding"`
+	CreatedAt time.Time `json:"created_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+
+	ChapterPatterns []string `json:"chapter_patterns"`
+}
+
+func (m *uploadMeta) partCount() int {
+	return int((m.Size + m.PartSize - 1) / m.PartSize)
+}
+
+// partLength 返回分片的长度，只有最后一个分片可以小于 PartSize
+func (m *uploadMeta) partLength(partNumber int) int64 {
+	if partNumber < m.partCount() {
+		return m.PartSize
+	}
+	return m.Size - int64(m.partCount()-1)*m.PartSize
+}
+
+func (s *Service) uploadsDir() string {
+	return filepath.Join(s.conf.Temp, uploadDirName)
+}
+
+func (s *Service) uploadDir(uploadID string) string {
+	return filepath.Join(s.uploadsDir(), uploadID)
+}
+
+func partFilename(dir string, partNumber int) string {
+	return filepath.Join(dir, strconv.Itoa(partNumber)+uploadPartSuffix)
+}
+
+func readUploadMeta(dir string) (*uploadMeta, error) {
+	b, err := os.ReadFile(filepath.Join(dir, uploadMetaFile))
+	if err != nil {
+		return nil, err
+	}
+	var meta uploadMeta
+	if err := json.Unmarshal(b, &meta); err != nil {
+		return nil, err
+	}
+	return &meta, nil
+}
+
+// loadUpload 读取分片上传的元信息，过期的上传会被删除，其他用户的上传视为不存在
+func (s *Service) loadUpload(ctx context.Context, uploadID string) (*uploadMeta, *proto.ApiError) {
+	if !uploadIDRegexp.MatchString(uploadID) {
+		return nil, hutil.NewApiError(http.StatusBadRequest, "invalid upload id")
+	}
+	dir := s.uploadDir(uploadID)
+	meta, err := readUploadMeta(dir)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, hutil.NewApiError(ErrNoSuchUploadCode, ErrNoSuchUpload)
+		}
+		return nil, hutil.NewApiError(hutil.ErrServerInternalCode, "read upload failed")
+	}
+	if time.Now().After(meta.ExpiresAt) {
+		os.RemoveAll(dir)
+		return nil, hutil.NewApiError(ErrNoSuchUploadCode, ErrNoSuchUpload)
+	}
+	if owner, ok := db.OwnerFromContext(ctx); ok && !owner.SuperAdmin && owner.ID != meta.OwnerID {
+		return nil, hutil.NewApiError(ErrNoSuchUploadCode, ErrNoSuchUpload)
+	}
+	return meta, nil
+}
+
+// uploadedParts 返回已上传的分片序号，按升序排列
+func uploadedParts(dir string) ([]int, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	parts := []int{}
+	for _, entry := range entries {
+		name, ok := strings.CutSuffix(entry.Name(), uploadPartSuffix)
+		if !ok {
+			continue
+		}
+		n, err := strconv.Atoi(name)
+		if err != nil {
+			continue
+		}
+		parts = append(parts, n)
+	}
+	sort.Ints(parts)
+	return parts, nil
+}
+
+func makeUpload(meta *uploadMeta, parts []int) api.Upload {
+	return api.Upload{
+		ID:            meta.ID,
+		Name:          meta.Name,
+		Filename:      meta.Filename,
+		Size:          meta.Size,
+		PartSize:      meta.PartSize,
+		PartCount:     meta.partCount(),
+		UploadedParts: parts,
+		ExpiresAt:     meta.ExpiresAt.Format(time.DateTime),
+	}
+}
+
+func (s *Service) HandleInitUpload(c *gin.Context) {
+	ctx := c.Request.Context()
+	log := logger.FromGinContext(c)
+
+	var args api.InitUploadArgs
+	if err := c.ShouldBindJSON(&args); err != nil {
+		log.Errorf("Invalid request body, err: %v", err)
+		hutil.AbortError(c, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	if args.Size > s.conf.Upload.MaxChunkedSizeMB<<20 {
+		hutil.AbortError(c, ErrFileTooLargeCode, "file exceeds maximum upload size")
+		return
+	}
+	ext, apiErr := fileExt(args.Filename)
+	if apiErr != nil {
+		hutil.AbortErr(c, apiErr)
+		return
+	}
+	encoding, apiErr := uploadEncoding(args.Encoding)
+	if apiErr != nil {
+		hutil.AbortErr(c, apiErr)
+		return
+	}
+	if apiErr := checkChapterPatterns(args.ChapterPatterns); apiErr != nil {
+		hutil.AbortErr(c, apiErr)
+		return
+	}
+	sum := strings.ToLower(args.SHA256)
+	if sum != "" {
+		if b, err := hex.DecodeString(sum); err != nil || len(b) != sha256.Size {
+			hutil.AbortError(c, http.StatusBadRequest, "invalid sha256")
+			return
+		}
+	}
+
+	partSize := s.conf.Upload.PartSizeMB << 20
+	if (args.Size+partSize-1)/partSize > maxUploadPartCount {
+		hutil.AbortError(c, ErrFileTooLargeCode, "file exceeds maximum part count")
+		return
+	}
+
+	log.Infof("Init upload, name: %s, file: %s, size: %d", args.Name, args.Filename, args.Size)
+
+	if apiErr := s.checkDocumentName(ctx, args.Name); apiErr != nil {
+		hutil.AbortErr(c, apiErr)
+		return
+	}
+
+	owner, _ := db.OwnerFromContext(ctx)
+	now := time.Now()
+	meta := &uploadMeta{
+		ID:        db.MakeUUID(),
+		OwnerID:   owner.ID,
+		Name:      args.Name,
+		Filename:  args.Filename,
+		Ext:       ext,
+		Size:      args.Size,
+		PartSize:  partSize,
+		SHA256:    sum,
+		Encoding:  encoding,
+		CreatedAt: now,
+		ExpiresAt: now.Add(time.Duration(s.conf.Upload.ExpireHours) * time.Hour),
+
+		ChapterPatterns: args.ChapterPatterns,
+	}
+	dir := s.uploadDir(meta.ID)
+	if err := os.MkdirAll(dir, 0776); err != nil {
+		log.Errorf("Failed to mkdir, err: %v", err)
+		hutil.AbortError(c, hutil.ErrServerInternalCode, "init upload failed")
+		return
+	}
+	b, _ := json.Marshal(meta)
+	if err := os.WriteFile(filepath.Join(dir, uploadMetaFile), b, 0666); err != nil {
+		log.Errorf("Failed to write upload meta, err: %v", err)
+		os.RemoveAll(dir)
+		hutil.AbortError(c, hutil.ErrServerInternalCode, "init upload failed")
+		return
+	}
+
+	hutil.WriteData(c, makeUpload(meta, []int{}))
+}
+
+func (s *Service) HandleGetUpload(c *gin.Context) {
+	log := logger.FromGinContext(c)
+
+	meta, apiErr := s.loadUpload(c.Request.Context(), c.Param("upload_id"))
+	if apiErr != nil {
+		hutil.AbortErr(c, apiErr)
+		return
+	}
+	parts, err := uploadedParts(s.uploadDir(meta.ID))
+	if err != nil {
+		log.Errorf("Failed to list upload parts, id: %s, err: %v", meta.ID, err)
+		hutil.AbortError(c, hutil.ErrServerInternalCode, "get upload failed")
+		return
+	}
+	hutil.WriteData(c, makeUpload(meta, parts))
+}
+
+func (s *Service) HandleUploadPart(c *gin.Context) {
+	log := logger.FromGinContext(c)
+
+	meta, apiErr := s.loadUpload(c.Request.Context(), c.Param("upload_id"))
+	if apiErr != nil {
+		hutil.AbortErr(c, apiErr)
+		return
+	}
+	partNumber, err := strconv.Atoi(c.Param("part_number"))
+	if err != nil || partNumber < 1 || partNumber > meta.partCount() {
+		hutil.AbortError(c, http.StatusBadRequest, "invalid part number")
+		return
+	}
+	expectSum := strings.ToLower(c.GetHeader(HeaderContentSHA256))
+	if expectSum == "" {
+		hutil.AbortError(c, http.StatusBadRequest, HeaderContentSHA256+" header is required")
+		return
+	}
+
+	length := meta.partLength(partNumber)
+	if c.Request.ContentLength > length {
+		hutil.AbortError(c, http.StatusBadRequest, "part size mismatch")
+		return
+	}
+
+	// 先写临时文件，校验通过后再重命名，避免留下不完整的分片
+	dir := s.uploadDir(meta.ID)
+	filename := partFilename(dir, partNumber)
+	tmpFile, err := os.CreateTemp(dir, strconv.Itoa(partNumber)+"-*"+uploadTempSuffix)
+	if err != nil {
+		log.Errorf("Failed to create part file, err: %v", err)
+		hutil.AbortError(c, hutil.ErrServerInternalCode, "save part failed")
+		return
+	}
+	defer os.Remove(tmpFile.Name())
+
+	h := sha256.New()
+	n, err := io.Copy(io.MultiWriter(tmpFile, h), io.LimitReader(c.Request.Body, length+1))
+	tmpFile.Close()
+	if err != nil {
+		log.Errorf("Failed to save part, id: %s, part: %d, err: %v", meta.ID, partNumber, err)
+		hutil.AbortError(c, hutil.ErrServerInternalCode, "save part failed")
+		return
+	}
+	if n != length {
+		log.Warnf("Part size mismatch, id: %s, part: %d, expect: %d, actual: %d", meta.ID, partNumber, length, n)
+		hutil.AbortError(c, http.StatusBadRequest, "part size mismatch")
+		return
+	}
+	sum := hex.EncodeToString(h.Sum(nil))
+	if sum != expectSum {
+		log.Warnf("Part checksum mismatch, id: %s, part: %d", meta.ID, partNumber)
+		hutil.AbortError(c, ErrChecksumMismatchCode, ErrChecksumMismatch)
+		return
+	}
+	if err := os.Rename(tmpFile.Name(), filename); err != nil {
+		log.Errorf("Failed to rename part file, err: %v", err)
+		hutil.AbortError(c, hutil.ErrServerInternalCode, "save part failed")
+		return
+	}
+
+	hutil.WriteData(c, api.UploadPartResult{
+		PartNumber: partNumber,
+		Size:       n,
+		SHA256:     sum,
+	})
+}
+
+func (s *Service) HandleCompleteUpload(c *gin.Context) {
+	ctx := c.Request.Context()
+	log := logger.FromGinContext(c)
+
+	meta, apiErr := s.loadUpload(ctx, c.Param("upload_id"))
+	if apiErr != nil {
+		hutil.AbortErr(c, apiErr)
+		return
+	}
+	dir := s.uploadDir(meta.ID)
+	parts, err := uploadedParts(dir)
+	if err != nil {
+		log.Errorf("Failed to list upload parts, id: %s, err: %v", meta.ID, err)
+		hutil.AbortError(c, hutil.ErrServerInternalCode, "complete upload failed")
+		return
+	}
+	if len(parts) != meta.partCount() {
+		hutil.AbortError(c, ErrUploadIncompleteCode, ErrUploadIncomplete)
+		return
+	}
+
+	log.Infof("Complete upload, id: %s, name: %s, file: %s", meta.ID, meta.Name, meta.Filename)
+
+	if apiErr := s.checkDocumentName(ctx, meta.Name); apiErr != nil {
+		hutil.AbortErr(c, apiErr)
+		return
+	}
+
+	docID := db.MakeUUID()
+	originFilename := s.originFilename(docID, meta.Ext)
+	if apiErr := assembleUpload(meta, dir, originFilename); apiErr != nil {
+		log.Errorf("Failed to assemble upload, id: %s, err: %v", meta.ID, apiErr)
+		os.Remove(originFilename)
+		hutil.AbortErr(c, apiErr)
+		return
+	}
+
+	args := &api.CreateDocumentArgs{Name: meta.Name}
+	opts := createDocumentOptions{
+		OriginFile:      originFilename,
+		Encoding:        meta.Encoding,
+		ChapterPatterns: meta.ChapterPatterns,
+	}
+	doc, apiErr := s.createDocument(ctx, docID, meta.Ext, args, opts)
+	if apiErr != nil {
+		// 保留分片，允许客户端重试
+		hutil.AbortErr(c, apiErr)
+		return
+	}
+	os.RemoveAll(dir)
+
+	hutil.WriteData(c, makeDocument(doc))
+}
+
+// assembleUpload 按序合并分片到目标文件，并校验总大小和整个文件的 sha256
+func assembleUpload(meta *uploadMeta, dir, filename string) *proto.ApiError {
+	f, err := os.Create(filename)
+	if err != nil {
+		return hutil.NewApiError(hutil.ErrServerInternalCode, "save file failed")
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	w := io.MultiWriter(f, h)
+	var total int64
+	for i := 1; i <= meta.partCount(); i++ {
+		n, err := copyFile(w, partFilename(dir, i))
+		if err != nil {
+			return hutil.NewApiError(hutil.ErrServerInternalCode, "save file failed")
+		}
+		total += n
+	}
+	if total != meta.Size {
+		return hutil.NewApiError(ErrUploadIncompleteCode, ErrUploadIncomplete)
+	}
+	if meta.SHA256 != "" && hex.EncodeToString(h.Sum(nil)) != meta.SHA256 {
+		return hutil.NewApiError(ErrChecksumMismatchCode, ErrChecksumMismatch)
+	}
+	if err := f.Close(); err != nil {
+		return hutil.NewApiError(hutil.ErrServerInternalCode, "save file failed")
+	}
+	return nil
+}
+
+func copyFile(w io.Writer, filename string) (int64, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	return io.Copy(w, f)
+}
+
+func (s *Service) HandleAbortUpload(c *gin.Context) {
+	log := logger.FromGinContext(c)
+
+	meta, apiErr := s.loadUpload(c.Request.Context(), c.Param("upload_id"))
+	if apiErr != nil {
+		hutil.AbortErr(c, apiErr)
+		return
+	}
+
+	log.Infof("Abort upload, id: %s", meta.ID)
+	if err := os.RemoveAll(s.uploadDir(meta.ID)); err != nil {
+		log.Errorf("Failed to remove upload, id: %s, err: %v", meta.ID, err)
+		hutil.AbortError(c, hutil.ErrServerInternalCode, "abort upload failed")
+		return
+	}
+	hutil.WriteData(c, nil)
+}
+
+func (s *Service) loopCleanupUploads() {
+	ticker := time.NewTicker(time.Second * time.Duration(s.conf.Upload.CleanupIntervalSecs))
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			ctx := logger.NewContext(fmt.Sprintf("CleanupUploads-%d", time.Now().Unix()))
+			s.CleanupExpiredUploads(ctx)
+		case <-s.close:
+			return
+		}
+	}
+}
+
+// CleanupExpiredUploads 删除过期未完成的分片上传
+func (s *Service) CleanupExpiredUploads(ctx context.Context) {
+	log := logger.FromContext(ctx)
+
+	entries, err := os.ReadDir(s.uploadsDir())
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			log.Errorf("Failed to read uploads dir, err: %v", err)
+		}
+		return
+	}
+	expire := time.Duration(s.conf.Upload.ExpireHours) * time.Hour
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		dir := s.uploadDir(entry.Name())
+		meta, err := readUploadMeta(dir)
+		if err == nil {
+			if time.Now().Before(meta.ExpiresAt) {
+				continue
+			}
+		} else {
+			// 元信息缺失或损坏的目录按修改时间判断是否过期
+			info, err := entry.Info()
+			if err != nil || time.Since(info.ModTime()) < expire {
+				continue
+			}
+		}
+		if err := os.RemoveAll(dir); err != nil {
+			log.Errorf("Failed to remove upload, dir: %s, err: %v", dir, err)
+			continue
+		}
+		log.Infof("Removed expired upload, id: %s", entry.Name())
+	}
+}
@@ -0,0 +1,184 @@
+package svr
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"imgagent/api"
+	"imgagent/proto"
+)
+
+func sha256Hex(b []byte) string {
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
+
+func TestChunkedUpload(t *testing.T) {
+	service, cleanup := setupTestService(t)
+	defer cleanup()
+	service.conf.Upload = UploadConfig{PartSizeMB: 1, MaxChunkedSizeMB: 4}
+	service.conf.Upload.setDefault()
+
+	router := testRouter(service, testAdminToken)
+
+	do := func(method, path string, body io.Reader, header map[string]string) proto.BaseResponse {
+		req := httptest.NewRequest(method, path, body)
+		for k, v := range header {
+			req.Header.Set(k, v)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		var resp proto.BaseResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		return resp
+	}
+	initUpload := func(args api.InitUploadArgs) (proto.BaseResponse, api.Upload) {
+		b, _ := json.Marshal(args)
+		resp := do(http.MethodPost, "/v1/uploads", bytes.NewReader(b), map[string]string{"Content-Type": "application/json"})
+		var upload api.Upload
+		if resp.Code == http.StatusOK {
+			b, _ = json.Marshal(resp.Data)
+			require.NoError(t, json.Unmarshal(b, &upload))
+		}
+		return resp, upload
+	}
+	uploadPart := func(uploadID string, partNumber int, data []byte, sum string) proto.BaseResponse {
+		path := fmt.Sprintf("/v1/uploads/%s/parts/%d", uploadID, partNumber)
+		return do(http.MethodPut, path, bytes.NewReader(data), map[string]string{HeaderContentSHA256: sum})
+	}
+	getUpload := func(uploadID string) (proto.BaseResponse, api.Upload) {
+		resp := do(http.MethodGet, "/v1/uploads/"+uploadID, nil, nil)
+		var upload api.Upload
+		if resp.Code == http.StatusOK {
+			b, _ := json.Marshal(resp.Data)
+			require.NoError(t, json.Unmarshal(b, &upload))
+		}
+		return resp, upload
+	}
+
+	// 1.5MB 文本，分两片
+	content := bytes.Repeat([]byte("第一章 开始\n"), 1<<20*3/2/16)
+	part1 := content[:1<<20]
+	part2 := content[1<<20:]
+
+	t.Run("初始化参数校验", func(t *testing.T) {
+		resp, _ := initUpload(api.InitUploadArgs{Name: "大文件", Filename: "big.txt", Size: 5 << 20})
+		assert.Equal(t, ErrFileTooLargeCode, resp.Code)
+
+		resp, _ = initUpload(api.InitUploadArgs{Name: "可执行文件", Filename: "run.exe", Size: 100})
+		assert.Equal(t, ErrUnsupportedFileCode, resp.Code)
+
+		resp, _ = initUpload(api.InitUploadArgs{Name: "错误校验和", Filename: "a.txt", Size: 100, SHA256: "xyz"})
+		assert.Equal(t, http.StatusBadRequest, resp.Code)
+	})
+
+	t.Run("分片上传与断点续传", func(t *testing.T) {
+		resp, upload := initUpload(api.InitUploadArgs{
+			Name:     "分片文档",
+			Filename: "novel.txt",
+			Size:     int64(len(content)),
+			SHA256:   sha256Hex(append([]byte("x"), content...)),
+		})
+		require.Equal(t, http.StatusOK, resp.Code)
+		assert.Equal(t, 2, upload.PartCount)
+		assert.Equal(t, int64(1<<20), upload.PartSize)
+		assert.Empty(t, upload.UploadedParts)
+
+		// 缺少校验和
+		resp = uploadPart(upload.ID, 1, part1, "")
+		assert.Equal(t, http.StatusBadRequest, resp.Code)
+
+		// 校验和不一致
+		resp = uploadPart(upload.ID, 1, part1, sha256Hex(part2))
+		assert.Equal(t, ErrChecksumMismatchCode, resp.Code)
+
+		// 分片大小不一致
+		resp = uploadPart(upload.ID, 1, part2, sha256Hex(part2))
+		assert.Equal(t, http.StatusBadRequest, resp.Code)
+
+		// 分片序号越界
+		resp = uploadPart(upload.ID, 3, part2, sha256Hex(part2))
+		assert.Equal(t, http.StatusBadRequest, resp.Code)
+
+		resp = uploadPart(upload.ID, 1, part1, sha256Hex(part1))
+		require.Equal(t, http.StatusOK, resp.Code)
+
+		// 未上传完成
+		resp = do(http.MethodPost, "/v1/uploads/"+upload.ID+"/complete", nil, nil)
+		assert.Equal(t, ErrUploadIncompleteCode, resp.Code)
+
+		// 查询已上传的分片后续传
+		resp, upload = getUpload(upload.ID)
+		require.Equal(t, http.StatusOK, resp.Code)
+		assert.Equal(t, []int{1}, upload.UploadedParts)
+
+		resp = uploadPart(upload.ID, 2, part2, sha256Hex(part2))
+		require.Equal(t, http.StatusOK, resp.Code)
+
+		// 整个文件的校验和不一致，分片保留
+		resp = do(http.MethodPost, "/v1/uploads/"+upload.ID+"/complete", nil, nil)
+		assert.Equal(t, ErrChecksumMismatchCode, resp.Code)
+		resp, upload = getUpload(upload.ID)
+		require.Equal(t, http.StatusOK, resp.Code)
+		assert.Equal(t, []int{1, 2}, upload.UploadedParts)
+
+		// 取消上传
+		resp = do(http.MethodDelete, "/v1/uploads/"+upload.ID, nil, nil)
+		assert.Equal(t, http.StatusOK, resp.Code)
+		resp, _ = getUpload(upload.ID)
+		assert.Equal(t, ErrNoSuchUploadCode, resp.Code)
+	})
+
+	t.Run("完成时校验文件内容", func(t *testing.T) {
+		data := []byte("这不是 PDF 文件")
+		resp, upload := initUpload(api.InitUploadArgs{Name: "伪造文档", Filename: "fake.pdf", Size: int64(len(data))})
+		require.Equal(t, http.StatusOK, resp.Code)
+		resp = uploadPart(upload.ID, 1, data, sha256Hex(data))
+		require.Equal(t, http.StatusOK, resp.Code)
+
+		resp = do(http.MethodPost, "/v1/uploads/"+upload.ID+"/complete", nil, nil)
+		assert.Equal(t, ErrUnsupportedFileCode, resp.Code)
+	})
+
+	t.Run("非法上传 ID", func(t *testing.T) {
+		resp, _ := getUpload("..")
+		assert.Equal(t, http.StatusBadRequest, resp.Code)
+		resp, _ = getUpload("0123456789abcdef0123456789abcdef")
+		assert.Equal(t, ErrNoSuchUploadCode, resp.Code)
+	})
+
+	t.Run("清理过期上传", func(t *testing.T) {
+		resp, upload := initUpload(api.InitUploadArgs{Name: "过期文档", Filename: "a.txt", Size: 10})
+		require.Equal(t, http.StatusOK, resp.Code)
+
+		service.CleanupExpiredUploads(context.Background())
+		_, err := os.Stat(service.uploadDir(upload.ID))
+		assert.NoError(t, err)
+
+		// 修改过期时间
+		meta, err := readUploadMeta(service.uploadDir(upload.ID))
+		require.NoError(t, err)
+		meta.ExpiresAt = time.Now().Add(-time.Minute)
+		b, _ := json.Marshal(meta)
+		require.NoError(t, os.WriteFile(service.uploadDir(upload.ID)+"/"+uploadMetaFile, b, 0666))
+
+		service.CleanupExpiredUploads(context.Background())
+		_, err = os.Stat(service.uploadDir(upload.ID))
+		assert.True(t, os.IsNotExist(err))
+	})
+}
@@ -2,25 +2,43 @@ package svr
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
 	"time"
 
 	"imgagent/bailian"
 	"imgagent/db"
 	"imgagent/pkg/logger"
+	"imgagent/spliter"
+	"imgagent/storage"
 )
 
 type DocumentConfigEx struct {
 	config DocumentConfig
 
-	db db.IDataBase
+	db       db.IDataBase
+	stg      *storage.Storage
+	temp     string
+	assets   *AssetCache
+	embedder *Embedder           // 未启用语义检索时为 nil
+	ocr      spliter.OCRProvider // 未配置 OCR 时为 nil
 }
 
 type DocumentConfig struct {
 	Enable                     bool `json:"enable"`
+	HandleOCRIntervalSecs      int  `json:"handle_ocr_interval_secs"`
 	HandleRoleIntervalSecs     int  `json:"handle_role_interval_secs"`
 	HandleSceneIntervalSecs    int  `json:"handle_scene_interval_secs"`
 	HandleImageGenIntervalSecs int  `json:"handle_image_gen_interval_secs"`
+	CleanupFileIntervalSecs    int  `json:"cleanup_file_interval_secs"`
+	PurgeTrashIntervalSecs     int  `json:"purge_trash_interval_secs"`
+	TrashRetentionDays         int  `json:"trash_retention_days"` // 回收站保留天数，超过后彻底删除
+
+	Chunking map[string]ChunkConfig `json:"chunking"` // 按文档类型（扩展名，如 txt、pdf）配置分块，default 为各类型的默认值
 }
 
 type DocumentMgr struct {
@@ -29,10 +47,17 @@ type DocumentMgr struct {
 	close         chan bool
 	db            db.IDataBase
 	bailianClient *bailian.Client
+
+	mu      sync.Mutex
+	seq     uint64
+	running map[string]map[uint64]context.CancelFunc // 正在处理的文档，删除文档时取消
 }
 
 func newDocumentMgr(confEx DocumentConfigEx, bailianClient *bailian.Client) (*DocumentMgr, error) {
 	// 设置默认值
+	if confEx.config.HandleOCRIntervalSecs == 0 {
+		confEx.config.HandleOCRIntervalSecs = 30
+	}
 	if confEx.config.HandleRoleIntervalSecs == 0 {
 		confEx.config.HandleRoleIntervalSecs = 30
 	}
@@ -42,19 +67,98 @@ func newDocumentMgr(confEx DocumentConfigEx, bailianClient *bailian.Client) (*Do
 	if confEx.config.HandleImageGenIntervalSecs == 0 {
 		confEx.config.HandleImageGenIntervalSecs = 30
 	}
+	if confEx.config.CleanupFileIntervalSecs == 0 {
+		confEx.config.CleanupFileIntervalSecs = 3600
+	}
+	if confEx.config.PurgeTrashIntervalSecs == 0 {
+		confEx.config.PurgeTrashIntervalSecs = 3600
+	}
+	if confEx.config.TrashRetentionDays == 0 {
+		confEx.config.TrashRetentionDays = 30
+	}
 
 	return &DocumentMgr{
 		DocumentConfigEx: confEx,
 		db:               confEx.db,
 		bailianClient:    bailianClient,
 		close:            make(chan bool),
+		running:          make(map[string]map[uint64]context.CancelFunc),
 	}, nil
 }
 
+// runDocument 使用可取消的 ctx 处理单个文档，CancelDocument 时取消
+// 开始处理前重新检查文档是否存在，跳过列出后已被删除的文档
+func (m *DocumentMgr) runDocument(ctx context.Context, docID string, fn func(ctx context.Context)) {
+	ctx, cancel := context.WithCancel(ctx)
+	m.mu.Lock()
+	m.seq++
+	id := m.seq
+	if m.running[docID] == nil {
+		m.running[docID] = make(map[uint64]context.CancelFunc)
+	}
+	m.running[docID][id] = cancel
+	m.mu.Unlock()
+	defer func() {
+		m.mu.Lock()
+		delete(m.running[docID], id)
+		if len(m.running[docID]) == 0 {
+			delete(m.running, docID)
+		}
+		m.mu.Unlock()
+		cancel()
+	}()
+
+	if _, err := m.db.GetDocument(ctx, docID); err != nil {
+		logger.FromContext(ctx).Warnf("Skip document, docID: %s, err: %v", docID, err)
+		return
+	}
+	fn(ctx)
+}
+
+// CancelDocument 取消正在处理该文档的任务，m 为 nil（未启用后台任务）时不做处理
+func (m *DocumentMgr) CancelDocument(docID string) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, cancel := range m.running[docID] {
+		cancel()
+	}
+}
+
 func (m *DocumentMgr) Run() {
+	if m.ocr != nil {
+		go m.loopHandleOCRTasks()
+	}
 	go m.loopHandleDocumentRoleTasks()
 	go m.loopHandleDocumentScenceTasks()
 	go m.loopHandleImageGenTasks()
+	go m.loopCleanupFileTasks()
+	go m.loopPurgeTrashTasks()
+	if m.embedder != nil {
+		go m.loopHandleEmbeddingTasks()
+	}
+}
+
+// Close 停止 Run 启动的后台任务
+func (m *DocumentMgr) Close() {
+	close(m.close)
+}
+
+func (m *DocumentMgr) loopHandleOCRTasks() {
+	ticker := time.NewTicker(time.Second * time.Duration(m.config.HandleOCRIntervalSecs))
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			ctx := logger.NewContext(fmt.Sprintf("HandleOCRTasks-%d", time.Now().Unix()))
+			m.HandleOCRTasks(ctx)
+		case <-m.close:
+			return
+		}
+	}
 }
 
 func (m *DocumentMgr) loopHandleDocumentRoleTasks() {
@@ -102,6 +206,104 @@ func (m *DocumentMgr) loopHandleImageGenTasks() {
 	}
 }
 
+func (m *DocumentMgr) loopCleanupFileTasks() {
+	ticker := time.NewTicker(time.Second * time.Duration(m.config.CleanupFileIntervalSecs))
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			ctx := logger.NewContext(fmt.Sprintf("CleanupFileTasks-%d", time.Now().Unix()))
+			m.CleanupFileTasks(ctx)
+		case <-m.close:
+			return
+		}
+	}
+}
+
+func (m *DocumentMgr) loopPurgeTrashTasks() {
+	ticker := time.NewTicker(time.Second * time.Duration(m.config.PurgeTrashIntervalSecs))
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			ctx := logger.NewContext(fmt.Sprintf("PurgeTrashTasks-%d", time.Now().Unix()))
+			m.PurgeTrashTasks(ctx)
+		case <-m.close:
+			return
+		}
+	}
+}
+
+func (m *DocumentMgr) loopHandleEmbeddingTasks() {
+	ticker := time.NewTicker(time.Second * time.Duration(m.embedder.conf.IntervalSecs))
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			ctx := logger.NewContext(fmt.Sprintf("HandleEmbeddingTasks-%d", time.Now().Unix()))
+			m.embedder.HandleEmbeddingTasks(ctx)
+		case <-m.close:
+			return
+		}
+	}
+}
+
+// HandleOCRTasks 识别扫描版 PDF 的文字并分割章节，完成后文档进入 chapterReady
+func (m *DocumentMgr) HandleOCRTasks(ctx context.Context) {
+	log := logger.FromContext(ctx)
+
+	docs, err := m.db.ListOCRPendingDocuments(ctx)
+	if err != nil {
+		log.Errorf("Failed to list ocrPending documents, err: %v", err)
+		return
+	}
+
+	for _, doc := range docs {
+		m.runDocument(ctx, doc.ID, func(ctx context.Context) {
+			if err := m.HandleDocumentOCR(ctx, doc); err != nil {
+				log.Errorf("Failed to handle document ocr, doc: %s, err: %v", doc.ID, err)
+			}
+		})
+	}
+}
+
+// HandleDocumentOCR 识别 PDF 中没有文字层的页面后分割章节，识别出的纯文本保存为 txt 替换原始文件，由后续任务上传百炼
+func (m *DocumentMgr) HandleDocumentOCR(ctx context.Context, doc db.Document) error {
+	log := logger.FromContext(ctx)
+	log.Infof("Handling document ocr, docID: %s, filename: %s", doc.ID, doc.OriginFile)
+
+	ext := strings.TrimPrefix(filepath.Ext(doc.OriginFile), ".")
+	opt := m.config.splitOption(ext, doc.ChapterPatterns)
+	opt.OCR = m.ocr
+	chunks, err := spliter.Split(ctx, doc.OriginFile, opt)
+	if err != nil {
+		return err
+	}
+
+	texts := make([]string, 0, len(chunks))
+	for _, chunk := range chunks {
+		texts = append(texts, chunk.Content)
+	}
+	textFilename := strings.TrimSuffix(doc.OriginFile, filepath.Ext(doc.OriginFile)) + ".txt"
+	if err := os.WriteFile(textFilename, []byte(strings.Join(texts, "\n\n")), 0666); err != nil {
+		return err
+	}
+
+	volumes, chapters := makeVolumeChapters(doc.ID, chunks)
+	if err := m.db.CompleteDocumentOCR(ctx, doc.ID, textFilename, volumes, chapters); err != nil {
+		os.Remove(textFilename)
+		return err
+	}
+	if err := os.Remove(doc.OriginFile); err != nil && !os.IsNotExist(err) {
+		log.Warnf("Failed to remove origin file, doc: %s, filename: %s, err: %v", doc.ID, doc.OriginFile, err)
+	}
+	log.Infof("Document ocr finished, docID: %s, chapters: %d", doc.ID, len(chapters))
+	return nil
+}
+
 func (m *DocumentMgr) HandleDocumentRoleTasks(ctx context.Context) {
 	log := logger.FromContext(ctx)
 
@@ -112,16 +314,17 @@ func (m *DocumentMgr) HandleDocumentRoleTasks(ctx context.Context) {
 	}
 
 	for _, doc := range docs {
-		err = m.HandleDocumentRole(ctx, doc)
-		if err != nil {
-			log.Errorf("Failed to handle document role, doc: %v, err: %v", doc, err)
-			continue
-		}
-		err = m.db.UpdateDocumentStatus(ctx, doc.ID, db.DocumentStatusRoleReady)
-		if err != nil {
-			log.Errorf("Failed to update document status, err: %v", err)
-			continue
-		}
+		m.runDocument(ctx, doc.ID, func(ctx context.Context) {
+			err := m.HandleDocumentRole(ctx, doc)
+			if err != nil {
+				log.Errorf("Failed to handle document role, doc: %v, err: %v", doc, err)
+				return
+			}
+			err = m.db.UpdateDocumentStatus(ctx, doc.ID, db.DocumentStatusRoleReady)
+			if err != nil {
+				log.Errorf("Failed to update document status, err: %v", err)
+			}
+		})
 	}
 }
 
@@ -132,7 +335,12 @@ func (m *DocumentMgr) HandleDocumentRole(ctx context.Context, doc db.Document) e
 	// 1. 先提取摘要
 	if doc.Summary == "" {
 		log.Infof("Extracting summary, docID: %s", doc.ID)
-		summary, err := m.bailianClient.ExtractSummary(ctx, doc.FileID)
+		var summary string
+		err := m.withFileID(ctx, &doc, func(fileID string) error {
+			var err error
+			summary, err = m.bailianClient.ExtractSummary(ctx, fileID)
+			return err
+		})
 		if err != nil {
 			log.Errorf("Failed to extract summary, doc: %s, err: %v", doc.ID, err)
 			return err
@@ -159,12 +367,16 @@ func (m *DocumentMgr) HandleDocumentRole(ctx context.Context, doc db.Document) e
 				log.Errorf("Failed to generate cover image, doc: %s, err: %v", doc.ID, err)
 				// 封面生成失败不影响后续流程，记录日志后继续
 			} else {
-				err = m.db.UpdateDocumentSummaryImageURL(ctx, doc.ID, coverImageURL)
+				variants := storeImage(ctx, m.stg, m.temp, coverImageURL, coverImageKey(doc.ID))
+				err = m.db.UpdateDocumentSummaryImageURL(ctx, doc.ID, variants.ImageURL)
+				if err == nil {
+					err = m.db.UpdateDocumentSummaryImageVariants(ctx, doc.ID, variants.ThumbnailURL, variants.MediumURL)
+				}
 				if err != nil {
 					log.Errorf("Failed to update document summary image URL, doc: %s, err: %v", doc.ID, err)
 					// 更新失败不影响后续流程
 				} else {
-					log.Infof("Cover image generated and saved for doc: %s, URL: %s", doc.ID, coverImageURL)
+					log.Infof("Cover image generated and saved for doc: %s, URL: %s", doc.ID, variants.ImageURL)
 				}
 			}
 		}
@@ -173,7 +385,7 @@ func (m *DocumentMgr) HandleDocumentRole(ctx context.Context, doc db.Document) e
 	}
 
 	// 2. 检查是否已有角色
-	existingRoles, err := m.db.ListRolesByDocument(ctx, doc.ID)
+	existingRoles, _, err := m.db.ListRolesByDocument(ctx, doc.ID, nil)
 	if err != nil {
 		log.Errorf("Failed to list existing roles, doc: %s, err: %v", doc.ID, err)
 		return err
@@ -184,36 +396,54 @@ func (m *DocumentMgr) HandleDocumentRole(ctx context.Context, doc db.Document) e
 		return nil
 	}
 
-	// 3. 提取角色（传入摘要以获得更好的结果）
-	log.Infof("Extracting roles, docID: %s", doc.ID)
-	roles, err := m.bailianClient.ExtractRoles(ctx, doc.FileID, doc.Summary)
+	// 3. 提取角色（传入摘要以获得更好的结果），分卷的文档按卷分别提取，角色记录所在的卷
+	volumes, err := m.db.ListVolumes(ctx, doc.ID)
 	if err != nil {
-		log.Errorf("Failed to extract roles, doc: %s, err: %v", doc.ID, err)
+		log.Errorf("Failed to list volumes, doc: %s, err: %v", doc.ID, err)
 		return err
 	}
+	scopes := []db.Volume{{}} // 空卷表示全书
+	if len(volumes) > 1 {
+		scopes = volumes
+	}
+
+	var dbRoles []db.Role
+	now := time.Now()
+	for _, volume := range scopes {
+		log.Infof("Extracting roles, docID: %s, volume: %s", doc.ID, volume.Title)
+		var roles []bailian.RoleInfo
+		err = m.withFileID(ctx, &doc, func(fileID string) error {
+			var err error
+			roles, err = m.bailianClient.ExtractRoles(ctx, fileID, doc.Summary, volume.Title)
+			return err
+		})
+		if err != nil {
+			log.Errorf("Failed to extract roles, doc: %s, volume: %s, err: %v", doc.ID, volume.Title, err)
+			return err
+		}
+
+		for _, r := range roles {
+			dbRoles = append(dbRoles, db.Role{
+				ID:         db.MakeUUID(),
+				DocumentID: doc.ID,
+				VolumeID:   volume.ID,
+				Name:       r.Name,
+				Gender:     r.Gender,
+				Character:  r.Character,
+				Appearance: r.Appearance,
+				CreatedAt:  now,
+				UpdatedAt:  now,
+			})
+		}
+	}
 
 	// 角色不允许为空
-	if len(roles) == 0 {
+	if len(dbRoles) == 0 {
 		log.Errorf("No roles extracted for doc: %s", doc.ID)
 		return fmt.Errorf("no roles extracted")
 	}
 
 	// 4. 保存角色到数据库
-	dbRoles := make([]db.Role, 0, len(roles))
-	now := time.Now()
-	for _, r := range roles {
-		dbRoles = append(dbRoles, db.Role{
-			ID:         db.MakeUUID(),
-			DocumentID: doc.ID,
-			Name:       r.Name,
-			Gender:     r.Gender,
-			Character:  r.Character,
-			Appearance: r.Appearance,
-			CreatedAt:  now,
-			UpdatedAt:  now,
-		})
-	}
-
 	err = m.db.CreateRoles(ctx, dbRoles)
 	if err != nil {
 		log.Errorf("Failed to create roles, doc: %s, err: %v", doc.ID, err)
@@ -234,16 +464,17 @@ func (m *DocumentMgr) HandleDocumentScenceTasks(ctx context.Context) {
 	}
 
 	for _, doc := range docs {
-		err = m.HandleDocumentScence(ctx, doc)
-		if err != nil {
-			log.Errorf("Failed to handle document scene, doc: %v, err: %v", doc, err)
-			continue
-		}
-		err = m.db.UpdateDocumentStatus(ctx, doc.ID, db.DocumentStatusSceneReady)
-		if err != nil {
-			log.Errorf("Failed to update document status, err: %v", err)
-			continue
-		}
+		m.runDocument(ctx, doc.ID, func(ctx context.Context) {
+			err := m.HandleDocumentScence(ctx, doc)
+			if err != nil {
+				log.Errorf("Failed to handle document scene, doc: %v, err: %v", doc, err)
+				return
+			}
+			err = m.db.UpdateDocumentStatus(ctx, doc.ID, db.DocumentStatusSceneReady)
+			if err != nil {
+				log.Errorf("Failed to update document status, err: %v", err)
+			}
+		})
 	}
 }
 
@@ -252,7 +483,7 @@ func (m *DocumentMgr) HandleDocumentScence(ctx context.Context, doc db.Document)
 	log.Infof("Handling document scene extraction, docID: %s", doc.ID)
 
 	// 1. 获取所有章节
-	chapters, err := m.db.ListChapters(ctx, doc.ID)
+	chapters, _, err := m.db.ListChapters(ctx, doc.ID, nil)
 	if err != nil {
 		log.Errorf("Failed to list chapters, doc: %s, err: %v", doc.ID, err)
 		return err
@@ -268,7 +499,7 @@ func (m *DocumentMgr) HandleDocumentScence(ctx context.Context, doc db.Document)
 	for _, chapter := range chapters {
 		log.Infof("Generating scenes for chapter, chapterID: %s, index: %d", chapter.ID, chapter.Index)
 
-		scenes, err := m.bailianClient.GenerateScenes(ctx, chapter.Content)
+		scenes, err := m.bailianClient.GenerateScenes(ctx, chapterPrompt(chapter))
 		if err != nil {
 			log.Errorf("Failed to generate scenes, chapter: %s, err: %v", chapter.ID, err)
 			return err
@@ -329,20 +560,22 @@ func (m *DocumentMgr) HandleImageGenTasks(ctx context.Context) {
 
 	// 逐个处理文档
 	for _, doc := range docs {
-		err = m.HandleDocumentImageGen(ctx, doc)
-		if err != nil {
-			log.Errorf("Failed to handle document image gen, doc: %s, err: %v", doc.ID, err)
-			continue // 失败保持状态，下次继续处理
-		}
+		m.runDocument(ctx, doc.ID, func(ctx context.Context) {
+			err := m.HandleDocumentImageGen(ctx, doc)
+			if err != nil {
+				log.Errorf("Failed to handle document image gen, doc: %s, err: %v", doc.ID, err)
+				return // 失败保持状态，下次继续处理
+			}
 
-		// 更新文档状态为 imgReady
-		err = m.db.UpdateDocumentStatus(ctx, doc.ID, db.DocumentStatusImgReady)
-		if err != nil {
-			log.Errorf("Failed to update document status, doc: %s, err: %v", doc.ID, err)
-			continue
-		}
+			// 更新文档状态为 imgReady
+			err = m.db.UpdateDocumentStatus(ctx, doc.ID, db.DocumentStatusImgReady)
+			if err != nil {
+				log.Errorf("Failed to update document status, doc: %s, err: %v", doc.ID, err)
+				return
+			}
 
-		log.Infof("Image generation completed for doc: %s", doc.ID)
+			log.Infof("Image generation completed for doc: %s", doc.ID)
+		})
 	}
 }
 
@@ -351,22 +584,21 @@ func (m *DocumentMgr) HandleDocumentImageGen(ctx context.Context, doc db.Documen
 	log := logger.FromContext(ctx)
 	log.Infof("Handling document image generation, docID: %s", doc.ID)
 
-	// 1. 获取文档的角色信息
-	dbRoles, err := m.db.ListRolesByDocument(ctx, doc.ID)
+	// 1. 获取文档的角色信息，以及章节所属的卷
+	dbRoles, _, err := m.db.ListRolesByDocument(ctx, doc.ID, nil)
 	if err != nil {
 		log.Errorf("Failed to list roles, doc: %s, err: %v", doc.ID, err)
 		return err
 	}
 
-	// 转换为 bailian.RoleInfo
-	roles := make([]bailian.RoleInfo, 0, len(dbRoles))
-	for _, r := range dbRoles {
-		roles = append(roles, bailian.RoleInfo{
-			Name:       r.Name,
-			Gender:     r.Gender,
-			Character:  r.Character,
-			Appearance: r.Appearance,
-		})
+	chapters, _, err := m.db.ListChapters(ctx, doc.ID, nil)
+	if err != nil {
+		log.Errorf("Failed to list chapters, doc: %s, err: %v", doc.ID, err)
+		return err
+	}
+	chapterVolumes := make(map[string]string, len(chapters))
+	for _, chapter := range chapters {
+		chapterVolumes[chapter.ID] = chapter.VolumeID
 	}
 
 	// 2. 获取所有未生成图片的场景
@@ -387,23 +619,30 @@ func (m *DocumentMgr) HandleDocumentImageGen(ctx context.Context, doc db.Documen
 	for _, scene := range scenes {
 		log.Infof("Generating image and voice for scene, sceneID: %s, content: %s", scene.ID, scene.Content)
 
-		imageURL, err := m.bailianClient.GenerateImage(ctx, scene.Content, doc.Summary, roles)
+		// 生成图片、缩略图并转存，只使用场景所在卷的角色
+		roles := volumeRoles(dbRoles, chapterVolumes[scene.ChapterID])
+		variants, err := m.assets.GenerateImage(ctx, scene.Content, doc.Summary, roles, sceneImageKey(doc.ID, scene.ID))
 		if err != nil {
 			log.Errorf("Failed to generate image, scene: %s, err: %v", scene.ID, err)
 			return err // 失败则整个文档重试
 		}
 
 		// 更新场景图片 URL
-		err = m.db.UpdateSceneImageURL(ctx, scene.ID, imageURL)
+		err = m.db.UpdateSceneImageURL(ctx, scene.ID, variants.ImageURL)
 		if err != nil {
 			log.Errorf("Failed to update scene imageURL, scene: %s, err: %v", scene.ID, err)
 			return err
 		}
+		err = m.db.UpdateSceneImageVariants(ctx, scene.ID, variants.ThumbnailURL, variants.MediumURL)
+		if err != nil {
+			log.Errorf("Failed to update scene image variants, scene: %s, err: %v", scene.ID, err)
+			return err
+		}
 
-		log.Infof("Image generated for scene: %s, URL: %s", scene.ID, imageURL)
+		log.Infof("Image generated for scene: %s, URL: %s", scene.ID, variants.ImageURL)
 
 		// 生成语音
-		voiceURL, err := m.bailianClient.GenerateTTS(ctx, scene.Content)
+		voiceURL, err := m.assets.GenerateTTS(ctx, scene.Content)
 		if err != nil {
 			log.Errorf("Failed to generate TTS, scene: %s, err: %v", scene.ID, err)
 			return err
@@ -422,3 +661,174 @@ func (m *DocumentMgr) HandleDocumentImageGen(ctx context.Context, doc db.Documen
 	log.Infof("All images generated for doc: %s", doc.ID)
 	return nil
 }
+
+// volumeRoles 返回某一卷可用的角色：全书角色和该卷的角色，同名角色只保留一个
+// 章节未分卷或该卷没有角色时使用全部角色
+func volumeRoles(dbRoles []db.Role, volumeID string) []bailian.RoleInfo {
+	var scoped []db.Role
+	for _, r := range dbRoles {
+		if volumeID == "" || r.VolumeID == "" || r.VolumeID == volumeID {
+			scoped = append(scoped, r)
+		}
+	}
+	if len(scoped) == 0 {
+		scoped = dbRoles
+	}
+
+	roles := make([]bailian.RoleInfo, 0, len(scoped))
+	seen := make(map[string]bool, len(scoped))
+	for _, r := range scoped {
+		if seen[r.Name] {
+			continue
+		}
+		seen[r.Name] = true
+		roles = append(roles, bailian.RoleInfo{
+			Name:       r.Name,
+			Gender:     r.Gender,
+			Character:  r.Character,
+			Appearance: r.Appearance,
+		})
+	}
+	return roles
+}
+
+// withFileID 执行依赖百炼 fileID 的调用
+// fileID 为空或百炼返回文件不存在时，使用原始文件重新上传并重试一次
+func (m *DocumentMgr) withFileID(ctx context.Context, doc *db.Document, fn func(fileID string) error) error {
+	log := logger.FromContext(ctx)
+
+	if doc.FileID == "" {
+		log.Infof("FileID is empty, reupload file, doc: %s", doc.ID)
+		if err := m.reuploadFile(ctx, doc); err != nil {
+			return err
+		}
+	}
+
+	err := fn(doc.FileID)
+	if !errors.Is(err, bailian.ErrFileNotFound) {
+		return err
+	}
+
+	log.Warnf("Bailian file not found, reupload file, doc: %s, fileID: %s", doc.ID, doc.FileID)
+	if err := m.reuploadFile(ctx, doc); err != nil {
+		return err
+	}
+	return fn(doc.FileID)
+}
+
+// reuploadFile 重新上传原始文件到百炼并更新文档的 fileID
+func (m *DocumentMgr) reuploadFile(ctx context.Context, doc *db.Document) error {
+	log := logger.FromContext(ctx)
+
+	if doc.OriginFile == "" {
+		return fmt.Errorf("no origin file for doc: %s", doc.ID)
+	}
+	if _, err := os.Stat(doc.OriginFile); err != nil {
+		log.Errorf("Failed to stat origin file, doc: %s, filename: %s, err: %v", doc.ID, doc.OriginFile, err)
+		return err
+	}
+
+	// 此前保存的原始文件格式百炼不支持时，提取纯文本后上传
+	filename := doc.OriginFile
+	if ext := strings.TrimPrefix(strings.ToLower(filepath.Ext(filename)), "."); !bailianFileTypes[ext] {
+		filename = filepath.Join(m.temp, doc.ID+".txt")
+		if err := writeTextFile(ctx, doc.OriginFile, filename); err != nil {
+			log.Errorf("Failed to write text file, doc: %s, filename: %s, err: %v", doc.ID, doc.OriginFile, err)
+			return err
+		}
+		defer os.Remove(filename)
+	}
+
+	fileID, err := m.bailianClient.UploadFile(ctx, filename)
+	if err != nil {
+		log.Errorf("Failed to reupload file, doc: %s, err: %v", doc.ID, err)
+		return err
+	}
+
+	err = m.db.UpdateDocumentFileID(ctx, doc.ID, fileID)
+	if err != nil {
+		log.Errorf("Failed to update document fileID, doc: %s, err: %v", doc.ID, err)
+		return err
+	}
+
+	log.Infof("File reuploaded, doc: %s, old fileID: %s, new fileID: %s", doc.ID, doc.FileID, fileID)
+	doc.FileID = fileID
+	return nil
+}
+
+// CleanupFileTasks 删除已完成文档在百炼上的文件，避免超出账号文件配额
+func (m *DocumentMgr) CleanupFileTasks(ctx context.Context) {
+	log := logger.FromContext(ctx)
+
+	docs, err := m.db.ListFileReleasableDocuments(ctx)
+	if err != nil {
+		log.Errorf("Failed to list file releasable documents, err: %v", err)
+		return
+	}
+
+	for _, doc := range docs {
+		err = m.bailianClient.DeleteFile(ctx, doc.FileID)
+		if err != nil {
+			log.Errorf("Failed to delete bailian file, doc: %s, fileID: %s, err: %v", doc.ID, doc.FileID, err)
+			continue
+		}
+
+		err = m.db.UpdateDocumentFileID(ctx, doc.ID, "")
+		if err != nil {
+			log.Errorf("Failed to clear document fileID, doc: %s, err: %v", doc.ID, err)
+			continue
+		}
+
+		log.Infof("Bailian file released for doc: %s, fileID: %s", doc.ID, doc.FileID)
+	}
+}
+
+// chapterPrompt 返回章节内容的提示词形式，旧数据没有提示词形式时由展示形式生成
+func chapterPrompt(chapter db.Chapter) string {
+	if chapter.Prompt != "" {
+		return chapter.Prompt
+	}
+	return spliter.PromptText(spliter.Paragraphs(chapter.Content))
+}
+
+// PurgeTrashTasks 彻底删除在回收站中超过保留期的文档、章节、场景和角色，并释放文档的百炼文件和原始文件
+// 生成的图片和语音按内容在文档之间共享，不删除
+func (m *DocumentMgr) PurgeTrashTasks(ctx context.Context) {
+	log := logger.FromContext(ctx)
+
+	before := time.Now().AddDate(0, 0, -m.config.TrashRetentionDays)
+	docs, err := m.db.ListPurgeableDocuments(ctx, before)
+	if err != nil {
+		log.Errorf("Failed to list purgeable documents, err: %v", err)
+		return
+	}
+
+	for _, doc := range docs {
+		err = m.db.DeleteDocumentCascade(ctx, doc.ID)
+		if err != nil {
+			log.Errorf("Failed to purge document, doc: %s, err: %v", doc.ID, err)
+			continue
+		}
+
+		// 释放百炼文件和原始文件，失败不影响删除结果
+		if doc.FileID != "" && m.bailianClient != nil {
+			if err := m.bailianClient.DeleteFile(ctx, doc.FileID); err != nil {
+				log.Warnf("Failed to delete bailian file, doc: %s, fileID: %s, err: %v", doc.ID, doc.FileID, err)
+			}
+		}
+		if doc.OriginFile != "" {
+			if err := os.Remove(doc.OriginFile); err != nil && !os.IsNotExist(err) {
+				log.Warnf("Failed to remove origin file, doc: %s, filename: %s, err: %v", doc.ID, doc.OriginFile, err)
+			}
+		}
+		if m.embedder != nil {
+			m.embedder.index.DeleteDocument(doc.ID)
+		}
+		log.Infof("Document purged from trash, doc: %s, name: %s", doc.ID, doc.Name)
+	}
+
+	err = m.db.PurgeTrash(ctx, before)
+	if err != nil {
+		log.Errorf("Failed to purge trash, err: %v", err)
+	}
+}
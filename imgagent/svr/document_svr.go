@@ -12,16 +12,14 @@ import (
 	"time"
 
 	"github.com/gin-gonic/gin"
-	"github.com/go-sql-driver/mysql"
 	"github.com/google/uuid"
-	"github.com/mattn/go-sqlite3"
 	"gorm.io/gorm"
 
 	"imgagent/api"
-	"imgagent/bailian"
 	"imgagent/db"
 	hutil "imgagent/httputil"
 	"imgagent/pkg/logger"
+	"imgagent/proto"
 	"imgagent/spliter"
 )
 
@@ -36,6 +34,10 @@ func (s *Service) HandleCreateDocument(c *gin.Context) {
 	ctx := c.Request.Context()
 	log := logger.FromGinContext(c)
 
+	if !s.parseUploadForm(c) {
+		return
+	}
+
 	name := c.PostForm("name")
 	if name == "" {
 		hutil.AbortError(c, http.StatusBadRequest, "name is required")
@@ -46,94 +48,211 @@ func (s *Service) HandleCreateDocument(c *gin.Context) {
 		return
 	}
 
-	file, err := c.FormFile("file")
-	if err != nil {
-		log.Errorf("Failed to get file, err: %v", err)
-		hutil.AbortError(c, http.StatusBadRequest, "file is required")
+	file, ext, ok := s.uploadFormFile(c)
+	if !ok {
 		return
 	}
-
-	log.Infof("Create document, name: %s, file: %s", name, file.Filename)
-
-	_, err = s.db.GetDocumentWithName(ctx, name)
-	if err != nil {
-		if !errors.Is(err, gorm.ErrRecordNotFound) {
-			log.Errorf("Failed to get document, err: %v", err)
-			hutil.AbortError(c, hutil.ErrServerInternalCode, "get document failed")
-			return
-		}
-	} else {
-		log.Warnf("Document existing")
-		hutil.AbortError(c, ErrExistingDocumentCode, ErrExistingDocument)
+	encoding, apiErr := uploadEncoding(c.PostForm("encoding"))
+	if apiErr != nil {
+		hutil.AbortErr(c, apiErr)
+		return
+	}
+	patterns := c.PostFormArray("chapter_patterns")
+	if apiErr := checkChapterPatterns(patterns); apiErr != nil {
+		hutil.AbortErr(c, apiErr)
 		return
 	}
 
-	index := strings.LastIndex(file.Filename, ".")
-	if index == -1 {
-		hutil.AbortError(c, http.StatusBadRequest, "file has no extension")
+	log.Infof("Create document, name: %s, file: %s, encoding: %s", name, file.Filename, encoding)
+
+	if apiErr := s.checkDocumentName(ctx, name); apiErr != nil {
+		hutil.AbortErr(c, apiErr)
 		return
 	}
-	ext := file.Filename[index+1:]
 
 	// 生成文档 ID
 	docID := db.MakeUUID()
 
-	// 保存临时文件用于分割
-	tempFilename := s.conf.Temp + "/" + docID + "_temp." + ext
-	err = c.SaveUploadedFile(file, tempFilename)
+	// 保存原始文件，百炼文件失效时用于重新上传
+	originFilename := s.originFilename(docID, ext)
+	err := c.SaveUploadedFile(file, originFilename)
 	if err != nil {
-		log.Errorf("Failed to save temp file, err: %v", err)
+		log.Errorf("Failed to save origin file, err: %v", err)
 		hutil.AbortError(c, hutil.ErrServerInternalCode, "save file failed")
 		return
 	}
-	defer os.Remove(tempFilename) // 临时文件使用后删除
 
-	// 分割章节
-	chunkOverlap := 100
-	texts, err := spliter.Split(ctx, tempFilename, spliter.Option{
-		ChunkSize:    5000,
-		ChunkOverlap: chunkOverlap,
-		Separator:    "\n\n",
-	})
+	args := &api.CreateDocumentArgs{Name: name}
+	opts := createDocumentOptions{
+		OriginFile:      originFilename,
+		Encoding:        encoding,
+		ChapterPatterns: patterns,
+	}
+	doc, apiErr := s.createDocument(ctx, docID, ext, args, opts)
+	if apiErr != nil {
+		hutil.AbortErr(c, apiErr)
+		return
+	}
+
+	hutil.WriteData(c, makeDocument(doc))
+}
+
+// checkDocumentName 检查当前用户的文档名是否已被占用，不同用户的文档可以同名
+func (s *Service) checkDocumentName(ctx context.Context, name string) *proto.ApiError {
+	log := logger.FromContext(ctx)
+
+	_, err := s.db.GetDocumentWithName(ctx, name)
+	if err == nil {
+		log.Warnf("Document existing, name: %s", name)
+		return hutil.NewApiError(ErrExistingDocumentCode, ErrExistingDocument)
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		log.Errorf("Failed to get document, err: %v", err)
+		return hutil.NewApiError(hutil.ErrServerInternalCode, "get document failed")
+	}
+	return nil
+}
+
+// originFilename 返回文档原始文件的保存路径
+func (s *Service) originFilename(docID, ext string) string {
+	return s.conf.Origin + "/" + docID + "." + ext
+}
+
+// bailianFileTypes 百炼（qwen-long）可以直接读取的文件格式，其他格式提取纯文本后以 txt 上传
+var bailianFileTypes = map[string]bool{
+	"txt":  true,
+	"md":   true,
+	"pdf":  true,
+	"docx": true,
+	"doc":  true,
+	"epub": true,
+}
+
+// writeTextFile 提取 filename 的纯文本并以 UTF-8 保存为 textFilename
+func writeTextFile(ctx context.Context, filename, textFilename string) error {
+	text, err := spliter.ReadText(ctx, filename, spliter.Option{Encoding: spliter.EncodingUTF8})
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(textFilename, []byte(text), 0666)
+}
+
+// createDocumentOptions 创建文档时由服务端确定或从上传表单解析的参数
+type createDocumentOptions struct {
+	OriginFile      string   // 服务端保存的原始文件路径
+	Encoding        string   // 上传时指定的文本编码，为空时自动检测
+	ChapterPatterns []string // 章节标题正则，为空时使用全局配置
+}
+
+// createDocument 基于已保存的原始文件 opts.OriginFile 创建文档：校验文件内容、文本转换为 UTF-8、分割章节并上传百炼，
+// 百炼不支持的格式上传提取的纯文本并以其替换原始文件，失败时删除原始文件
+// 需要 OCR 的扫描版 PDF 只创建文档，由后台任务识别文字后分割章节
+func (s *Service) createDocument(ctx context.Context, docID, ext string, args *api.CreateDocumentArgs, opts createDocumentOptions) (*db.Document, *proto.ApiError) {
+	log := logger.FromContext(ctx)
+	originFilename := opts.OriginFile
+	uploadFilename := originFilename
+	file := db.DocumentFile{OriginFile: originFilename}
+
+	created := false
+	defer func() {
+		// 创建失败时删除原始文件，成功时只保留上传百炼的文件用于重新上传
+		if !created {
+			os.Remove(uploadFilename)
+		}
+		if !created || uploadFilename != originFilename {
+			os.Remove(originFilename)
+		}
+	}()
+
+	// 校验文件内容
+	if apiErr := validateUploadFile(s.conf.Upload, originFilename, ext); apiErr != nil {
+		log.Warnf("Invalid upload file, doc: %s, err: %v", docID, apiErr)
+		return nil, apiErr
+	}
+
+	// 扫描版 PDF 的文字识别耗时较长，由后台任务识别后分割章节，见 DocumentMgr.HandleDocumentOCR
+	if ext == "pdf" && s.ocr != nil {
+		needsOCR, err := spliter.PDFNeedsOCR(originFilename)
+		if err != nil {
+			log.Warnf("Failed to read pdf, doc: %s, err: %v", docID, err)
+			return nil, hutil.NewApiError(ErrInvalidFileCode, "invalid pdf file")
+		}
+		if needsOCR {
+			file.OCRPending = true
+			file.ChapterPatterns = s.chapterPatterns(opts.ChapterPatterns)
+			doc, err := s.db.CreateDocument(ctx, docID, file, args)
+			if err != nil {
+				log.Errorf("Failed to create document, err: %v", err)
+				return nil, documentApiErr(err, "create document failed")
+			}
+			log.Infof("Document waits for ocr, doc: %s", docID)
+			created = true
+			return doc, nil
+		}
+	}
+
+	// 文本文件统一转换为 UTF-8，百炼和后续分割都读取转换后的文件
+	if textFileTypes[ext] {
+		encoding, apiErr := transcodeUploadFile(originFilename, opts.Encoding)
+		if apiErr != nil {
+			log.Warnf("Failed to transcode upload file, doc: %s, encoding: %s, err: %v", docID, opts.Encoding, apiErr)
+			return nil, apiErr
+		}
+		log.Infof("Transcoded upload file, doc: %s, encoding: %s", docID, encoding)
+		file.Encoding = encoding
+	}
+
+	// 分割章节，分块大小按文档类型配置
+	chunks, err := spliter.Split(ctx, originFilename, s.splitOption(ext, opts.ChapterPatterns))
 	if err != nil {
 		log.Errorf("Failed to split text, err: %v", err)
-		hutil.AbortError(c, hutil.ErrServerInternalCode, "split text failed")
-		return
+		return nil, hutil.NewApiError(hutil.ErrServerInternalCode, "split text failed")
 	}
 
-	err = s.db.CreateChapters(ctx, docID, texts)
+	volumes, chapters := makeVolumeChapters(docID, chunks)
+	err = s.db.CreateVolumes(ctx, volumes)
+	if err != nil {
+		log.Errorf("Failed to create volumes, err: %v", err)
+		return nil, hutil.NewApiError(hutil.ErrServerInternalCode, "create volumes failed")
+	}
+	log.Infof("Created %d volumes, doc: %s", len(volumes), docID)
+
+	err = s.db.CreateChapters(ctx, docID, chapters)
 	if err != nil {
 		log.Errorf("Failed to create chapters, err: %v", err)
-		hutil.AbortError(c, hutil.ErrServerInternalCode, "create chapters failed")
-		return
+		return nil, hutil.NewApiError(hutil.ErrServerInternalCode, "create chapters failed")
+	}
+
+	// 百炼不支持的格式上传提取的纯文本
+	if !bailianFileTypes[ext] {
+		uploadFilename = s.originFilename(docID, "txt")
+		if err := writeTextFile(ctx, originFilename, uploadFilename); err != nil {
+			log.Errorf("Failed to write text file, doc: %s, filename: %s, err: %v", docID, originFilename, err)
+			return nil, hutil.NewApiError(hutil.ErrServerInternalCode, "extract text failed")
+		}
+		file.OriginFile = uploadFilename
 	}
 
 	// 上传文件到百炼
-	log.Infof("Uploading file to Bailian, filename: %s", tempFilename)
-	fileID, err := s.bailianClient.UploadFile(ctx, tempFilename)
+	log.Infof("Uploading file to Bailian, filename: %s", uploadFilename)
+	file.FileID, err = s.bailianClient.UploadFile(ctx, uploadFilename)
 	if err != nil {
-		log.Errorf("Failed to upload file to Bailian, doc: %s, filename: %s, err: %v", docID, tempFilename, err)
-		hutil.AbortError(c, hutil.ErrServerInternalCode, "upload file to Bailian failed")
-		return
+		log.Errorf("Failed to upload file to Bailian, doc: %s, filename: %s, err: %v", docID, uploadFilename, err)
+		return nil, hutil.NewApiError(hutil.ErrServerInternalCode, "upload file to Bailian failed")
 	}
 
-	args := &api.CreateDocumentArgs{
-		Name: name,
-	}
-	doc, err := s.db.CreateDocument(ctx, docID, fileID, args)
+	doc, err := s.db.CreateDocument(ctx, docID, file, args)
 	if err != nil {
 		log.Errorf("Failed to create document, err: %v", err)
-		documentErr(c, err, "create document failed")
-		return
+		return nil, documentApiErr(err, "create document failed")
 	}
-
-	hutil.WriteData(c, makeDocument(doc))
+	created = true
+	return doc, nil
 }
 
 func (s *Service) HandleGetDocument(c *gin.Context) {
 	ctx := c.Request.Context()
 	log := logger.FromGinContext(c)
-	//ui := GetUserInfo(c)
 
 	docID := c.Param("document_id")
 	if docID == "" {
@@ -185,7 +304,6 @@ func (s *Service) HandleUpdateDocument(c *gin.Context) {
 func (s *Service) HandleDeleteDocument(c *gin.Context) {
 	ctx := c.Request.Context()
 	log := logger.FromGinContext(c)
-	// ui := GetUserInfo(c)
 
 	docID := c.Param("document_id")
 	if docID == "" {
@@ -194,16 +312,21 @@ func (s *Service) HandleDeleteDocument(c *gin.Context) {
 	}
 
 	log.Infof("Delete document, docID: %s", docID)
-	// 删除对应的 Chapter
-	err := s.db.DeleteAllChapter(ctx, docID)
-	if err != nil {
-		log.Errorf("Failed to delete document Chapter, err: %v", err)
-		hutil.AbortError(c, hutil.ErrServerInternalCode, "delete document Chapter failed")
+	if _, err := s.db.GetDocument(ctx, docID); err != nil {
+		log.Errorf("get document failed, id: %s, err: %v", docID, err)
+		documentErr(c, err, "delete document failed")
+		return
 	}
-	err = s.db.DeleteDocument(ctx, docID)
+
+	// 先停止后台正在处理该文档的任务，再在事务中将文档及其章节、场景和角色移入回收站
+	// 移入后再取消一次，避免期间开始处理的任务继续写入
+	// 原始文件和百炼文件保留到从回收站中彻底删除时释放，以便恢复后继续处理
+	s.documentMgr.CancelDocument(docID)
+	err := s.db.TrashDocument(ctx, docID)
+	s.documentMgr.CancelDocument(docID)
 	if err != nil {
-		log.Errorf("Failed to delete document, err: %v", err)
-		hutil.AbortError(c, hutil.ErrServerInternalCode, "delete document failed")
+		log.Errorf("Failed to trash document, err: %v", err)
+		documentErr(c, err, "delete document failed")
 		return
 	}
 	hutil.WriteData(c, nil)
@@ -212,17 +335,23 @@ func (s *Service) HandleDeleteDocument(c *gin.Context) {
 func (s *Service) HandleListDocuments(c *gin.Context) {
 	ctx := c.Request.Context()
 	log := logger.FromGinContext(c)
-	// ui := GetUserInfo(c)
 
-	log.Infof("List documents")
-	docs, err := s.db.ListDocuments(ctx)
+	var args api.ListDocumentsArgs
+	if err := bindListArgs(c, &args, &args.ListArgs); err != nil {
+		log.Warnf("Invalid list args, err: %v", err)
+		hutil.AbortError(c, http.StatusBadRequest, "invalid list args")
+		return
+	}
+
+	log.Infof("List documents, args: %+v", args)
+	docs, total, err := s.db.ListDocuments(ctx, &args)
 	if err != nil {
 		log.Errorf("Failed to list documents, err: %v", err)
 		hutil.AbortError(c, hutil.ErrServerInternalCode, "list documents failed")
 		return
 	}
 
-	ret := &api.ListDocumentsResult{}
+	ret := &api.ListDocumentsResult{Pagination: makePagination(&args.ListArgs, len(docs), total)}
 	for _, d := range docs {
 		ret.Documents = append(ret.Documents, makeDocument(&d))
 	}
@@ -248,7 +377,7 @@ func (s *Service) HandleGetChapter(c *gin.Context) {
 	Chapter, err := s.db.GetChapter(ctx, id, docID)
 	if err != nil {
 		log.Errorf("Failed to get Chapter, err: %v", err)
-		hutil.AbortError(c, http.StatusInternalServerError, "get Chapter failed")
+		documentErr(c, err, "get Chapter failed")
 		return
 	}
 
@@ -277,17 +406,26 @@ func (s *Service) HandleUpdateChapter(c *gin.Context) {
 		return
 	}
 
+	// 与分割结果一致，同时保存展示形式和提示词形式
+	paragraphs := spliter.Paragraphs(args.Content)
+	if len(paragraphs) == 0 {
+		hutil.AbortError(c, http.StatusBadRequest, "invalid content")
+		return
+	}
+	args.Content = spliter.DisplayText(paragraphs)
+	args.Prompt = spliter.PromptText(paragraphs)
+
 	log.Infof("Update Chapter, docID: %s, id: %s", docID, id)
-	err := s.db.UpdateChapter(ctx, id, &args)
+	err := s.db.UpdateChapter(ctx, id, docID, &args, authorName(c))
 	if err != nil {
 		log.Errorf("Failed to update db Chapter, err: %v", err)
-		hutil.AbortError(c, http.StatusInternalServerError, "update Chapter failed")
+		documentErr(c, err, "update Chapter failed")
 		return
 	}
 	Chapter, err := s.db.GetChapter(ctx, id, docID)
 	if err != nil {
 		log.Errorf("Failed to get Chapter, err: %v", err)
-		hutil.AbortError(c, http.StatusInternalServerError, "get Chapter failed")
+		documentErr(c, err, "get Chapter failed")
 		return
 	}
 
@@ -311,19 +449,15 @@ func (s *Service) HandleDeleteChapter(c *gin.Context) {
 
 	log.Infof("Delete Chapter, docID: %s, id: %s", docID, id)
 
-	// 先删除章节关联的所有场景
-	err := s.db.DeleteScenesByChapter(ctx, id)
-	if err != nil {
-		log.Errorf("Failed to delete scenes by chapter, err: %v", err)
-		hutil.AbortError(c, http.StatusInternalServerError, "delete scenes failed")
-		return
-	}
-
-	// 再删除章节
-	err = s.db.DeleteChapter(ctx, id, docID)
+	// 章节和关联的场景一起移入回收站
+	err := s.db.TrashChapter(ctx, id, docID)
 	if err != nil {
-		log.Errorf("Failed to delete db Chapter, err: %v", err)
-		hutil.AbortError(c, http.StatusInternalServerError, "delete Chapter failed")
+		log.Errorf("Failed to trash Chapter, err: %v", err)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			hutil.AbortError(c, http.StatusNotFound, "chapter not found")
+		} else {
+			hutil.AbortError(c, http.StatusInternalServerError, "delete Chapter failed")
+		}
 		return
 	}
 
@@ -340,68 +474,175 @@ func (s *Service) HandleListChapters(c *gin.Context) {
 		return
 	}
 
-	// todo： 后续需要考虑分页
-	log.Infof("List chapters, docID: %s", docID)
-	chapters, err := s.db.ListChapters(ctx, docID)
+	var args api.ListChaptersArgs
+	if err := bindListArgs(c, &args, &args.ListArgs); err != nil {
+		log.Warnf("Invalid list args, err: %v", err)
+		hutil.AbortError(c, http.StatusBadRequest, "invalid list args")
+		return
+	}
+
+	log.Infof("List chapters, docID: %s, args: %+v", docID, args)
+	chapters, total, err := s.db.ListChapters(ctx, docID, &args)
 	if err != nil {
 		log.Errorf("list chapters failed, err: %v", err)
 		hutil.AbortError(c, http.StatusBadRequest, "list chapters failed")
 		return
 	}
 
-	result := &api.ListChaptersResult{}
+	result := &api.ListChaptersResult{Pagination: makePagination(&args.ListArgs, len(chapters), total)}
 	for _, seg := range chapters {
 		result.Chapters = append(result.Chapters, makeChapter(&seg))
 	}
 	hutil.WriteData(c, result)
 }
 
+// HandleListVolumes 获取文档的卷列表，包含每卷的章节 id
+func (s *Service) HandleListVolumes(c *gin.Context) {
+	ctx := c.Request.Context()
+	log := logger.FromGinContext(c)
+
+	docID := c.Param("document_id")
+	if docID == "" {
+		hutil.AbortError(c, http.StatusBadRequest, "invalid doc id")
+		return
+	}
+
+	log.Infof("List volumes, docID: %s", docID)
+	// 卷没有 owner_id，先检查文档属于当前用户
+	if _, err := s.db.GetDocument(ctx, docID); err != nil {
+		log.Errorf("get document failed, id: %s, err: %v", docID, err)
+		documentErr(c, err, "get document failed")
+		return
+	}
+	volumes, err := s.db.ListVolumes(ctx, docID)
+	if err != nil {
+		log.Errorf("list volumes failed, err: %v", err)
+		hutil.AbortError(c, http.StatusInternalServerError, "list volumes failed")
+		return
+	}
+	chapters, _, err := s.db.ListChapters(ctx, docID, nil)
+	if err != nil {
+		log.Errorf("list chapters failed, err: %v", err)
+		hutil.AbortError(c, http.StatusInternalServerError, "list chapters failed")
+		return
+	}
+
+	chapterIDs := make(map[string][]string)
+	for _, chapter := range chapters {
+		if chapter.VolumeID != "" {
+			chapterIDs[chapter.VolumeID] = append(chapterIDs[chapter.VolumeID], chapter.ID)
+		}
+	}
+	result := &api.ListVolumesResult{Volumes: []api.Volume{}}
+	for _, v := range volumes {
+		volume := makeVolume(&v)
+		volume.ChapterIDs = chapterIDs[v.ID]
+		result.Volumes = append(result.Volumes, volume)
+	}
+	hutil.WriteData(c, result)
+}
+
+// makeVolumeChapters 按分割结果中的卷标题生成卷，连续相同卷标题的章节归入同一卷
+func makeVolumeChapters(docID string, chunks []spliter.Chunk) ([]db.Volume, []api.CreateChapterArgs) {
+	var volumes []db.Volume
+	chapters := make([]api.CreateChapterArgs, 0, len(chunks))
+	now := time.Now()
+	volumeID, volumeTitle := "", ""
+	for _, chunk := range chunks {
+		if chunk.Volume != "" && chunk.Volume != volumeTitle {
+			volumeID, volumeTitle = db.MakeUUID(), chunk.Volume
+			volumes = append(volumes, db.Volume{
+				ID:         volumeID,
+				Index:      len(volumes),
+				DocumentID: docID,
+				Title:      chunk.Volume,
+				Number:     chunk.VolumeNumber,
+				CreatedAt:  now,
+				UpdatedAt:  now,
+			})
+		}
+		chapters = append(chapters, api.CreateChapterArgs{
+			VolumeID: volumeID,
+			Title:    chunk.Title,
+			Kind:     chunk.Kind,
+			Content:  chunk.Content,
+			Prompt:   chunk.Prompt,
+		})
+	}
+	return volumes, chapters
+}
+
 func makeDocument(d *db.Document) api.Document {
 	return api.Document{
 		ID:              d.ID,
 		Name:            d.Name,
 		FileID:          d.FileID,
 		SummaryImageURL: d.SummaryImageURL,
+		ThumbnailURL:    d.ThumbnailURL,
+		MediumURL:       d.MediumURL,
+		Encoding:        d.Encoding,
 		Status:          d.Status,
 		CreatedAt:       d.CreatedAt.Format(time.DateTime),
 		UpdatedAt:       d.UpdatedAt.Format(time.DateTime),
+		DeletedAt:       formatDeletedAt(d.DeletedAt),
 	}
 }
 
+// formatDeletedAt 格式化移入回收站的时间，未删除时为空
+func formatDeletedAt(t gorm.DeletedAt) string {
+	if !t.Valid {
+		return ""
+	}
+	return t.Time.Format(time.DateTime)
+}
+
 func makeChapter(d *db.Chapter) api.Chapter {
 	return api.Chapter{
 		ID:         d.ID,
 		DocumentID: d.DocumentID,
+		VolumeID:   d.VolumeID,
 		Index:      d.Index,
 		Title:      d.Title,
+		Kind:       d.Kind,
 		Content:    d.Content,
+		Paragraphs: spliter.Paragraphs(d.Content),
 		SceneIDs:   d.SceneIDs,
 		CreatedAt:  d.CreatedAt.Format(time.DateTime),
 		UpdatedAt:  d.UpdatedAt.Format(time.DateTime),
+		DeletedAt:  formatDeletedAt(d.DeletedAt),
 	}
 }
 
-func documentErr(c *gin.Context, err error, errMsg string) {
-	var mysqlErr *mysql.MySQLError
-	if errors.As(err, &mysqlErr) && mysqlErr.Number == 1062 {
-		hutil.AbortError(c, ErrExistingDocumentCode, ErrExistingDocument)
-		return
+func makeVolume(v *db.Volume) api.Volume {
+	return api.Volume{
+		ID:         v.ID,
+		Index:      v.Index,
+		DocumentID: v.DocumentID,
+		Title:      v.Title,
+		Number:     v.Number,
+		CreatedAt:  v.CreatedAt.Format(time.DateTime),
+		UpdatedAt:  v.UpdatedAt.Format(time.DateTime),
 	}
-	// sqlite for test
-	if sqliteErr, ok := err.(sqlite3.Error); ok {
-		if sqliteErr.Code == 19 && sqliteErr.ExtendedCode == 2067 {
-			hutil.AbortError(c, ErrExistingDocumentCode, ErrExistingDocument)
-			return
-		}
+}
+
+func documentErr(c *gin.Context, err error, errMsg string) {
+	hutil.AbortErr(c, documentApiErr(err, errMsg))
+}
+
+// documentApiErr 将数据库错误转换为文档相关的 ApiError
+func documentApiErr(err error, errMsg string) *proto.ApiError {
+	// 各数据库的唯一索引冲突由 gorm TranslateError 统一转换
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		return hutil.NewApiError(ErrExistingDocumentCode, ErrExistingDocument)
 	}
 	if errors.Is(err, gorm.ErrRecordNotFound) {
-		hutil.AbortError(c, ErrNoSuchDocumentCode, ErrNoSuchDocument)
-	} else {
-		hutil.AbortError(c, hutil.ErrServerInternalCode, errMsg)
+		return hutil.NewApiError(ErrNoSuchDocumentCode, ErrNoSuchDocument)
 	}
+	return hutil.NewApiError(hutil.ErrServerInternalCode, errMsg)
 }
 
-func (s *Service) downloadFile(ctx context.Context, textURL string) (string, error) {
+// downloadFile 下载文件到 dir 目录，返回本地文件名
+func downloadFile(ctx context.Context, dir, textURL string) (string, error) {
 	log := logger.FromContext(ctx)
 
 	url, err := url.ParseRequestURI(textURL)
@@ -415,8 +656,12 @@ func (s *Service) downloadFile(ctx context.Context, textURL string) (string, err
 	ext := url.Path[index+1:]
 	id := uuid.New()
 	uid := hex.EncodeToString(id[:])
-	filename := s.conf.Temp + "/" + uid + "." + ext
-	resp, err := http.Get(textURL)
+	filename := dir + "/" + uid + "." + ext
+	req, err := http.NewRequestWithContext(ctx, "GET", textURL, nil)
+	if err != nil {
+		return "", err
+	}
+	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
 		return "", err
 	}
@@ -454,15 +699,22 @@ func (s *Service) HandleGetRoles(c *gin.Context) {
 		return
 	}
 
-	log.Infof("Get roles, docID: %s", docID)
-	roles, err := s.db.ListRolesByDocument(ctx, docID)
+	var args api.ListRolesArgs
+	if err := bindListArgs(c, &args, &args.ListArgs); err != nil {
+		log.Warnf("Invalid list args, err: %v", err)
+		hutil.AbortError(c, http.StatusBadRequest, "invalid list args")
+		return
+	}
+
+	log.Infof("Get roles, docID: %s, args: %+v", docID, args)
+	roles, total, err := s.db.ListRolesByDocument(ctx, docID, &args)
 	if err != nil {
 		log.Errorf("Failed to list roles, err: %v", err)
 		hutil.AbortError(c, http.StatusInternalServerError, "list roles failed")
 		return
 	}
 
-	result := &api.ListRolesResult{}
+	result := &api.ListRolesResult{Pagination: makePagination(&args.ListArgs, len(roles), total)}
 	for _, role := range roles {
 		result.Roles = append(result.Roles, makeRole(&role))
 	}
@@ -480,15 +732,22 @@ func (s *Service) HandleListScenesByDocument(c *gin.Context) {
 		return
 	}
 
-	log.Infof("List scenes by document, docID: %s", docID)
-	scenes, err := s.db.ListScenesByDocument(ctx, docID)
+	var args api.ListScenesArgs
+	if err := bindListArgs(c, &args, &args.ListArgs); err != nil {
+		log.Warnf("Invalid list args, err: %v", err)
+		hutil.AbortError(c, http.StatusBadRequest, "invalid list args")
+		return
+	}
+
+	log.Infof("List scenes by document, docID: %s, args: %+v", docID, args)
+	scenes, total, err := s.db.ListScenesByDocument(ctx, docID, &args)
 	if err != nil {
 		log.Errorf("Failed to list scenes, err: %v", err)
 		hutil.AbortError(c, http.StatusInternalServerError, "list scenes failed")
 		return
 	}
 
-	result := &api.ListScenesResult{}
+	result := &api.ListScenesResult{Pagination: makePagination(&args.ListArgs, len(scenes), total)}
 	for _, scene := range scenes {
 		result.Scenes = append(result.Scenes, makeScene(&scene))
 	}
@@ -514,7 +773,8 @@ func (s *Service) HandleListScenesByChapter(c *gin.Context) {
 		return
 	}
 
-	result := &api.ListScenesResult{}
+	// 章节的场景数量有限，不分页
+	result := &api.ListScenesResult{Pagination: api.Pagination{Total: int64(len(scenes))}}
 	for _, scene := range scenes {
 		result.Scenes = append(result.Scenes, makeScene(&scene))
 	}
@@ -525,6 +785,7 @@ func makeRole(r *db.Role) api.Role {
 	return api.Role{
 		ID:         r.ID,
 		DocumentID: r.DocumentID,
+		VolumeID:   r.VolumeID,
 		Name:       r.Name,
 		Gender:     r.Gender,
 		Character:  r.Character,
@@ -536,15 +797,18 @@ func makeRole(r *db.Role) api.Role {
 
 func makeScene(s *db.Scene) api.Scene {
 	return api.Scene{
-		ID:         s.ID,
-		ChapterID:  s.ChapterID,
-		DocumentID: s.DocumentID,
-		Index:      s.Index,
-		Content:    s.Content,
-		ImageURL:   s.ImageURL,
-		VoiceURL:   s.VoiceURL,
-		CreatedAt:  s.CreatedAt.Format(time.DateTime),
-		UpdatedAt:  s.UpdatedAt.Format(time.DateTime),
+		ID:           s.ID,
+		ChapterID:    s.ChapterID,
+		DocumentID:   s.DocumentID,
+		Index:        s.Index,
+		Content:      s.Content,
+		ImageURL:     s.ImageURL,
+		ThumbnailURL: s.ThumbnailURL,
+		MediumURL:    s.MediumURL,
+		VoiceURL:     s.VoiceURL,
+		CreatedAt:    s.CreatedAt.Format(time.DateTime),
+		UpdatedAt:    s.UpdatedAt.Format(time.DateTime),
+		DeletedAt:    formatDeletedAt(s.DeletedAt),
 	}
 }
 
@@ -567,7 +831,7 @@ func (s *Service) HandleUpdateRole(c *gin.Context) {
 	}
 
 	log.Infof("Update role, roleID: %s", roleID)
-	err := s.db.UpdateRole(ctx, roleID, &args)
+	err := s.db.UpdateRole(ctx, roleID, &args, authorName(c))
 	if err != nil {
 		log.Errorf("Failed to update role, err: %v", err)
 		if errors.Is(err, gorm.ErrRecordNotFound) {
@@ -620,7 +884,7 @@ func (s *Service) HandleUpdateScene(c *gin.Context) {
 
 	// 2. 更新场景内容
 	log.Infof("Update scene content, sceneID: %s", sceneID)
-	err = s.db.UpdateScene(ctx, sceneID, &args)
+	rev, err := s.db.UpdateScene(ctx, sceneID, &args, authorName(c))
 	if err != nil {
 		log.Errorf("Failed to update scene, err: %v", err)
 		hutil.AbortError(c, http.StatusInternalServerError, "update scene failed")
@@ -635,28 +899,24 @@ func (s *Service) HandleUpdateScene(c *gin.Context) {
 		return
 	}
 
-	// 4. 获取角色信息
-	dbRoles, err := s.db.ListRolesByDocument(ctx, doc.ID)
+	// 4. 获取角色信息，只使用场景所在卷的角色
+	chapter, err := s.db.GetChapter(ctx, scene.ChapterID, doc.ID)
+	if err != nil {
+		log.Errorf("Failed to get chapter, chapterID: %s, err: %v", scene.ChapterID, err)
+		hutil.AbortError(c, http.StatusInternalServerError, "get chapter failed")
+		return
+	}
+	dbRoles, _, err := s.db.ListRolesByDocument(ctx, doc.ID, nil)
 	if err != nil {
 		log.Errorf("Failed to list roles, err: %v", err)
 		hutil.AbortError(c, http.StatusInternalServerError, "list roles failed")
 		return
 	}
-
-	// 转换为 bailian.RoleInfo
-	roles := make([]bailian.RoleInfo, 0, len(dbRoles))
-	for _, r := range dbRoles {
-		roles = append(roles, bailian.RoleInfo{
-			Name:       r.Name,
-			Gender:     r.Gender,
-			Character:  r.Character,
-			Appearance: r.Appearance,
-		})
-	}
+	roles := volumeRoles(dbRoles, chapter.VolumeID)
 
 	// 5. 生成图片
 	log.Infof("Generating image for scene, sceneID: %s", sceneID)
-	imageURL, err := s.bailianClient.GenerateImage(ctx, args.Content, doc.Summary, roles)
+	variants, err := s.assets.GenerateImage(ctx, args.Content, doc.Summary, roles, sceneImageKey(scene.DocumentID, sceneID))
 	if err != nil {
 		log.Errorf("Failed to generate image, scene: %s, err: %v", sceneID, err)
 		hutil.AbortError(c, http.StatusInternalServerError, "generate image failed")
@@ -664,18 +924,24 @@ func (s *Service) HandleUpdateScene(c *gin.Context) {
 	}
 
 	// 更新图片 URL
-	err = s.db.UpdateSceneImageURL(ctx, sceneID, imageURL)
+	err = s.db.UpdateSceneImageURL(ctx, sceneID, variants.ImageURL)
 	if err != nil {
 		log.Errorf("Failed to update scene imageURL, err: %v", err)
 		hutil.AbortError(c, http.StatusInternalServerError, "update image failed")
 		return
 	}
+	err = s.db.UpdateSceneImageVariants(ctx, sceneID, variants.ThumbnailURL, variants.MediumURL)
+	if err != nil {
+		log.Errorf("Failed to update scene image variants, err: %v", err)
+		hutil.AbortError(c, http.StatusInternalServerError, "update image failed")
+		return
+	}
 
-	log.Infof("Image generated for scene: %s, URL: %s", sceneID, imageURL)
+	log.Infof("Image generated for scene: %s, URL: %s", sceneID, variants.ImageURL)
 
 	// 6. 生成语音
 	log.Infof("Generating TTS for scene, sceneID: %s", sceneID)
-	voiceURL, err := s.bailianClient.GenerateTTS(ctx, args.Content)
+	voiceURL, err := s.assets.GenerateTTS(ctx, args.Content)
 	if err != nil {
 		log.Errorf("Failed to generate TTS, scene: %s, err: %v", sceneID, err)
 		hutil.AbortError(c, http.StatusInternalServerError, "generate voice failed")
@@ -692,6 +958,11 @@ func (s *Service) HandleUpdateScene(c *gin.Context) {
 
 	log.Infof("Voice generated for scene: %s, URL: %s", sceneID, voiceURL)
 
+	// 记录新内容生成的图片和语音到修订中
+	if err := s.db.UpdateSceneRevisionMedia(ctx, sceneID, rev); err != nil {
+		log.Errorf("Failed to update scene revision media, sceneID: %s, rev: %d, err: %v", sceneID, rev, err)
+	}
+
 	// 7. 返回更新后的场景
 	scene, err = s.db.GetScene(ctx, sceneID)
 	if err != nil {
@@ -2,6 +2,7 @@ package svr
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -11,18 +12,20 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"go.uber.org/zap"
-	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
 
 	"imgagent/api"
 	"imgagent/bailian"
 	"imgagent/db"
+	"imgagent/pkg/dbutil"
 	"imgagent/pkg/logger"
 	"imgagent/proto"
+	"imgagent/spliter"
 	"imgagent/storage"
 )
 
@@ -39,12 +42,21 @@ func setupTestService(t *testing.T) (*Service, func()) {
 	require.NoError(t, err)
 
 	// 使用 SQLite 内存数据库进行测试
-	gormDB, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	gormDB, err := dbutil.NewDatabase(dbutil.Config{Driver: dbutil.DriverSQLite, Database: dbutil.SQLiteMemory})
 	require.NoError(t, err)
 
-	// 自动迁移表结构
-	err = gormDB.AutoMigrate(&db.Document{}, &db.Chapter{})
+	// 执行迁移脚本创建表结构
+	migrator, err := db.NewMigrator(gormDB)
 	require.NoError(t, err)
+	_, err = migrator.Up(context.Background())
+	require.NoError(t, err)
+
+	// 用户表由 xrobot 维护，不在迁移脚本中
+	require.NoError(t, gormDB.AutoMigrate(&db.User{}, &db.UserToken{}))
+	createTestUser(t, gormDB, 1, testAdminToken, true, time.Hour)
+	createTestUser(t, gormDB, 2, testUserToken, false, time.Hour)
+	createTestUser(t, gormDB, 3, testOtherToken, false, time.Hour)
+	createTestUser(t, gormDB, 4, testExpiredToken, false, -time.Hour)
 
 	database := &db.Database{}
 	database.SetDB(gormDB)
@@ -68,6 +80,7 @@ func setupTestService(t *testing.T) (*Service, func()) {
 		conf: Config{
 			APIVersion: "/v1",
 			Temp:       tempDir,
+			Origin:     tempDir,
 			Storage:    storage.Config{},
 		},
 		db:            database,
@@ -86,6 +99,35 @@ func setupTestService(t *testing.T) (*Service, func()) {
 	return service, cleanup
 }
 
+// setupTestService 创建的用户，1 为超级管理员，2 和 3 为普通用户，4 的 token 已过期
+const (
+	testAdminToken   = "admin-token"
+	testUserToken    = "user-token"
+	testOtherToken   = "other-token"
+	testExpiredToken = "expired-token"
+)
+
+func createTestUser(t *testing.T, gormDB *gorm.DB, id int64, token string, superAdmin bool, expire time.Duration) {
+	now := time.Now()
+	user := db.User{ID: id, Username: fmt.Sprintf("user%d", id), Status: 1, CreateDate: now, UpdateDate: now}
+	if superAdmin {
+		user.SuperAdmin = 1
+	}
+	require.NoError(t, gormDB.Create(&user).Error)
+	require.NoError(t, gormDB.Create(&db.UserToken{UserID: id, Token: token, ExpireDate: now.Add(expire), CreateDate: now, UpdateDate: now}).Error)
+}
+
+// testRouter 注册路由，没有 Authorization 的请求使用 token 认证
+func testRouter(service *Service, token string) http.Handler {
+	router := service.RegisterRouter(os.Stdout)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") == "" {
+			r.Header.Set("Authorization", "Bearer "+token)
+		}
+		router.ServeHTTP(w, r)
+	})
+}
+
 func TestDocumentCRUD(t *testing.T) {
 	// 注意：此测试需要真实的 Bailian API key，设置环境变量 BAILIAN_API_KEY 来指定
 	if os.Getenv("BAILIAN_API_KEY") == "" {
@@ -96,7 +138,7 @@ func TestDocumentCRUD(t *testing.T) {
 	service, cleanup := setupTestService(t)
 	defer cleanup()
 
-	router := service.RegisterRouter(os.Stdout)
+	router := testRouter(service, testAdminToken)
 
 	var createdDocID string
 	var createdChapterID string
@@ -319,7 +361,7 @@ func TestDocumentCRUD(t *testing.T) {
 		}
 
 		updateArgs := api.UpdateChapterArgs{
-			Content: "这是更新后的章节内容，用于测试。",
+			Content: "　　这是更新后的章节内容，\r\n\r\n\r\n　　用于测试。",
 		}
 		body, err := json.Marshal(updateArgs)
 		require.NoError(t, err)
@@ -343,7 +385,9 @@ func TestDocumentCRUD(t *testing.T) {
 		err = json.Unmarshal(chapterData, &chapter)
 		require.NoError(t, err)
 
-		assert.Equal(t, updateArgs.Content, chapter.Content)
+		// 保存时规范段落
+		assert.Equal(t, "这是更新后的章节内容，\n\n用于测试。", chapter.Content)
+		assert.Equal(t, []string{"这是更新后的章节内容，", "用于测试。"}, chapter.Paragraphs)
 		zap.S().Infof("更新章节内容成功")
 	})
 
@@ -405,7 +449,7 @@ func TestErrorCases(t *testing.T) {
 	service, cleanup := setupTestService(t)
 	defer cleanup()
 
-	router := service.RegisterRouter(os.Stdout)
+	router := testRouter(service, testAdminToken)
 
 	t.Run("创建文档 - 缺少 name", func(t *testing.T) {
 		body := &bytes.Buffer{}
@@ -481,6 +525,122 @@ func TestErrorCases(t *testing.T) {
 	})
 }
 
+func TestListVolumes(t *testing.T) {
+	service, cleanup := setupTestService(t)
+	defer cleanup()
+
+	router := testRouter(service, testAdminToken)
+	ctx := context.Background()
+
+	docID := db.MakeUUID()
+	chunks := []spliter.Chunk{
+		{Title: "序章", Content: "序章内容"},
+		{Title: "第一章", Content: "第一卷 风起\n\n第一章", Volume: "第一卷 风起", VolumeNumber: 1},
+		{Title: "第二章", Content: "第二章", Volume: "第一卷 风起", VolumeNumber: 1},
+		{Title: "第三章", Content: "第二卷\n\n第三章", Volume: "第二卷", VolumeNumber: 2},
+	}
+	volumes, chapters := makeVolumeChapters(docID, chunks)
+	require.Len(t, volumes, 2)
+	require.Len(t, chapters, 4)
+	assert.Equal(t, "", chapters[0].VolumeID)
+	assert.Equal(t, volumes[0].ID, chapters[1].VolumeID)
+	assert.Equal(t, volumes[0].ID, chapters[2].VolumeID)
+	assert.Equal(t, volumes[1].ID, chapters[3].VolumeID)
+	assert.Equal(t, 1, volumes[1].Index)
+
+	_, err := service.db.CreateDocument(ctx, docID, db.DocumentFile{}, &api.CreateDocumentArgs{Name: "分卷文档"})
+	require.NoError(t, err)
+	require.NoError(t, service.db.CreateVolumes(ctx, volumes))
+	require.NoError(t, service.db.CreateChapters(ctx, docID, chapters))
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/documents/"+docID+"/volumes", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var resp proto.BaseResponse
+	err = json.Unmarshal(w.Body.Bytes(), &resp)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.Code)
+
+	var result api.ListVolumesResult
+	b, _ := json.Marshal(resp.Data)
+	require.NoError(t, json.Unmarshal(b, &result))
+	require.Len(t, result.Volumes, 2)
+	assert.Equal(t, "第一卷 风起", result.Volumes[0].Title)
+	assert.Equal(t, 1, result.Volumes[0].Number)
+	assert.Len(t, result.Volumes[0].ChapterIDs, 2)
+	assert.Len(t, result.Volumes[1].ChapterIDs, 1)
+}
+
+func TestVolumeRoles(t *testing.T) {
+	dbRoles := []db.Role{
+		{Name: "祥子"},
+		{Name: "虎妞", VolumeID: "v1"},
+		{Name: "小福子", VolumeID: "v2"},
+		{Name: "祥子", VolumeID: "v2"},
+	}
+	names := func(roles []bailian.RoleInfo) []string {
+		var ret []string
+		for _, r := range roles {
+			ret = append(ret, r.Name)
+		}
+		return ret
+	}
+	assert.Equal(t, []string{"祥子", "虎妞"}, names(volumeRoles(dbRoles, "v1")))
+	assert.Equal(t, []string{"祥子", "小福子"}, names(volumeRoles(dbRoles, "v2")))
+	assert.Equal(t, []string{"祥子", "虎妞", "小福子"}, names(volumeRoles(dbRoles, "")))
+	// 该卷没有角色时使用全部角色
+	assert.Equal(t, []string{"虎妞"}, names(volumeRoles(dbRoles[1:2], "v3")))
+}
+
+func TestUpdateChapterParagraphs(t *testing.T) {
+	service, cleanup := setupTestService(t)
+	defer cleanup()
+
+	router := testRouter(service, testAdminToken)
+	ctx := context.Background()
+
+	docID := db.MakeUUID()
+	_, chapters := makeVolumeChapters(docID, []spliter.Chunk{{Title: "第一章", Content: "第一章\n\n旧内容", Prompt: "第一章\n旧内容"}})
+	require.NoError(t, service.db.CreateChapters(ctx, docID, chapters))
+	found, _, err := service.db.ListChapters(ctx, docID, nil)
+	require.NoError(t, err)
+	require.Len(t, found, 1)
+	assert.Equal(t, "第一章\n旧内容", found[0].Prompt)
+
+	update := func(content string) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(api.UpdateChapterArgs{Content: content})
+		req := httptest.NewRequest(http.MethodPut, fmt.Sprintf("/v1/documents/%s/chapters/%s", docID, found[0].ID), bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := update("　　“走吧。”  他说。\r\n\r\n\r\n　　她没有回答。")
+	var resp proto.BaseResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, http.StatusOK, resp.Code)
+	var chapter api.Chapter
+	b, _ := json.Marshal(resp.Data)
+	require.NoError(t, json.Unmarshal(b, &chapter))
+	assert.Equal(t, "“走吧。”  他说。\n\n她没有回答。", chapter.Content)
+	assert.Equal(t, []string{"“走吧。”  他说。", "她没有回答。"}, chapter.Paragraphs)
+
+	stored, err := service.db.GetChapter(ctx, found[0].ID, docID)
+	require.NoError(t, err)
+	assert.Equal(t, "“走吧。” 他说。\n她没有回答。", stored.Prompt)
+	assert.Equal(t, "“走吧。” 他说。\n她没有回答。", chapterPrompt(stored))
+	stored.Prompt = ""
+	assert.Equal(t, "“走吧。” 他说。\n她没有回答。", chapterPrompt(stored))
+
+	// 只有空白的内容
+	w = update(" \n\n ")
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+}
+
 // TestCreateDocumentWithSampleFile 使用小文件测试创建文档
 // 注意：此测试需要真实的 Bailian API key，设置环境变量 BAILIAN_API_KEY 来指定
 func TestCreateDocumentWithSampleFile(t *testing.T) {
@@ -492,7 +652,7 @@ func TestCreateDocumentWithSampleFile(t *testing.T) {
 	service, cleanup := setupTestService(t)
 	defer cleanup()
 
-	router := service.RegisterRouter(os.Stdout)
+	router := testRouter(service, testAdminToken)
 
 	// 创建临时测试文件
 	tempFile, err := os.CreateTemp("", "test-*.txt")
@@ -547,3 +707,256 @@ func TestCreateDocumentWithSampleFile(t *testing.T) {
 	assert.Equal(t, "测试文档", doc.Name)
 	zap.S().Infof("使用临时文件创建文档成功，ID: %s", doc.ID)
 }
+
+func TestListChaptersPagination(t *testing.T) {
+	service, cleanup := setupTestService(t)
+	defer cleanup()
+
+	router := testRouter(service, testAdminToken)
+	ctx := context.Background()
+
+	docID := db.MakeUUID()
+	_, chapters := makeVolumeChapters(docID, []spliter.Chunk{
+		{Title: "第一章", Kind: spliter.KindChapter, Content: "一"},
+		{Title: "第二章", Kind: spliter.KindChapter, Content: "二"},
+		{Title: "第三章", Kind: spliter.KindChapter, Content: "三"},
+		{Title: "番外", Kind: spliter.KindExtra, Content: "外"},
+	})
+	require.NoError(t, service.db.CreateChapters(ctx, docID, chapters))
+
+	list := func(query string) (int, api.ListChaptersResult) {
+		req := httptest.NewRequest(http.MethodGet, fmt.Sprintf("/v1/documents/%s/chapters?%s", docID, query), nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		var resp proto.BaseResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		var result api.ListChaptersResult
+		b, _ := json.Marshal(resp.Data)
+		require.NoError(t, json.Unmarshal(b, &result))
+		return resp.Code, result
+	}
+	titles := func(result api.ListChaptersResult) []string {
+		var ret []string
+		for _, c := range result.Chapters {
+			ret = append(ret, c.Title)
+		}
+		return ret
+	}
+
+	// 按游标翻页直到没有下一页
+	var got []string
+	query := "limit=3"
+	for {
+		code, result := list(query)
+		require.Equal(t, http.StatusOK, code)
+		assert.Equal(t, int64(4), result.Total)
+		got = append(got, titles(result)...)
+		if result.NextCursor == "" {
+			break
+		}
+		query = "limit=3&cursor=" + result.NextCursor
+	}
+	assert.Equal(t, []string{"第一章", "第二章", "第三章", "番外"}, got)
+
+	code, result := list("kind=chapter&sort=index&order=desc")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, int64(3), result.Total)
+	assert.Empty(t, result.NextCursor)
+	assert.Equal(t, []string{"第三章", "第二章", "第一章"}, titles(result))
+
+	for _, query := range []string{"cursor=bad", "limit=1001", "sort=content", "order=up"} {
+		code, _ := list(query)
+		assert.Equal(t, http.StatusBadRequest, code, query)
+	}
+}
+
+func TestDeleteAndRestoreDocument(t *testing.T) {
+	service, cleanup := setupTestService(t)
+	defer cleanup()
+
+	mgr, err := newDocumentMgr(DocumentConfigEx{db: service.db}, nil)
+	require.NoError(t, err)
+	service.documentMgr = mgr
+	router := testRouter(service, testAdminToken)
+	ctx := context.Background()
+
+	docID := db.MakeUUID()
+	_, err = service.db.CreateDocument(ctx, docID, db.DocumentFile{}, &api.CreateDocumentArgs{Name: "删除测试"})
+	require.NoError(t, err)
+	_, chapters := makeVolumeChapters(docID, []spliter.Chunk{{Title: "第一章", Content: "内容"}})
+	require.NoError(t, service.db.CreateChapters(ctx, docID, chapters))
+	require.NoError(t, service.db.CreateScenes(ctx, []db.Scene{{ID: db.MakeUUID(), DocumentID: docID, Content: "场景"}}))
+	require.NoError(t, service.db.CreateRoles(ctx, []db.Role{{ID: db.MakeUUID(), DocumentID: docID, Name: "祥子"}}))
+
+	// 后台正在处理该文档的任务在删除时被取消
+	started, stopped := make(chan struct{}), make(chan error)
+	go mgr.runDocument(ctx, docID, func(ctx context.Context) {
+		close(started)
+		<-ctx.Done()
+		stopped <- ctx.Err()
+	})
+	<-started
+
+	deleteDoc := func() int {
+		req := httptest.NewRequest(http.MethodDelete, "/v1/documents/"+docID, nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		var resp proto.BaseResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		return resp.Code
+	}
+	assert.Equal(t, http.StatusOK, deleteDoc())
+	select {
+	case err := <-stopped:
+		assert.ErrorIs(t, err, context.Canceled)
+	case <-time.After(5 * time.Second):
+		t.Fatal("document task not canceled")
+	}
+
+	scenes, _, err := service.db.ListScenesByDocument(ctx, docID, nil)
+	require.NoError(t, err)
+	assert.Empty(t, scenes)
+	roles, _, err := service.db.ListRolesByDocument(ctx, docID, nil)
+	require.NoError(t, err)
+	assert.Empty(t, roles)
+
+	// 已删除的文档不再处理
+	mgr.runDocument(ctx, docID, func(ctx context.Context) {
+		t.Error("deleted document should be skipped")
+	})
+	assert.Equal(t, ErrNoSuchDocumentCode, deleteDoc())
+
+	request := func(method, path string) proto.BaseResponse {
+		req := httptest.NewRequest(method, path, nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		var resp proto.BaseResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		return resp
+	}
+
+	// 回收站列表
+	resp := request(http.MethodGet, "/v1/trash/documents")
+	require.Equal(t, http.StatusOK, resp.Code)
+	var trash api.ListDocumentsResult
+	b, _ := json.Marshal(resp.Data)
+	require.NoError(t, json.Unmarshal(b, &trash))
+	assert.Equal(t, int64(1), trash.Total)
+	require.Len(t, trash.Documents, 1)
+	assert.Equal(t, docID, trash.Documents[0].ID)
+	assert.NotEmpty(t, trash.Documents[0].DeletedAt)
+
+	// 恢复文档及其场景和角色
+	resp = request(http.MethodPost, "/v1/documents/"+docID+"/restore")
+	require.Equal(t, http.StatusOK, resp.Code)
+	scenes, _, err = service.db.ListScenesByDocument(ctx, docID, nil)
+	require.NoError(t, err)
+	assert.Len(t, scenes, 1)
+	resp = request(http.MethodPost, "/v1/documents/"+docID+"/restore")
+	assert.Equal(t, ErrNoSuchDocumentCode, resp.Code)
+
+	// 单独删除的章节在文档回收站中，恢复时连同场景一起恢复
+	found, _, err := service.db.ListChapters(ctx, docID, nil)
+	require.NoError(t, err)
+	require.Len(t, found, 1)
+	require.NoError(t, service.db.UpdateChapterSceneIDs(ctx, found[0].ID, nil))
+	require.NoError(t, service.db.CreateScenes(ctx, []db.Scene{{ID: db.MakeUUID(), ChapterID: found[0].ID, DocumentID: docID, Content: "章节场景"}}))
+	chapterPath := fmt.Sprintf("/v1/documents/%s/chapters/%s", docID, found[0].ID)
+	require.Equal(t, http.StatusOK, request(http.MethodDelete, chapterPath).Code)
+	resp = request(http.MethodGet, "/v1/documents/"+docID+"/trash")
+	require.Equal(t, http.StatusOK, resp.Code)
+	var docTrash api.DocumentTrashResult
+	b, _ = json.Marshal(resp.Data)
+	require.NoError(t, json.Unmarshal(b, &docTrash))
+	require.Len(t, docTrash.Chapters, 1)
+	assert.Empty(t, docTrash.Scenes)
+	require.Equal(t, http.StatusOK, request(http.MethodPost, chapterPath+"/restore").Code)
+	assert.Equal(t, http.StatusNotFound, request(http.MethodPost, chapterPath+"/restore").Code)
+	chapterScenes, err := service.db.ListScenesByChapter(ctx, found[0].ID)
+	require.NoError(t, err)
+	assert.Len(t, chapterScenes, 1)
+
+	// 超过保留期后彻底删除，并删除原始文件
+	originFile := filepath.Join(service.conf.Origin, docID+".txt")
+	require.NoError(t, os.WriteFile(originFile, []byte("内容"), 0o644))
+	purgeID := db.MakeUUID()
+	_, err = service.db.CreateDocument(ctx, purgeID, db.DocumentFile{OriginFile: originFile}, &api.CreateDocumentArgs{Name: "删除测试2"})
+	require.NoError(t, err)
+	require.NoError(t, service.db.TrashDocument(ctx, purgeID))
+	mgr.PurgeTrashTasks(ctx)
+	_, total, err := service.db.ListTrashedDocuments(ctx, nil)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), total)
+
+	mgr.config.TrashRetentionDays = -1
+	mgr.PurgeTrashTasks(ctx)
+	_, total, err = service.db.ListTrashedDocuments(ctx, nil)
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), total)
+	assert.NoFileExists(t, originFile)
+	assert.Equal(t, ErrNoSuchDocumentCode, request(http.MethodPost, "/v1/documents/"+purgeID+"/restore").Code)
+	_, err = service.db.GetDocument(ctx, docID)
+	assert.NoError(t, err)
+}
+
+func TestChapterRevisions(t *testing.T) {
+	service, cleanup := setupTestService(t)
+	defer cleanup()
+
+	router := testRouter(service, testAdminToken)
+	ctx := context.Background()
+
+	docID := db.MakeUUID()
+	_, chapters := makeVolumeChapters(docID, []spliter.Chunk{{Title: "第一章", Kind: spliter.KindChapter, Content: "原文"}})
+	require.NoError(t, service.db.CreateChapters(ctx, docID, chapters))
+	found, _, err := service.db.ListChapters(ctx, docID, nil)
+	require.NoError(t, err)
+	require.Len(t, found, 1)
+	chapterPath := fmt.Sprintf("/v1/documents/%s/chapters/%s", docID, found[0].ID)
+
+	request := func(method, path string, body any) proto.BaseResponse {
+		var reader io.Reader
+		if body != nil {
+			b, _ := json.Marshal(body)
+			reader = bytes.NewReader(b)
+		}
+		req := httptest.NewRequest(method, path, reader)
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		var resp proto.BaseResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		return resp
+	}
+	listRevisions := func() api.ListChapterRevisionsResult {
+		resp := request(http.MethodGet, chapterPath+"/revisions", nil)
+		require.Equal(t, http.StatusOK, resp.Code)
+		var result api.ListChapterRevisionsResult
+		b, _ := json.Marshal(resp.Data)
+		require.NoError(t, json.Unmarshal(b, &result))
+		return result
+	}
+
+	require.Equal(t, http.StatusOK, request(http.MethodPut, chapterPath, api.UpdateChapterArgs{Content: "修改后"}).Code)
+	result := listRevisions()
+	assert.Equal(t, int64(2), result.Total)
+	require.Len(t, result.Revisions, 2)
+	assert.Equal(t, "修改后", result.Revisions[0].Content)
+	assert.Equal(t, "-原文\n+修改后\n", result.Revisions[0].Diff)
+	assert.Equal(t, "原文", result.Revisions[1].Content)
+
+	// 恢复原始版本
+	resp := request(http.MethodPost, chapterPath+"/revisions/1/restore", nil)
+	require.Equal(t, http.StatusOK, resp.Code)
+	var chapter api.Chapter
+	b, _ := json.Marshal(resp.Data)
+	require.NoError(t, json.Unmarshal(b, &chapter))
+	assert.Equal(t, "原文", chapter.Content)
+	result = listRevisions()
+	require.Len(t, result.Revisions, 3)
+	assert.Equal(t, 1, result.Revisions[0].RestoredFrom)
+
+	assert.Equal(t, http.StatusNotFound, request(http.MethodPost, chapterPath+"/revisions/9/restore", nil).Code)
+	assert.Equal(t, http.StatusBadRequest, request(http.MethodPost, chapterPath+"/revisions/x/restore", nil).Code)
+	assert.Equal(t, http.StatusNotFound, request(http.MethodGet, fmt.Sprintf("/v1/documents/%s/chapters/missing/revisions", docID), nil).Code)
+}
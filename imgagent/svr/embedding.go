@@ -0,0 +1,197 @@
+package svr
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"imgagent/api"
+	"imgagent/db"
+	"imgagent/embedding"
+	"imgagent/pkg/logger"
+)
+
+const (
+	defaultEmbeddingBatchSize     = 10 // DashScope 每次请求最多 10 条
+	defaultEmbeddingMaxInputChars = 4000
+	defaultEmbeddingIntervalSecs  = 30
+	defaultEmbeddingTimeout       = 60
+
+	// embeddingTaskLimit 每次后台任务最多生成向量的记录数
+	embeddingTaskLimit = 500
+)
+
+// Embedder 为章节和场景生成向量，保存到数据库并维护内存中的向量索引
+// 向量索引在启动时从数据库加载，内容修改后旧向量在重新生成前仍参与检索
+type Embedder struct {
+	conf   EmbeddingConfig
+	db     db.IDataBase
+	client *embedding.Client
+	index  embedding.Index
+}
+
+// newEmbedder 创建 Embedder 并加载已生成的向量，未配置 URL 时返回 nil
+func newEmbedder(conf EmbeddingConfig, database db.IDataBase) (*Embedder, error) {
+	if conf.URL == "" {
+		return nil, nil
+	}
+	if conf.Model == "" {
+		return nil, errors.New("embedding model is required")
+	}
+	if conf.BatchSize <= 0 {
+		conf.BatchSize = defaultEmbeddingBatchSize
+	}
+	if conf.MaxInputChars <= 0 {
+		conf.MaxInputChars = defaultEmbeddingMaxInputChars
+	}
+	if conf.IntervalSecs <= 0 {
+		conf.IntervalSecs = defaultEmbeddingIntervalSecs
+	}
+	if conf.RequestTimeout <= 0 {
+		conf.RequestTimeout = defaultEmbeddingTimeout
+	}
+	index, err := embedding.NewIndex(conf.Index)
+	if err != nil {
+		return nil, err
+	}
+
+	e := &Embedder{
+		conf: conf,
+		db:   database,
+		client: &embedding.Client{
+			URL:        conf.URL,
+			Model:      conf.Model,
+			APIKey:     conf.APIKey,
+			Dimensions: conf.Dimensions,
+			HTTPClient: &http.Client{Timeout: time.Duration(conf.RequestTimeout) * time.Second},
+		},
+		index: index,
+	}
+	ctx := logger.NewContext("LoadEmbeddings")
+	embeddings, err := database.ListEmbeddings(ctx, conf.Model)
+	if err != nil {
+		return nil, err
+	}
+	for _, emb := range embeddings {
+		e.index.Add(embeddingItem(&emb))
+	}
+	logger.FromContext(ctx).Infof("Embeddings loaded, model: %s, count: %d", conf.Model, e.index.Len())
+	return e, nil
+}
+
+func embeddingItem(emb *db.Embedding) embedding.Item {
+	return embedding.Item{
+		Type:       emb.TargetType,
+		ID:         emb.TargetID,
+		DocumentID: emb.DocumentID,
+		Vector:     db.DecodeVector(emb.Vector),
+	}
+}
+
+// input 生成向量的文本，章节包含标题，超过 MaxInputChars 的部分截断
+func (e *Embedder) input(hit *db.SearchHit) string {
+	text := hit.Text
+	if hit.Type == api.SearchTypeChapter && hit.Title != "" {
+		text = hit.Title + "\n" + text
+	}
+	if runes := []rune(text); len(runes) > e.conf.MaxInputChars {
+		text = string(runes[:e.conf.MaxInputChars])
+	}
+	return text
+}
+
+// HandleEmbeddingTasks 为还没有向量的章节和场景生成向量，请求失败时等待下次任务重试
+func (e *Embedder) HandleEmbeddingTasks(ctx context.Context) {
+	log := logger.FromContext(ctx)
+
+	hits, err := e.db.ListPendingEmbeddings(ctx, e.conf.Model, embeddingTaskLimit)
+	if err != nil {
+		log.Errorf("Failed to list pending embeddings, err: %v", err)
+		return
+	}
+	if len(hits) == 0 {
+		return
+	}
+
+	total := 0
+	for start := 0; start < len(hits); start += e.conf.BatchSize {
+		batch := hits[start:min(start+e.conf.BatchSize, len(hits))]
+		inputs := make([]string, len(batch))
+		for i := range batch {
+			inputs[i] = e.input(&batch[i])
+		}
+		vectors, err := e.client.Embed(ctx, inputs)
+		if err != nil {
+			log.Errorf("Failed to embed, err: %v", err)
+			return
+		}
+
+		now := time.Now()
+		embeddings := make([]db.Embedding, len(batch))
+		for i, hit := range batch {
+			embeddings[i] = db.Embedding{
+				TargetType:  hit.Type,
+				TargetID:    hit.ID,
+				DocumentID:  hit.DocumentID,
+				Model:       e.conf.Model,
+				ContentHash: db.ContentHash(hit.Text),
+				Vector:      db.EncodeVector(vectors[i]),
+				CreatedAt:   now,
+			}
+		}
+		saved, err := e.db.SaveEmbeddings(ctx, embeddings)
+		if err != nil {
+			log.Errorf("Failed to save embeddings, err: %v", err)
+			return
+		}
+		for _, emb := range saved {
+			e.index.Add(embeddingItem(&emb))
+		}
+		total += len(saved)
+	}
+	log.Infof("Embeddings generated, count: %d, pending: %d", total, len(hits))
+}
+
+// Search 检索文档中与 q 语义相近的章节和场景，按相似度倒序返回，回收站中的记录不返回
+func (e *Embedder) Search(ctx context.Context, documentID string, args *api.SemanticSearchArgs) ([]db.SearchHit, error) {
+	limit := args.Limit
+	if limit <= 0 {
+		limit = api.DefaultSearchLimit
+	}
+	vectors, err := e.client.Embed(ctx, []string{args.Q})
+	if err != nil {
+		return nil, err
+	}
+
+	// 回收站中的记录在查询数据库时过滤，多取一些候选
+	candidates := e.index.Search(embedding.Query{DocumentID: documentID, Vector: vectors[0], K: 2 * limit, Type: args.Type})
+	ids := make(map[string][]string)
+	for _, c := range candidates {
+		ids[c.Type] = append(ids[c.Type], c.ID)
+	}
+	records := make(map[string]db.SearchHit, len(candidates))
+	for typ, typeIDs := range ids {
+		hits, err := e.db.GetSearchHits(ctx, typ, typeIDs)
+		if err != nil {
+			return nil, err
+		}
+		for _, hit := range hits {
+			records[hit.Type+"/"+hit.ID] = hit
+		}
+	}
+
+	var hits []db.SearchHit
+	for _, c := range candidates {
+		hit, ok := records[c.Type+"/"+c.ID]
+		if !ok {
+			continue
+		}
+		hit.Score = float64(c.Score)
+		hits = append(hits, hit)
+		if len(hits) == limit {
+			break
+		}
+	}
+	return hits, nil
+}
@@ -0,0 +1,78 @@
+package svr
+
+import (
+	"context"
+	"errors"
+	"mime"
+	"os"
+	"path/filepath"
+
+	"imgagent/imgproc"
+	"imgagent/pkg/logger"
+	"imgagent/storage"
+)
+
+// imageVariants 图片原图及各规格的访问 URL
+type imageVariants struct {
+	ImageURL     string
+	ThumbnailURL string
+	MediumURL    string
+}
+
+// storeImage 下载百炼生成的图片，生成缩略图和中图后与原图一起上传到存储，key 为不含扩展名的对象名
+// 后处理失败不影响生成结果，回退为百炼返回的原始 URL
+func storeImage(ctx context.Context, stg *storage.Storage, temp, imageURL, key string) imageVariants {
+	log := logger.FromContext(ctx)
+
+	variants, err := processImage(ctx, stg, temp, imageURL, key)
+	if err != nil {
+		log.Warnf("Failed to process image, url: %s, err: %v", imageURL, err)
+		return imageVariants{ImageURL: imageURL}
+	}
+	log.Infof("Image processed, key: %s, variants: %+v", key, variants)
+	return variants
+}
+
+func processImage(ctx context.Context, stg *storage.Storage, temp, imageURL, key string) (imageVariants, error) {
+	if stg == nil {
+		return imageVariants{}, errors.New("storage not configured")
+	}
+
+	filename, err := downloadFile(ctx, temp, imageURL)
+	if err != nil {
+		return imageVariants{}, err
+	}
+	defer os.Remove(filename)
+
+	files, err := imgproc.Process(ctx, filename, imgproc.DefaultVariants)
+	for _, f := range files {
+		defer os.Remove(f)
+	}
+	if err != nil {
+		return imageVariants{}, err
+	}
+
+	ext := filepath.Ext(filename)
+	var variants imageVariants
+	variants.ImageURL, err = stg.UploadFile(ctx, key+ext, filename, mime.TypeByExtension(ext))
+	if err != nil {
+		return imageVariants{}, err
+	}
+	variants.ThumbnailURL, err = stg.UploadFile(ctx, key+"_"+imgproc.VariantThumbnail+".jpg", files[imgproc.VariantThumbnail], "image/jpeg")
+	if err != nil {
+		return imageVariants{}, err
+	}
+	variants.MediumURL, err = stg.UploadFile(ctx, key+"_"+imgproc.VariantMedium+".jpg", files[imgproc.VariantMedium], "image/jpeg")
+	if err != nil {
+		return imageVariants{}, err
+	}
+	return variants, nil
+}
+
+func sceneImageKey(docID, sceneID string) string {
+	return "images/" + docID + "/" + sceneID
+}
+
+func coverImageKey(docID string) string {
+	return "images/" + docID + "/cover"
+}
@@ -0,0 +1,60 @@
+package svr
+
+import (
+	"encoding/base64"
+	"errors"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"imgagent/api"
+)
+
+// errInvalidCursor 游标不是服务端生成的
+var errInvalidCursor = errors.New("invalid cursor")
+
+// encodeCursor 将下一页的偏移量编码为不透明的游标
+func encodeCursor(offset int) string {
+	return base64.RawURLEncoding.EncodeToString([]byte("o:" + strconv.Itoa(offset)))
+}
+
+// decodeCursor 解析 encodeCursor 生成的游标，为空时返回 0
+func decodeCursor(cursor string) (int, error) {
+	if cursor == "" {
+		return 0, nil
+	}
+	b, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil || len(b) < 3 || string(b[:2]) != "o:" {
+		return 0, errInvalidCursor
+	}
+	offset, err := strconv.Atoi(string(b[2:]))
+	if err != nil || offset < 0 {
+		return 0, errInvalidCursor
+	}
+	return offset, nil
+}
+
+// bindListArgs 绑定列表接口的查询参数，page 为 args 中内嵌的分页参数
+func bindListArgs(c *gin.Context, args any, page *api.ListArgs) error {
+	if err := c.ShouldBindQuery(args); err != nil {
+		return err
+	}
+	offset, err := decodeCursor(page.Cursor)
+	if err != nil {
+		return err
+	}
+	page.Offset = offset
+	if page.Limit == 0 {
+		page.Limit = api.DefaultListLimit
+	}
+	return nil
+}
+
+// makePagination 根据本页数量和总数生成分页信息
+func makePagination(page *api.ListArgs, count int, total int64) api.Pagination {
+	p := api.Pagination{Total: total}
+	if next := page.Offset + count; count > 0 && int64(next) < total {
+		p.NextCursor = encodeCursor(next)
+	}
+	return p
+}
@@ -0,0 +1,233 @@
+package svr
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"imgagent/api"
+	"imgagent/db"
+	hutil "imgagent/httputil"
+	"imgagent/pkg/logger"
+)
+
+const (
+	ErrNoSuchMemberCode = 631
+	ErrNoSuchUserCode   = 632
+	ErrNoSuchMember     = "no such member"
+	ErrNoSuchUser       = "no such user"
+)
+
+// memberRoleLevels 角色的权限级别，高的角色包含低的角色的权限
+var memberRoleLevels = map[string]int{
+	api.MemberRoleViewer: 1,
+	api.MemberRoleEditor: 2,
+	api.MemberRoleOwner:  3,
+}
+
+// documentResolver 返回请求访问的文档 id
+type documentResolver func(c *gin.Context) (string, error)
+
+// documentParam 从路由参数 document_id 获取文档 id
+func documentParam(c *gin.Context) (string, error) {
+	return c.Param("document_id"), nil
+}
+
+// chapterDocument 从路由参数 chapter_id 指定的章节获取文档 id
+func (s *Service) chapterDocument(c *gin.Context) (string, error) {
+	return s.db.RecordDocumentID(c.Request.Context(), api.SearchTypeChapter, c.Param("chapter_id"))
+}
+
+// sceneDocument 从路由参数 id 指定的场景获取文档 id
+func (s *Service) sceneDocument(c *gin.Context) (string, error) {
+	return s.db.RecordDocumentID(c.Request.Context(), api.SearchTypeScene, c.Param("id"))
+}
+
+// roleDocument 从路由参数 id 指定的角色获取文档 id
+func (s *Service) roleDocument(c *gin.Context) (string, error) {
+	return s.db.RecordDocumentID(c.Request.Context(), api.SearchTypeRole, c.Param("id"))
+}
+
+// RequireRole 检查当前用户在请求访问的文档中至少具有 role 角色，超级管理员不检查
+// 文档不存在或用户不是成员时返回文档不存在，角色不足时返回 403
+func (s *Service) RequireRole(role string, resolve documentResolver) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+		log := logger.FromGinContext(c)
+
+		ui := GetUserInfo(c)
+		if ui.SuperAdmin {
+			c.Next()
+			return
+		}
+
+		docID, err := resolve(c)
+		if err != nil {
+			log.Warnf("Failed to get document of request, err: %v", err)
+			documentErr(c, err, "get document failed")
+			return
+		}
+		userRole, err := s.db.DocumentRole(ctx, docID, ui.ID)
+		if err != nil {
+			log.Warnf("Failed to get document role, docID: %s, user: %d, err: %v", docID, ui.ID, err)
+			documentErr(c, err, "get document role failed")
+			return
+		}
+		if memberRoleLevels[userRole] < memberRoleLevels[role] {
+			log.Warnf("Permission denied, docID: %s, user: %d, role: %s, required: %s", docID, ui.ID, userRole, role)
+			hutil.AbortError(c, http.StatusForbidden, "permission denied")
+			return
+		}
+		c.Next()
+	}
+}
+
+// viewer、editor 和 owner 分别要求文档的查看、编辑和所有者权限
+func (s *Service) viewer(resolve documentResolver) gin.HandlerFunc {
+	return s.RequireRole(api.MemberRoleViewer, resolve)
+}
+
+func (s *Service) editor(resolve documentResolver) gin.HandlerFunc {
+	return s.RequireRole(api.MemberRoleEditor, resolve)
+}
+
+func (s *Service) owner(resolve documentResolver) gin.HandlerFunc {
+	return s.RequireRole(api.MemberRoleOwner, resolve)
+}
+
+// HandleListMembers 获取文档成员，创建者在最前面
+func (s *Service) HandleListMembers(c *gin.Context) {
+	ctx := c.Request.Context()
+	log := logger.FromGinContext(c)
+
+	docID := c.Param("document_id")
+	log.Infof("List members, docID: %s", docID)
+	doc, err := s.db.GetDocument(ctx, docID)
+	if err != nil {
+		log.Errorf("Failed to get document, id: %s, err: %v", docID, err)
+		documentErr(c, err, "get document failed")
+		return
+	}
+	members, err := s.db.ListMembers(ctx, docID)
+	if err != nil {
+		log.Errorf("Failed to list members, err: %v", err)
+		hutil.AbortError(c, http.StatusInternalServerError, "list members failed")
+		return
+	}
+
+	// 升级前创建的文档没有创建者
+	uids := make([]int64, 0, len(members)+1)
+	if doc.OwnerID != 0 {
+		uids = append(uids, doc.OwnerID)
+	}
+	for _, m := range members {
+		uids = append(uids, m.UserID)
+	}
+	users, err := s.db.ListUsers(ctx, uids)
+	if err != nil {
+		log.Errorf("Failed to list users, err: %v", err)
+		hutil.AbortError(c, http.StatusInternalServerError, "list members failed")
+		return
+	}
+	names := make(map[int64]string, len(users))
+	for _, u := range users {
+		names[u.ID] = u.Username
+	}
+
+	ret := &api.ListMembersResult{Members: []api.Member{}}
+	if doc.OwnerID != 0 {
+		ret.Members = append(ret.Members, api.Member{
+			UserID:    doc.OwnerID,
+			Username:  names[doc.OwnerID],
+			Role:      api.MemberRoleOwner,
+			Creator:   true,
+			CreatedAt: doc.CreatedAt.Format(time.DateTime),
+		})
+	}
+	for _, m := range members {
+		ret.Members = append(ret.Members, makeMember(&m, names[m.UserID]))
+	}
+	hutil.WriteData(c, ret)
+}
+
+func makeMember(m *db.DocumentMember, username string) api.Member {
+	return api.Member{
+		UserID:    m.UserID,
+		Username:  username,
+		Role:      m.Role,
+		CreatedAt: m.CreatedAt.Format(time.DateTime),
+	}
+}
+
+// HandleAddMember 邀请用户成为文档成员，用户已是成员时修改角色
+func (s *Service) HandleAddMember(c *gin.Context) {
+	ctx := c.Request.Context()
+	log := logger.FromGinContext(c)
+
+	docID := c.Param("document_id")
+	var args api.AddMemberArgs
+	if err := c.ShouldBindJSON(&args); err != nil {
+		log.Errorf("Invalid request body, err: %v", err)
+		hutil.AbortError(c, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	log.Infof("Add member, docID: %s, args: %+v", docID, args)
+	doc, err := s.db.GetDocument(ctx, docID)
+	if err != nil {
+		log.Errorf("Failed to get document, id: %s, err: %v", docID, err)
+		documentErr(c, err, "get document failed")
+		return
+	}
+	if args.UserID == doc.OwnerID {
+		hutil.AbortError(c, http.StatusBadRequest, "user is the document creator")
+		return
+	}
+	user, err := s.db.User(ctx, args.UserID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			hutil.AbortError(c, ErrNoSuchUserCode, ErrNoSuchUser)
+			return
+		}
+		log.Errorf("Failed to get user %d, err: %v", args.UserID, err)
+		hutil.AbortError(c, http.StatusInternalServerError, "get user failed")
+		return
+	}
+
+	member := db.DocumentMember{DocumentID: docID, UserID: args.UserID, Role: args.Role}
+	if err := s.db.SaveMember(ctx, &member); err != nil {
+		log.Errorf("Failed to save member, err: %v", err)
+		hutil.AbortError(c, http.StatusInternalServerError, "add member failed")
+		return
+	}
+	hutil.WriteData(c, makeMember(&member, user.Username))
+}
+
+// HandleRemoveMember 移除文档成员，文档创建者不能移除
+func (s *Service) HandleRemoveMember(c *gin.Context) {
+	ctx := c.Request.Context()
+	log := logger.FromGinContext(c)
+
+	docID := c.Param("document_id")
+	userID, err := strconv.ParseInt(c.Param("user_id"), 10, 64)
+	if err != nil {
+		hutil.AbortError(c, http.StatusBadRequest, "invalid user id")
+		return
+	}
+
+	log.Infof("Remove member, docID: %s, user: %d", docID, userID)
+	if err := s.db.DeleteMember(ctx, docID, userID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			hutil.AbortError(c, ErrNoSuchMemberCode, ErrNoSuchMember)
+			return
+		}
+		log.Errorf("Failed to delete member, err: %v", err)
+		hutil.AbortError(c, http.StatusInternalServerError, "remove member failed")
+		return
+	}
+	hutil.WriteData(c, nil)
+}
@@ -0,0 +1,121 @@
+package svr
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"imgagent/api"
+	"imgagent/db"
+	"imgagent/proto"
+)
+
+func TestDocumentMembers(t *testing.T) {
+	service, cleanup := setupTestService(t)
+	defer cleanup()
+
+	creator := testRouter(service, testUserToken)
+	member := testRouter(service, testOtherToken)
+	do := func(router http.Handler, method, path string, body any) proto.BaseResponse {
+		var b []byte
+		if body != nil {
+			b, _ = json.Marshal(body)
+		}
+		req := httptest.NewRequest(method, path, bytes.NewReader(b))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		var resp proto.BaseResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		return resp
+	}
+
+	// 用户 2 创建的文档
+	ctx := db.WithOwner(t.Context(), db.Owner{ID: 2})
+	docID := db.MakeUUID()
+	_, err := service.db.CreateDocument(ctx, docID, db.DocumentFile{}, &api.CreateDocumentArgs{Name: "骆驼祥子"})
+	require.NoError(t, err)
+	require.NoError(t, service.db.CreateChapters(ctx, docID, []api.CreateChapterArgs{{Title: "第一章", Content: "祥子拉车"}}))
+	chapters, _, err := service.db.ListChapters(ctx, docID, nil)
+	require.NoError(t, err)
+	roleID := db.MakeUUID()
+	require.NoError(t, service.db.CreateRoles(ctx, []db.Role{{ID: roleID, DocumentID: docID, Name: "祥子"}}))
+
+	docPath := "/v1/documents/" + docID
+	chapterPath := docPath + "/chapters/" + chapters[0].ID
+	rolePath := "/v1/roles/" + roleID
+	roleArgs := api.UpdateRoleArgs{Name: "祥子", Gender: "男", Character: "要强", Appearance: "高大"}
+
+	// 不是成员时看不到文档，顶层路由同样检查
+	assert.Equal(t, ErrNoSuchDocumentCode, do(member, http.MethodGet, docPath, nil).Code)
+	assert.Equal(t, ErrNoSuchDocumentCode, do(member, http.MethodPut, rolePath, roleArgs).Code)
+	assert.Equal(t, ErrNoSuchDocumentCode, do(member, http.MethodGet, "/v1/chapters/"+chapters[0].ID+"/scenes", nil).Code)
+
+	// 邀请参数校验
+	assert.Equal(t, http.StatusBadRequest, do(creator, http.MethodPost, docPath+"/members", api.AddMemberArgs{UserID: 3, Role: "admin"}).Code)
+	assert.Equal(t, http.StatusBadRequest, do(creator, http.MethodPost, docPath+"/members", api.AddMemberArgs{UserID: 2, Role: api.MemberRoleViewer}).Code)
+	assert.Equal(t, ErrNoSuchUserCode, do(creator, http.MethodPost, docPath+"/members", api.AddMemberArgs{UserID: 99, Role: api.MemberRoleViewer}).Code)
+
+	// viewer 可以查看，不能编辑和管理成员
+	resp := do(creator, http.MethodPost, docPath+"/members", api.AddMemberArgs{UserID: 3, Role: api.MemberRoleViewer})
+	require.Equal(t, http.StatusOK, resp.Code)
+	assert.Equal(t, api.MemberRoleViewer, resp.Data.(map[string]any)["role"])
+	assert.Equal(t, http.StatusOK, do(member, http.MethodGet, docPath, nil).Code)
+	assert.Equal(t, http.StatusOK, do(member, http.MethodGet, chapterPath, nil).Code)
+	assert.Equal(t, http.StatusOK, do(member, http.MethodGet, "/v1/roles/"+roleID+"/revisions", nil).Code)
+	resp = do(member, http.MethodGet, "/v1/search?q=祥子", nil)
+	require.Equal(t, http.StatusOK, resp.Code)
+	assert.Len(t, resp.Data.(map[string]any)["records"], 2)
+	assert.Equal(t, http.StatusForbidden, do(member, http.MethodPut, chapterPath, api.UpdateChapterArgs{Content: "虎妞"}).Code)
+	assert.Equal(t, http.StatusForbidden, do(member, http.MethodPut, rolePath, roleArgs).Code)
+	assert.Equal(t, http.StatusForbidden, do(member, http.MethodPost, docPath+"/members", api.AddMemberArgs{UserID: 4, Role: api.MemberRoleViewer}).Code)
+
+	resp = do(member, http.MethodGet, docPath+"/members", nil)
+	require.Equal(t, http.StatusOK, resp.Code)
+	var members api.ListMembersResult
+	b, _ := json.Marshal(resp.Data)
+	require.NoError(t, json.Unmarshal(b, &members))
+	require.Len(t, members.Members, 2)
+	assert.Equal(t, int64(2), members.Members[0].UserID)
+	assert.Equal(t, "user2", members.Members[0].Username)
+	assert.Equal(t, api.MemberRoleOwner, members.Members[0].Role)
+	assert.True(t, members.Members[0].Creator)
+	assert.Equal(t, int64(3), members.Members[1].UserID)
+	assert.Equal(t, api.MemberRoleViewer, members.Members[1].Role)
+	assert.False(t, members.Members[1].Creator)
+
+	// editor 可以编辑，不能删除文档
+	require.Equal(t, http.StatusOK, do(creator, http.MethodPost, docPath+"/members", api.AddMemberArgs{UserID: 3, Role: api.MemberRoleEditor}).Code)
+	assert.Equal(t, http.StatusOK, do(member, http.MethodPut, chapterPath, api.UpdateChapterArgs{Content: "虎妞"}).Code)
+	assert.Equal(t, http.StatusOK, do(member, http.MethodPut, rolePath, roleArgs).Code)
+	assert.Equal(t, http.StatusForbidden, do(member, http.MethodDelete, docPath, nil).Code)
+
+	// 只能查看的文档中的章节不能通过可编辑文档的路径修改
+	otherID := db.MakeUUID()
+	_, err = service.db.CreateDocument(ctx, otherID, db.DocumentFile{}, &api.CreateDocumentArgs{Name: "四世同堂"})
+	require.NoError(t, err)
+	require.NoError(t, service.db.CreateChapters(ctx, otherID, []api.CreateChapterArgs{{Title: "第一章", Content: "祁老人"}}))
+	otherChapters, _, err := service.db.ListChapters(ctx, otherID, nil)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, do(creator, http.MethodPost, "/v1/documents/"+otherID+"/members", api.AddMemberArgs{UserID: 3, Role: api.MemberRoleViewer}).Code)
+	assert.Equal(t, ErrNoSuchDocumentCode, do(member, http.MethodPut, docPath+"/chapters/"+otherChapters[0].ID, api.UpdateChapterArgs{Content: "虎妞"}).Code)
+	chapter, err := service.db.GetChapter(ctx, otherChapters[0].ID, otherID)
+	require.NoError(t, err)
+	assert.Equal(t, "祁老人", chapter.Content)
+
+	// 移除后不可见
+	assert.Equal(t, ErrNoSuchMemberCode, do(creator, http.MethodDelete, docPath+"/members/4", nil).Code)
+	assert.Equal(t, http.StatusOK, do(creator, http.MethodDelete, docPath+"/members/3", nil).Code)
+	assert.Equal(t, ErrNoSuchDocumentCode, do(member, http.MethodGet, docPath, nil).Code)
+
+	// owner 可以管理成员和删除文档
+	require.Equal(t, http.StatusOK, do(creator, http.MethodPost, docPath+"/members", api.AddMemberArgs{UserID: 3, Role: api.MemberRoleOwner}).Code)
+	assert.Equal(t, http.StatusOK, do(member, http.MethodPost, docPath+"/members", api.AddMemberArgs{UserID: 4, Role: api.MemberRoleViewer}).Code)
+	assert.Equal(t, http.StatusOK, do(member, http.MethodDelete, docPath, nil).Code)
+	assert.Equal(t, http.StatusOK, do(creator, http.MethodPost, docPath+"/restore", nil).Code)
+}
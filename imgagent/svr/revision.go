@@ -0,0 +1,318 @@
+package svr
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"imgagent/api"
+	"imgagent/db"
+	hutil "imgagent/httputil"
+	"imgagent/pkg/logger"
+)
+
+func makeChapterRevision(r *db.ChapterRevision) api.ChapterRevision {
+	return api.ChapterRevision{
+		Rev:          r.Rev,
+		ChapterID:    r.ChapterID,
+		DocumentID:   r.DocumentID,
+		Author:       r.Author,
+		Content:      r.Content,
+		Diff:         r.Diff,
+		RestoredFrom: r.RestoredFrom,
+		CreatedAt:    r.CreatedAt.Format(time.DateTime),
+	}
+}
+
+func makeSceneRevision(r *db.SceneRevision) api.SceneRevision {
+	return api.SceneRevision{
+		Rev:          r.Rev,
+		SceneID:      r.SceneID,
+		DocumentID:   r.DocumentID,
+		Author:       r.Author,
+		Content:      r.Content,
+		ImageURL:     r.ImageURL,
+		ThumbnailURL: r.ThumbnailURL,
+		MediumURL:    r.MediumURL,
+		VoiceURL:     r.VoiceURL,
+		Diff:         r.Diff,
+		RestoredFrom: r.RestoredFrom,
+		CreatedAt:    r.CreatedAt.Format(time.DateTime),
+	}
+}
+
+func makeRoleRevision(r *db.RoleRevision) api.RoleRevision {
+	return api.RoleRevision{
+		Rev:          r.Rev,
+		RoleID:       r.RoleID,
+		DocumentID:   r.DocumentID,
+		Author:       r.Author,
+		Name:         r.Name,
+		Gender:       r.Gender,
+		Character:    r.Character,
+		Appearance:   r.Appearance,
+		Diff:         r.Diff,
+		RestoredFrom: r.RestoredFrom,
+		CreatedAt:    r.CreatedAt.Format(time.DateTime),
+	}
+}
+
+// revParam 解析路径中的修订号
+func revParam(c *gin.Context) (int, bool) {
+	rev, err := strconv.Atoi(c.Param("rev"))
+	if err != nil || rev < 1 {
+		return 0, false
+	}
+	return rev, true
+}
+
+// HandleListChapterRevisions 获取章节的修订记录，按修订号倒序
+func (s *Service) HandleListChapterRevisions(c *gin.Context) {
+	ctx := c.Request.Context()
+	log := logger.FromGinContext(c)
+
+	docID := c.Param("document_id")
+	id := c.Param("id")
+	if docID == "" {
+		hutil.AbortError(c, http.StatusBadRequest, "invalid doc id")
+		return
+	}
+	if id == "" {
+		hutil.AbortError(c, http.StatusBadRequest, "invalid id")
+		return
+	}
+
+	var args api.ListArgs
+	if err := bindListArgs(c, &args, &args); err != nil {
+		log.Warnf("Invalid list args, err: %v", err)
+		hutil.AbortError(c, http.StatusBadRequest, "invalid list args")
+		return
+	}
+
+	log.Infof("List chapter revisions, docID: %s, id: %s, args: %+v", docID, id, args)
+	if _, err := s.db.GetChapter(ctx, id, docID); err != nil {
+		log.Errorf("Failed to get chapter, err: %v", err)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			hutil.AbortError(c, http.StatusNotFound, "chapter not found")
+		} else {
+			hutil.AbortError(c, http.StatusInternalServerError, "get chapter failed")
+		}
+		return
+	}
+	revisions, total, err := s.db.ListChapterRevisions(ctx, id, &args)
+	if err != nil {
+		log.Errorf("Failed to list chapter revisions, err: %v", err)
+		hutil.AbortError(c, http.StatusInternalServerError, "list revisions failed")
+		return
+	}
+
+	ret := &api.ListChapterRevisionsResult{Pagination: makePagination(&args, len(revisions), total)}
+	for _, r := range revisions {
+		ret.Revisions = append(ret.Revisions, makeChapterRevision(&r))
+	}
+	hutil.WriteData(c, ret)
+}
+
+// HandleRestoreChapterRevision 将章节内容恢复为指定修订
+func (s *Service) HandleRestoreChapterRevision(c *gin.Context) {
+	ctx := c.Request.Context()
+	log := logger.FromGinContext(c)
+
+	docID := c.Param("document_id")
+	id := c.Param("id")
+	if docID == "" {
+		hutil.AbortError(c, http.StatusBadRequest, "invalid doc id")
+		return
+	}
+	if id == "" {
+		hutil.AbortError(c, http.StatusBadRequest, "invalid id")
+		return
+	}
+	rev, ok := revParam(c)
+	if !ok {
+		hutil.AbortError(c, http.StatusBadRequest, "invalid rev")
+		return
+	}
+
+	log.Infof("Restore chapter revision, docID: %s, id: %s, rev: %d", docID, id, rev)
+	err := s.db.RestoreChapterRevision(ctx, id, docID, rev, authorName(c))
+	if err != nil {
+		log.Errorf("Failed to restore chapter revision, err: %v", err)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			hutil.AbortError(c, http.StatusNotFound, "revision not found")
+		} else {
+			hutil.AbortError(c, http.StatusInternalServerError, "restore revision failed")
+		}
+		return
+	}
+
+	chapter, err := s.db.GetChapter(ctx, id, docID)
+	if err != nil {
+		log.Errorf("Failed to get chapter, err: %v", err)
+		hutil.AbortError(c, http.StatusInternalServerError, "get chapter failed")
+		return
+	}
+	hutil.WriteData(c, makeChapter(&chapter))
+}
+
+// HandleListSceneRevisions 获取场景的修订记录，按修订号倒序
+func (s *Service) HandleListSceneRevisions(c *gin.Context) {
+	ctx := c.Request.Context()
+	log := logger.FromGinContext(c)
+
+	sceneID := c.Param("id")
+	if sceneID == "" {
+		hutil.AbortError(c, http.StatusBadRequest, "invalid scene id")
+		return
+	}
+
+	var args api.ListArgs
+	if err := bindListArgs(c, &args, &args); err != nil {
+		log.Warnf("Invalid list args, err: %v", err)
+		hutil.AbortError(c, http.StatusBadRequest, "invalid list args")
+		return
+	}
+
+	log.Infof("List scene revisions, sceneID: %s, args: %+v", sceneID, args)
+	if _, err := s.db.GetScene(ctx, sceneID); err != nil {
+		log.Errorf("Failed to get scene, err: %v", err)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			hutil.AbortError(c, http.StatusNotFound, "scene not found")
+		} else {
+			hutil.AbortError(c, http.StatusInternalServerError, "get scene failed")
+		}
+		return
+	}
+	revisions, total, err := s.db.ListSceneRevisions(ctx, sceneID, &args)
+	if err != nil {
+		log.Errorf("Failed to list scene revisions, err: %v", err)
+		hutil.AbortError(c, http.StatusInternalServerError, "list revisions failed")
+		return
+	}
+
+	ret := &api.ListSceneRevisionsResult{Pagination: makePagination(&args, len(revisions), total)}
+	for _, r := range revisions {
+		ret.Revisions = append(ret.Revisions, makeSceneRevision(&r))
+	}
+	hutil.WriteData(c, ret)
+}
+
+// HandleRestoreSceneRevision 将场景内容恢复为指定修订，同时恢复该修订的图片和语音，不重新生成
+func (s *Service) HandleRestoreSceneRevision(c *gin.Context) {
+	ctx := c.Request.Context()
+	log := logger.FromGinContext(c)
+
+	sceneID := c.Param("id")
+	if sceneID == "" {
+		hutil.AbortError(c, http.StatusBadRequest, "invalid scene id")
+		return
+	}
+	rev, ok := revParam(c)
+	if !ok {
+		hutil.AbortError(c, http.StatusBadRequest, "invalid rev")
+		return
+	}
+
+	log.Infof("Restore scene revision, sceneID: %s, rev: %d", sceneID, rev)
+	err := s.db.RestoreSceneRevision(ctx, sceneID, rev, authorName(c))
+	if err != nil {
+		log.Errorf("Failed to restore scene revision, err: %v", err)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			hutil.AbortError(c, http.StatusNotFound, "revision not found")
+		} else {
+			hutil.AbortError(c, http.StatusInternalServerError, "restore revision failed")
+		}
+		return
+	}
+
+	scene, err := s.db.GetScene(ctx, sceneID)
+	if err != nil {
+		log.Errorf("Failed to get scene, err: %v", err)
+		hutil.AbortError(c, http.StatusInternalServerError, "get scene failed")
+		return
+	}
+	hutil.WriteData(c, makeScene(&scene))
+}
+
+// HandleListRoleRevisions 获取角色的修订记录，按修订号倒序
+func (s *Service) HandleListRoleRevisions(c *gin.Context) {
+	ctx := c.Request.Context()
+	log := logger.FromGinContext(c)
+
+	roleID := c.Param("id")
+	if roleID == "" {
+		hutil.AbortError(c, http.StatusBadRequest, "invalid role id")
+		return
+	}
+
+	var args api.ListArgs
+	if err := bindListArgs(c, &args, &args); err != nil {
+		log.Warnf("Invalid list args, err: %v", err)
+		hutil.AbortError(c, http.StatusBadRequest, "invalid list args")
+		return
+	}
+
+	log.Infof("List role revisions, roleID: %s, args: %+v", roleID, args)
+	if _, err := s.db.GetRole(ctx, roleID); err != nil {
+		log.Errorf("Failed to get role, err: %v", err)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			hutil.AbortError(c, http.StatusNotFound, "role not found")
+		} else {
+			hutil.AbortError(c, http.StatusInternalServerError, "get role failed")
+		}
+		return
+	}
+	revisions, total, err := s.db.ListRoleRevisions(ctx, roleID, &args)
+	if err != nil {
+		log.Errorf("Failed to list role revisions, err: %v", err)
+		hutil.AbortError(c, http.StatusInternalServerError, "list revisions failed")
+		return
+	}
+
+	ret := &api.ListRoleRevisionsResult{Pagination: makePagination(&args, len(revisions), total)}
+	for _, r := range revisions {
+		ret.Revisions = append(ret.Revisions, makeRoleRevision(&r))
+	}
+	hutil.WriteData(c, ret)
+}
+
+// HandleRestoreRoleRevision 将角色信息恢复为指定修订
+func (s *Service) HandleRestoreRoleRevision(c *gin.Context) {
+	ctx := c.Request.Context()
+	log := logger.FromGinContext(c)
+
+	roleID := c.Param("id")
+	if roleID == "" {
+		hutil.AbortError(c, http.StatusBadRequest, "invalid role id")
+		return
+	}
+	rev, ok := revParam(c)
+	if !ok {
+		hutil.AbortError(c, http.StatusBadRequest, "invalid rev")
+		return
+	}
+
+	log.Infof("Restore role revision, roleID: %s, rev: %d", roleID, rev)
+	err := s.db.RestoreRoleRevision(ctx, roleID, rev, authorName(c))
+	if err != nil {
+		log.Errorf("Failed to restore role revision, err: %v", err)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			hutil.AbortError(c, http.StatusNotFound, "revision not found")
+		} else {
+			hutil.AbortError(c, http.StatusInternalServerError, "restore revision failed")
+		}
+		return
+	}
+
+	role, err := s.db.GetRole(ctx, roleID)
+	if err != nil {
+		log.Errorf("Failed to get role, err: %v", err)
+		hutil.AbortError(c, http.StatusInternalServerError, "get role failed")
+		return
+	}
+	hutil.WriteData(c, makeRole(&role))
+}
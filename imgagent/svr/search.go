@@ -0,0 +1,183 @@
+package svr
+
+import (
+	"html"
+	"net/http"
+	"strings"
+	"unicode"
+
+	"github.com/gin-gonic/gin"
+
+	"imgagent/api"
+	"imgagent/db"
+	hutil "imgagent/httputil"
+	"imgagent/pkg/logger"
+)
+
+// snippetRadius 命中片段在第一个命中位置前后保留的字符数
+const snippetRadius = 40
+
+// HandleSearch 全文检索章节内容、场景描述和角色名字、外貌，返回高亮的命中片段
+func (s *Service) HandleSearch(c *gin.Context) {
+	ctx := c.Request.Context()
+	log := logger.FromGinContext(c)
+
+	var args api.SearchArgs
+	if err := c.ShouldBindQuery(&args); err != nil {
+		log.Warnf("Invalid search args, err: %v", err)
+		hutil.AbortError(c, http.StatusBadRequest, "invalid search args")
+		return
+	}
+	args.Q = strings.TrimSpace(args.Q)
+	if args.Q == "" {
+		hutil.AbortError(c, http.StatusBadRequest, "invalid search args")
+		return
+	}
+
+	log.Infof("Search, args: %+v", args)
+	if args.DocumentID != "" {
+		if _, err := s.db.GetDocument(ctx, args.DocumentID); err != nil {
+			log.Errorf("Failed to get document, id: %s, err: %v", args.DocumentID, err)
+			documentErr(c, err, "get document failed")
+			return
+		}
+	}
+	hits, err := s.db.Search(ctx, &args)
+	if err != nil {
+		log.Errorf("Failed to search, err: %v", err)
+		hutil.AbortError(c, http.StatusInternalServerError, "search failed")
+		return
+	}
+
+	ret := &api.SearchResult{Records: []api.Records{}}
+	for i := range hits {
+		ret.Records = append(ret.Records, makeRecord(&hits[i], args.Q))
+	}
+	hutil.WriteData(c, ret)
+}
+
+func makeRecord(hit *db.SearchHit, q string) api.Records {
+	return api.Records{
+		ID:         hit.ID,
+		Type:       hit.Type,
+		DocumentID: hit.DocumentID,
+		ChapterID:  hit.ChapterID,
+		Title:      hit.Title,
+		Content:    highlightSnippet(hit.Text, q, snippetRadius),
+		Score:      float32(hit.Score),
+	}
+}
+
+// highlightSnippet 截取第一个命中位置前后 radius 个字符的片段，HTML 转义后用 <em></em> 标记命中的检索词
+// 匹配不区分大小写，片段不在开头或结尾时补充省略号
+func highlightSnippet(text, q string, radius int) string {
+	runes := []rune(text)
+	query := []rune(q)
+	matches := findMatches(runes, query)
+
+	start, end := 0, len(runes)
+	if len(matches) > 0 {
+		start = max(matches[0]-radius, 0)
+		end = min(matches[0]+len(query)+radius, len(runes))
+	} else if end > 2*radius {
+		end = 2 * radius
+	}
+
+	var b strings.Builder
+	if start > 0 {
+		b.WriteString("…")
+	}
+	pos := start
+	for _, m := range matches {
+		if m < start {
+			continue
+		}
+		if m+len(query) > end {
+			break
+		}
+		b.WriteString(html.EscapeString(string(runes[pos:m])))
+		b.WriteString("<em>")
+		b.WriteString(html.EscapeString(string(runes[m : m+len(query)])))
+		b.WriteString("</em>")
+		pos = m + len(query)
+	}
+	b.WriteString(html.EscapeString(string(runes[pos:end])))
+	if end < len(runes) {
+		b.WriteString("…")
+	}
+	return b.String()
+}
+
+// findMatches 返回 query 在 text 中不重叠出现的位置，不区分大小写
+func findMatches(text, query []rune) []int {
+	if len(query) == 0 {
+		return nil
+	}
+	var matches []int
+	for i := 0; i+len(query) <= len(text); {
+		if equalFoldRunes(text[i:i+len(query)], query) {
+			matches = append(matches, i)
+			i += len(query)
+		} else {
+			i++
+		}
+	}
+	return matches
+}
+
+func equalFoldRunes(a, b []rune) bool {
+	for i := range a {
+		if a[i] != b[i] && unicode.ToLower(a[i]) != unicode.ToLower(b[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// HandleSemanticSearch 检索文档中与检索内容语义相近的章节和场景，返回相似度和内容片段
+func (s *Service) HandleSemanticSearch(c *gin.Context) {
+	ctx := c.Request.Context()
+	log := logger.FromGinContext(c)
+
+	docID := c.Param("document_id")
+	if docID == "" {
+		hutil.AbortError(c, http.StatusBadRequest, "invalid doc id")
+		return
+	}
+	if s.embedder == nil {
+		hutil.AbortError(c, http.StatusNotImplemented, "semantic search is not enabled")
+		return
+	}
+
+	var args api.SemanticSearchArgs
+	if err := c.ShouldBindQuery(&args); err != nil {
+		log.Warnf("Invalid search args, err: %v", err)
+		hutil.AbortError(c, http.StatusBadRequest, "invalid search args")
+		return
+	}
+	args.Q = strings.TrimSpace(args.Q)
+	if args.Q == "" {
+		hutil.AbortError(c, http.StatusBadRequest, "invalid search args")
+		return
+	}
+
+	log.Infof("Semantic search, docID: %s, args: %+v", docID, args)
+	if _, err := s.db.GetDocument(ctx, docID); err != nil {
+		log.Errorf("Failed to get document, id: %s, err: %v", docID, err)
+		documentErr(c, err, "get document failed")
+		return
+	}
+	hits, err := s.embedder.Search(ctx, docID, &args)
+	if err != nil {
+		log.Errorf("Failed to semantic search, err: %v", err)
+		hutil.AbortError(c, http.StatusInternalServerError, "semantic search failed")
+		return
+	}
+
+	// 语义检索没有命中的词，片段为内容开头
+	ret := &api.SearchResult{Records: []api.Records{}}
+	for i := range hits {
+		ret.Records = append(ret.Records, makeRecord(&hits[i], ""))
+	}
+	hutil.WriteData(c, ret)
+}
@@ -0,0 +1,165 @@
+package svr
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"imgagent/api"
+	"imgagent/db"
+	"imgagent/embedding"
+	"imgagent/proto"
+)
+
+func TestHighlightSnippet(t *testing.T) {
+	assert.Equal(t, "<em>祥子</em>拉车，<em>祥子</em>", highlightSnippet("祥子拉车，祥子", "祥子", 40))
+	assert.Equal(t, "a&lt;b&gt; <em>GO</em> &amp;", highlightSnippet("a<b> GO &", "go", 40))
+	assert.Equal(t, "…三四<em>五</em>六七…", highlightSnippet("一二三四五六七八", "五", 2))
+	// 未命中时截取开头
+	assert.Equal(t, "一二三四…", highlightSnippet("一二三四五六", "x", 2))
+	// 片段边界截断的命中不标记
+	assert.Equal(t, "…xx<em>ab</em>xa…", highlightSnippet("xxxabxab", "ab", 2))
+}
+
+func TestSearch(t *testing.T) {
+	service, cleanup := setupTestService(t)
+	defer cleanup()
+
+	router := testRouter(service, testAdminToken)
+	ctx := context.Background()
+
+	docID := db.MakeUUID()
+	_, err := service.db.CreateDocument(ctx, docID, db.DocumentFile{}, &api.CreateDocumentArgs{Name: "骆驼祥子"})
+	require.NoError(t, err)
+	require.NoError(t, service.db.CreateRoles(ctx, []db.Role{{ID: db.MakeUUID(), DocumentID: docID, Name: "祥子", Appearance: "<高大>的祥子"}}))
+
+	search := func(query url.Values) proto.BaseResponse {
+		req := httptest.NewRequest(http.MethodGet, "/v1/search?"+query.Encode(), nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		var resp proto.BaseResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		return resp
+	}
+
+	resp := search(url.Values{"q": {"祥子"}, "document_id": {docID}})
+	require.Equal(t, http.StatusOK, resp.Code)
+	var result api.SearchResult
+	b, _ := json.Marshal(resp.Data)
+	require.NoError(t, json.Unmarshal(b, &result))
+	require.Len(t, result.Records, 1)
+	assert.Equal(t, api.SearchTypeRole, result.Records[0].Type)
+	assert.Equal(t, "祥子", result.Records[0].Title)
+	assert.Equal(t, "<em>祥子</em>\n&lt;高大&gt;的<em>祥子</em>", result.Records[0].Content)
+	assert.Equal(t, float32(1), result.Records[0].Score)
+
+	resp = search(url.Values{"q": {"虎妞"}})
+	require.Equal(t, http.StatusOK, resp.Code)
+	b, _ = json.Marshal(resp.Data)
+	require.NoError(t, json.Unmarshal(b, &result))
+	assert.Empty(t, result.Records)
+
+	assert.Equal(t, http.StatusBadRequest, search(url.Values{"q": {" "}}).Code)
+	assert.Equal(t, http.StatusBadRequest, search(url.Values{"q": {"祥子"}, "type": {"volume"}}).Code)
+	assert.Equal(t, http.StatusBadRequest, search(url.Values{"q": {strings.Repeat("a", 101)}}).Code)
+	assert.Equal(t, ErrNoSuchDocumentCode, search(url.Values{"q": {"祥子"}, "document_id": {"missing"}}).Code)
+}
+
+// fakeEmbeddingServer 按字符计数生成向量的 embeddings 接口，字符相同的文本相似度高
+func fakeEmbeddingServer(t *testing.T) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req embedding.EmbeddingRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		var resp embedding.EmbeddingResponse
+		for i, text := range req.Input {
+			vector := make([]float32, 64)
+			for _, r := range text {
+				vector[r%64]++
+			}
+			resp.Data = append(resp.Data, embedding.EmbeddingData{Index: i, Embedding: vector})
+		}
+		json.NewEncoder(w).Encode(resp)
+	}))
+}
+
+func TestSemanticSearch(t *testing.T) {
+	service, cleanup := setupTestService(t)
+	defer cleanup()
+
+	router := testRouter(service, testAdminToken)
+	ctx := context.Background()
+
+	docID := db.MakeUUID()
+	_, err := service.db.CreateDocument(ctx, docID, db.DocumentFile{}, &api.CreateDocumentArgs{Name: "骆驼祥子"})
+	require.NoError(t, err)
+	require.NoError(t, service.db.CreateChapters(ctx, docID, []api.CreateChapterArgs{
+		{Title: "第一章", Content: "祥子拉着洋车在街上跑"},
+		{Title: "第二章", Content: "虎妞在屋里等着"},
+	}))
+	chapters, _, err := service.db.ListChapters(ctx, docID, nil)
+	require.NoError(t, err)
+	require.NoError(t, service.db.CreateScenes(ctx, []db.Scene{{ID: db.MakeUUID(), ChapterID: chapters[1].ID, DocumentID: docID, Content: "虎妞坐在屋里"}}))
+
+	searchDocument := func(docID string, query url.Values) proto.BaseResponse {
+		req := httptest.NewRequest(http.MethodGet, "/v1/documents/"+docID+"/semantic-search?"+query.Encode(), nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		var resp proto.BaseResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		return resp
+	}
+	search := func(query url.Values) proto.BaseResponse {
+		return searchDocument(docID, query)
+	}
+
+	// 未配置向量模型
+	assert.Equal(t, http.StatusNotImplemented, search(url.Values{"q": {"洋车"}}).Code)
+
+	server := fakeEmbeddingServer(t)
+	defer server.Close()
+	service.embedder, err = newEmbedder(EmbeddingConfig{URL: server.URL, Model: "fake", BatchSize: 2}, service.db)
+	require.NoError(t, err)
+	service.embedder.HandleEmbeddingTasks(ctx)
+	assert.Equal(t, 3, service.embedder.index.Len())
+
+	resp := search(url.Values{"q": {"祥子拉洋车"}})
+	require.Equal(t, http.StatusOK, resp.Code)
+	var result api.SearchResult
+	b, _ := json.Marshal(resp.Data)
+	require.NoError(t, json.Unmarshal(b, &result))
+	require.Len(t, result.Records, 3)
+	assert.Equal(t, chapters[0].ID, result.Records[0].ID)
+	assert.Equal(t, "第一章", result.Records[0].Title)
+	assert.Equal(t, "祥子拉着洋车在街上跑", result.Records[0].Content)
+	assert.Greater(t, result.Records[0].Score, result.Records[1].Score)
+
+	resp = search(url.Values{"q": {"虎妞"}, "type": {"scene"}, "limit": {"1"}})
+	require.Equal(t, http.StatusOK, resp.Code)
+	b, _ = json.Marshal(resp.Data)
+	require.NoError(t, json.Unmarshal(b, &result))
+	require.Len(t, result.Records, 1)
+	assert.Equal(t, api.SearchTypeScene, result.Records[0].Type)
+	assert.Equal(t, chapters[1].ID, result.Records[0].ChapterID)
+
+	// 重启后从数据库加载向量，回收站中的章节不返回
+	service.embedder, err = newEmbedder(EmbeddingConfig{URL: server.URL, Model: "fake", Index: embedding.IndexHNSW}, service.db)
+	require.NoError(t, err)
+	assert.Equal(t, 3, service.embedder.index.Len())
+	require.NoError(t, service.db.TrashChapter(ctx, chapters[0].ID, docID))
+	resp = search(url.Values{"q": {"祥子拉洋车"}, "type": {"chapter"}})
+	require.Equal(t, http.StatusOK, resp.Code)
+	b, _ = json.Marshal(resp.Data)
+	require.NoError(t, json.Unmarshal(b, &result))
+	require.Len(t, result.Records, 1)
+	assert.Equal(t, chapters[1].ID, result.Records[0].ID)
+
+	assert.Equal(t, http.StatusBadRequest, search(url.Values{"q": {"祥子"}, "type": {"role"}}).Code)
+	assert.Equal(t, ErrNoSuchDocumentCode, searchDocument("missing", url.Values{"q": {"祥子"}}).Code)
+}
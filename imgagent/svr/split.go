@@ -0,0 +1,247 @@
+package svr
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+
+	"imgagent/api"
+	"imgagent/bailian"
+	"imgagent/db"
+	hutil "imgagent/httputil"
+	"imgagent/pkg/logger"
+	"imgagent/proto"
+	"imgagent/spliter"
+)
+
+const (
+	// maxChapterPatterns 上传时可指定的章节标题正则数量上限
+	maxChapterPatterns = 10
+	// previewRunes 分割预览中每章返回的内容长度
+	previewRunes = 100
+)
+
+// OCR 服务类型
+const (
+	OCRProviderQwenVL  = "qwen-vl" // 百炼 Qwen-VL OCR
+	OCRProviderCommand = "command" // 本地 OCR 命令，如 tesseract
+)
+
+// SplitConfig 章节分割配置
+type SplitConfig struct {
+	ChapterPatterns []string  `json:"chapter_patterns"` // 章节标题正则，为空时使用 spliter.DefaultChapterPatterns
+	OCR             OCRConfig `json:"ocr"`
+}
+
+// OCRConfig 扫描版 PDF 的文字识别配置，页面通过 pdftoppm 渲染为图片后识别
+type OCRConfig struct {
+	Provider string   `json:"provider"` // 为空时不识别没有文字层的页面，可选 qwen-vl、command
+	Command  string   `json:"command"`  // provider 为 command 时的命令，图片从标准输入传入，结果从标准输出读取
+	Args     []string `json:"args"`     // 命令参数，如 ["stdin", "stdout", "-l", "chi_sim"]
+	DPI      int      `json:"dpi"`      // 渲染分辨率，为 0 时使用 spliter.DefaultOCRDPI
+}
+
+// 分块长度的计算单位
+const (
+	LengthUnitToken = "token" // 近似 Qwen 分词的 token 数
+	LengthUnitRune  = "rune"  // 字符数
+)
+
+const (
+	// defaultChunkSize 未识别到章节时每块的默认 token 数，约 5000 个汉字
+	defaultChunkSize = 3000
+	// defaultChunkOverlap 相邻块默认重叠的 token 数
+	defaultChunkOverlap = 60
+	// defaultChunkType DocumentConfig.Chunking 中各文档类型的默认配置
+	defaultChunkType = "default"
+)
+
+// ChunkConfig 未识别到章节时按长度分块的配置
+type ChunkConfig struct {
+	ChunkSize    int    `json:"chunk_size"`    // 每块最大长度
+	ChunkOverlap int    `json:"chunk_overlap"` // 相邻块的重叠长度
+	LengthUnit   string `json:"length_unit"`   // 长度单位 token、rune，为空时使用 token
+}
+
+// chunkConfig 返回文档类型的分块配置，未配置的字段依次使用 default 配置和内置默认值
+func (c *DocumentConfig) chunkConfig(ext string) ChunkConfig {
+	conf := ChunkConfig{ChunkSize: defaultChunkSize, ChunkOverlap: defaultChunkOverlap, LengthUnit: LengthUnitToken}
+	for _, key := range []string{defaultChunkType, strings.TrimPrefix(ext, ".")} {
+		v, ok := c.Chunking[key]
+		if !ok {
+			continue
+		}
+		if v.ChunkSize > 0 {
+			conf.ChunkSize = v.ChunkSize
+		}
+		if v.ChunkOverlap > 0 {
+			conf.ChunkOverlap = v.ChunkOverlap
+		}
+		if v.LengthUnit != "" {
+			conf.LengthUnit = v.LengthUnit
+		}
+	}
+	return conf
+}
+
+// checkChunking 校验分块配置
+func (c *DocumentConfig) checkChunking() error {
+	for key, v := range c.Chunking {
+		if v.ChunkSize < 0 || v.ChunkOverlap < 0 {
+			return fmt.Errorf("invalid chunking config %s: negative size", key)
+		}
+		if v.LengthUnit != "" && v.LengthUnit != LengthUnitToken && v.LengthUnit != LengthUnitRune {
+			return fmt.Errorf("invalid chunking config %s: unknown length unit %s", key, v.LengthUnit)
+		}
+		if conf := c.chunkConfig(key); conf.ChunkOverlap >= conf.ChunkSize {
+			return fmt.Errorf("invalid chunking config %s: overlap must be less than chunk size", key)
+		}
+	}
+	return nil
+}
+
+// splitOption 返回请求中分割文档使用的参数，不识别扫描页面，扫描版 PDF 由后台任务识别文字后分割
+func (s *Service) splitOption(ext string, patterns []string) spliter.Option {
+	return s.conf.DocumentConfig.splitOption(ext, s.chapterPatterns(patterns))
+}
+
+// splitOption 按文档类型的分块配置返回分割参数，patterns 为实际使用的章节标题正则
+func (c *DocumentConfig) splitOption(ext string, patterns []string) spliter.Option {
+	chunk := c.chunkConfig(ext)
+	length := spliter.TokenLength
+	if chunk.LengthUnit == LengthUnitRune {
+		length = spliter.RuneLength
+	}
+	return spliter.Option{
+		ChunkSize:       chunk.ChunkSize,
+		ChunkOverlap:    chunk.ChunkOverlap,
+		LengthFunc:      length,
+		Separator:       "\n\n",
+		Encoding:        spliter.EncodingUTF8,
+		ChapterPatterns: patterns,
+	}
+}
+
+// newOCRProvider 根据配置创建 OCR 服务，未配置时返回 nil
+func newOCRProvider(conf OCRConfig, bailianClient *bailian.Client) (spliter.OCRProvider, error) {
+	switch conf.Provider {
+	case "":
+		return nil, nil
+	case OCRProviderQwenVL:
+		if bailianClient == nil {
+			return nil, errors.New("ocr provider qwen-vl requires bailian client")
+		}
+		return &spliter.ImageOCR{DPI: conf.DPI, Recognize: bailianClient.RecognizeImage}, nil
+	case OCRProviderCommand:
+		if conf.Command == "" {
+			return nil, errors.New("ocr command is required")
+		}
+		return &spliter.ImageOCR{DPI: conf.DPI, Recognize: spliter.CommandRecognizer(conf.Command, conf.Args...)}, nil
+	default:
+		return nil, fmt.Errorf("unknown ocr provider: %s", conf.Provider)
+	}
+}
+
+// checkChapterPatterns 校验上传时指定的章节标题正则
+func checkChapterPatterns(patterns []string) *proto.ApiError {
+	if len(patterns) > maxChapterPatterns {
+		return hutil.NewApiError(http.StatusBadRequest, "too many chapter patterns")
+	}
+	if _, err := spliter.CompilePatterns(patterns); err != nil {
+		return hutil.NewApiError(http.StatusBadRequest, err.Error())
+	}
+	return nil
+}
+
+// chapterPatterns 返回分割使用的章节标题正则，上传时未指定则使用全局配置
+func (s *Service) chapterPatterns(patterns []string) []string {
+	if len(patterns) > 0 {
+		return patterns
+	}
+	return s.conf.Split.ChapterPatterns
+}
+
+// HandleSplitPreview 按创建文档的方式分割上传的文件，只返回识别到的章节列表，不创建文档
+// 预览不识别扫描页面，PDF 中没有文字层的页面被跳过
+func (s *Service) HandleSplitPreview(c *gin.Context) {
+	ctx := c.Request.Context()
+	log := logger.FromGinContext(c)
+
+	if !s.parseUploadForm(c) {
+		return
+	}
+	file, ext, ok := s.uploadFormFile(c)
+	if !ok {
+		return
+	}
+	encoding, apiErr := uploadEncoding(c.PostForm("encoding"))
+	if apiErr != nil {
+		hutil.AbortErr(c, apiErr)
+		return
+	}
+	patterns := c.PostFormArray("chapter_patterns")
+	if apiErr := checkChapterPatterns(patterns); apiErr != nil {
+		hutil.AbortErr(c, apiErr)
+		return
+	}
+
+	log.Infof("Split preview, file: %s, encoding: %s, patterns: %v", file.Filename, encoding, patterns)
+
+	filename := filepath.Join(s.conf.Temp, "preview-"+db.MakeUUID()+"."+ext)
+	if err := c.SaveUploadedFile(file, filename); err != nil {
+		log.Errorf("Failed to save preview file, err: %v", err)
+		hutil.AbortError(c, hutil.ErrServerInternalCode, "save file failed")
+		return
+	}
+	defer os.Remove(filename)
+
+	if apiErr := validateUploadFile(s.conf.Upload, filename, ext); apiErr != nil {
+		log.Warnf("Invalid upload file, file: %s, err: %v", file.Filename, apiErr)
+		hutil.AbortErr(c, apiErr)
+		return
+	}
+	if textFileTypes[ext] {
+		encoding, apiErr = transcodeUploadFile(filename, encoding)
+		if apiErr != nil {
+			hutil.AbortErr(c, apiErr)
+			return
+		}
+	} else {
+		encoding = ""
+	}
+
+	chunks, err := spliter.Split(ctx, filename, s.splitOption(ext, patterns))
+	if err != nil {
+		log.Errorf("Failed to split text, err: %v", err)
+		if errors.Is(err, spliter.ErrNoPDFText) {
+			hutil.AbortError(c, ErrInvalidFileCode, "pdf has no text layer, preview does not run ocr")
+			return
+		}
+		hutil.AbortError(c, ErrInvalidFileCode, "split text failed")
+		return
+	}
+
+	result := &api.SplitPreviewResult{Encoding: encoding, Chapters: []api.SplitPreviewChapter{}}
+	for i, chunk := range chunks {
+		preview := chunk.Content
+		if utf8.RuneCountInString(preview) > previewRunes {
+			preview = string([]rune(preview)[:previewRunes])
+		}
+		result.Chapters = append(result.Chapters, api.SplitPreviewChapter{
+			Index:   i,
+			Title:   chunk.Title,
+			Kind:    chunk.Kind,
+			Number:  chunk.Number,
+			Volume:  chunk.Volume,
+			Length:  utf8.RuneCountInString(chunk.Content),
+			Preview: preview,
+		})
+	}
+	hutil.WriteData(c, result)
+}
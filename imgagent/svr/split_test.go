@@ -0,0 +1,254 @@
+package svr
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/text/encoding/simplifiedchinese"
+
+	"imgagent/api"
+	"imgagent/db"
+	"imgagent/proto"
+	"imgagent/spliter"
+)
+
+func TestSplitPreview(t *testing.T) {
+	service, cleanup := setupTestService(t)
+	defer cleanup()
+	service.conf.Upload.setDefault()
+
+	router := testRouter(service, testAdminToken)
+
+	preview := func(filename string, content []byte, patterns ...string) (proto.BaseResponse, api.SplitPreviewResult) {
+		body := &bytes.Buffer{}
+		writer := multipart.NewWriter(body)
+		for _, p := range patterns {
+			writer.WriteField("chapter_patterns", p)
+		}
+		part, err := writer.CreateFormFile("file", filename)
+		require.NoError(t, err)
+		_, err = part.Write(content)
+		require.NoError(t, err)
+		writer.Close()
+
+		req := httptest.NewRequest(http.MethodPost, "/v1/split/preview", body)
+		req.Header.Set("Content-Type", writer.FormDataContentType())
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		var resp proto.BaseResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		var result api.SplitPreviewResult
+		if resp.Code == http.StatusOK {
+			b, _ := json.Marshal(resp.Data)
+			require.NoError(t, json.Unmarshal(b, &result))
+		}
+		return resp, result
+	}
+
+	t.Run("默认规则", func(t *testing.T) {
+		text := "楔子\n很久以前。\n\n第一章 风起\n" + strings.Repeat("天色暗了。", 30) + "\n\n第二章 云涌\n雨下了起来。\n\n番外 重逢\n又见面了。"
+		gbk, err := simplifiedchinese.GBK.NewEncoder().String(text)
+		require.NoError(t, err)
+
+		resp, result := preview("book.txt", []byte(gbk))
+		require.Equal(t, http.StatusOK, resp.Code)
+		assert.Equal(t, spliter.EncodingGBK, result.Encoding)
+		require.Len(t, result.Chapters, 4)
+		assert.Equal(t, "楔子", result.Chapters[0].Title)
+		assert.Equal(t, spliter.KindPrologue, result.Chapters[0].Kind)
+		assert.Equal(t, "第一章 风起", result.Chapters[1].Title)
+		assert.Equal(t, 1, result.Chapters[1].Number)
+		assert.Equal(t, spliter.KindChapter, result.Chapters[1].Kind)
+		assert.Equal(t, previewRunes, len([]rune(result.Chapters[1].Preview)))
+		assert.Equal(t, spliter.KindExtra, result.Chapters[3].Kind)
+	})
+
+	t.Run("自定义规则", func(t *testing.T) {
+		text := "=== 一 ===\n天色暗了。\n\n=== 二 ===\n雨下了起来。"
+		resp, result := preview("book.md", []byte(text), `(?m)^=== .+ ===$`)
+		require.Equal(t, http.StatusOK, resp.Code)
+		require.Len(t, result.Chapters, 2)
+		assert.Equal(t, "=== 二 ===", result.Chapters[1].Title)
+	})
+
+	t.Run("全局规则", func(t *testing.T) {
+		service.conf.Split.ChapterPatterns = []string{`(?m)^\* .+$`}
+		defer func() { service.conf.Split.ChapterPatterns = nil }()
+
+		resp, result := preview("book.txt", []byte("* 开始\n天色暗了。\n* 结束\n雨下了起来。"))
+		require.Equal(t, http.StatusOK, resp.Code)
+		require.Len(t, result.Chapters, 2)
+		assert.Equal(t, "* 开始", result.Chapters[0].Title)
+	})
+
+	t.Run("无效规则", func(t *testing.T) {
+		resp, _ := preview("book.txt", []byte("第一章"), `第(`)
+		assert.Equal(t, http.StatusBadRequest, resp.Code)
+	})
+
+	// 预览不创建文档，也不保留临时文件
+	docs, _, err := service.db.ListDocuments(t.Context(), nil)
+	require.NoError(t, err)
+	assert.Empty(t, docs)
+	entries, err := os.ReadDir(service.conf.Temp)
+	require.NoError(t, err)
+	for _, e := range entries {
+		assert.False(t, strings.HasPrefix(e.Name(), "preview-"), e.Name())
+	}
+}
+
+func TestNewOCRProvider(t *testing.T) {
+	ocr, err := newOCRProvider(OCRConfig{}, nil)
+	require.NoError(t, err)
+	assert.Nil(t, ocr)
+
+	ocr, err = newOCRProvider(OCRConfig{Provider: OCRProviderCommand, Command: "tesseract", Args: []string{"stdin", "stdout"}, DPI: 200}, nil)
+	require.NoError(t, err)
+	require.IsType(t, &spliter.ImageOCR{}, ocr)
+	assert.Equal(t, 200, ocr.(*spliter.ImageOCR).DPI)
+
+	_, err = newOCRProvider(OCRConfig{Provider: OCRProviderCommand}, nil)
+	assert.Error(t, err)
+	_, err = newOCRProvider(OCRConfig{Provider: OCRProviderQwenVL}, nil)
+	assert.Error(t, err)
+	_, err = newOCRProvider(OCRConfig{Provider: "unknown"}, nil)
+	assert.Error(t, err)
+}
+
+func TestChunkConfig(t *testing.T) {
+	conf := DocumentConfig{Chunking: map[string]ChunkConfig{
+		"default": {ChunkSize: 4000},
+		"pdf":     {ChunkSize: 2000, LengthUnit: LengthUnitRune},
+		"md":      {ChunkOverlap: 200},
+	}}
+	require.NoError(t, conf.checkChunking())
+
+	assert.Equal(t, ChunkConfig{ChunkSize: 4000, ChunkOverlap: defaultChunkOverlap, LengthUnit: LengthUnitToken}, conf.chunkConfig("txt"))
+	assert.Equal(t, ChunkConfig{ChunkSize: 2000, ChunkOverlap: defaultChunkOverlap, LengthUnit: LengthUnitRune}, conf.chunkConfig(".pdf"))
+	assert.Equal(t, ChunkConfig{ChunkSize: 4000, ChunkOverlap: 200, LengthUnit: LengthUnitToken}, conf.chunkConfig("md"))
+	assert.Equal(t, ChunkConfig{ChunkSize: defaultChunkSize, ChunkOverlap: defaultChunkOverlap, LengthUnit: LengthUnitToken}, (&DocumentConfig{}).chunkConfig("txt"))
+
+	service := &Service{conf: Config{DocumentConfig: conf}}
+	assert.Equal(t, 2000, service.splitOption("pdf", nil).ChunkSize)
+	assert.Equal(t, spliter.RuneLength("天色暗了"), service.splitOption("pdf", nil).LengthFunc("天色暗了"))
+	assert.Equal(t, spliter.TokenLength("天色暗了"), service.splitOption("txt", nil).LengthFunc("天色暗了"))
+
+	invalid := []map[string]ChunkConfig{
+		{"txt": {LengthUnit: "word"}},
+		{"txt": {ChunkSize: 100, ChunkOverlap: 100}},
+		{"txt": {ChunkSize: -1}},
+	}
+	for _, chunking := range invalid {
+		assert.Error(t, (&DocumentConfig{Chunking: chunking}).checkChunking())
+	}
+}
+
+// blankPDF 生成每页都没有文字层的 PDF（扫描件）
+func blankPDF(pages int) []byte {
+	objects := []string{"<< /Type /Catalog /Pages 2 0 R >>"}
+	kids := make([]string, pages)
+	for i := range pages {
+		kids[i] = fmt.Sprintf("%d 0 R", 3+i)
+	}
+	objects = append(objects, fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), pages))
+	for range pages {
+		objects = append(objects, "<< /Type /Page /Parent 2 0 R /MediaBox [0 0 600 800] >>")
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("%PDF-1.4\n")
+	offsets := make([]int, len(objects))
+	for i, obj := range objects {
+		offsets[i] = buf.Len()
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", i+1, obj)
+	}
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, off := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", off)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, xref)
+	return buf.Bytes()
+}
+
+// pageOCR 按页返回固定文字，记录识别次数
+type pageOCR struct {
+	pages map[int]string
+	calls atomic.Int32
+}
+
+func (o *pageOCR) RecognizePage(ctx context.Context, filename string, page int) (string, error) {
+	o.calls.Add(1)
+	return o.pages[page], nil
+}
+
+func TestScannedPDF(t *testing.T) {
+	service, cleanup := setupTestService(t)
+	defer cleanup()
+	service.conf.Upload.setDefault()
+	ocr := &pageOCR{pages: map[int]string{1: "第一章 开始\n天色暗了。", 2: "第二章 结束\n雨下了起来。"}}
+	service.ocr = ocr
+	ctx := t.Context()
+
+	// 预览不识别扫描页面
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	part, err := writer.CreateFormFile("file", "scan.pdf")
+	require.NoError(t, err)
+	_, err = part.Write(blankPDF(2))
+	require.NoError(t, err)
+	writer.Close()
+	req := httptest.NewRequest(http.MethodPost, "/v1/split/preview", body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	w := httptest.NewRecorder()
+	testRouter(service, testAdminToken).ServeHTTP(w, req)
+	var resp proto.BaseResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, ErrInvalidFileCode, resp.Code)
+	assert.Zero(t, ocr.calls.Load())
+
+	// 创建文档时只保存文件，不在请求中识别
+	docID := db.MakeUUID()
+	originFile := service.originFilename(docID, "pdf")
+	require.NoError(t, os.WriteFile(originFile, blankPDF(2), 0o644))
+	doc, apiErr := service.createDocument(ctx, docID, "pdf", &api.CreateDocumentArgs{Name: "扫描版"}, createDocumentOptions{OriginFile: originFile})
+	require.Nil(t, apiErr)
+	assert.Equal(t, db.DocumentStatusOCRPending, doc.Status)
+	assert.Zero(t, ocr.calls.Load())
+	chapters, _, err := service.db.ListChapters(ctx, docID, nil)
+	require.NoError(t, err)
+	assert.Empty(t, chapters)
+
+	// 后台识别后分割章节，识别出的文字替换原始文件
+	mgr, err := newDocumentMgr(DocumentConfigEx{db: service.db, ocr: ocr}, nil)
+	require.NoError(t, err)
+	mgr.HandleOCRTasks(ctx)
+	assert.Equal(t, int32(2), ocr.calls.Load())
+
+	stored, err := service.db.GetDocument(ctx, docID)
+	require.NoError(t, err)
+	assert.Equal(t, db.DocumentStatusChapterReady, stored.Status)
+	assert.Equal(t, service.originFilename(docID, "txt"), stored.OriginFile)
+	text, err := os.ReadFile(stored.OriginFile)
+	require.NoError(t, err)
+	assert.Contains(t, string(text), "雨下了起来。")
+	_, err = os.Stat(originFile)
+	assert.True(t, os.IsNotExist(err))
+	chapters, _, err = service.db.ListChapters(ctx, docID, nil)
+	require.NoError(t, err)
+	require.Len(t, chapters, 2)
+	assert.Equal(t, "第二章 结束", chapters[1].Title)
+}
@@ -11,22 +11,34 @@ import (
 	"imgagent/db"
 	"imgagent/pkg/dbutil"
 	"imgagent/pkg/middleware"
+	"imgagent/spliter"
 	"imgagent/storage"
 )
 
 type Config struct {
-	APIVersion     string         `json:"api_version"`
-	Temp           string         `json:"temp"`
-	Storage        storage.Config `json:"storage"`
-	DB             dbutil.Config  `json:"db"`
-	BailianConfig  bailian.Config `json:"-"` // 从外部传入
-	DocumentConfig DocumentConfig `json:"-"` // 从外部传入
+	APIVersion     string          `json:"api_version"`
+	Temp           string          `json:"temp"`
+	Origin         string          `json:"origin"` // 原始上传文件目录
+	Upload         UploadConfig    `json:"upload"`
+	Split          SplitConfig     `json:"split"`
+	Storage        storage.Config  `json:"storage"`
+	DB             dbutil.Config   `json:"db"`
+	Embedding      EmbeddingConfig `json:"embedding"`
+	BailianConfig  bailian.Config  `json:"-"` // 从外部传入
+	DocumentConfig DocumentConfig  `json:"-"` // 从外部传入
 }
 
+// EmbeddingConfig 语义检索的向量模型配置，URL 为空时不启用语义检索
 type EmbeddingConfig struct {
-	URL    string `json:"url"`
-	Model  string `json:"model"`
-	APIKey string `json:"api_key"`
+	URL            string `json:"url"`             // OpenAI 兼容的 embeddings 接口地址
+	Model          string `json:"model"`           // 向量模型，更换后全部内容重新生成向量
+	APIKey         string `json:"api_key"`         // API 密钥
+	Dimensions     int    `json:"dimensions"`      // 向量维度，为 0 时使用模型默认维度
+	BatchSize      int    `json:"batch_size"`      // 每次请求的文本数，默认 10
+	MaxInputChars  int    `json:"max_input_chars"` // 超过该字符数的内容截断后生成向量，默认 4000
+	Index          string `json:"index"`           // 向量索引 flat|hnsw，默认 flat
+	IntervalSecs   int    `json:"interval_secs"`   // 后台生成向量的间隔，默认 30
+	RequestTimeout int    `json:"request_timeout"` // 请求超时时间（秒），默认 60
 }
 
 type Service struct {
@@ -34,7 +46,11 @@ type Service struct {
 	db            db.IDataBase
 	stg           *storage.Storage
 	bailianClient *bailian.Client
+	assets        *AssetCache
 	documentMgr   *DocumentMgr
+	ocr           spliter.OCRProvider
+	embedder      *Embedder
+	close         chan bool
 }
 
 func New(conf Config, bailianClient *bailian.Client) (*Service, error) {
@@ -46,6 +62,29 @@ func New(conf Config, bailianClient *bailian.Client) (*Service, error) {
 		zap.S().Errorf("Failed to mkdir, err: %v", err)
 		return nil, err
 	}
+	if conf.Origin == "" {
+		conf.Origin = "./origin"
+	}
+	err = os.MkdirAll(conf.Origin, 0776)
+	if err != nil {
+		zap.S().Errorf("Failed to mkdir, err: %v", err)
+		return nil, err
+	}
+
+	conf.Upload.setDefault()
+	if _, err := spliter.CompilePatterns(conf.Split.ChapterPatterns); err != nil {
+		zap.S().Errorf("Invalid split config, err: %v", err)
+		return nil, err
+	}
+	if err := conf.DocumentConfig.checkChunking(); err != nil {
+		zap.S().Errorf("Invalid document config, err: %v", err)
+		return nil, err
+	}
+	ocr, err := newOCRProvider(conf.Split.OCR, bailianClient)
+	if err != nil {
+		zap.S().Errorf("Invalid ocr config, err: %v", err)
+		return nil, err
+	}
 
 	stg, err := storage.NewStorage(conf.Storage)
 	if err != nil {
@@ -58,12 +97,24 @@ func New(conf Config, bailianClient *bailian.Client) (*Service, error) {
 		return nil, err
 	}
 
+	assets := newAssetCache(db, stg, conf.Temp, bailianClient)
+	embedder, err := newEmbedder(conf.Embedding, db)
+	if err != nil {
+		zap.S().Errorf("Failed to new embedder, err: %v", err)
+		return nil, err
+	}
+
 	// 创建文档管理器
 	var docMgr *DocumentMgr
 	if conf.DocumentConfig.Enable {
 		confEx := DocumentConfigEx{
-			config: conf.DocumentConfig,
-			db:     db,
+			config:   conf.DocumentConfig,
+			db:       db,
+			stg:      stg,
+			temp:     conf.Temp,
+			assets:   assets,
+			embedder: embedder,
+			ocr:      ocr,
 		}
 		var err error
 		docMgr, err = newDocumentMgr(confEx, bailianClient)
@@ -76,44 +127,96 @@ func New(conf Config, bailianClient *bailian.Client) (*Service, error) {
 		zap.S().Info("Document manager started")
 	}
 
-	return &Service{
+	s := &Service{
 		conf:          conf,
 		db:            db,
 		stg:           stg,
 		bailianClient: bailianClient,
+		assets:        assets,
 		documentMgr:   docMgr,
-	}, nil
+		ocr:           ocr,
+		embedder:      embedder,
+		close:         make(chan bool),
+	}
+	// 清理过期的分片上传
+	go s.loopCleanupUploads()
+	return s, nil
+}
+
+// Close 停止后台任务，只能调用一次
+func (s *Service) Close() {
+	close(s.close)
+	if s.documentMgr != nil {
+		s.documentMgr.Close()
+	}
 }
 
 func (s *Service) RegisterRouter(writer io.Writer) *gin.Engine {
 	router := middleware.NewRouter(writer)
 	api := router.Group(s.conf.APIVersion)
 	authGroup := api.Group("")
-	// 暂不需要 auth
-	authGroup.Use(s.NilAuth())
+	// 文档及其章节、场景和角色只有创建者和超级管理员可以访问
+	authGroup.Use(s.Auth())
 
-	// Document
+	// Document，文档创建者和成员按角色访问，见 RequireRole
 	authGroup.POST("/documents", s.HandleCreateDocument)
-	authGroup.GET("/documents/:document_id", s.HandleGetDocument)
-	authGroup.PUT("/documents/:document_id", s.HandleUpdateDocument)
-	authGroup.DELETE("/documents/:document_id", s.HandleDeleteDocument)
+	authGroup.GET("/documents/:document_id", s.viewer(documentParam), s.HandleGetDocument)
+	authGroup.PUT("/documents/:document_id", s.editor(documentParam), s.HandleUpdateDocument)
+	authGroup.DELETE("/documents/:document_id", s.owner(documentParam), s.HandleDeleteDocument)
 	authGroup.GET("/documents", s.HandleListDocuments)
 
+	// Member，文档共享给其他用户
+	authGroup.GET("/documents/:document_id/members", s.viewer(documentParam), s.HandleListMembers)
+	authGroup.POST("/documents/:document_id/members", s.owner(documentParam), s.HandleAddMember)
+	authGroup.DELETE("/documents/:document_id/members/:user_id", s.owner(documentParam), s.HandleRemoveMember)
+
+	// Upload，大文件分片上传，完成后创建文档
+	authGroup.POST("/uploads", s.HandleInitUpload)
+	authGroup.GET("/uploads/:upload_id", s.HandleGetUpload)
+	authGroup.PUT("/uploads/:upload_id/parts/:part_number", s.HandleUploadPart)
+	authGroup.POST("/uploads/:upload_id/complete", s.HandleCompleteUpload)
+	authGroup.DELETE("/uploads/:upload_id", s.HandleAbortUpload)
+
+	// Split
+	authGroup.POST("/split/preview", s.HandleSplitPreview)
+
 	// Chapter
-	authGroup.GET("/documents/:document_id/chapters/:id", s.HandleGetChapter)
-	authGroup.PUT("/documents/:document_id/chapters/:id", s.HandleUpdateChapter)
-	authGroup.DELETE("/documents/:document_id/chapters/:id", s.HandleDeleteChapter)
-	authGroup.GET("/documents/:document_id/chapters", s.HandleListChapters)
+	authGroup.GET("/documents/:document_id/chapters/:id", s.viewer(documentParam), s.HandleGetChapter)
+	authGroup.PUT("/documents/:document_id/chapters/:id", s.editor(documentParam), s.HandleUpdateChapter)
+	authGroup.DELETE("/documents/:document_id/chapters/:id", s.editor(documentParam), s.HandleDeleteChapter)
+	authGroup.GET("/documents/:document_id/chapters", s.viewer(documentParam), s.HandleListChapters)
+
+	// Volume
+	authGroup.GET("/documents/:document_id/volumes", s.viewer(documentParam), s.HandleListVolumes)
 
 	// Role
-	authGroup.GET("/documents/:document_id/roles", s.HandleGetRoles)
-	authGroup.PUT("/roles/:id", s.HandleUpdateRole)
+	authGroup.GET("/documents/:document_id/roles", s.viewer(documentParam), s.HandleGetRoles)
+	authGroup.PUT("/roles/:id", s.editor(s.roleDocument), s.HandleUpdateRole)
 
 	// Scene
-	authGroup.GET("/documents/:document_id/scenes", s.HandleListScenesByDocument)
-	authGroup.GET("/chapters/:chapter_id/scenes", s.HandleListScenesByChapter)
-	authGroup.PUT("/scenes/:id", s.HandleUpdateScene)
-	authGroup.DELETE("/scenes/:id", s.HandleDeleteScene)
+	authGroup.GET("/documents/:document_id/scenes", s.viewer(documentParam), s.HandleListScenesByDocument)
+	authGroup.GET("/chapters/:chapter_id/scenes", s.viewer(s.chapterDocument), s.HandleListScenesByChapter)
+	authGroup.PUT("/scenes/:id", s.editor(s.sceneDocument), s.HandleUpdateScene)
+	authGroup.DELETE("/scenes/:id", s.editor(s.sceneDocument), s.HandleDeleteScene)
+
+	// Trash，删除的文档、章节和场景保留在回收站中，超过保留期后彻底删除
+	authGroup.GET("/trash/documents", s.HandleListTrash)
+	authGroup.POST("/documents/:document_id/restore", s.owner(documentParam), s.HandleRestoreDocument)
+	authGroup.GET("/documents/:document_id/trash", s.viewer(documentParam), s.HandleListDocumentTrash)
+	authGroup.POST("/documents/:document_id/chapters/:id/restore", s.editor(documentParam), s.HandleRestoreChapter)
+	authGroup.POST("/scenes/:id/restore", s.editor(s.sceneDocument), s.HandleRestoreScene)
+
+	// Revision，编辑章节、场景和角色时保存修订记录，可以恢复到任意修订
+	authGroup.GET("/documents/:document_id/chapters/:id/revisions", s.viewer(documentParam), s.HandleListChapterRevisions)
+	authGroup.POST("/documents/:document_id/chapters/:id/revisions/:rev/restore", s.editor(documentParam), s.HandleRestoreChapterRevision)
+	authGroup.GET("/scenes/:id/revisions", s.viewer(s.sceneDocument), s.HandleListSceneRevisions)
+	authGroup.POST("/scenes/:id/revisions/:rev/restore", s.editor(s.sceneDocument), s.HandleRestoreSceneRevision)
+	authGroup.GET("/roles/:id/revisions", s.viewer(s.roleDocument), s.HandleListRoleRevisions)
+	authGroup.POST("/roles/:id/revisions/:rev/restore", s.editor(s.roleDocument), s.HandleRestoreRoleRevision)
+
+	// 全文检索
+	authGroup.GET("/search", s.HandleSearch)
+	authGroup.GET("/documents/:document_id/semantic-search", s.viewer(documentParam), s.HandleSemanticSearch)
 
 	return router
 }
@@ -0,0 +1,177 @@
+package svr
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"imgagent/api"
+	hutil "imgagent/httputil"
+	"imgagent/pkg/logger"
+)
+
+// HandleListTrash 获取回收站中的文档，按删除时间倒序
+func (s *Service) HandleListTrash(c *gin.Context) {
+	ctx := c.Request.Context()
+	log := logger.FromGinContext(c)
+
+	var args api.ListArgs
+	if err := bindListArgs(c, &args, &args); err != nil {
+		log.Warnf("Invalid list args, err: %v", err)
+		hutil.AbortError(c, http.StatusBadRequest, "invalid list args")
+		return
+	}
+
+	log.Infof("List trash, args: %+v", args)
+	docs, total, err := s.db.ListTrashedDocuments(ctx, &args)
+	if err != nil {
+		log.Errorf("Failed to list trashed documents, err: %v", err)
+		hutil.AbortError(c, hutil.ErrServerInternalCode, "list trash failed")
+		return
+	}
+
+	ret := &api.ListDocumentsResult{Pagination: makePagination(&args, len(docs), total)}
+	for _, d := range docs {
+		ret.Documents = append(ret.Documents, makeDocument(&d))
+	}
+	hutil.WriteData(c, ret)
+}
+
+// HandleRestoreDocument 从回收站恢复文档，已有同名文档时返回 ErrExistingDocumentCode
+func (s *Service) HandleRestoreDocument(c *gin.Context) {
+	ctx := c.Request.Context()
+	log := logger.FromGinContext(c)
+
+	docID := c.Param("document_id")
+	if docID == "" {
+		hutil.AbortError(c, http.StatusBadRequest, "invalid doc id")
+		return
+	}
+
+	log.Infof("Restore document, docID: %s", docID)
+	err := s.db.RestoreDocument(ctx, docID)
+	if err != nil {
+		log.Errorf("Failed to restore document, err: %v", err)
+		documentErr(c, err, "restore document failed")
+		return
+	}
+
+	doc, err := s.db.GetDocument(ctx, docID)
+	if err != nil {
+		log.Errorf("get document failed, id: %s, err: %v", docID, err)
+		documentErr(c, err, "get document failed")
+		return
+	}
+	hutil.WriteData(c, makeDocument(&doc))
+}
+
+// HandleListDocumentTrash 获取文档中单独删除的章节和场景
+func (s *Service) HandleListDocumentTrash(c *gin.Context) {
+	ctx := c.Request.Context()
+	log := logger.FromGinContext(c)
+
+	docID := c.Param("document_id")
+	if docID == "" {
+		hutil.AbortError(c, http.StatusBadRequest, "invalid doc id")
+		return
+	}
+
+	log.Infof("List document trash, docID: %s", docID)
+	if _, err := s.db.GetDocument(ctx, docID); err != nil {
+		log.Errorf("get document failed, id: %s, err: %v", docID, err)
+		documentErr(c, err, "get document failed")
+		return
+	}
+	chapters, err := s.db.ListTrashedChapters(ctx, docID)
+	if err != nil {
+		log.Errorf("Failed to list trashed chapters, err: %v", err)
+		hutil.AbortError(c, http.StatusInternalServerError, "list trash failed")
+		return
+	}
+	scenes, err := s.db.ListTrashedScenes(ctx, docID)
+	if err != nil {
+		log.Errorf("Failed to list trashed scenes, err: %v", err)
+		hutil.AbortError(c, http.StatusInternalServerError, "list trash failed")
+		return
+	}
+
+	result := &api.DocumentTrashResult{}
+	for _, chapter := range chapters {
+		result.Chapters = append(result.Chapters, makeChapter(&chapter))
+	}
+	for _, scene := range scenes {
+		result.Scenes = append(result.Scenes, makeScene(&scene))
+	}
+	hutil.WriteData(c, result)
+}
+
+// HandleRestoreChapter 从回收站恢复章节及随章节删除的场景
+func (s *Service) HandleRestoreChapter(c *gin.Context) {
+	ctx := c.Request.Context()
+	log := logger.FromGinContext(c)
+
+	docID := c.Param("document_id")
+	id := c.Param("id")
+	if docID == "" {
+		hutil.AbortError(c, http.StatusBadRequest, "invalid doc id")
+		return
+	}
+	if id == "" {
+		hutil.AbortError(c, http.StatusBadRequest, "invalid id")
+		return
+	}
+
+	log.Infof("Restore chapter, docID: %s, id: %s", docID, id)
+	err := s.db.RestoreChapter(ctx, id, docID)
+	if err != nil {
+		log.Errorf("Failed to restore chapter, err: %v", err)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			hutil.AbortError(c, http.StatusNotFound, "chapter not found in trash")
+		} else {
+			hutil.AbortError(c, http.StatusInternalServerError, "restore chapter failed")
+		}
+		return
+	}
+
+	chapter, err := s.db.GetChapter(ctx, id, docID)
+	if err != nil {
+		log.Errorf("Failed to get chapter, err: %v", err)
+		hutil.AbortError(c, http.StatusInternalServerError, "get chapter failed")
+		return
+	}
+	hutil.WriteData(c, makeChapter(&chapter))
+}
+
+// HandleRestoreScene 从回收站恢复场景
+func (s *Service) HandleRestoreScene(c *gin.Context) {
+	ctx := c.Request.Context()
+	log := logger.FromGinContext(c)
+
+	sceneID := c.Param("id")
+	if sceneID == "" {
+		hutil.AbortError(c, http.StatusBadRequest, "invalid scene id")
+		return
+	}
+
+	log.Infof("Restore scene, sceneID: %s", sceneID)
+	err := s.db.RestoreScene(ctx, sceneID)
+	if err != nil {
+		log.Errorf("Failed to restore scene, err: %v", err)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			hutil.AbortError(c, http.StatusNotFound, "scene not found in trash")
+		} else {
+			hutil.AbortError(c, http.StatusInternalServerError, "restore scene failed")
+		}
+		return
+	}
+
+	scene, err := s.db.GetScene(ctx, sceneID)
+	if err != nil {
+		log.Errorf("Failed to get scene, err: %v", err)
+		hutil.AbortError(c, http.StatusInternalServerError, "get scene failed")
+		return
+	}
+	hutil.WriteData(c, makeScene(&scene))
+}
@@ -0,0 +1,257 @@
+package svr
+
+import (
+	"archive/zip"
+	"errors"
+	"mime/multipart"
+	"net/http"
+	"strings"
+
+	"github.com/gabriel-vasile/mimetype"
+	"github.com/gin-gonic/gin"
+
+	hutil "imgagent/httputil"
+	"imgagent/pkg/logger"
+	"imgagent/proto"
+	"imgagent/spliter"
+)
+
+const (
+	ErrFileTooLargeCode    = http.StatusRequestEntityTooLarge
+	ErrUnsupportedFileCode = http.StatusUnsupportedMediaType
+	ErrInvalidFileCode     = http.StatusUnprocessableEntity
+
+	// multipart 表单中除文件外其他字段的预留大小
+	formOverhead = 1 << 20
+)
+
+type UploadConfig struct {
+	MaxSizeMB        int64 `json:"max_size_mb"`        // 上传文件大小上限
+	MaxUnzipSizeMB   int64 `json:"max_unzip_size_mb"`  // docx、epub、odt 等压缩格式解压后的总大小上限
+	MaxZipEntries    int   `json:"max_zip_entries"`    // 压缩格式中的文件数上限
+	MaxCompressRatio int   `json:"max_compress_ratio"` // 单个文件的最大压缩比，超过视为压缩炸弹
+
+	// 分片上传
+	MaxChunkedSizeMB    int64 `json:"max_chunked_size_mb"`   // 分片上传的文件大小上限
+	PartSizeMB          int64 `json:"part_size_mb"`          // 分片大小
+	ExpireHours         int   `json:"expire_hours"`          // 未完成的分片上传过期时间
+	CleanupIntervalSecs int   `json:"cleanup_interval_secs"` // 清理过期分片上传的间隔
+}
+
+func (conf *UploadConfig) setDefault() {
+	if conf.MaxSizeMB == 0 {
+		conf.MaxSizeMB = 50
+	}
+	if conf.MaxUnzipSizeMB == 0 {
+		conf.MaxUnzipSizeMB = 200
+	}
+	if conf.MaxZipEntries == 0 {
+		conf.MaxZipEntries = 1000
+	}
+	if conf.MaxCompressRatio == 0 {
+		conf.MaxCompressRatio = 100
+	}
+	if conf.MaxChunkedSizeMB == 0 {
+		conf.MaxChunkedSizeMB = 500
+	}
+	if conf.PartSizeMB == 0 {
+		conf.PartSizeMB = 5
+	}
+	if conf.ExpireHours == 0 {
+		conf.ExpireHours = 24
+	}
+	if conf.CleanupIntervalSecs == 0 {
+		conf.CleanupIntervalSecs = 3600
+	}
+}
+
+// allowedFileTypes 支持的文件扩展名及其允许的 MIME 类型，文件内容探测结果必须与扩展名一致
+var allowedFileTypes = map[string][]string{
+	"txt":  {"text/plain"},
+	"md":   {"text/plain"},
+	"pdf":  {"application/pdf"},
+	"docx": {"application/vnd.openxmlformats-officedocument.wordprocessingml.document"},
+	"doc":  {"application/vnd.openxmlformats-officedocument.wordprocessingml.document"},
+	"epub": {"application/epub+zip"},
+	"html": {"text/html"},
+	"htm":  {"text/html"},
+	"rtf":  {"text/rtf"},
+	"odt":  {"application/vnd.oasis.opendocument.text"},
+	"fb2":  {"text/xml"},
+}
+
+// legacyWordTypes 旧版 Word 的二进制格式（OLE 复合文档），只支持 OOXML 格式的 .docx 和另存为 .doc 的 OOXML 文件
+var legacyWordTypes = []string{"application/msword", "application/x-ole-storage"}
+
+// zipFileTypes 需要检查解压限制的压缩格式
+var zipFileTypes = map[string]bool{
+	"docx": true,
+	"epub": true,
+	"odt":  true,
+}
+
+// textFileTypes 纯文本格式，上传后检测编码并转换为 UTF-8
+var textFileTypes = map[string]bool{
+	"txt": true,
+	"md":  true,
+}
+
+// parseUploadForm 限制请求体大小并解析 multipart 表单，失败时写入错误响应并返回 false
+func (s *Service) parseUploadForm(c *gin.Context) bool {
+	log := logger.FromGinContext(c)
+
+	// 限制请求体大小，超限时解析表单返回 MaxBytesError
+	if s.conf.Upload.MaxSizeMB > 0 {
+		maxBytes := s.conf.Upload.MaxSizeMB<<20 + formOverhead
+		if c.Request.ContentLength > maxBytes {
+			hutil.AbortError(c, ErrFileTooLargeCode, "file exceeds maximum upload size")
+			return false
+		}
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxBytes)
+	}
+	if err := c.Request.ParseMultipartForm(32 << 20); err != nil {
+		log.Errorf("Failed to parse multipart form, err: %v", err)
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			hutil.AbortError(c, ErrFileTooLargeCode, "file exceeds maximum upload size")
+		} else {
+			hutil.AbortError(c, http.StatusBadRequest, "invalid multipart form")
+		}
+		return false
+	}
+	return true
+}
+
+// uploadFormFile 返回表单中的 file 字段及其扩展名，校验文件大小和类型，失败时写入错误响应并返回 false
+func (s *Service) uploadFormFile(c *gin.Context) (*multipart.FileHeader, string, bool) {
+	log := logger.FromGinContext(c)
+
+	file, err := c.FormFile("file")
+	if err != nil {
+		log.Errorf("Failed to get file, err: %v", err)
+		hutil.AbortError(c, http.StatusBadRequest, "file is required")
+		return nil, "", false
+	}
+	if s.conf.Upload.MaxSizeMB > 0 && file.Size > s.conf.Upload.MaxSizeMB<<20 {
+		hutil.AbortError(c, ErrFileTooLargeCode, "file exceeds maximum upload size")
+		return nil, "", false
+	}
+	ext, apiErr := fileExt(file.Filename)
+	if apiErr != nil {
+		hutil.AbortErr(c, apiErr)
+		return nil, "", false
+	}
+	return file, ext, true
+}
+
+// fileExt 返回小写的文件扩展名，不支持的类型返回错误
+func fileExt(filename string) (string, *proto.ApiError) {
+	index := strings.LastIndex(filename, ".")
+	if index == -1 {
+		return "", hutil.NewApiError(http.StatusBadRequest, "file has no extension")
+	}
+	ext := strings.ToLower(filename[index+1:])
+	if _, ok := allowedFileTypes[ext]; !ok {
+		return "", hutil.NewApiError(ErrUnsupportedFileCode, "unsupported file type: "+ext)
+	}
+	return ext, nil
+}
+
+// validateUploadFile 校验已保存的上传文件：内容类型需与扩展名一致，压缩格式检查解压限制
+// 纯文本格式能检测出编码时同样接受，非 UTF-8 的文本（如没有 BOM 的 UTF-16）按内容探测不是 text/plain
+func validateUploadFile(conf UploadConfig, filename, ext string) *proto.ApiError {
+	mtype, err := mimetype.DetectFile(filename)
+	if err != nil {
+		return hutil.NewApiError(ErrInvalidFileCode, "read file failed")
+	}
+	if mimeMatches(mtype, legacyWordTypes) {
+		return hutil.NewApiError(ErrUnsupportedFileCode, "legacy Word .doc format is not supported, please save it as .docx")
+	}
+	if !mimeMatches(mtype, allowedFileTypes[ext]) && !(textFileTypes[ext] && detectableText(filename)) {
+		return hutil.NewApiError(ErrUnsupportedFileCode, "file content "+mtype.String()+" does not match extension "+ext)
+	}
+
+	if zipFileTypes[ext] {
+		if apiErr := checkZipLimits(conf, filename); apiErr != nil {
+			return apiErr
+		}
+	}
+	return nil
+}
+
+// detectableText 判断文件是否为可以检测出编码的文本
+func detectableText(filename string) bool {
+	_, err := spliter.DetectFileEncoding(filename)
+	return err == nil
+}
+
+// mimeMatches 判断探测到的类型或其父类型是否在允许列表中
+func mimeMatches(mtype *mimetype.MIME, allowed []string) bool {
+	for m := mtype; m != nil; m = m.Parent() {
+		for _, a := range allowed {
+			if m.Is(a) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// checkZipLimits 检查压缩文件的文件数、解压总大小和压缩比，防止压缩炸弹
+// archive/zip 读取时会校验实际解压大小与声明一致，因此按声明大小检查即可
+func checkZipLimits(conf UploadConfig, filename string) *proto.ApiError {
+	r, err := zip.OpenReader(filename)
+	if err != nil {
+		return hutil.NewApiError(ErrInvalidFileCode, "invalid zip archive")
+	}
+	defer r.Close()
+
+	if conf.MaxZipEntries > 0 && len(r.File) > conf.MaxZipEntries {
+		return hutil.NewApiError(ErrInvalidFileCode, "archive has too many entries")
+	}
+
+	var total uint64
+	maxTotal := uint64(conf.MaxUnzipSizeMB) << 20
+	for _, f := range r.File {
+		total += f.UncompressedSize64
+		if maxTotal > 0 && total > maxTotal {
+			return hutil.NewApiError(ErrInvalidFileCode, "archive decompressed size exceeds limit")
+		}
+		// 小文件压缩比天然较高，只检查超过 1MB 的文件
+		if conf.MaxCompressRatio > 0 && f.UncompressedSize64 > 1<<20 &&
+			f.UncompressedSize64 > uint64(conf.MaxCompressRatio)*max(f.CompressedSize64, 1) {
+			return hutil.NewApiError(ErrInvalidFileCode, "archive compression ratio too high")
+		}
+	}
+	return nil
+}
+
+// uploadEncoding 校验上传时指定的文本编码，为空表示自动检测
+func uploadEncoding(name string) (string, *proto.ApiError) {
+	if name == "" {
+		return "", nil
+	}
+	encoding, err := spliter.NormalizeEncoding(name)
+	if err != nil {
+		return "", hutil.NewApiError(http.StatusBadRequest, "unsupported encoding: "+name)
+	}
+	return encoding, nil
+}
+
+// transcodeUploadFile 检测（未指定时）文本编码并将文件原地转换为 UTF-8，返回文件的原始编码
+func transcodeUploadFile(filename, encoding string) (string, *proto.ApiError) {
+	if encoding == "" {
+		var err error
+		encoding, err = spliter.DetectFileEncoding(filename)
+		if err != nil {
+			return "", hutil.NewApiError(ErrInvalidFileCode, "unable to detect text encoding")
+		}
+	}
+	if err := spliter.TranscodeFile(filename, encoding); err != nil {
+		if errors.Is(err, spliter.ErrInvalidText) {
+			return "", hutil.NewApiError(ErrInvalidFileCode, "file is not valid "+encoding+" text")
+		}
+		return "", hutil.NewApiError(hutil.ErrServerInternalCode, "transcode file failed")
+	}
+	return encoding, nil
+}
@@ -0,0 +1,244 @@
+package svr
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"unicode/utf16"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"imgagent/proto"
+)
+
+func writeTestZip(t *testing.T, filename string, entries map[string][]byte) {
+	t.Helper()
+	f, err := os.Create(filename)
+	require.NoError(t, err)
+	defer f.Close()
+	w := zip.NewWriter(f)
+	for name, data := range entries {
+		part, err := w.Create(name)
+		require.NoError(t, err)
+		_, err = part.Write(data)
+		require.NoError(t, err)
+	}
+	require.NoError(t, w.Close())
+}
+
+func TestFileExt(t *testing.T) {
+	ext, apiErr := fileExt("骆驼祥子.TXT")
+	require.Nil(t, apiErr)
+	assert.Equal(t, "txt", ext)
+
+	_, apiErr = fileExt("noext")
+	require.NotNil(t, apiErr)
+	assert.Equal(t, http.StatusBadRequest, apiErr.Code)
+
+	_, apiErr = fileExt("evil.exe")
+	require.NotNil(t, apiErr)
+	assert.Equal(t, ErrUnsupportedFileCode, apiErr.Code)
+
+	// 扩展名中包含路径分隔符
+	_, apiErr = fileExt("a./../../etc/passwd")
+	require.NotNil(t, apiErr)
+	assert.Equal(t, ErrUnsupportedFileCode, apiErr.Code)
+}
+
+func TestValidateUploadFile(t *testing.T) {
+	dir := t.TempDir()
+	conf := UploadConfig{}
+	conf.setDefault()
+
+	txt := filepath.Join(dir, "a.txt")
+	require.NoError(t, os.WriteFile(txt, []byte("第一章 风起\n\n祥子来到了北平。"), 0o600))
+	assert.Nil(t, validateUploadFile(conf, txt, "txt"))
+
+	// 内容与扩展名不一致
+	apiErr := validateUploadFile(conf, txt, "pdf")
+	require.NotNil(t, apiErr)
+	assert.Equal(t, ErrUnsupportedFileCode, apiErr.Code)
+
+	// 非 UTF-8 文本（GBK 编码的“第一章”）在后续转换编码
+	gbk := filepath.Join(dir, "gbk.txt")
+	require.NoError(t, os.WriteFile(gbk, []byte{0xb5, 0xda, 0xd2, 0xbb, 0xd5, 0xc2}, 0o600))
+	assert.Nil(t, validateUploadFile(conf, gbk, "txt"))
+
+	// 没有 BOM 的 UTF-16 文本按内容探测不是 text/plain，能检测出编码时接受
+	utf16File := filepath.Join(dir, "utf16.txt")
+	var utf16Data []byte
+	for _, r := range utf16.Encode([]rune("Chapter 1\n\nXiangzi came to Peking and pulled a rickshaw.")) {
+		utf16Data = append(utf16Data, byte(r), byte(r>>8))
+	}
+	require.NoError(t, os.WriteFile(utf16File, utf16Data, 0o600))
+	assert.Nil(t, validateUploadFile(conf, utf16File, "txt"))
+	// 无法检测编码的二进制内容
+	bin := filepath.Join(dir, "bin.txt")
+	require.NoError(t, os.WriteFile(bin, []byte{0x00, 0x01, 0x02, 0x03, 0xff, 0xfe, 0x00, 0x00, 0x80, 0x81}, 0o600))
+	apiErr = validateUploadFile(conf, bin, "txt")
+	require.NotNil(t, apiErr)
+	assert.Equal(t, ErrUnsupportedFileCode, apiErr.Code)
+
+	// 旧版 Word 的二进制格式（OLE 复合文档）不支持
+	doc := filepath.Join(dir, "a.doc")
+	oleData := append([]byte{0xd0, 0xcf, 0x11, 0xe0, 0xa1, 0xb1, 0x1a, 0xe1}, make([]byte, 1024)...)
+	require.NoError(t, os.WriteFile(doc, oleData, 0o600))
+	apiErr = validateUploadFile(conf, doc, "doc")
+	require.NotNil(t, apiErr)
+	assert.Equal(t, ErrUnsupportedFileCode, apiErr.Code)
+	assert.Contains(t, apiErr.Message, ".docx")
+
+	// EPUB 要求 mimetype 为第一个文件
+	epub := filepath.Join(dir, "book.epub")
+	f, err := os.Create(epub)
+	require.NoError(t, err)
+	w := zip.NewWriter(f)
+	mw, err := w.CreateHeader(&zip.FileHeader{Name: "mimetype", Method: zip.Store})
+	require.NoError(t, err)
+	_, err = mw.Write([]byte("application/epub+zip"))
+	require.NoError(t, err)
+	cw, err := w.Create("META-INF/container.xml")
+	require.NoError(t, err)
+	_, err = cw.Write([]byte("<container/>"))
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+	require.NoError(t, f.Close())
+	assert.Nil(t, validateUploadFile(conf, epub, "epub"))
+
+	html := filepath.Join(dir, "a.html")
+	require.NoError(t, os.WriteFile(html, []byte("<html><body><p>第一章</p></body></html>"), 0o600))
+	assert.Nil(t, validateUploadFile(conf, html, "html"))
+	rtf := filepath.Join(dir, "a.rtf")
+	require.NoError(t, os.WriteFile(rtf, []byte(`{\rtf1\ansi 第一章\par}`), 0o600))
+	assert.Nil(t, validateUploadFile(conf, rtf, "rtf"))
+	fb2 := filepath.Join(dir, "a.fb2")
+	require.NoError(t, os.WriteFile(fb2, []byte(`<?xml version="1.0"?><FictionBook><body/></FictionBook>`), 0o600))
+	assert.Nil(t, validateUploadFile(conf, fb2, "fb2"))
+	apiErr = validateUploadFile(conf, txt, "rtf")
+	require.NotNil(t, apiErr)
+	assert.Equal(t, ErrUnsupportedFileCode, apiErr.Code)
+	apiErr = validateUploadFile(conf, epub, "docx")
+	require.NotNil(t, apiErr)
+	assert.Equal(t, ErrUnsupportedFileCode, apiErr.Code)
+}
+
+func TestTranscodeUploadFile(t *testing.T) {
+	dir := t.TempDir()
+	gbkText := []byte{0xb5, 0xda, 0xd2, 0xbb, 0xd5, 0xc2, 0x20, 0xb7, 0xe7, 0xc6, 0xf0} // GBK 编码的“第一章 风起”
+
+	// 自动检测并转换为 UTF-8
+	gbk := filepath.Join(dir, "gbk.txt")
+	require.NoError(t, os.WriteFile(gbk, gbkText, 0o600))
+	encoding, apiErr := transcodeUploadFile(gbk, "")
+	require.Nil(t, apiErr)
+	assert.Equal(t, "gbk", encoding)
+	b, err := os.ReadFile(gbk)
+	require.NoError(t, err)
+	assert.Equal(t, "第一章 风起", string(b))
+
+	// 指定编码与内容不一致
+	bad := filepath.Join(dir, "bad.txt")
+	require.NoError(t, os.WriteFile(bad, gbkText, 0o600))
+	_, apiErr = transcodeUploadFile(bad, "utf-8")
+	require.NotNil(t, apiErr)
+	assert.Equal(t, ErrInvalidFileCode, apiErr.Code)
+
+	// UTF-8 BOM 被去掉
+	bom := filepath.Join(dir, "bom.txt")
+	require.NoError(t, os.WriteFile(bom, []byte("\xef\xbb\xbf第一章"), 0o600))
+	encoding, apiErr = transcodeUploadFile(bom, "")
+	require.Nil(t, apiErr)
+	assert.Equal(t, "utf-8", encoding)
+	b, err = os.ReadFile(bom)
+	require.NoError(t, err)
+	assert.Equal(t, "第一章", string(b))
+
+	encoding, apiErr = uploadEncoding("GB2312")
+	require.Nil(t, apiErr)
+	assert.Equal(t, "gbk", encoding)
+	_, apiErr = uploadEncoding("latin9")
+	require.NotNil(t, apiErr)
+	assert.Equal(t, http.StatusBadRequest, apiErr.Code)
+}
+
+func TestCheckZipLimits(t *testing.T) {
+	dir := t.TempDir()
+	conf := UploadConfig{}
+	conf.setDefault()
+
+	normal := filepath.Join(dir, "normal.zip")
+	writeTestZip(t, normal, map[string][]byte{"word/document.xml": []byte("<w:document/>")})
+	assert.Nil(t, checkZipLimits(conf, normal))
+
+	// 高压缩比的大文件
+	bomb := filepath.Join(dir, "bomb.zip")
+	writeTestZip(t, bomb, map[string][]byte{"word/document.xml": make([]byte, 8<<20)})
+	apiErr := checkZipLimits(conf, bomb)
+	require.NotNil(t, apiErr)
+	assert.Equal(t, ErrInvalidFileCode, apiErr.Code)
+
+	// 文件数超限
+	conf.MaxZipEntries = 2
+	many := filepath.Join(dir, "many.zip")
+	writeTestZip(t, many, map[string][]byte{"a": nil, "b": nil, "c": nil})
+	apiErr = checkZipLimits(conf, many)
+	require.NotNil(t, apiErr)
+	assert.Equal(t, ErrInvalidFileCode, apiErr.Code)
+
+	// 非 zip 文件
+	notZip := filepath.Join(dir, "a.docx")
+	require.NoError(t, os.WriteFile(notZip, []byte("plain text"), 0o600))
+	assert.NotNil(t, checkZipLimits(conf, notZip))
+}
+
+func TestCreateDocumentUploadValidation(t *testing.T) {
+	service, cleanup := setupTestService(t)
+	defer cleanup()
+	service.conf.Upload = UploadConfig{MaxSizeMB: 1}
+	service.conf.Upload.setDefault()
+
+	router := testRouter(service, testAdminToken)
+
+	upload := func(filename string, content []byte) proto.BaseResponse {
+		body := &bytes.Buffer{}
+		writer := multipart.NewWriter(body)
+		writer.WriteField("name", "测试文档")
+		part, err := writer.CreateFormFile("file", filename)
+		require.NoError(t, err)
+		_, err = part.Write(content)
+		require.NoError(t, err)
+		writer.Close()
+
+		req := httptest.NewRequest(http.MethodPost, "/v1/documents", body)
+		req.Header.Set("Content-Type", writer.FormDataContentType())
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		var resp proto.BaseResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		return resp
+	}
+
+	t.Run("文件过大", func(t *testing.T) {
+		resp := upload("big.txt", bytes.Repeat([]byte("a"), 3<<20))
+		assert.Equal(t, ErrFileTooLargeCode, resp.Code)
+	})
+
+	t.Run("不支持的文件类型", func(t *testing.T) {
+		resp := upload("run.exe", []byte("MZ"))
+		assert.Equal(t, ErrUnsupportedFileCode, resp.Code)
+	})
+
+	t.Run("内容与扩展名不一致", func(t *testing.T) {
+		resp := upload("fake.pdf", []byte("这不是 PDF 文件"))
+		assert.Equal(t, ErrUnsupportedFileCode, resp.Code)
+	})
+}
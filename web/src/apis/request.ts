@@ -6,9 +6,13 @@ const request: AxiosInstance = axios.create({
   timeout: 30000,
 })
 
-// 请求拦截器
+// 请求拦截器，携带 xrobot 登录后保存的 token
 request.interceptors.request.use(
   (config) => {
+    const token = localStorage.getItem('token')
+    if (token) {
+      config.headers.Authorization = `Bearer ${token}`
+    }
     return config
   },
   (error) => {